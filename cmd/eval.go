@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"personatrip/internal/config"
+	"personatrip/internal/eval"
+	"personatrip/internal/models"
+	"personatrip/internal/repository"
+	"personatrip/internal/secrets"
+	"personatrip/internal/services"
+	"personatrip/internal/utils/logger"
+	"personatrip/pkg/einosdk"
+)
+
+// evalEndpoint 评测调用模型时记录的调用来源
+const evalEndpoint = "eval"
+
+// errRegression 评测结果相对基线出现退化
+var errRegression = errors.New("评测结果相对基线出现退化")
+
+// Eval 运行离线评测：用指定的模型配置生成评测集中的每个用例并自动打分，
+// 输出JSON和Markdown报告，指定基线时附带与基线的对比
+func Eval(args []string) error {
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	goldenFile := flags.String("golden", "testdata/eval/golden.yaml", "评测集文件，YAML格式的用例数组或每行一个用例的JSONL")
+	model := flags.String("model", "", "模型配置的ID或名称，为空时使用当前激活的配置")
+	promptVersion := flags.Int("prompt-version", 0, "trip_plan提示词版本，为0时使用当前启用的版本")
+	baselineFile := flags.String("baseline", "", "作为基线的JSON报告，为空时不对比")
+	updateBaseline := flags.Bool("update-baseline", false, "评测完成后用本次结果覆盖基线文件，出现退化时不覆盖并以非0状态退出")
+	failOnRegression := flags.Bool("fail-on-regression", false, "有用例相对基线退化时以非0状态退出")
+	outDir := flags.String("out", "eval-report", "报告输出目录")
+	concurrency := flags.Int("concurrency", 1, "同时生成的用例数")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *updateBaseline && *baselineFile == "" {
+		return fmt.Errorf("-update-baseline需要同时指定-baseline")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if cfg.LogConfig != nil {
+		logger.SetLogLevel(cfg.LogConfig.Level)
+	}
+	keyring, err := secrets.LoadKeyring(cfg.SecretConfig, cfg.Environment)
+	if err != nil {
+		return err
+	}
+	secrets.SetDefault(keyring)
+	if err := einosdk.ConfigureReplay(cfg.LLMConfig.ReplayDir, einosdk.ReplayMode(cfg.LLMConfig.ReplayMode)); err != nil {
+		return err
+	}
+
+	cases, err := eval.LoadCases(*goldenFile)
+	if err != nil {
+		return err
+	}
+	var baseline *eval.Report
+	if *baselineFile != "" {
+		baseline, err = eval.LoadReport(*baselineFile)
+		if err != nil && !(*updateBaseline && errors.Is(err, os.ErrNotExist)) {
+			return err
+		}
+	}
+
+	mysqlDB, err := repository.NewMySQL(cfg.MySQLDSN)
+	if err != nil {
+		return fmt.Errorf("连接MySQL失败: %w", err)
+	}
+	defer mysqlDB.Close()
+	db := repository.NewGormDatabase(mysqlDB.DB)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx = services.WithCallInfo(ctx, "", evalEndpoint)
+
+	configService := services.NewModelConfigService(db)
+	modelConfig, err := findModelConfig(ctx, configService, *model)
+	if err != nil {
+		return err
+	}
+
+	// 评测不使用缓存、A/B实验和生成过程记录，用量照常记录
+	einoService := services.NewEinoService(configService, services.NewUsageService(db), services.NewPromptService(db), nil, nil, nil, services.NewExchangeRateService(db), cfg.LLMConfig)
+	defer einoService.Close()
+
+	logger.Infof("使用模型配置 %s(ID: %d) 评测 %d 个用例", modelConfig.Name, modelConfig.ID, len(cases))
+	report := eval.NewRunner(einoService, modelConfig, *promptVersion, *concurrency).Run(ctx, cases)
+	report.GoldenFile = *goldenFile
+	if baseline != nil {
+		report.Compare(baseline, *baselineFile)
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		return err
+	}
+	jsonPath := filepath.Join(*outDir, "report.json")
+	markdownPath := filepath.Join(*outDir, "report.md")
+	if err := report.WriteJSON(jsonPath); err != nil {
+		return fmt.Errorf("保存评测报告失败: %w", err)
+	}
+	if err := report.WriteMarkdown(markdownPath); err != nil {
+		return fmt.Errorf("保存评测报告失败: %w", err)
+	}
+	logger.Infof("评测完成，平均分 %.3f，报告已保存到 %s 和 %s", report.Summary.Score, jsonPath, markdownPath)
+
+	// 更新基线时会去掉报告中的对比，先取出退化的用例数
+	regressions := 0
+	if report.Baseline != nil {
+		regressions = report.Baseline.Regressions
+	}
+
+	if *updateBaseline {
+		// 出现退化时不覆盖基线，避免退化的结果成为新的基线后不再被发现
+		if regressions > 0 {
+			return fmt.Errorf("%w: %d 个用例，未更新基线", errRegression, regressions)
+		}
+		// 基线只保存本次结果，不嵌套与旧基线的对比
+		report.Baseline = nil
+		if err := report.WriteJSON(*baselineFile); err != nil {
+			return fmt.Errorf("更新基线失败: %w", err)
+		}
+		logger.Infof("已更新基线 %s", *baselineFile)
+	}
+
+	if *failOnRegression && regressions > 0 {
+		return fmt.Errorf("%w: %d 个用例", errRegression, regressions)
+	}
+	return nil
+}
+
+// findModelConfig 按ID或名称查找模型配置，model为空时返回当前激活的配置
+func findModelConfig(ctx context.Context, configService services.ModelConfigService, model string) (*models.ModelConfig, error) {
+	if model == "" {
+		config, err := configService.GetActiveModelConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取激活的模型配置失败: %w", err)
+		}
+		return config, nil
+	}
+	if id, err := strconv.ParseUint(model, 10, 64); err == nil {
+		config, err := configService.GetModelConfigByID(ctx, uint(id))
+		if err != nil {
+			return nil, fmt.Errorf("获取模型配置%d失败: %w", id, err)
+		}
+		return config, nil
+	}
+
+	configs, err := configService.GetAllModelConfigs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取模型配置失败: %w", err)
+	}
+	for i := range configs {
+		if configs[i].Name == model {
+			return &configs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("模型配置不存在: %s", model)
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"personatrip/internal/config"
+	"personatrip/internal/mcpserver"
+	"personatrip/internal/repository"
+	"personatrip/internal/secrets"
+	"personatrip/internal/services"
+	"personatrip/internal/utils/logger"
+	"personatrip/pkg/einosdk"
+)
+
+// mcpServerVersion 对MCP客户端报告的服务器版本
+const mcpServerVersion = "1.0.0"
+
+// apiTokenEnv stdio模式下未指定-token时读取API令牌的环境变量
+const apiTokenEnv = "PERSONATRIP_API_TOKEN"
+
+// MCPServe 把PersonaTrip作为MCP服务器运行，通过stdio或SSE向其他智能体和IDE助手提供旅行规划工具。
+// stdio模式下进程代表API令牌所属的用户，标准输出只用于MCP消息，日志输出到标准错误；
+// SSE模式下每个请求在Authorization请求头中带上各自用户的API令牌
+func MCPServe(args []string) error {
+	flags := flag.NewFlagSet("mcp-serve", flag.ContinueOnError)
+	transport := flags.String("transport", "stdio", "传输方式: stdio或sse")
+	addr := flags.String("addr", ":8090", "SSE模式监听的地址")
+	baseURL := flags.String("base-url", "", "SSE模式下客户端访问本服务的地址，为空时由监听地址得出")
+	token := flags.String("token", os.Getenv(apiTokenEnv), "stdio模式使用的用户API令牌，默认读取环境变量"+apiTokenEnv)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *transport != "stdio" && *transport != "sse" {
+		return fmt.Errorf("不支持的传输方式: %s", *transport)
+	}
+	if *transport == "stdio" && *token == "" {
+		return fmt.Errorf("stdio模式需要通过-token或环境变量%s指定API令牌", apiTokenEnv)
+	}
+
+	// stdio模式的标准输出只能写MCP消息，日志改到标准错误
+	if *transport == "stdio" {
+		logger.SetConsole(os.Stderr)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if cfg.LogConfig != nil {
+		logger.SetLogLevel(cfg.LogConfig.Level)
+		logger.SetLogOutput(cfg.LogConfig.Path)
+	}
+	keyring, err := secrets.LoadKeyring(cfg.SecretConfig, cfg.Environment)
+	if err != nil {
+		return err
+	}
+	secrets.SetDefault(keyring)
+	if err := einosdk.ConfigureReplay(cfg.LLMConfig.ReplayDir, einosdk.ReplayMode(cfg.LLMConfig.ReplayMode)); err != nil {
+		return err
+	}
+
+	mysqlDB, err := repository.NewMySQL(cfg.MySQLDSN)
+	if err != nil {
+		return fmt.Errorf("连接MySQL失败: %w", err)
+	}
+	defer mysqlDB.Close()
+	db := repository.NewGormDatabase(mysqlDB.DB)
+	mongoDB, err := repository.NewMongoDB(cfg.MongoURI)
+	if err != nil {
+		return fmt.Errorf("连接MongoDB失败: %w", err)
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := mongoDB.Close(closeCtx); err != nil {
+			logger.Errorf("关闭MongoDB连接失败: %v", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// 与HTTP服务使用相同的缓存、A/B实验、生成过程记录和MCP提供者，同样探测并自动重启MCP服务器
+	experiments := services.NewExperimentService(db)
+	einoService := services.NewEinoService(services.NewModelConfigService(db), services.NewUsageService(db), services.NewPromptService(db), experiments,
+		services.NewPlanCacheService(mongoDB, cfg.PlanCacheConfig), services.NewTraceService(mongoDB, cfg.TraceConfig), services.NewExchangeRateService(db), cfg.LLMConfig)
+	defer einoService.Close()
+	mcpServers := services.NewMCPServerService(db, einoService)
+	mcpServers.Start(ctx)
+	defer mcpServers.Stop()
+	supervisor := services.NewMCPSupervisor(einoService, cfg.MCPConfig)
+	supervisor.Start(ctx)
+	defer supervisor.Stop()
+	toolCache := services.NewMCPToolCacheService(einoService, mongoDB, cfg.MCPToolCacheConfig)
+	toolCache.Start(ctx)
+	defer toolCache.Stop()
+
+	srv := mcpserver.New(einoService, mongoDB, services.NewAuthService(db, cfg.JWTSecret), experiments, mcpServerVersion)
+	if *transport == "stdio" {
+		return srv.ServeStdio(ctx, *token, os.Stdin, os.Stdout)
+	}
+	return srv.ServeSSE(ctx, *addr, *baseURL)
+}
@@ -73,6 +73,9 @@ func Execute() error {
 		logger.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// 停止后台任务
+	application.Close()
+
 	logger.Info("Server exiting")
 	return nil
 }
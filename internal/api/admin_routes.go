@@ -7,7 +7,7 @@ import (
 )
 
 // SetupAdminRoutes 设置管理员相关路由
-func SetupAdminRoutes(router *gin.Engine, adminHandler *handlers.AdminHandler, modelConfigHandler *handlers.ModelConfigHandler, jwtSecret string) {
+func SetupAdminRoutes(router *gin.Engine, adminHandler *handlers.AdminHandler, modelConfigHandler *handlers.ModelConfigHandler, usageHandler *handlers.UsageHandler, planCacheHandler *handlers.PlanCacheHandler, promptHandler *handlers.PromptHandler, experimentHandler *handlers.ExperimentHandler, traceHandler *handlers.TraceHandler, mcpHandler *handlers.MCPHandler, exchangeRateHandler *handlers.ExchangeRateHandler, jwtSecret string) {
 	// 管理员API组
 	adminGroup := router.Group("/api/admin")
 
@@ -35,10 +35,88 @@ func SetupAdminRoutes(router *gin.Engine, adminHandler *handlers.AdminHandler, m
 		modelGroup.POST("", modelConfigHandler.Create)
 		modelGroup.GET("", modelConfigHandler.GetAll)
 		modelGroup.GET("/active", modelConfigHandler.GetActive)
+		modelGroup.GET("/providers", modelConfigHandler.GetProviders)
 		modelGroup.GET("/:id", modelConfigHandler.GetByID)
 		modelGroup.PUT("/:id", modelConfigHandler.Update)
 		modelGroup.DELETE("/:id", modelConfigHandler.Delete)
 		modelGroup.POST("/:id/activate", modelConfigHandler.SetActive)
 		modelGroup.POST("/:id/test", modelConfigHandler.TestModel)
+		modelGroup.GET("/:id/health", modelConfigHandler.GetHealth)
+		modelGroup.POST("/:id/health-check", modelConfigHandler.CheckHealth)
+	}
+
+	// 大模型用量和费用统计
+	usageGroup := authGroup.Group("/usage")
+	{
+		usageGroup.GET("/users", usageHandler.GetByUser)
+		usageGroup.GET("/models", usageHandler.GetByModel)
+		usageGroup.GET("/daily", usageHandler.GetByDay)
+	}
+
+	// 旅行计划缓存管理
+	cacheGroup := authGroup.Group("/cache/plans")
+	{
+		cacheGroup.GET("", planCacheHandler.List)
+		cacheGroup.DELETE("", planCacheHandler.InvalidateAll)
+		cacheGroup.DELETE("/:hash", planCacheHandler.Invalidate)
+	}
+
+	// MCP工具调用结果缓存管理
+	toolCacheGroup := authGroup.Group("/cache/mcp-tools")
+	{
+		toolCacheGroup.GET("", mcpHandler.CacheStats)
+		toolCacheGroup.DELETE("", mcpHandler.ClearCache)
+	}
+
+	// 提示词模板管理
+	promptGroup := authGroup.Group("/prompts")
+	{
+		promptGroup.GET("/definitions", promptHandler.GetDefinitions)
+		promptGroup.POST("/preview", promptHandler.Preview)
+		promptGroup.POST("", promptHandler.Create)
+		promptGroup.GET("", promptHandler.List)
+		promptGroup.GET("/:id", promptHandler.GetByID)
+		promptGroup.DELETE("/:id", promptHandler.Delete)
+		promptGroup.POST("/:id/activate", promptHandler.Activate)
+	}
+
+	// A/B实验管理
+	experimentGroup := authGroup.Group("/experiments")
+	{
+		experimentGroup.POST("", experimentHandler.Create)
+		experimentGroup.GET("", experimentHandler.List)
+		experimentGroup.GET("/:id", experimentHandler.GetByID)
+		experimentGroup.DELETE("/:id", experimentHandler.Delete)
+		experimentGroup.POST("/:id/start", experimentHandler.Start)
+		experimentGroup.POST("/:id/stop", experimentHandler.Stop)
+		experimentGroup.GET("/:id/report", experimentHandler.Report)
+	}
+
+	// 生成过程记录
+	traceGroup := authGroup.Group("/traces")
+	{
+		traceGroup.GET("", traceHandler.List)
+		traceGroup.GET("/:id", traceHandler.GetByID)
+	}
+	authGroup.GET("/plans/:id/trace", traceHandler.GetByPlanID)
+
+	// 汇率管理，智能体的货币换算工具使用
+	exchangeRateGroup := authGroup.Group("/exchange-rates")
+	{
+		exchangeRateGroup.GET("", exchangeRateHandler.List)
+		exchangeRateGroup.PUT("/:currency", exchangeRateHandler.Set)
+		exchangeRateGroup.DELETE("/:currency", exchangeRateHandler.Delete)
+	}
+
+	// MCP提供者管理（仅超级管理员可访问，stdio服务器会在服务器上执行命令）
+	mcpGroup := authGroup.Group("/mcp/providers")
+	mcpGroup.Use(middleware.RequireSuperAdmin())
+	{
+		mcpGroup.GET("", mcpHandler.List)
+		mcpGroup.POST("", mcpHandler.Create)
+		mcpGroup.GET("/:name", mcpHandler.GetByName)
+		mcpGroup.DELETE("/:name", mcpHandler.Delete)
+		mcpGroup.POST("/:name/restart", mcpHandler.Restart)
+		mcpGroup.POST("/:name/tools/:tool/call", mcpHandler.CallTool)
 	}
 }
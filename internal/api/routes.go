@@ -14,8 +14,16 @@ func SetupRoutes(
 	router *gin.Engine,
 	authHandler *handlers.AuthHandler,
 	tripHandler *handlers.TripHandler,
+	tripJobHandler *handlers.TripJobHandler,
 	adminHandler *handlers.AdminHandler,
 	modelConfigHandler *handlers.ModelConfigHandler,
+	usageHandler *handlers.UsageHandler,
+	planCacheHandler *handlers.PlanCacheHandler,
+	promptHandler *handlers.PromptHandler,
+	experimentHandler *handlers.ExperimentHandler,
+	traceHandler *handlers.TraceHandler,
+	mcpHandler *handlers.MCPHandler,
+	exchangeRateHandler *handlers.ExchangeRateHandler,
 	authMiddleware gin.HandlerFunc,
 	jwtSecret string,
 ) {
@@ -29,12 +37,18 @@ func SetupRoutes(
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
 			auth.GET("/profile", authMiddleware, authHandler.GetProfile)
+			auth.POST("/tokens", authMiddleware, authHandler.CreateAPIToken)
+			auth.GET("/tokens", authMiddleware, authHandler.ListAPITokens)
+			auth.DELETE("/tokens/:id", authMiddleware, authHandler.DeleteAPIToken)
 		}
 
 		// 旅行计划相关路由
 		trips := api.Group("/trips")
 		{
 			trips.POST("/generate", authMiddleware, tripHandler.GenerateTripPlan)
+			trips.POST("/generate/stream", authMiddleware, tripHandler.GenerateTripPlanStream)
+			trips.POST("/jobs", authMiddleware, tripJobHandler.Submit)
+			trips.GET("/jobs/:id", authMiddleware, tripJobHandler.GetJob)
 			trips.GET("/:id", tripHandler.GetTripPlan)
 			trips.GET("/user", authMiddleware, tripHandler.GetUserTripPlans)
 			trips.PUT("/:id", authMiddleware, tripHandler.UpdateTripPlan)
@@ -49,7 +63,7 @@ func SetupRoutes(
 	}
 
 	// 设置管理员路由
-	SetupAdminRoutes(router, adminHandler, modelConfigHandler, jwtSecret)
+	SetupAdminRoutes(router, adminHandler, modelConfigHandler, usageHandler, planCacheHandler, promptHandler, experimentHandler, traceHandler, mcpHandler, exchangeRateHandler, jwtSecret)
 
 	// Swagger文档
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -9,8 +9,10 @@ import (
 	"personatrip/internal/middleware"
 	"personatrip/internal/models"
 	"personatrip/internal/repository"
+	"personatrip/internal/secrets"
 	"personatrip/internal/services"
 	"personatrip/internal/utils/logger"
+	"personatrip/pkg/einosdk"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,7 +29,11 @@ type Application struct {
 
 // Repositories 包含所有仓库实例
 type Repositories struct {
-	TripRepo handlers.TripRepository
+	TripRepo      handlers.TripRepository
+	TripJobRepo   services.TripJobRepository
+	PlanCacheRepo services.PlanCacheRepository
+	TraceRepo     services.GenerationTraceRepository
+	ToolCacheRepo services.MCPToolCacheRepository
 }
 
 // Services 包含所有服务实例
@@ -35,15 +41,34 @@ type Services struct {
 	AuthService        *services.AuthService
 	AdminService       services.AdminService
 	ModelConfigService services.ModelConfigService
+	UsageService       services.UsageService
+	PromptService      services.PromptService
+	ExperimentService  services.ExperimentService
+	PlanCacheService   *services.PlanCacheService
+	TraceService       *services.TraceService
+	ModelHealthService *services.ModelHealthService
+	MCPServerService   *services.MCPServerService
+	MCPSupervisor      *services.MCPSupervisor
+	MCPToolCache       *services.MCPToolCacheService
+	ExchangeRates      *services.ExchangeRateService
 	EinoService        handlers.EinoServiceInterface
+	TripJobService     *services.TripJobService
 }
 
 // Handlers 包含所有处理程序实例
 type Handlers struct {
-	AuthHandler        *handlers.AuthHandler
-	AdminHandler       *handlers.AdminHandler
-	ModelConfigHandler *handlers.ModelConfigHandler
-	TripHandler        *handlers.TripHandler
+	AuthHandler         *handlers.AuthHandler
+	AdminHandler        *handlers.AdminHandler
+	ModelConfigHandler  *handlers.ModelConfigHandler
+	UsageHandler        *handlers.UsageHandler
+	PlanCacheHandler    *handlers.PlanCacheHandler
+	PromptHandler       *handlers.PromptHandler
+	ExperimentHandler   *handlers.ExperimentHandler
+	TraceHandler        *handlers.TraceHandler
+	MCPHandler          *handlers.MCPHandler
+	ExchangeRateHandler *handlers.ExchangeRateHandler
+	TripHandler         *handlers.TripHandler
+	TripJobHandler      *handlers.TripJobHandler
 }
 
 // New 创建并初始化一个新的应用实例
@@ -54,6 +79,26 @@ func New() (*Application, error) {
 		logger.Errorf("Failed to load config: %v", err)
 		return nil, err
 	}
+	// 注册自定义请求校验规则
+	if err := models.RegisterValidations(); err != nil {
+		logger.Errorf("Failed to register validations: %v", err)
+		return nil, err
+	}
+	// 加载加密模型API密钥的主密钥
+	keyring, err := secrets.LoadKeyring(cfg.SecretConfig, cfg.Environment)
+	if err != nil {
+		logger.Errorf("Failed to load secret master keys: %v", err)
+		return nil, err
+	}
+	if !keyring.Enabled() {
+		logger.Warn("未配置SECRET_MASTER_KEYS，模型API密钥将以明文保存")
+	}
+	secrets.SetDefault(keyring)
+	// 配置录制回放模型的录制目录和工作模式
+	if err := einosdk.ConfigureReplay(cfg.LLMConfig.ReplayDir, einosdk.ReplayMode(cfg.LLMConfig.ReplayMode)); err != nil {
+		logger.Errorf("Failed to configure replay model: %v", err)
+		return nil, err
+	}
 	// 创建应用实例
 	app := &Application{
 		Router: gin.Default(),
@@ -79,6 +124,14 @@ func New() (*Application, error) {
 	}
 	// 创建默认的大模型配置
 	app.createDefaultModelConfigIfNeeded()
+	// 用当前主密钥重新加密模型API密钥和MCP服务器的密钥
+	app.rotateSecrets()
+
+	// 启动后台任务
+	err = app.startBackgroundServices()
+	if err != nil {
+		return nil, err
+	}
 
 	return app, nil
 }
@@ -109,6 +162,10 @@ func (a *Application) initRepositories() error {
 		return err
 	} else {
 		a.Repositories.TripRepo = mongoDB
+		a.Repositories.TripJobRepo = mongoDB
+		a.Repositories.PlanCacheRepo = mongoDB
+		a.Repositories.TraceRepo = mongoDB
+		a.Repositories.ToolCacheRepo = mongoDB
 	}
 	return nil
 }
@@ -119,19 +176,41 @@ func (a *Application) initServices() {
 		AuthService:        services.NewAuthService(a.DB, a.Cfg.JWTSecret),
 		AdminService:       services.NewAdminService(a.DB, a.Cfg.JWTSecret),
 		ModelConfigService: services.NewModelConfigService(a.DB),
+		UsageService:       services.NewUsageService(a.DB),
+		PromptService:      services.NewPromptService(a.DB),
+		ExperimentService:  services.NewExperimentService(a.DB),
+		PlanCacheService:   services.NewPlanCacheService(a.Repositories.PlanCacheRepo, a.Cfg.PlanCacheConfig),
+		TraceService:       services.NewTraceService(a.Repositories.TraceRepo, a.Cfg.TraceConfig),
+		ExchangeRates:      services.NewExchangeRateService(a.DB),
 	}
+	a.Services.ModelHealthService = services.NewModelHealthService(a.DB, a.Services.UsageService, a.Cfg.HealthCheckConfig)
 
 	// 初始化Eino服务
-	a.Services.EinoService = services.NewEinoService(a.Services.ModelConfigService)
+	einoService := services.NewEinoService(a.Services.ModelConfigService, a.Services.UsageService, a.Services.PromptService, a.Services.ExperimentService, a.Services.PlanCacheService, a.Services.TraceService, a.Services.ExchangeRates, a.Cfg.LLMConfig)
+	a.Services.EinoService = einoService
+	a.Services.MCPServerService = services.NewMCPServerService(a.DB, einoService)
+	a.Services.MCPSupervisor = services.NewMCPSupervisor(einoService, a.Cfg.MCPConfig)
+	a.Services.MCPToolCache = services.NewMCPToolCacheService(einoService, a.Repositories.ToolCacheRepo, a.Cfg.MCPToolCacheConfig)
+
+	// 初始化异步任务服务
+	a.Services.TripJobService = services.NewTripJobService(a.Services.EinoService, a.Repositories.TripJobRepo, a.Cfg.JobConfig)
 }
 
 // initHandlers 初始化所有处理程序
 func (a *Application) initHandlers() {
 	a.Handlers = &Handlers{
-		AuthHandler:        handlers.NewAuthHandler(a.Services.AuthService),
-		AdminHandler:       handlers.NewAdminHandler(a.Services.AdminService),
-		ModelConfigHandler: handlers.NewModelConfigHandler(a.Services.ModelConfigService, a.Services.EinoService),
-		TripHandler:        handlers.NewTripHandler(a.Services.EinoService, a.Repositories.TripRepo),
+		AuthHandler:         handlers.NewAuthHandler(a.Services.AuthService),
+		AdminHandler:        handlers.NewAdminHandler(a.Services.AdminService),
+		ModelConfigHandler:  handlers.NewModelConfigHandler(a.Services.ModelConfigService, a.Services.EinoService, a.Services.ModelHealthService),
+		UsageHandler:        handlers.NewUsageHandler(a.Services.UsageService),
+		PlanCacheHandler:    handlers.NewPlanCacheHandler(a.Services.PlanCacheService),
+		PromptHandler:       handlers.NewPromptHandler(a.Services.PromptService),
+		ExperimentHandler:   handlers.NewExperimentHandler(a.Services.ExperimentService),
+		TraceHandler:        handlers.NewTraceHandler(a.Services.TraceService),
+		MCPHandler:          handlers.NewMCPHandler(a.Services.MCPServerService, a.Services.MCPToolCache),
+		ExchangeRateHandler: handlers.NewExchangeRateHandler(a.Services.ExchangeRates),
+		TripHandler:         handlers.NewTripHandler(a.Services.EinoService, a.Repositories.TripRepo, a.Services.ExperimentService),
+		TripJobHandler:      handlers.NewTripJobHandler(a.Services.TripJobService),
 	}
 }
 
@@ -145,8 +224,16 @@ func (a *Application) setupRoutes() {
 		a.Router,
 		a.Handlers.AuthHandler,
 		a.Handlers.TripHandler,
+		a.Handlers.TripJobHandler,
 		a.Handlers.AdminHandler,
 		a.Handlers.ModelConfigHandler,
+		a.Handlers.UsageHandler,
+		a.Handlers.PlanCacheHandler,
+		a.Handlers.PromptHandler,
+		a.Handlers.ExperimentHandler,
+		a.Handlers.TraceHandler,
+		a.Handlers.MCPHandler,
+		a.Handlers.ExchangeRateHandler,
 		authMiddleware,
 		a.Cfg.JWTSecret,
 	)
@@ -214,6 +301,47 @@ func (a *Application) createDefaultModelConfigIfNeeded() {
 	}
 }
 
+// rotateSecrets 用当前主密钥重新加密以旧主密钥加密或以明文保存的模型API密钥和MCP服务器的环境变量、请求头
+func (a *Application) rotateSecrets() {
+	if !secrets.Default().Enabled() {
+		return
+	}
+	updated, err := a.Services.ModelConfigService.RotateSecrets(context.Background())
+	if err != nil {
+		logger.Errorf("轮换模型API密钥失败: %v", err)
+	} else if updated > 0 {
+		logger.Infof("已用主密钥%s重新加密%d个模型配置的API密钥", secrets.Default().PrimaryKeyID(), updated)
+	}
+	updated, err = a.Services.MCPServerService.RotateSecrets(context.Background())
+	if err != nil {
+		logger.Errorf("轮换MCP服务器密钥失败: %v", err)
+	} else if updated > 0 {
+		logger.Infof("已用主密钥%s重新加密%d个MCP服务器的密钥", secrets.Default().PrimaryKeyID(), updated)
+	}
+}
+
+// startBackgroundServices 启动后台任务服务
+func (a *Application) startBackgroundServices() error {
+	if err := a.Services.TripJobService.Start(context.Background()); err != nil {
+		logger.Errorf("Failed to start trip job service: %v", err)
+		return err
+	}
+	a.Services.ModelHealthService.Start(context.Background())
+	a.Services.MCPServerService.Start(context.Background())
+	a.Services.MCPSupervisor.Start(context.Background())
+	a.Services.MCPToolCache.Start(context.Background())
+	return nil
+}
+
+// Close 停止后台任务并释放资源
+func (a *Application) Close() {
+	a.Services.TripJobService.Stop()
+	a.Services.ModelHealthService.Stop()
+	a.Services.MCPServerService.Stop()
+	a.Services.MCPSupervisor.Stop()
+	a.Services.MCPToolCache.Stop()
+}
+
 // Run 启动应用程序
 func (a *Application) Run() error {
 	return a.Router.Run(a.Cfg.ServerAddress)
@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,7 +17,64 @@ type LogConfig struct {
 
 // MCPConfig MCP相关配置
 type MCPConfig struct {
-	AMapAPIKey string // 高德地图API密钥
+	AMapAPIKey        string        // 高德地图API密钥
+	ServersFile       string        // 声明其他MCP服务器的YAML或JSON文件，为空时只使用高德地图
+	PingInterval      time.Duration // 探测MCP服务器的间隔，为0时不探测也不自动重启
+	PingTimeout       time.Duration // 单次探测的最长时间
+	RestartBackoff    time.Duration // 第一次自动重启失败后的等待时间，之后每次失败翻倍
+	MaxRestartBackoff time.Duration // 自动重启的最长等待时间
+}
+
+// MCPToolCacheConfig MCP工具调用结果缓存配置
+type MCPToolCacheConfig struct {
+	DefaultTTL time.Duration            // 未单独配置有效期的工具的缓存有效期，为0时不缓存这些工具
+	ToolTTLs   map[string]time.Duration // 按工具名称配置的缓存有效期，为0时不缓存该工具
+	MaxEntries int                      // 内存中最多保存的结果数量，超出时淘汰最久未使用的结果
+	Persist    bool                     // 是否同时保存到MongoDB，重启后和其他实例也可以命中
+}
+
+// JobConfig 异步任务相关配置
+type JobConfig struct {
+	Workers     int           // 并发执行任务的worker数量
+	QueueSize   int           // 等待执行的任务队列长度
+	Timeout     time.Duration // 单个任务的最长执行时间
+	MaxAttempts int           // 任务最多执行次数，进程重启后恢复的任务也计入次数
+}
+
+// LLMConfig 大模型调用相关配置
+type LLMConfig struct {
+	AttemptTimeout  time.Duration // 单个模型配置的最长生成时间，超时后切换到备用链中的下一个配置
+	MaxRepairs      int           // 输出未通过校验时要求同一模型修正的最多次数，用完后切换到下一个配置
+	ReplayDir       string        // replay模型类型的录制文件目录
+	ReplayMode      string        // replay模型类型的工作模式: replay、record或auto
+	QueueTimeout    time.Duration // 模型配置达到并发或每分钟请求数限制时排队等待的最长时间，为0时不排队
+	PipelineMinDays int           // 行程天数达到该值时分阶段并行生成旅行计划，为0时总是一次生成完整计划
+	ToolPolicyFile  string        // 各场景智能体工具策略的YAML文件，为空时使用默认策略
+}
+
+// PlanCacheConfig 旅行计划缓存配置
+type PlanCacheConfig struct {
+	TTL time.Duration // 缓存有效期，为0时不使用缓存
+}
+
+// HealthCheckConfig 模型配置后台探测配置
+type HealthCheckConfig struct {
+	Interval        time.Duration // 探测间隔，为0时不进行后台探测
+	Timeout         time.Duration // 单次探测的最长时间
+	DegradedLatency time.Duration // 探测延迟达到该值时标记为degraded
+	DownThreshold   int           // 连续失败达到该次数时标记为down
+	Retention       time.Duration // 探测历史的保存时间
+}
+
+// SecretConfig 加密保存密钥所用的主密钥配置
+type SecretConfig struct {
+	MasterKeys    string // 逗号分隔的"ID:base64密钥"列表，第一个用于加密，其余只用于解密轮换前的数据
+	MasterKeyFile string // 每行一个"ID:base64密钥"的文件，排在MasterKeys之后
+}
+
+// TraceConfig 生成过程记录配置
+type TraceConfig struct {
+	TTL time.Duration // 记录的保存时间，为0时不记录
 }
 
 // Config 应用配置
@@ -25,12 +85,30 @@ type Config struct {
 	MongoURI           string
 	MySQLDSN           string
 	JWTSecret          string
-	CreateSuperAdmin   bool       // 是否创建超级管理员
-	SuperAdminUsername string     // 超级管理员用户名
-	SuperAdminPassword string     // 超级管理员密码
-	SuperAdminEmail    string     // 超级管理员邮箱
-	LogConfig          *LogConfig // 日志配置
-	MCPConfig          *MCPConfig // MCP相关配置
+	CreateSuperAdmin   bool                // 是否创建超级管理员
+	SuperAdminUsername string              // 超级管理员用户名
+	SuperAdminPassword string              // 超级管理员密码
+	SuperAdminEmail    string              // 超级管理员邮箱
+	LogConfig          *LogConfig          // 日志配置
+	MCPConfig          *MCPConfig          // MCP相关配置
+	MCPToolCacheConfig *MCPToolCacheConfig // MCP工具调用结果缓存配置
+	JobConfig          *JobConfig          // 异步任务相关配置
+	LLMConfig          *LLMConfig          // 大模型调用相关配置
+	PlanCacheConfig    *PlanCacheConfig    // 旅行计划缓存配置
+	TraceConfig        *TraceConfig        // 生成过程记录配置
+	HealthCheckConfig  *HealthCheckConfig  // 模型配置后台探测配置
+	SecretConfig       *SecretConfig       // 密钥加密配置
+}
+
+// defaultToolCacheTTLs 默认缓存的高德地图工具：地理编码结果基本不变，POI信息按天更新，天气按小时更新，
+// 路线规划受实时路况影响，默认不缓存
+var defaultToolCacheTTLs = map[string]time.Duration{
+	"maps_geo":           30 * 24 * time.Hour,
+	"maps_regeocode":     30 * 24 * time.Hour,
+	"maps_text_search":   24 * time.Hour,
+	"maps_around_search": 24 * time.Hour,
+	"maps_search_detail": 24 * time.Hour,
+	"maps_weather":       time.Hour,
 }
 
 // Load 从环境变量加载配置
@@ -57,7 +135,50 @@ func Load() (*Config, error) {
 			Path:  getEnv("LOG_PATH", ""),
 		},
 		MCPConfig: &MCPConfig{
-			AMapAPIKey: getEnv("AMAP_API_KEY", "66297b6685c934c7e48df4f6891091f3"),
+			AMapAPIKey:        getEnv("AMAP_API_KEY", "66297b6685c934c7e48df4f6891091f3"),
+			ServersFile:       getEnv("MCP_SERVERS_FILE", ""),
+			PingInterval:      getEnvDuration("MCP_PING_INTERVAL", 30*time.Second),
+			PingTimeout:       getEnvDuration("MCP_PING_TIMEOUT", 5*time.Second),
+			RestartBackoff:    getEnvDuration("MCP_RESTART_BACKOFF", 5*time.Second),
+			MaxRestartBackoff: getEnvDuration("MCP_MAX_RESTART_BACKOFF", 5*time.Minute),
+		},
+		MCPToolCacheConfig: &MCPToolCacheConfig{
+			DefaultTTL: getEnvDuration("MCP_TOOL_CACHE_TTL", 0),
+			ToolTTLs:   getEnvDurationMap("MCP_TOOL_CACHE_TTLS", defaultToolCacheTTLs),
+			MaxEntries: getEnvInt("MCP_TOOL_CACHE_SIZE", 1000),
+			Persist:    getEnvBool("MCP_TOOL_CACHE_PERSIST", false),
+		},
+		JobConfig: &JobConfig{
+			Workers:     getEnvInt("JOB_WORKERS", 4),
+			QueueSize:   getEnvInt("JOB_QUEUE_SIZE", 100),
+			Timeout:     getEnvDuration("JOB_TIMEOUT", 10*time.Minute),
+			MaxAttempts: getEnvInt("JOB_MAX_ATTEMPTS", 3),
+		},
+		LLMConfig: &LLMConfig{
+			AttemptTimeout:  getEnvDuration("LLM_ATTEMPT_TIMEOUT", 3*time.Minute),
+			MaxRepairs:      getEnvInt("LLM_MAX_REPAIRS", 2),
+			ReplayDir:       getEnv("LLM_REPLAY_DIR", "testdata/llm_fixtures"),
+			ReplayMode:      getEnv("LLM_REPLAY_MODE", "replay"),
+			QueueTimeout:    getEnvDuration("LLM_QUEUE_TIMEOUT", 30*time.Second),
+			PipelineMinDays: getEnvInt("LLM_PIPELINE_MIN_DAYS", 4),
+			ToolPolicyFile:  getEnv("TOOL_POLICY_FILE", ""),
+		},
+		PlanCacheConfig: &PlanCacheConfig{
+			TTL: getEnvDuration("PLAN_CACHE_TTL", 24*time.Hour),
+		},
+		TraceConfig: &TraceConfig{
+			TTL: getEnvDuration("TRACE_TTL", 7*24*time.Hour),
+		},
+		HealthCheckConfig: &HealthCheckConfig{
+			Interval:        getEnvDuration("HEALTH_CHECK_INTERVAL", 5*time.Minute),
+			Timeout:         getEnvDuration("HEALTH_CHECK_TIMEOUT", 30*time.Second),
+			DegradedLatency: getEnvDuration("HEALTH_CHECK_DEGRADED_LATENCY", 10*time.Second),
+			DownThreshold:   getEnvInt("HEALTH_CHECK_DOWN_THRESHOLD", 3),
+			Retention:       getEnvDuration("HEALTH_CHECK_RETENTION", 7*24*time.Hour),
+		},
+		SecretConfig: &SecretConfig{
+			MasterKeys:    getEnv("SECRET_MASTER_KEYS", ""),
+			MasterKeyFile: getEnv("SECRET_MASTER_KEY_FILE", ""),
 		},
 	}
 
@@ -85,3 +206,51 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return value == "true" || value == "1" || value == "yes"
 }
+
+// getEnvInt 获取整数类型的环境变量，解析失败时返回默认值
+func getEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+	return n
+}
+
+// getEnvDurationMap 获取"名称=时长"逗号分隔列表类型的环境变量（如 "maps_geo=720h,maps_weather=1h"），
+// 跳过无法解析的项，未设置时返回默认值
+func getEnvDurationMap(key string, defaultValue map[string]time.Duration) map[string]time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	result := make(map[string]time.Duration)
+	for _, item := range strings.Split(value, ",") {
+		name, duration, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil {
+			continue
+		}
+		result[strings.TrimSpace(name)] = d
+	}
+	return result
+}
+
+// getEnvDuration 获取时长类型的环境变量（如 "30s"、"10m"），解析失败时返回默认值
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue
+	}
+	return d
+}
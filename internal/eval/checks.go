@@ -0,0 +1,287 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"personatrip/internal/models"
+	"personatrip/internal/services"
+	"personatrip/internal/traveltools"
+)
+
+// 各项检查的名称
+const (
+	CheckCompleteness = "completeness" // Schema中有值的字段占比
+	CheckDayCount     = "day_count"    // 天数与日期范围一致
+	CheckBudget       = "budget"       // 每日预算之和与总预算一致
+	CheckCoordinates  = "coordinates"  // 坐标合理
+	CheckDietary      = "dietary"      // 餐饮符合饮食偏好
+)
+
+// CheckNames 按报告中的展示顺序排列的检查名称
+var CheckNames = []string{CheckCompleteness, CheckDayCount, CheckBudget, CheckCoordinates, CheckDietary}
+
+const (
+	// budgetTolerance 每日预算之和与总预算的相对误差在该范围内视为一致，允许四舍五入带来的误差
+	budgetTolerance = 0.01
+	// maxSpreadKm 坐标与计划中所有坐标中位点的距离超过该值时视为不合理，用于发现编造或经纬度颠倒的坐标
+	maxSpreadKm = 500
+	// maxDetails 每项检查在报告中列出的问题条数上限
+	maxDetails = 10
+)
+
+// CheckResult 一项检查的结果
+type CheckResult struct {
+	Name    string   `json:"name"`
+	Score   float64  `json:"score"`             // 0到1之间，1表示完全通过
+	Skipped bool     `json:"skipped,omitempty"` // 用例不适用该检查，不计入总分
+	Details []string `json:"details,omitempty"` // 扣分的原因
+}
+
+// Score 对生成的旅行计划逐项打分，返回各项检查的结果和未跳过的检查的平均分
+func Score(req *models.PlanRequest, plan *models.TripPlan) ([]CheckResult, float64) {
+	checks := []CheckResult{
+		checkCompleteness(plan),
+		checkDayCount(req, plan),
+		checkBudget(plan),
+		checkCoordinates(plan),
+		checkDietary(req, plan),
+	}
+	return checks, meanScore(checks)
+}
+
+// meanScore 计算未跳过的检查的平均分
+func meanScore(checks []CheckResult) float64 {
+	var total float64
+	count := 0
+	for _, c := range checks {
+		if !c.Skipped {
+			total += c.Score
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
+
+// addDetail 添加扣分原因，超过maxDetails条时只保留计数
+func (c *CheckResult) addDetail(format string, args ...interface{}) {
+	if len(c.Details) < maxDetails {
+		c.Details = append(c.Details, fmt.Sprintf(format, args...))
+	} else if len(c.Details) == maxDetails {
+		c.Details = append(c.Details, "...")
+	}
+}
+
+// checkCompleteness 按旅行计划的JSON Schema统计有值的字段占比。每个字段权重相同，
+// 对象字段取其属性的占比，对象数组取各元素占比的平均值，字符串和数组为空时视为缺失
+func checkCompleteness(plan *models.TripPlan) CheckResult {
+	result := CheckResult{Name: CheckCompleteness}
+	schema, err := services.TripPlanSchema()
+	if err != nil {
+		result.addDetail("生成Schema失败: %v", err)
+		return result
+	}
+	data, err := json.Marshal(plan)
+	if err != nil {
+		result.addDetail("序列化计划失败: %v", err)
+		return result
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		result.addDetail("序列化计划失败: %v", err)
+		return result
+	}
+
+	missing := make(map[string]bool)
+	result.Score = fillRatio(schema, value, "", missing)
+	paths := make([]string, 0, len(missing))
+	for path := range missing {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		result.addDetail("缺少%s", path)
+	}
+	return result
+}
+
+// fillRatio 计算value中有值的字段占比，缺失字段的路径写入missing，数组下标统一记为[]
+func fillRatio(schema *openapi3.Schema, value interface{}, path string, missing map[string]bool) float64 {
+	switch v := value.(type) {
+	case nil:
+		missing[path] = true
+		return 0
+	case string:
+		if strings.TrimSpace(v) == "" {
+			missing[path] = true
+			return 0
+		}
+		return 1
+	case []interface{}:
+		if len(v) == 0 {
+			missing[path] = true
+			return 0
+		}
+		if schema.Items == nil || schema.Items.Value == nil || len(schema.Items.Value.Properties) == 0 {
+			return 1
+		}
+		var total float64
+		for _, item := range v {
+			total += fillRatio(schema.Items.Value, item, path+"[]", missing)
+		}
+		return total / float64(len(v))
+	case map[string]interface{}:
+		if len(schema.Properties) == 0 {
+			return 1
+		}
+		var total float64
+		for name, prop := range schema.Properties {
+			if prop.Value == nil {
+				continue
+			}
+			total += fillRatio(prop.Value, v[name], joinPath(path, name), missing)
+		}
+		return total / float64(len(schema.Properties))
+	default:
+		// 数字和布尔值为0或false时也可能是有效值
+		return 1
+	}
+}
+
+// joinPath 拼接字段路径
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// checkDayCount 检查计划的天数与请求的日期范围一致，且每天按第1天开始依次编号
+func checkDayCount(req *models.PlanRequest, plan *models.TripPlan) CheckResult {
+	result := CheckResult{Name: CheckDayCount}
+	expected := services.TripDays(req.StartDate, req.EndDate)
+	if len(plan.Days) != expected {
+		result.addDetail("日期范围为%d天，计划有%d天", expected, len(plan.Days))
+		return result
+	}
+	for i, day := range plan.Days {
+		if day.Day != i+1 {
+			result.addDetail("第%d个日程的编号为%d", i+1, day.Day)
+			return result
+		}
+	}
+	result.Score = 1
+	return result
+}
+
+// checkBudget 检查每日预算之和与总预算一致，相对误差超过budgetTolerance时按误差扣分
+func checkBudget(plan *models.TripPlan) CheckResult {
+	result := CheckResult{Name: CheckBudget}
+	budget := plan.Budget
+	if len(budget.DailyBreakdown) == 0 {
+		result.addDetail("缺少每日预算")
+		return result
+	}
+	if budget.TotalEstimate <= 0 {
+		result.addDetail("总预算为%.2f", budget.TotalEstimate)
+		return result
+	}
+
+	var sum float64
+	for _, day := range budget.DailyBreakdown {
+		sum += day.Total
+	}
+	relErr := math.Abs(sum-budget.TotalEstimate) / budget.TotalEstimate
+	if relErr <= budgetTolerance {
+		result.Score = 1
+		return result
+	}
+	result.Score = math.Max(0, 1-relErr)
+	result.addDetail("每日预算之和为%.2f，总预算为%.2f，相差%.1f%%", sum, budget.TotalEstimate, relErr*100)
+	return result
+}
+
+// checkCoordinates 检查活动、餐饮和住宿的坐标：经纬度在有效范围内、不为(0, 0)，
+// 且与所有坐标中位点的距离不超过maxSpreadKm，得分为合理坐标的占比
+func checkCoordinates(plan *models.TripPlan) CheckResult {
+	result := CheckResult{Name: CheckCoordinates}
+
+	type place struct {
+		label  string
+		coords models.Coordinates
+	}
+	var places []place
+	for _, day := range plan.Days {
+		for _, activity := range day.Activities {
+			places = append(places, place{fmt.Sprintf("第%d天活动%s", day.Day, activity.Name), activity.Location.Coordinates})
+		}
+		for _, meal := range day.Meals {
+			places = append(places, place{fmt.Sprintf("第%d天%s%s", day.Day, meal.Type, meal.Venue), meal.Location.Coordinates})
+		}
+		if day.Accommodation.Name != "" {
+			places = append(places, place{fmt.Sprintf("第%d天住宿%s", day.Day, day.Accommodation.Name), day.Accommodation.Location.Coordinates})
+		}
+	}
+	if len(places) == 0 {
+		result.addDetail("计划中没有地点")
+		return result
+	}
+
+	var valid []models.Coordinates
+	for _, p := range places {
+		if validCoordinates(p.coords) {
+			valid = append(valid, p.coords)
+		}
+	}
+	center := medianCoordinates(valid)
+
+	plausible := 0
+	for _, p := range places {
+		switch {
+		case !validCoordinates(p.coords):
+			result.addDetail("%s的坐标无效(%.4f, %.4f)", p.label, p.coords.Latitude, p.coords.Longitude)
+		case distanceKm(center, p.coords) > maxSpreadKm:
+			result.addDetail("%s的坐标(%.4f, %.4f)距其他地点%.0f公里", p.label, p.coords.Latitude, p.coords.Longitude, distanceKm(center, p.coords))
+		default:
+			plausible++
+		}
+	}
+	result.Score = float64(plausible) / float64(len(places))
+	return result
+}
+
+// validCoordinates 经纬度在有效范围内且不为(0, 0)
+func validCoordinates(c models.Coordinates) bool {
+	if c.Latitude == 0 && c.Longitude == 0 {
+		return false
+	}
+	return c.Latitude >= -90 && c.Latitude <= 90 && c.Longitude >= -180 && c.Longitude <= 180
+}
+
+// medianCoordinates 分别取纬度和经度的中位数，少数编造的坐标不会影响结果
+func medianCoordinates(coords []models.Coordinates) models.Coordinates {
+	if len(coords) == 0 {
+		return models.Coordinates{}
+	}
+	lats := make([]float64, len(coords))
+	lngs := make([]float64, len(coords))
+	for i, c := range coords {
+		lats[i] = c.Latitude
+		lngs[i] = c.Longitude
+	}
+	sort.Float64s(lats)
+	sort.Float64s(lngs)
+	return models.Coordinates{Latitude: lats[len(lats)/2], Longitude: lngs[len(lngs)/2]}
+}
+
+// distanceKm 计算两个坐标之间的公里数
+func distanceKm(a, b models.Coordinates) float64 {
+	return traveltools.Haversine(a.Latitude, a.Longitude, b.Latitude, b.Longitude)
+}
@@ -0,0 +1,177 @@
+package eval
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"personatrip/internal/models"
+)
+
+// planRequest 返回从2025-05-01开始、共days天的请求
+func planRequest(days int) *models.PlanRequest {
+	start := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	return &models.PlanRequest{Destination: "杭州", StartDate: start, EndDate: start.AddDate(0, 0, days-1)}
+}
+
+// at 返回位于指定坐标的地点
+func at(lat, lng float64) models.Location {
+	return models.Location{Coordinates: models.Coordinates{Latitude: lat, Longitude: lng}}
+}
+
+func TestCheckDayCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   *models.PlanRequest
+		days  []int
+		score float64
+	}{
+		{name: "matches", req: planRequest(3), days: []int{1, 2, 3}, score: 1},
+		{name: "too few days", req: planRequest(3), days: []int{1, 2}, score: 0},
+		{name: "wrong numbering", req: planRequest(3), days: []int{1, 3, 2}, score: 0},
+		{name: "time of day ignored", req: &models.PlanRequest{
+			StartDate: time.Date(2025, 5, 1, 23, 0, 0, 0, time.UTC),
+			EndDate:   time.Date(2025, 5, 2, 1, 0, 0, 0, time.UTC),
+		}, days: []int{1, 2}, score: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &models.TripPlan{}
+			for _, day := range tt.days {
+				plan.Days = append(plan.Days, models.TripDay{Day: day})
+			}
+			if got := checkDayCount(tt.req, plan); got.Score != tt.score {
+				t.Fatalf("score = %v, want %v (%v)", got.Score, tt.score, got.Details)
+			}
+		})
+	}
+}
+
+func TestCheckBudget(t *testing.T) {
+	tests := []struct {
+		name   string
+		total  float64
+		daily  []float64
+		score  float64
+		detail bool
+	}{
+		{name: "exact", total: 300, daily: []float64{100, 200}, score: 1},
+		{name: "rounding within tolerance", total: 300, daily: []float64{100, 202}, score: 1},
+		{name: "off by half", total: 200, daily: []float64{100, 200}, score: 0.5, detail: true},
+		{name: "far off", total: 100, daily: []float64{500}, score: 0, detail: true},
+		{name: "no daily breakdown", total: 100, score: 0, detail: true},
+		{name: "no total", total: 0, daily: []float64{100}, score: 0, detail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &models.TripPlan{Budget: models.Budget{TotalEstimate: tt.total}}
+			for i, total := range tt.daily {
+				plan.Budget.DailyBreakdown = append(plan.Budget.DailyBreakdown, models.DailyBudget{Day: i + 1, Total: total})
+			}
+			got := checkBudget(plan)
+			if math.Abs(got.Score-tt.score) > 1e-9 {
+				t.Fatalf("score = %v, want %v", got.Score, tt.score)
+			}
+			if (len(got.Details) > 0) != tt.detail {
+				t.Fatalf("details = %v, want details %v", got.Details, tt.detail)
+			}
+		})
+	}
+}
+
+func TestCheckCoordinates(t *testing.T) {
+	westLake := at(30.2460, 120.1487)
+	lingyin := at(30.2408, 120.1010)
+	hotel := at(30.2590, 120.1640)
+
+	tests := []struct {
+		name       string
+		activities []models.Location
+		meals      []models.Location
+		score      float64
+	}{
+		{name: "all nearby", activities: []models.Location{westLake, lingyin}, meals: []models.Location{hotel}, score: 1},
+		{name: "missing coordinates", activities: []models.Location{westLake, at(0, 0)}, meals: []models.Location{hotel}, score: 2.0 / 3},
+		{name: "out of range", activities: []models.Location{westLake, at(120.1, 30.2)}, meals: []models.Location{hotel}, score: 2.0 / 3},
+		{name: "far from the rest", activities: []models.Location{westLake, lingyin, at(39.9042, 116.4074)}, meals: []models.Location{hotel}, score: 3.0 / 4},
+		{name: "no places", score: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day := models.TripDay{Day: 1}
+			for _, location := range tt.activities {
+				day.Activities = append(day.Activities, models.Activity{Name: "活动", Location: location})
+			}
+			for _, location := range tt.meals {
+				day.Meals = append(day.Meals, models.Meal{Type: "午餐", Location: location})
+			}
+			got := checkCoordinates(&models.TripPlan{Days: []models.TripDay{day}})
+			if math.Abs(got.Score-tt.score) > 1e-9 {
+				t.Fatalf("score = %v, want %v (%v)", got.Score, tt.score, got.Details)
+			}
+		})
+	}
+}
+
+func TestCheckDietary(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     models.PlanRequest
+		meals   []models.Meal
+		score   float64
+		skipped bool
+	}{
+		{name: "no restriction", req: models.PlanRequest{FoodPreferences: []string{"当地美食"}},
+			meals: []models.Meal{{Venue: "烤肉店"}}, skipped: true},
+		{name: "vegetarian violated", req: models.PlanRequest{FoodPreferences: []string{"素食"}},
+			meals: []models.Meal{{Venue: "素食餐厅", Specialties: []string{"素鸡"}}, {Venue: "外婆家", Specialties: []string{"东坡肉"}}}, score: 0.5},
+		{name: "mock meat allowed", req: models.PlanRequest{FoodPreferences: []string{"Vegetarian"}},
+			meals: []models.Meal{{Venue: "功德林", Specialties: []string{"素鸭", "牛油果沙拉"}}}, score: 1},
+		{name: "restriction in special requests", req: models.PlanRequest{SpecialRequests: "同行者海鲜过敏"},
+			meals: []models.Meal{{Venue: "海鲜大排档"}, {Venue: "川菜馆", Specialties: []string{"鱼香肉丝"}}}, score: 0.5},
+		{name: "several restrictions", req: models.PlanRequest{FoodPreferences: []string{"清真", "不吃辣"}},
+			meals: []models.Meal{{Venue: "兰州拉面"}, {Venue: "麻辣香锅"}, {Venue: "酒店自助餐"}}, score: 2.0 / 3},
+		{name: "no meals", req: models.PlanRequest{FoodPreferences: []string{"纯素"}}, score: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &models.TripPlan{Days: []models.TripDay{{Day: 1, Meals: tt.meals}}}
+			got := checkDietary(&tt.req, plan)
+			if got.Skipped != tt.skipped {
+				t.Fatalf("skipped = %v, want %v", got.Skipped, tt.skipped)
+			}
+			if math.Abs(got.Score-tt.score) > 1e-9 {
+				t.Fatalf("score = %v, want %v (%v)", got.Score, tt.score, got.Details)
+			}
+		})
+	}
+}
+
+func TestCheckCompleteness(t *testing.T) {
+	empty := checkCompleteness(&models.TripPlan{})
+	if empty.Score >= 0.5 || len(empty.Details) == 0 {
+		t.Fatalf("empty plan score = %v, details = %v", empty.Score, empty.Details)
+	}
+	if len(empty.Details) > maxDetails+1 {
+		t.Fatalf("got %d details, want at most %d", len(empty.Details), maxDetails+1)
+	}
+
+	filled := checkCompleteness(&models.TripPlan{
+		Title:       "杭州三日游",
+		Destination: "杭州",
+		Days:        []models.TripDay{{Day: 1, Date: "2025-05-01", Activities: []models.Activity{{Name: "西湖"}}}},
+	})
+	if filled.Score <= empty.Score {
+		t.Fatalf("filled plan score %v not above empty plan score %v", filled.Score, empty.Score)
+	}
+}
+
+func TestMeanScore(t *testing.T) {
+	checks := []CheckResult{{Score: 1}, {Score: 0.5}, {Score: 0, Skipped: true}}
+	if got := meanScore(checks); got != 0.75 {
+		t.Fatalf("meanScore = %v, want 0.75", got)
+	}
+	if got := meanScore([]CheckResult{{Skipped: true}}); got != 0 {
+		t.Fatalf("meanScore with all skipped = %v, want 0", got)
+	}
+}
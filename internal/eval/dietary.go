@@ -0,0 +1,130 @@
+package eval
+
+import (
+	"fmt"
+	"strings"
+
+	"personatrip/internal/models"
+)
+
+// dietaryRule 一种饮食限制：请求的饮食偏好包含keywords之一时生效，
+// 餐饮的描述中出现forbidden之一视为违反，allowed中的词在检查前去掉以免误判（如素鸡）
+type dietaryRule struct {
+	name      string
+	keywords  []string
+	forbidden []string
+	allowed   []string
+}
+
+// 肉类和海鲜，素食和纯素共用
+var (
+	meatTerms = []string{
+		"肉", "猪", "牛", "羊", "鸡", "鸭", "鹅", "排骨", "火腿", "培根", "香肠", "腊肠", "内脏",
+		"pork", "beef", "lamb", "mutton", "chicken", "duck", "bacon", "sausage", "steak",
+	}
+	seafoodTerms = []string{
+		"鱼", "虾", "蟹", "贝", "蚝", "生蚝", "鱿鱼", "海鲜", "海参", "鲍鱼", "刺身",
+		"fish", "shrimp", "prawn", "crab", "oyster", "squid", "seafood", "sashimi", "lobster",
+	}
+	// mockMeatTerms 包含肉类字样但不含肉的食物
+	mockMeatTerms = []string{"素肉", "素鸡", "素鸭", "素鹅", "素鱼", "素火腿", "牛油果", "肉桂", "肉豆蔻", "贝果"}
+)
+
+// dietaryRules 评测支持的饮食限制
+var dietaryRules = []dietaryRule{
+	{
+		name:      "素食",
+		keywords:  []string{"素食", "吃素", "vegetarian"},
+		forbidden: append(append([]string{}, meatTerms...), seafoodTerms...),
+		allowed:   append(append([]string{}, mockMeatTerms...), "牛奶", "鸡蛋", "鸭蛋"),
+	},
+	{
+		name:      "纯素",
+		keywords:  []string{"纯素", "vegan"},
+		forbidden: append(append(append([]string{}, meatTerms...), seafoodTerms...), "蛋", "奶", "芝士", "黄油", "蜂蜜", "egg", "milk", "cheese", "butter", "honey"),
+		allowed:   append(append([]string{}, mockMeatTerms...), "椰奶", "豆奶", "燕麦奶", "杏仁奶", "eggplant"),
+	},
+	{
+		name:      "清真",
+		keywords:  []string{"清真", "halal"},
+		forbidden: []string{"猪", "培根", "火腿", "酒", "pork", "bacon", "wine", "beer"},
+		allowed:   []string{"酒店"},
+	},
+	{
+		name:      "不吃辣",
+		keywords:  []string{"不吃辣", "不辣", "忌辣", "no spicy", "not spicy"},
+		forbidden: []string{"麻辣", "香辣", "辣子", "火锅", "剁椒", "spicy", "chili"},
+		allowed:   []string{"不辣", "微辣可选", "可做不辣"},
+	},
+	{
+		name:      "海鲜过敏",
+		keywords:  []string{"海鲜过敏", "不吃海鲜", "无海鲜", "seafood allergy", "no seafood"},
+		forbidden: seafoodTerms,
+		allowed:   []string{"鱼香"},
+	},
+}
+
+// matchDietaryRules 获取请求的饮食偏好和特殊要求中提到的饮食限制
+func matchDietaryRules(req *models.PlanRequest) []dietaryRule {
+	text := strings.ToLower(strings.Join(append(append([]string{}, req.FoodPreferences...), req.SpecialRequests), " "))
+	var rules []dietaryRule
+	for _, rule := range dietaryRules {
+		for _, keyword := range rule.keywords {
+			if strings.Contains(text, keyword) {
+				rules = append(rules, rule)
+				break
+			}
+		}
+	}
+	return rules
+}
+
+// checkDietary 检查每一餐的餐厅、菜系、描述和特色菜是否违反请求的饮食限制，
+// 得分为符合要求的餐饮占比；请求没有可识别的饮食限制时跳过
+func checkDietary(req *models.PlanRequest, plan *models.TripPlan) CheckResult {
+	result := CheckResult{Name: CheckDietary}
+	rules := matchDietaryRules(req)
+	if len(rules) == 0 {
+		result.Skipped = true
+		return result
+	}
+
+	total, respected := 0, 0
+	for _, day := range plan.Days {
+		for _, meal := range day.Meals {
+			total++
+			text := strings.ToLower(strings.Join(append([]string{meal.Venue, meal.Cuisine, meal.Description}, meal.Specialties...), " "))
+			violation := ""
+			for _, rule := range rules {
+				if term := rule.violation(text); term != "" {
+					violation = fmt.Sprintf("%s要求，包含\"%s\"", rule.name, term)
+					break
+				}
+			}
+			if violation == "" {
+				respected++
+				continue
+			}
+			result.addDetail("第%d天%s(%s)不符合%s", day.Day, meal.Type, meal.Venue, violation)
+		}
+	}
+	if total == 0 {
+		result.addDetail("计划中没有餐饮安排")
+		return result
+	}
+	result.Score = float64(respected) / float64(total)
+	return result
+}
+
+// violation 返回text中出现的第一个禁用词，没有时返回空字符串
+func (r dietaryRule) violation(text string) string {
+	for _, term := range r.allowed {
+		text = strings.ReplaceAll(text, strings.ToLower(term), " ")
+	}
+	for _, term := range r.forbidden {
+		if strings.Contains(text, strings.ToLower(term)) {
+			return term
+		}
+	}
+	return ""
+}
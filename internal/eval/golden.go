@@ -0,0 +1,101 @@
+package eval
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"personatrip/internal/models"
+)
+
+// Case 评测集中的一个用例
+type Case struct {
+	Name    string             `json:"name"`
+	Request models.PlanRequest `json:"request"`
+}
+
+// LoadCases 读取评测集，.yaml/.yml文件为用例数组，其他文件按JSONL处理，每行一个用例。
+// 未填写名称的用例按序号命名，名称重复、缺少目的地或日期范围无效时返回错误
+func LoadCases(path string) ([]Case, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取评测集失败: %w", err)
+	}
+
+	var cases []Case
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		cases, err = parseYAMLCases(data)
+	default:
+		cases, err = parseJSONLCases(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析评测集%s失败: %w", path, err)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("评测集%s中没有用例", path)
+	}
+
+	names := make(map[string]bool, len(cases))
+	for i := range cases {
+		c := &cases[i]
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("case-%d", i+1)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("用例名称重复: %s", c.Name)
+		}
+		names[c.Name] = true
+
+		if c.Request.Destination == "" {
+			return nil, fmt.Errorf("用例%s缺少目的地", c.Name)
+		}
+		if c.Request.StartDate.IsZero() || c.Request.EndDate.Before(c.Request.StartDate) {
+			return nil, fmt.Errorf("用例%s的日期范围无效", c.Name)
+		}
+	}
+	return cases, nil
+}
+
+// parseYAMLCases 解析YAML格式的用例数组。先解码为通用结构再转换为JSON，
+// 使字段名和日期格式与接口请求保持一致
+func parseYAMLCases(data []byte) ([]Case, error) {
+	var raw []interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	jsonData, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []Case
+	if err := json.Unmarshal(jsonData, &cases); err != nil {
+		return nil, err
+	}
+	return cases, nil
+}
+
+// parseJSONLCases 解析JSONL格式的用例，忽略空行和以#开头的注释行
+func parseJSONLCases(data []byte) ([]Case, error) {
+	var cases []Case
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var c Case
+		if err := json.Unmarshal([]byte(text), &c); err != nil {
+			return nil, fmt.Errorf("第%d行: %w", line, err)
+		}
+		cases = append(cases, c)
+	}
+	return cases, scanner.Err()
+}
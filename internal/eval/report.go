@@ -0,0 +1,311 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"personatrip/internal/models"
+)
+
+// regressionThreshold 用例得分比基线下降超过该值时视为退化
+const regressionThreshold = 0.05
+
+// Report 一次评测的结果，保存为JSON后可作为之后评测的基线
+type Report struct {
+	ModelConfigID   uint         `json:"model_config_id"`
+	ModelConfigName string       `json:"model_config_name"`
+	ModelType       string       `json:"model_type"`
+	ModelName       string       `json:"model_name"`
+	PromptVersion   int          `json:"prompt_version"` // 为0时使用当时启用的提示词
+	GoldenFile      string       `json:"golden_file"`
+	StartedAt       time.Time    `json:"started_at"`
+	FinishedAt      time.Time    `json:"finished_at"`
+	Summary         Summary      `json:"summary"`
+	Cases           []CaseResult `json:"cases"`
+	Baseline        *Comparison  `json:"baseline,omitempty"` // 与基线的对比，未指定基线时为空
+}
+
+// CaseResult 一个用例的评测结果
+type CaseResult struct {
+	Name        string           `json:"name"`
+	Destination string           `json:"destination"`
+	Success     bool             `json:"success"`
+	Error       string           `json:"error,omitempty"`
+	Score       float64          `json:"score"` // 未跳过的检查的平均分，生成失败时为0
+	Checks      []CheckResult    `json:"checks,omitempty"`
+	LatencyMs   int64            `json:"latency_ms"`
+	TotalTokens int              `json:"total_tokens"`
+	Cost        float64          `json:"cost"`
+	Plan        *models.TripPlan `json:"plan,omitempty"`
+}
+
+// Summary 评测结果汇总
+type Summary struct {
+	Cases        int                `json:"cases"`
+	Succeeded    int                `json:"succeeded"`
+	Score        float64            `json:"score"`        // 所有用例的平均分，生成失败的用例计0分
+	CheckScores  map[string]float64 `json:"check_scores"` // 各项检查在生成成功且未跳过的用例中的平均分
+	AvgLatencyMs int64              `json:"avg_latency_ms"`
+	TotalTokens  int                `json:"total_tokens"`
+	Cost         float64            `json:"cost"`
+}
+
+// Comparison 与基线报告的对比
+type Comparison struct {
+	File            string             `json:"file"`
+	ModelConfigName string             `json:"model_config_name"`
+	PromptVersion   int                `json:"prompt_version"`
+	Score           float64            `json:"score"`        // 基线的平均分
+	ScoreDelta      float64            `json:"score_delta"`  // 本次平均分减去基线平均分
+	CheckDeltas     map[string]float64 `json:"check_deltas"` // 各项检查平均分的变化
+	Regressions     int                `json:"regressions"`
+	Improvements    int                `json:"improvements"`
+	Cases           []CaseComparison   `json:"cases"`
+}
+
+// 用例与基线对比的状态
+const (
+	StatusRegressed = "regressed"
+	StatusImproved  = "improved"
+	StatusUnchanged = "unchanged"
+	StatusNew       = "new" // 基线中没有该用例
+)
+
+// CaseComparison 一个用例与基线的对比
+type CaseComparison struct {
+	Name          string  `json:"name"`
+	BaselineScore float64 `json:"baseline_score"`
+	Score         float64 `json:"score"`
+	Delta         float64 `json:"delta"`
+	Status        string  `json:"status"`
+}
+
+// summarize 汇总各用例的结果
+func summarize(cases []CaseResult) Summary {
+	summary := Summary{
+		Cases:       len(cases),
+		CheckScores: make(map[string]float64),
+	}
+	checkCounts := make(map[string]int)
+	var totalScore float64
+	var totalLatency int64
+	for _, c := range cases {
+		totalScore += c.Score
+		totalLatency += c.LatencyMs
+		summary.TotalTokens += c.TotalTokens
+		summary.Cost += c.Cost
+		if !c.Success {
+			continue
+		}
+		summary.Succeeded++
+		for _, check := range c.Checks {
+			if !check.Skipped {
+				summary.CheckScores[check.Name] += check.Score
+				checkCounts[check.Name]++
+			}
+		}
+	}
+	if len(cases) > 0 {
+		summary.Score = totalScore / float64(len(cases))
+		summary.AvgLatencyMs = totalLatency / int64(len(cases))
+	}
+	for name, count := range checkCounts {
+		summary.CheckScores[name] /= float64(count)
+	}
+	return summary
+}
+
+// Compare 对比本次结果与基线，写入r.Baseline。用例得分下降超过regressionThreshold
+// 或由生成成功变为失败时视为退化，基线中有而本次没有的用例不参与对比
+func (r *Report) Compare(baseline *Report, file string) {
+	baselineCases := make(map[string]CaseResult, len(baseline.Cases))
+	for _, c := range baseline.Cases {
+		baselineCases[c.Name] = c
+	}
+
+	comparison := &Comparison{
+		File:            file,
+		ModelConfigName: baseline.ModelConfigName,
+		PromptVersion:   baseline.PromptVersion,
+		Score:           baseline.Summary.Score,
+		ScoreDelta:      r.Summary.Score - baseline.Summary.Score,
+		CheckDeltas:     make(map[string]float64),
+	}
+	for name, score := range r.Summary.CheckScores {
+		if baseScore, ok := baseline.Summary.CheckScores[name]; ok {
+			comparison.CheckDeltas[name] = score - baseScore
+		}
+	}
+
+	for _, c := range r.Cases {
+		cc := CaseComparison{Name: c.Name, Score: c.Score, Status: StatusNew}
+		if base, ok := baselineCases[c.Name]; ok {
+			cc.BaselineScore = base.Score
+			cc.Delta = c.Score - base.Score
+			switch {
+			case cc.Delta < -regressionThreshold || (base.Success && !c.Success):
+				cc.Status = StatusRegressed
+				comparison.Regressions++
+			case cc.Delta > regressionThreshold || (!base.Success && c.Success):
+				cc.Status = StatusImproved
+				comparison.Improvements++
+			default:
+				cc.Status = StatusUnchanged
+			}
+		}
+		comparison.Cases = append(comparison.Cases, cc)
+	}
+	r.Baseline = comparison
+}
+
+// LoadReport 读取JSON格式的评测报告
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取评测报告失败: %w", err)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("解析评测报告%s失败: %w", path, err)
+	}
+	return &report, nil
+}
+
+// WriteJSON 以JSON格式保存报告
+func (r *Report) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// WriteMarkdown 以Markdown格式保存报告，不包含生成的计划
+func (r *Report) WriteMarkdown(path string) error {
+	return os.WriteFile(path, []byte(r.Markdown()), 0o644)
+}
+
+// Markdown 生成Markdown格式的报告
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# 旅行计划评测报告\n\n")
+	fmt.Fprintf(&b, "- 模型配置: %s (ID: %d, %s/%s)\n", r.ModelConfigName, r.ModelConfigID, r.ModelType, r.ModelName)
+	fmt.Fprintf(&b, "- 提示词版本: %s\n", promptVersionLabel(r.PromptVersion))
+	fmt.Fprintf(&b, "- 评测集: %s\n", r.GoldenFile)
+	fmt.Fprintf(&b, "- 时间: %s，耗时%s\n\n", r.StartedAt.Format(time.RFC3339), r.FinishedAt.Sub(r.StartedAt).Round(time.Second))
+
+	s := r.Summary
+	fmt.Fprintf(&b, "## 汇总\n\n")
+	fmt.Fprintf(&b, "| 指标 | 本次 |")
+	if r.Baseline != nil {
+		fmt.Fprintf(&b, " 基线 | 变化 |")
+	}
+	fmt.Fprintf(&b, "\n|---|---|")
+	if r.Baseline != nil {
+		fmt.Fprintf(&b, "---|---|")
+	}
+	fmt.Fprintf(&b, "\n| 平均分 | %.3f |", s.Score)
+	if r.Baseline != nil {
+		fmt.Fprintf(&b, " %.3f | %s |", r.Baseline.Score, formatDelta(r.Baseline.ScoreDelta))
+	}
+	for _, name := range CheckNames {
+		score, ok := s.CheckScores[name]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&b, "\n| %s | %.3f |", name, score)
+		if r.Baseline != nil {
+			if delta, ok := r.Baseline.CheckDeltas[name]; ok {
+				fmt.Fprintf(&b, " %.3f | %s |", score-delta, formatDelta(delta))
+			} else {
+				fmt.Fprintf(&b, " - | - |")
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\n\n生成成功 %d/%d，平均耗时 %dms，共 %d tokens，费用 %.4f\n\n", s.Succeeded, s.Cases, s.AvgLatencyMs, s.TotalTokens, s.Cost)
+
+	if r.Baseline != nil {
+		fmt.Fprintf(&b, "与基线 %s（%s，提示词版本%s）相比：退化 %d 个用例，提升 %d 个用例\n\n",
+			r.Baseline.File, r.Baseline.ModelConfigName, promptVersionLabel(r.Baseline.PromptVersion), r.Baseline.Regressions, r.Baseline.Improvements)
+	}
+
+	fmt.Fprintf(&b, "## 用例\n\n| 用例 | 目的地 | 得分 |")
+	for _, name := range CheckNames {
+		fmt.Fprintf(&b, " %s |", name)
+	}
+	if r.Baseline != nil {
+		fmt.Fprintf(&b, " 基线 | 状态 |")
+	}
+	fmt.Fprintf(&b, "\n|---|---|---|%s", strings.Repeat("---|", len(CheckNames)))
+	if r.Baseline != nil {
+		fmt.Fprintf(&b, "---|---|")
+	}
+	for i, c := range r.Cases {
+		fmt.Fprintf(&b, "\n| %s | %s | %.3f |", c.Name, c.Destination, c.Score)
+		for _, name := range CheckNames {
+			fmt.Fprintf(&b, " %s |", checkCell(c, name))
+		}
+		if r.Baseline != nil {
+			cc := r.Baseline.Cases[i]
+			if cc.Status == StatusNew {
+				fmt.Fprintf(&b, " - | %s |", cc.Status)
+			} else {
+				fmt.Fprintf(&b, " %.3f | %s (%s) |", cc.BaselineScore, cc.Status, formatDelta(cc.Delta))
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\n")
+
+	var problems strings.Builder
+	for _, c := range r.Cases {
+		if !c.Success {
+			fmt.Fprintf(&problems, "\n### %s\n\n- 生成失败: %s\n", c.Name, c.Error)
+			continue
+		}
+		var lines []string
+		for _, check := range c.Checks {
+			for _, detail := range check.Details {
+				lines = append(lines, fmt.Sprintf("- %s: %s", check.Name, detail))
+			}
+		}
+		if len(lines) > 0 {
+			fmt.Fprintf(&problems, "\n### %s\n\n%s\n", c.Name, strings.Join(lines, "\n"))
+		}
+	}
+	if problems.Len() > 0 {
+		fmt.Fprintf(&b, "\n## 问题\n%s", problems.String())
+	}
+	return b.String()
+}
+
+// checkCell 用例某项检查在表格中的内容
+func checkCell(c CaseResult, name string) string {
+	if !c.Success {
+		return "失败"
+	}
+	for _, check := range c.Checks {
+		if check.Name == name {
+			if check.Skipped {
+				return "-"
+			}
+			return fmt.Sprintf("%.2f", check.Score)
+		}
+	}
+	return "-"
+}
+
+// formatDelta 带符号显示分数变化
+func formatDelta(delta float64) string {
+	return fmt.Sprintf("%+.3f", delta)
+}
+
+// promptVersionLabel 提示词版本的显示名称
+func promptVersionLabel(version int) string {
+	if version == 0 {
+		return "当前启用"
+	}
+	return fmt.Sprintf("v%d", version)
+}
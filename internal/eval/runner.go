@@ -0,0 +1,101 @@
+package eval
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"personatrip/internal/models"
+	"personatrip/internal/utils/logger"
+)
+
+// Generator 使用指定模型配置生成旅行计划，由services.EinoService实现
+type Generator interface {
+	GenerateTripPlanWithConfig(ctx context.Context, req *models.PlanRequest, config *models.ModelConfig, promptVersion int) (*models.TripPlan, error)
+}
+
+// Runner 使用一个模型配置运行评测集并打分
+type Runner struct {
+	generator     Generator
+	config        *models.ModelConfig
+	promptVersion int // 为0时使用当前启用的提示词
+	concurrency   int // 同时生成的用例数
+}
+
+// NewRunner 创建新的评测运行器，concurrency小于1时逐个生成
+func NewRunner(generator Generator, config *models.ModelConfig, promptVersion, concurrency int) *Runner {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Runner{
+		generator:     generator,
+		config:        config,
+		promptVersion: promptVersion,
+		concurrency:   concurrency,
+	}
+}
+
+// Run 生成并评分所有用例，结果按用例在评测集中的顺序排列。生成失败的用例得0分，
+// ctx被取消时未开始的用例记为失败
+func (r *Runner) Run(ctx context.Context, cases []Case) *Report {
+	report := &Report{
+		ModelConfigID:   r.config.ID,
+		ModelConfigName: r.config.Name,
+		ModelType:       r.config.ModelType,
+		ModelName:       r.config.ModelName,
+		PromptVersion:   r.promptVersion,
+		StartedAt:       time.Now(),
+		Cases:           make([]CaseResult, len(cases)),
+	}
+
+	sem := make(chan struct{}, r.concurrency)
+	var wg sync.WaitGroup
+	for i := range cases {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				report.Cases[i] = CaseResult{Name: cases[i].Name, Destination: cases[i].Request.Destination, Error: ctx.Err().Error()}
+				return
+			}
+			report.Cases[i] = r.runCase(ctx, &cases[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report.FinishedAt = time.Now()
+	report.Summary = summarize(report.Cases)
+	return report
+}
+
+// runCase 生成并评分一个用例
+func (r *Runner) runCase(ctx context.Context, c *Case) CaseResult {
+	result := CaseResult{
+		Name:        c.Name,
+		Destination: c.Request.Destination,
+	}
+
+	logger.Infof("开始评测用例 %s", c.Name)
+	start := time.Now()
+	req := c.Request
+	plan, err := r.generator.GenerateTripPlanWithConfig(ctx, &req, r.config, r.promptVersion)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		logger.Errorf("用例 %s 生成失败: %v", c.Name, err)
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Success = true
+	result.Checks, result.Score = Score(&req, plan)
+	if plan.Generation != nil {
+		result.TotalTokens = plan.Generation.TotalTokens
+		result.Cost = plan.Generation.Cost
+	}
+	result.Plan = plan
+	logger.Infof("用例 %s 得分 %.3f", c.Name, result.Score)
+	return result
+}
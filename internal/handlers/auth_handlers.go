@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"strconv"
+
 	"personatrip/internal/models"
+	"personatrip/internal/repository"
 	"personatrip/internal/services"
 	"personatrip/internal/utils/httputil"
 
@@ -111,3 +115,102 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 
 	httputil.ReturnSuccessWithBean(c, "获取用户资料成功", user)
 }
+
+// CreateAPIToken 创建API令牌
+// @Summary 创建API令牌
+// @Description 为当前用户创建API令牌，供MCP客户端等程序代表用户调用，令牌明文只在本次响应中返回
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.APITokenCreateRequest true "令牌名称和有效天数"
+// @Success 201 {object} models.ApiResponse
+// @Failure 400 {object} models.ApiResponse
+// @Failure 401 {object} models.ApiResponse
+// @Failure 500 {object} models.ApiResponse
+// @Router /api/auth/tokens [post]
+func (h *AuthHandler) CreateAPIToken(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		httputil.ReturnUnauthorized(c, "用户未认证")
+		return
+	}
+
+	var req models.APITokenCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.ReturnBadRequest(c, "无效的请求格式")
+		return
+	}
+
+	response, err := h.authService.CreateAPIToken(c.Request.Context(), userID, &req)
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnCreated(c, "API令牌创建成功，请妥善保存，令牌不会再次显示", response)
+}
+
+// ListAPITokens 获取API令牌列表
+// @Summary 获取API令牌列表
+// @Description 获取当前用户的所有API令牌，不包含令牌明文
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.ApiResponse
+// @Failure 401 {object} models.ApiResponse
+// @Failure 500 {object} models.ApiResponse
+// @Router /api/auth/tokens [get]
+func (h *AuthHandler) ListAPITokens(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		httputil.ReturnUnauthorized(c, "用户未认证")
+		return
+	}
+
+	tokens, err := h.authService.ListAPITokens(c.Request.Context(), userID)
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithList(c, "获取API令牌成功", tokens)
+}
+
+// DeleteAPIToken 吊销API令牌
+// @Summary 吊销API令牌
+// @Description 删除当前用户的API令牌，删除后使用该令牌的MCP客户端无法再调用
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "令牌ID"
+// @Success 200 {object} models.ApiResponse
+// @Failure 400 {object} models.ApiResponse
+// @Failure 401 {object} models.ApiResponse
+// @Failure 404 {object} models.ApiResponse
+// @Failure 500 {object} models.ApiResponse
+// @Router /api/auth/tokens/{id} [delete]
+func (h *AuthHandler) DeleteAPIToken(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		httputil.ReturnUnauthorized(c, "用户未认证")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httputil.ReturnBadRequest(c, "无效的ID")
+		return
+	}
+
+	if err := h.authService.DeleteAPIToken(c.Request.Context(), userID, uint(id)); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			httputil.ReturnNotFound(c, "API令牌不存在")
+			return
+		}
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccess(c, "API令牌已吊销")
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+
+	"personatrip/internal/models"
+	"personatrip/internal/repository"
+	"personatrip/internal/services"
+	"personatrip/internal/utils/httputil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExchangeRateHandler 处理汇率管理相关的请求
+type ExchangeRateHandler struct {
+	exchangeRates *services.ExchangeRateService
+}
+
+// NewExchangeRateHandler 创建新的汇率管理处理器
+func NewExchangeRateHandler(exchangeRates *services.ExchangeRateService) *ExchangeRateHandler {
+	return &ExchangeRateHandler{exchangeRates: exchangeRates}
+}
+
+// List 获取所有汇率
+func (h *ExchangeRateHandler) List(c *gin.Context) {
+	rates, err := h.exchangeRates.ListExchangeRates(c.Request.Context())
+	if err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	httputil.ReturnSuccessWithData(c, "获取汇率成功", gin.H{
+		"base_currency": models.ExchangeRateBaseCurrency,
+		"rates":         rates,
+	})
+}
+
+// Set 设置货币的汇率，已存在时更新
+func (h *ExchangeRateHandler) Set(c *gin.Context) {
+	var req models.ExchangeRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.ReturnBadRequest(c, err.Error())
+		return
+	}
+
+	rate, err := h.exchangeRates.SetExchangeRate(c.Request.Context(), c.Param("currency"), &req)
+	if err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	httputil.ReturnSuccessWithBean(c, "汇率设置成功", rate)
+}
+
+// Delete 删除货币的汇率
+func (h *ExchangeRateHandler) Delete(c *gin.Context) {
+	if err := h.exchangeRates.DeleteExchangeRate(c.Request.Context(), c.Param("currency")); err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	httputil.ReturnSuccess(c, "汇率删除成功")
+}
+
+// returnError 按错误类型返回对应的状态码
+func (h *ExchangeRateHandler) returnError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidExchangeRate):
+		httputil.ReturnBadRequest(c, err.Error())
+	case errors.Is(err, repository.ErrNotFound):
+		httputil.ReturnNotFound(c, "汇率不存在")
+	default:
+		httputil.ReturnInternalError(c, err.Error())
+	}
+}
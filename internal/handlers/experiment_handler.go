@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"personatrip/internal/models"
+	"personatrip/internal/services"
+	"personatrip/internal/utils/httputil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExperimentHandler 处理A/B实验管理相关的请求
+type ExperimentHandler struct {
+	experimentService services.ExperimentService
+}
+
+// NewExperimentHandler 创建新的A/B实验处理器
+func NewExperimentHandler(experimentService services.ExperimentService) *ExperimentHandler {
+	return &ExperimentHandler{
+		experimentService: experimentService,
+	}
+}
+
+// Create 创建实验，创建后为草稿状态，需要开始后才会分配流量
+func (h *ExperimentHandler) Create(c *gin.Context) {
+	var req models.ExperimentCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.ReturnBadRequest(c, err.Error())
+		return
+	}
+
+	experiment, err := h.experimentService.CreateExperiment(c.Request.Context(), &req)
+	if err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	httputil.ReturnCreated(c, "实验创建成功", experiment)
+}
+
+// List 获取所有实验
+func (h *ExperimentHandler) List(c *gin.Context) {
+	experiments, err := h.experimentService.ListExperiments(c.Request.Context())
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithList(c, "获取实验列表成功", experiments)
+}
+
+// GetByID 根据ID获取实验
+func (h *ExperimentHandler) GetByID(c *gin.Context) {
+	id, ok := parseExperimentID(c)
+	if !ok {
+		return
+	}
+
+	experiment, err := h.experimentService.GetExperimentByID(c.Request.Context(), id)
+	if err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	httputil.ReturnSuccessWithBean(c, "获取实验成功", experiment)
+}
+
+// Delete 删除实验及其统计数据
+func (h *ExperimentHandler) Delete(c *gin.Context) {
+	id, ok := parseExperimentID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.experimentService.DeleteExperiment(c.Request.Context(), id); err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	httputil.ReturnSuccess(c, "实验删除成功")
+}
+
+// Start 开始实验
+func (h *ExperimentHandler) Start(c *gin.Context) {
+	id, ok := parseExperimentID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.experimentService.StartExperiment(c.Request.Context(), id); err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	httputil.ReturnSuccess(c, "实验已开始")
+}
+
+// Stop 停止实验
+func (h *ExperimentHandler) Stop(c *gin.Context) {
+	id, ok := parseExperimentID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.experimentService.StopExperiment(c.Request.Context(), id); err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	httputil.ReturnSuccess(c, "实验已停止")
+}
+
+// Report 获取实验各分组的统计报告
+func (h *ExperimentHandler) Report(c *gin.Context) {
+	id, ok := parseExperimentID(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.experimentService.GetReport(c.Request.Context(), id)
+	if err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	httputil.ReturnSuccessWithBean(c, "获取实验报告成功", report)
+}
+
+// returnError 按错误类型返回对应的状态码
+func (h *ExperimentHandler) returnError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidExperiment):
+		httputil.ReturnBadRequest(c, err.Error())
+	case errors.Is(err, services.ErrExperimentNotFound):
+		httputil.ReturnNotFound(c, err.Error())
+	default:
+		httputil.ReturnInternalError(c, err.Error())
+	}
+}
+
+// parseExperimentID 解析路径中的实验ID，无效时返回400
+func parseExperimentID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httputil.ReturnBadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
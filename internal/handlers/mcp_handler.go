@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"personatrip/internal/models"
+	"personatrip/internal/services"
+	"personatrip/internal/utils/httputil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MCPHandler 处理MCP提供者和工具调用结果缓存管理相关的请求
+type MCPHandler struct {
+	mcpServerService *services.MCPServerService
+	toolCache        *services.MCPToolCacheService
+}
+
+// NewMCPHandler 创建新的MCP提供者管理处理器
+func NewMCPHandler(mcpServerService *services.MCPServerService, toolCache *services.MCPToolCacheService) *MCPHandler {
+	return &MCPHandler{
+		mcpServerService: mcpServerService,
+		toolCache:        toolCache,
+	}
+}
+
+// List 获取所有提供者的配置、连接状态和工具
+func (h *MCPHandler) List(c *gin.Context) {
+	providers, err := h.mcpServerService.List(c.Request.Context())
+	if err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	httputil.ReturnSuccessWithList(c, "获取MCP提供者列表成功", providers)
+}
+
+// GetByName 获取一个提供者的配置、连接状态和工具
+func (h *MCPHandler) GetByName(c *gin.Context) {
+	provider, err := h.mcpServerService.Get(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	httputil.ReturnSuccessWithBean(c, "获取MCP提供者成功", provider)
+}
+
+// Create 添加MCP服务器并立即连接，连接失败时仍然保存，可以之后重启
+func (h *MCPHandler) Create(c *gin.Context) {
+	var req models.MCPServerCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.ReturnBadRequest(c, err.Error())
+		return
+	}
+
+	provider, err := h.mcpServerService.Create(c.Request.Context(), &req)
+	if err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	httputil.ReturnCreated(c, "MCP服务器添加成功", provider)
+}
+
+// Delete 断开并删除提供者
+func (h *MCPHandler) Delete(c *gin.Context) {
+	if err := h.mcpServerService.Delete(c.Request.Context(), c.Param("name")); err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	httputil.ReturnSuccess(c, "MCP提供者删除成功")
+}
+
+// Restart 重新连接提供者
+func (h *MCPHandler) Restart(c *gin.Context) {
+	provider, err := h.mcpServerService.Restart(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	httputil.ReturnSuccessWithBean(c, "MCP提供者重启成功", provider)
+}
+
+// CallTool 使用JSON参数手动调用提供者的工具，工具自身返回的错误在结果的isError中
+func (h *MCPHandler) CallTool(c *gin.Context) {
+	var req models.MCPToolCallRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.ReturnBadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.mcpServerService.CallTool(c.Request.Context(), c.Param("name"), c.Param("tool"), req.Arguments)
+	if err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	httputil.ReturnSuccessWithBean(c, "调用MCP工具成功", result)
+}
+
+// CacheStats 获取工具调用结果缓存的命中统计
+func (h *MCPHandler) CacheStats(c *gin.Context) {
+	httputil.ReturnSuccessWithBean(c, "获取MCP工具缓存统计成功", h.toolCache.Stats())
+}
+
+// ClearCache 删除缓存的工具调用结果，可按提供者和工具名称过滤，都未指定时清空所有缓存
+func (h *MCPHandler) ClearCache(c *gin.Context) {
+	deleted, err := h.toolCache.Clear(c.Request.Context(), c.Query("provider"), c.Query("tool"))
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithData(c, "MCP工具缓存已删除", gin.H{"deleted": deleted})
+}
+
+// returnError 按错误类型返回对应的状态码
+func (h *MCPHandler) returnError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMCPServer):
+		httputil.ReturnBadRequest(c, err.Error())
+	case errors.Is(err, services.ErrMCPProviderNotFound):
+		httputil.ReturnNotFound(c, err.Error())
+	case errors.Is(err, services.ErrMCPProviderExists):
+		httputil.ReturnError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrMCPConnectFailed):
+		httputil.ReturnError(c, http.StatusBadGateway, err.Error())
+	case errors.Is(err, services.ErrMCPNotReady):
+		httputil.ReturnError(c, http.StatusServiceUnavailable, err.Error())
+	default:
+		httputil.ReturnInternalError(c, err.Error())
+	}
+}
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"errors"
+	"net/http"
 	"strconv"
 
 	"personatrip/internal/models"
 	"personatrip/internal/services"
 	"personatrip/internal/utils/httputil"
+	"personatrip/pkg/einosdk"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,13 +17,15 @@ import (
 type ModelConfigHandler struct {
 	configService services.ModelConfigService
 	einoService   EinoServiceInterface
+	healthService *services.ModelHealthService
 }
 
 // NewModelConfigHandler 创建新的模型配置处理器
-func NewModelConfigHandler(configService services.ModelConfigService, einoService EinoServiceInterface) *ModelConfigHandler {
+func NewModelConfigHandler(configService services.ModelConfigService, einoService EinoServiceInterface, healthService *services.ModelHealthService) *ModelConfigHandler {
 	return &ModelConfigHandler{
 		configService: configService,
 		einoService:   einoService,
+		healthService: healthService,
 	}
 }
 
@@ -34,6 +39,10 @@ func (h *ModelConfigHandler) Create(c *gin.Context) {
 
 	config, err := h.configService.CreateModelConfig(c.Request.Context(), &req)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidModelConfig) {
+			httputil.ReturnBadRequest(c, err.Error())
+			return
+		}
 		httputil.ReturnInternalError(c, err.Error())
 		return
 	}
@@ -63,14 +72,16 @@ func (h *ModelConfigHandler) Update(c *gin.Context) {
 
 	config, err := h.configService.UpdateModelConfig(c.Request.Context(), uint(id), &req)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidModelConfig) {
+			httputil.ReturnBadRequest(c, err.Error())
+			return
+		}
 		httputil.ReturnInternalError(c, err.Error())
 		return
 	}
 
-	// 如果设置为活跃，刷新Eino服务的模型配置
-	if config.IsActive {
-		h.einoService.RefreshModelConfig(c.Request.Context())
-	}
+	// 刷新Eino服务的模型配置，连接参数变化时重新创建客户端
+	h.einoService.RefreshModelConfig(c.Request.Context())
 
 	httputil.ReturnSuccessWithBean(c, "模型配置更新成功", config.ToResponse())
 }
@@ -89,6 +100,9 @@ func (h *ModelConfigHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	// 刷新Eino服务的模型配置，释放已删除配置的客户端
+	h.einoService.RefreshModelConfig(c.Request.Context())
+
 	httputil.ReturnSuccess(c, "模型配置删除成功")
 }
 
@@ -146,8 +160,14 @@ func (h *ModelConfigHandler) SetActive(c *gin.Context) {
 		return
 	}
 
-	err = h.configService.SetActiveModelConfig(c.Request.Context(), uint(id))
+	// 被探测为不可用的配置需要指定force=true才能激活
+	force := c.Query("force") == "true"
+	err = h.configService.SetActiveModelConfig(c.Request.Context(), uint(id), force)
 	if err != nil {
+		if errors.Is(err, services.ErrModelConfigDown) {
+			httputil.ReturnError(c, http.StatusConflict, err.Error())
+			return
+		}
 		httputil.ReturnInternalError(c, err.Error())
 		return
 	}
@@ -190,7 +210,11 @@ func (h *ModelConfigHandler) TestModel(c *gin.Context) {
 	}
 
 	// 创建临时的Eino客户端
-	client := services.NewEinoServiceWithConfig(config)
+	client, err := services.NewEinoServiceWithConfig(config)
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
 
 	// 测试生成文本
 	result, err := client.TestGenerateText(c.Request.Context(), req.Prompt)
@@ -201,3 +225,71 @@ func (h *ModelConfigHandler) TestModel(c *gin.Context) {
 
 	httputil.ReturnSuccessWithData(c, "模型测试成功", map[string]string{"result": result})
 }
+
+// GetHealth 获取模型配置最近的探测结果
+func (h *ModelConfigHandler) GetHealth(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httputil.ReturnBadRequest(c, "无效的ID")
+		return
+	}
+
+	var query models.ModelHealthQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		httputil.ReturnBadRequest(c, err.Error())
+		return
+	}
+
+	checks, err := h.healthService.History(c.Request.Context(), uint(id), &query)
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithList(c, "获取模型配置探测历史成功", checks)
+}
+
+// CheckHealth 立即探测模型配置并更新其健康状态
+func (h *ModelConfigHandler) CheckHealth(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httputil.ReturnBadRequest(c, "无效的ID")
+		return
+	}
+
+	config, err := h.configService.GetModelConfigByID(c.Request.Context(), uint(id))
+	if err != nil {
+		httputil.ReturnNotFound(c, err.Error())
+		return
+	}
+
+	check, err := h.healthService.Probe(c.Request.Context(), config)
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithBean(c, "模型配置探测完成", check)
+}
+
+// ProviderInfo 已注册的模型提供者信息
+type ProviderInfo struct {
+	ModelType    string                   `json:"model_type"`
+	Defaults     einosdk.ProviderDefaults `json:"defaults"`
+	Capabilities einosdk.Capabilities     `json:"capabilities"`
+}
+
+// GetProviders 获取所有已注册的模型提供者及其能力
+func (h *ModelConfigHandler) GetProviders(c *gin.Context) {
+	providers := einosdk.Providers()
+	response := make([]ProviderInfo, 0, len(providers))
+	for _, p := range providers {
+		response = append(response, ProviderInfo{
+			ModelType:    string(p.Type()),
+			Defaults:     p.Defaults(),
+			Capabilities: p.Capabilities(),
+		})
+	}
+
+	httputil.ReturnSuccessWithList(c, "获取模型提供者成功", response)
+}
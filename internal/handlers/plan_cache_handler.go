@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+
+	"personatrip/internal/repository"
+	"personatrip/internal/services"
+	"personatrip/internal/utils/httputil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PlanCacheHandler 处理旅行计划缓存管理相关的请求
+type PlanCacheHandler struct {
+	cacheService *services.PlanCacheService
+}
+
+// NewPlanCacheHandler 创建新的缓存管理处理器
+func NewPlanCacheHandler(cacheService *services.PlanCacheService) *PlanCacheHandler {
+	return &PlanCacheHandler{
+		cacheService: cacheService,
+	}
+}
+
+// List 获取缓存列表，可按目的地过滤
+func (h *PlanCacheHandler) List(c *gin.Context) {
+	entries, err := h.cacheService.List(c.Request.Context(), c.Query("destination"))
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithList(c, "获取旅行计划缓存成功", entries)
+}
+
+// Invalidate 删除指定摘要的缓存
+func (h *PlanCacheHandler) Invalidate(c *gin.Context) {
+	err := h.cacheService.Invalidate(c.Request.Context(), c.Param("hash"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			httputil.ReturnNotFound(c, "缓存不存在")
+			return
+		}
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccess(c, "缓存已删除")
+}
+
+// InvalidateAll 删除指定目的地的全部缓存，未指定目的地时清空所有缓存
+func (h *PlanCacheHandler) InvalidateAll(c *gin.Context) {
+	deleted, err := h.cacheService.InvalidateAll(c.Request.Context(), c.Query("destination"))
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithData(c, "缓存已删除", gin.H{"deleted": deleted})
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"personatrip/internal/models"
+	"personatrip/internal/services"
+	"personatrip/internal/utils/httputil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PromptHandler 处理提示词模板管理相关的请求
+type PromptHandler struct {
+	promptService services.PromptService
+}
+
+// NewPromptHandler 创建新的提示词模板处理器
+func NewPromptHandler(promptService services.PromptService) *PromptHandler {
+	return &PromptHandler{
+		promptService: promptService,
+	}
+}
+
+// GetDefinitions 获取所有提示词模板名称、可用变量和当前启用的版本
+func (h *PromptHandler) GetDefinitions(c *gin.Context) {
+	definitions, err := h.promptService.GetDefinitions(c.Request.Context())
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithList(c, "获取提示词定义成功", definitions)
+}
+
+// Create 创建提示词模板的新版本
+func (h *PromptHandler) Create(c *gin.Context) {
+	var req models.PromptTemplateCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.ReturnBadRequest(c, err.Error())
+		return
+	}
+
+	prompt, err := h.promptService.CreatePromptTemplate(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPromptTemplate) {
+			httputil.ReturnBadRequest(c, err.Error())
+			return
+		}
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnCreated(c, "提示词模板创建成功", prompt)
+}
+
+// List 获取提示词模板版本列表，可按名称过滤
+func (h *PromptHandler) List(c *gin.Context) {
+	prompts, err := h.promptService.ListPromptTemplates(c.Request.Context(), c.Query("name"))
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithList(c, "获取提示词模板成功", prompts)
+}
+
+// GetByID 根据ID获取提示词模板版本
+func (h *PromptHandler) GetByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httputil.ReturnBadRequest(c, "无效的ID")
+		return
+	}
+
+	prompt, err := h.promptService.GetPromptTemplateByID(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrPromptTemplateNotFound) {
+			httputil.ReturnNotFound(c, err.Error())
+			return
+		}
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithBean(c, "获取提示词模板成功", prompt)
+}
+
+// Delete 删除提示词模板版本，启用中的版本和运行中的实验使用的版本不能删除
+func (h *PromptHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httputil.ReturnBadRequest(c, "无效的ID")
+		return
+	}
+
+	if err := h.promptService.DeletePromptTemplate(c.Request.Context(), uint(id)); err != nil {
+		if errors.Is(err, services.ErrPromptTemplateNotFound) {
+			httputil.ReturnNotFound(c, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrPromptTemplateInUse) {
+			httputil.ReturnError(c, http.StatusConflict, err.Error())
+			return
+		}
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccess(c, "提示词模板删除成功")
+}
+
+// Activate 启用指定版本，之后的生成立即使用该版本
+func (h *PromptHandler) Activate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		httputil.ReturnBadRequest(c, "无效的ID")
+		return
+	}
+
+	if err := h.promptService.SetActivePromptTemplate(c.Request.Context(), uint(id)); err != nil {
+		if errors.Is(err, services.ErrPromptTemplateNotFound) {
+			httputil.ReturnNotFound(c, err.Error())
+			return
+		}
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	prompt, err := h.promptService.GetPromptTemplateByID(c.Request.Context(), uint(id))
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithBean(c, "提示词模板启用成功", prompt)
+}
+
+// Preview 使用给定的数据渲染提示词模板
+func (h *PromptHandler) Preview(c *gin.Context) {
+	var req models.PromptPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.ReturnBadRequest(c, err.Error())
+		return
+	}
+
+	preview, err := h.promptService.Preview(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPromptTemplate) {
+			httputil.ReturnBadRequest(c, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrPromptTemplateNotFound) {
+			httputil.ReturnNotFound(c, err.Error())
+			return
+		}
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithBean(c, "提示词渲染成功", preview)
+}
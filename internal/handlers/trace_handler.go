@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+
+	"personatrip/internal/models"
+	"personatrip/internal/repository"
+	"personatrip/internal/services"
+	"personatrip/internal/utils/httputil"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TraceHandler 处理生成过程记录相关的请求
+type TraceHandler struct {
+	traceService *services.TraceService
+}
+
+// NewTraceHandler 创建新的生成过程记录处理器
+func NewTraceHandler(traceService *services.TraceService) *TraceHandler {
+	return &TraceHandler{
+		traceService: traceService,
+	}
+}
+
+// List 获取最近的记录，可按用户过滤，failed=true时只返回失败的记录
+func (h *TraceHandler) List(c *gin.Context) {
+	traces, err := h.traceService.List(c.Request.Context(), c.Query("user_id"), c.Query("failed") == "true")
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithList(c, "获取生成过程记录成功", traces)
+}
+
+// GetByID 根据ID获取记录
+func (h *TraceHandler) GetByID(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		httputil.ReturnBadRequest(c, "无效的ID格式")
+		return
+	}
+
+	trace, err := h.traceService.Get(c.Request.Context(), id)
+	h.returnTrace(c, trace, err)
+}
+
+// GetByPlanID 获取生成指定旅行计划时的记录
+func (h *TraceHandler) GetByPlanID(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		httputil.ReturnBadRequest(c, "无效的ID格式")
+		return
+	}
+
+	trace, err := h.traceService.GetByPlanID(c.Request.Context(), id)
+	h.returnTrace(c, trace, err)
+}
+
+// returnTrace 返回记录，不存在或已过期时返回404
+func (h *TraceHandler) returnTrace(c *gin.Context, trace *models.GenerationTrace, err error) {
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			httputil.ReturnNotFound(c, "生成过程记录不存在或已过期")
+			return
+		}
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithBean(c, "获取生成过程记录成功", trace)
+}
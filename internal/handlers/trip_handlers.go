@@ -2,11 +2,15 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"time"
 
 	"personatrip/internal/models"
+	"personatrip/internal/services"
 	"personatrip/internal/utils/httputil"
 	"personatrip/internal/utils/logger"
+	"personatrip/pkg/einosdk"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,6 +19,7 @@ import (
 // EinoServiceInterface 定义Eino服务接口
 type EinoServiceInterface interface {
 	GenerateTripPlan(ctx context.Context, req *models.PlanRequest) (*models.TripPlan, error)
+	GenerateTripPlanStream(ctx context.Context, req *models.PlanRequest, handler einosdk.StreamHandler) (*models.TripPlan, error)
 	GenerateDestinationRecommendations(ctx context.Context, preferences *models.UserPreferences) ([]string, error)
 	TestGenerateText(ctx context.Context, prompt string) (string, error)
 	RefreshModelConfig(ctx context.Context) error
@@ -24,22 +29,24 @@ type EinoServiceInterface interface {
 type TripHandler struct {
 	einoService EinoServiceInterface
 	repository  TripRepository
+	experiments services.ExperimentService // 为空时不统计实验计划的修改和删除
 }
 
 // TripRepository 定义仓库接口
 type TripRepository interface {
-	CreateTripPlan(ctx *gin.Context, plan *models.TripPlan) (*models.TripPlan, error)
-	GetTripPlanByID(ctx *gin.Context, id primitive.ObjectID) (*models.TripPlan, error)
-	GetTripPlansByUserID(ctx *gin.Context, userID primitive.ObjectID) ([]*models.TripPlan, error)
-	UpdateTripPlan(ctx *gin.Context, plan *models.TripPlan) error
-	DeleteTripPlan(ctx *gin.Context, id primitive.ObjectID) error
+	CreateTripPlan(ctx context.Context, plan *models.TripPlan) (*models.TripPlan, error)
+	GetTripPlanByID(ctx context.Context, id primitive.ObjectID) (*models.TripPlan, error)
+	GetTripPlansByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.TripPlan, error)
+	UpdateTripPlan(ctx context.Context, plan *models.TripPlan) error
+	DeleteTripPlan(ctx context.Context, id primitive.ObjectID) error
 }
 
 // NewTripHandler 创建新的旅行处理程序
-func NewTripHandler(einoService EinoServiceInterface, repository TripRepository) *TripHandler {
+func NewTripHandler(einoService EinoServiceInterface, repository TripRepository, experiments services.ExperimentService) *TripHandler {
 	return &TripHandler{
 		einoService: einoService,
 		repository:  repository,
+		experiments: experiments,
 	}
 }
 
@@ -52,6 +59,7 @@ func NewTripHandler(einoService EinoServiceInterface, repository TripRepository)
 // @Param request body models.PlanRequest true "旅行计划请求"
 // @Success 200 {object} models.ApiResponse
 // @Failure 400 {object} models.ApiResponse
+// @Failure 429 {object} models.ApiResponse
 // @Failure 500 {object} models.ApiResponse
 // @Router /api/trips/generate [post]
 func (h *TripHandler) GenerateTripPlan(c *gin.Context) {
@@ -69,9 +77,12 @@ func (h *TripHandler) GenerateTripPlan(c *gin.Context) {
 	}
 
 	// 调用Eino服务生成旅行计划
-	plan, err := h.einoService.GenerateTripPlan(c.Request.Context(), &req)
+	plan, err := h.einoService.GenerateTripPlan(callContext(c), &req)
 	if err != nil {
 		logger.Errorf("生成旅行计划失败: %v", err)
+		if returnModelBusy(c, err) {
+			return
+		}
 		httputil.ReturnInternalError(c, "生成旅行计划失败")
 		return
 	}
@@ -108,6 +119,91 @@ func (h *TripHandler) GenerateTripPlan(c *gin.Context) {
 	httputil.ReturnSuccessWithBean(c, "旅行计划生成成功", savedPlan)
 }
 
+// GenerateTripPlanStream 以SSE方式流式生成旅行计划
+// @Summary 流式生成AI旅行计划
+// @Description 以Server-Sent Events推送模型增量输出、工具调用事件，最后推送保存后的旅行计划
+// @Tags trips
+// @Accept json
+// @Produce text/event-stream
+// @Param request body models.PlanRequest true "旅行计划请求"
+// @Success 200 {string} string "SSE事件流: delta, reasoning, tool_call, tool_result, plan, error，模型繁忙时error事件带retry_after秒数"
+// @Failure 400 {object} models.ApiResponse
+// @Failure 401 {object} models.ApiResponse
+// @Router /api/trips/generate/stream [post]
+func (h *TripHandler) GenerateTripPlanStream(c *gin.Context) {
+	var req models.PlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.ReturnBadRequest(c, "无效的请求格式")
+		return
+	}
+	logger.Infof("收到流式旅行计划请求: %+v", req)
+
+	// 验证日期
+	if req.StartDate.After(req.EndDate) {
+		httputil.ReturnBadRequest(c, "开始日期不能晚于结束日期")
+		return
+	}
+
+	// 开始推送事件前先校验用户，之后就只能通过事件返回错误
+	userIDStr := c.GetString("user_id")
+	if userIDStr == "" {
+		httputil.ReturnUnauthorized(c, "用户未认证")
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		httputil.ReturnBadRequest(c, "无效的用户ID")
+		return
+	}
+
+	// 设置SSE响应头
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	// 客户端断开后不再写入事件
+	send := func(event string, data interface{}) {
+		if c.Request.Context().Err() != nil {
+			return
+		}
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+	}
+
+	plan, err := h.einoService.GenerateTripPlanStream(callContext(c), &req, func(event *einosdk.StreamEvent) {
+		send(string(event.Type), event)
+	})
+	if err != nil {
+		logger.Errorf("流式生成旅行计划失败: %v", err)
+		var busy *services.ModelBusyError
+		if errors.As(err, &busy) {
+			send("error", gin.H{"message": busy.Error(), "retry_after": busy.RetryAfterSeconds()})
+			return
+		}
+		send("error", gin.H{"message": "生成旅行计划失败"})
+		return
+	}
+
+	plan.UserID = userID
+	if plan.Title == "" {
+		plan.Title = req.Destination + " Trip " + time.Now().Format("2006-01-02")
+	}
+
+	savedPlan, err := h.repository.CreateTripPlan(c, plan)
+	if err != nil {
+		logger.Errorf("保存旅行计划失败: %v", err)
+		send("error", gin.H{"message": "保存旅行计划失败"})
+		return
+	}
+
+	logger.Infof("成功流式生成旅行计划, ID: %s", savedPlan.ID.Hex())
+	send("plan", savedPlan)
+}
+
 // GetTripPlan 获取旅行计划
 // @Summary 获取旅行计划
 // @Description 通过ID获取旅行计划详情
@@ -223,15 +319,22 @@ func (h *TripHandler) UpdateTripPlan(c *gin.Context) {
 		return
 	}
 
-	// 保持原始ID和用户ID
+	// 保持原始ID、用户ID、生成信息和生成时的请求
 	updatedPlan.ID = id
 	updatedPlan.UserID = userID
+	updatedPlan.Generation = existingPlan.Generation
+	updatedPlan.Request = existingPlan.Request
 
 	// 更新计划
 	if err := h.repository.UpdateTripPlan(c, &updatedPlan); err != nil {
 		httputil.ReturnInternalError(c, "更新旅行计划失败")
 		return
 	}
+	if h.experiments != nil {
+		if err := h.experiments.RecordPlanEdited(c, existingPlan); err != nil {
+			logger.Errorf("记录实验计划修改失败: %v", err)
+		}
+	}
 
 	httputil.ReturnSuccessWithBean(c, "旅行计划更新成功", updatedPlan)
 }
@@ -287,6 +390,11 @@ func (h *TripHandler) DeleteTripPlan(c *gin.Context) {
 		httputil.ReturnInternalError(c, "删除旅行计划失败")
 		return
 	}
+	if h.experiments != nil {
+		if err := h.experiments.RecordPlanDeleted(c, existingPlan); err != nil {
+			logger.Errorf("记录实验计划删除失败: %v", err)
+		}
+	}
 
 	httputil.ReturnSuccess(c, "旅行计划删除成功")
 }
@@ -300,6 +408,7 @@ func (h *TripHandler) DeleteTripPlan(c *gin.Context) {
 // @Param preferences body models.UserPreferences true "用户偏好"
 // @Success 200 {object} models.ApiResponse
 // @Failure 400 {object} models.ApiResponse
+// @Failure 429 {object} models.ApiResponse
 // @Failure 500 {object} models.ApiResponse
 // @Router /api/recommendations/destinations [post]
 func (h *TripHandler) GenerateDestinationRecommendations(c *gin.Context) {
@@ -310,11 +419,24 @@ func (h *TripHandler) GenerateDestinationRecommendations(c *gin.Context) {
 	}
 
 	// 调用Eino服务生成推荐
-	recommendations, err := h.einoService.GenerateDestinationRecommendations(c.Request.Context(), &preferences)
+	recommendations, err := h.einoService.GenerateDestinationRecommendations(callContext(c), &preferences)
 	if err != nil {
+		if returnModelBusy(c, err) {
+			return
+		}
 		httputil.ReturnInternalError(c, "生成推荐失败")
 		return
 	}
 
 	httputil.ReturnSuccessWithList(c, "目的地推荐生成成功", recommendations)
 }
+
+// returnModelBusy 所有模型配置都繁忙时返回429和Retry-After，返回是否已写入响应
+func returnModelBusy(c *gin.Context, err error) bool {
+	var busy *services.ModelBusyError
+	if !errors.As(err, &busy) {
+		return false
+	}
+	httputil.ReturnTooManyRequests(c, busy.Error(), busy.RetryAfterSeconds())
+	return true
+}
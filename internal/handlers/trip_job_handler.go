@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"personatrip/internal/models"
+	"personatrip/internal/services"
+	"personatrip/internal/utils/httputil"
+	"personatrip/internal/utils/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TripJobHandler 处理异步旅行计划生成任务的请求
+type TripJobHandler struct {
+	jobService *services.TripJobService
+}
+
+// NewTripJobHandler 创建新的任务处理程序
+func NewTripJobHandler(jobService *services.TripJobService) *TripJobHandler {
+	return &TripJobHandler{
+		jobService: jobService,
+	}
+}
+
+// Submit 提交旅行计划生成任务
+// @Summary 提交异步旅行计划生成任务
+// @Description 提交后立即返回任务ID，通过查询任务接口轮询生成结果
+// @Tags trips
+// @Accept json
+// @Produce json
+// @Param request body models.PlanRequest true "旅行计划请求"
+// @Success 202 {object} models.ApiResponse
+// @Failure 400 {object} models.ApiResponse
+// @Failure 401 {object} models.ApiResponse
+// @Failure 503 {object} models.ApiResponse
+// @Router /api/trips/jobs [post]
+func (h *TripJobHandler) Submit(c *gin.Context) {
+	var req models.PlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httputil.ReturnBadRequest(c, "无效的请求格式")
+		return
+	}
+
+	// 验证日期
+	if req.StartDate.After(req.EndDate) {
+		httputil.ReturnBadRequest(c, "开始日期不能晚于结束日期")
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.jobService.Submit(c.Request.Context(), userID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrJobQueueFull) {
+			httputil.ReturnError(c, http.StatusServiceUnavailable, "任务队列已满，请稍后重试")
+			return
+		}
+		logger.Errorf("提交旅行计划任务失败: %v", err)
+		httputil.ReturnInternalError(c, "提交旅行计划任务失败")
+		return
+	}
+
+	logger.Infof("已提交旅行计划任务, ID: %s", job.ID.Hex())
+	response := models.NewSuccessResponse("旅行计划任务已提交").WithBean(job)
+	c.JSON(http.StatusAccepted, response)
+}
+
+// GetJob 查询旅行计划生成任务
+// @Summary 查询旅行计划生成任务
+// @Description 获取任务状态，成功时返回生成的旅行计划ID
+// @Tags trips
+// @Produce json
+// @Param id path string true "任务ID"
+// @Success 200 {object} models.ApiResponse
+// @Failure 400 {object} models.ApiResponse
+// @Failure 403 {object} models.ApiResponse
+// @Failure 404 {object} models.ApiResponse
+// @Router /api/trips/jobs/{id} [get]
+func (h *TripJobHandler) GetJob(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		httputil.ReturnBadRequest(c, "无效的ID格式")
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.jobService.GetJob(c.Request.Context(), id)
+	if err != nil {
+		httputil.ReturnNotFound(c, "任务未找到")
+		return
+	}
+
+	if job.UserID != userID {
+		httputil.ReturnForbidden(c, "无权查看此任务")
+		return
+	}
+
+	httputil.ReturnSuccessWithBean(c, "获取任务成功", job)
+}
+
+// currentUserID 从认证中间件设置的上下文中获取用户ID，失败时直接写入错误响应
+func currentUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userIDStr := c.GetString("user_id")
+	if userIDStr == "" {
+		httputil.ReturnUnauthorized(c, "用户未认证")
+		return primitive.NilObjectID, false
+	}
+
+	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		httputil.ReturnBadRequest(c, "无效的用户ID")
+		return primitive.NilObjectID, false
+	}
+
+	return userID, true
+}
+
+// callContext 返回附带用户ID和接口路径的请求context，用于记录模型调用用量
+func callContext(c *gin.Context) context.Context {
+	return services.WithCallInfo(c.Request.Context(), c.GetString("user_id"), c.FullPath())
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"personatrip/internal/models"
+	"personatrip/internal/services"
+	"personatrip/internal/utils/httputil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UsageHandler 处理大模型用量统计相关的请求
+type UsageHandler struct {
+	usageService services.UsageService
+}
+
+// NewUsageHandler 创建新的用量统计处理器
+func NewUsageHandler(usageService services.UsageService) *UsageHandler {
+	return &UsageHandler{
+		usageService: usageService,
+	}
+}
+
+// GetByUser 按用户统计用量和费用
+func (h *UsageHandler) GetByUser(c *gin.Context) {
+	var query models.UsageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		httputil.ReturnBadRequest(c, "无效的日期格式，应为YYYY-MM-DD")
+		return
+	}
+
+	summaries, err := h.usageService.GetUsageByUser(c.Request.Context(), &query)
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithList(c, "获取用户用量成功", summaries)
+}
+
+// GetByModel 按模型配置统计用量和费用
+func (h *UsageHandler) GetByModel(c *gin.Context) {
+	var query models.UsageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		httputil.ReturnBadRequest(c, "无效的日期格式，应为YYYY-MM-DD")
+		return
+	}
+
+	summaries, err := h.usageService.GetUsageByModel(c.Request.Context(), &query)
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithList(c, "获取模型用量成功", summaries)
+}
+
+// GetByDay 按天统计用量和费用
+func (h *UsageHandler) GetByDay(c *gin.Context) {
+	var query models.UsageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		httputil.ReturnBadRequest(c, "无效的日期格式，应为YYYY-MM-DD")
+		return
+	}
+
+	summaries, err := h.usageService.GetUsageByDay(c.Request.Context(), &query)
+	if err != nil {
+		httputil.ReturnInternalError(c, err.Error())
+		return
+	}
+
+	httputil.ReturnSuccessWithList(c, "获取每日用量成功", summaries)
+}
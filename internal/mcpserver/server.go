@@ -0,0 +1,196 @@
+package mcpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"personatrip/internal/handlers"
+	"personatrip/internal/models"
+	"personatrip/internal/services"
+	"personatrip/internal/utils/logger"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// serverName 对MCP客户端报告的服务器名称
+const serverName = "personatrip"
+
+// endpointPrefix 通过MCP调用模型时记录的调用来源前缀，后面是工具名称
+const endpointPrefix = "mcp:"
+
+// Planner 生成旅行计划和目的地推荐，由EinoService实现
+type Planner interface {
+	GenerateTripPlan(ctx context.Context, req *models.PlanRequest) (*models.TripPlan, error)
+	GenerateDestinationRecommendations(ctx context.Context, preferences *models.UserPreferences) ([]string, error)
+	RegenerateTripDay(ctx context.Context, plan *models.TripPlan, day int, instructions string) (*models.TripPlan, error)
+}
+
+// TokenValidator 验证用户API令牌并返回用户ID，由AuthService实现
+type TokenValidator interface {
+	ValidateAPIToken(ctx context.Context, token string) (string, error)
+}
+
+// Server 把PersonaTrip的旅行规划能力作为MCP工具提供给其他智能体和IDE助手，
+// 每个会话代表一个用户，用户通过API令牌认证
+type Server struct {
+	planner     Planner
+	trips       handlers.TripRepository
+	tokens      TokenValidator
+	experiments services.ExperimentService // 为空时不统计实验计划的修改
+	mcp         *server.MCPServer
+}
+
+// New 创建MCP服务器并注册所有工具
+func New(planner Planner, trips handlers.TripRepository, tokens TokenValidator, experiments services.ExperimentService, version string) *Server {
+	s := &Server{
+		planner:     planner,
+		trips:       trips,
+		tokens:      tokens,
+		experiments: experiments,
+	}
+	s.mcp = server.NewMCPServer(serverName, version,
+		server.WithToolCapabilities(false),
+		server.WithRecovery(),
+		server.WithToolHandlerMiddleware(s.authenticated),
+		server.WithInstructions("PersonaTrip旅行规划服务：生成、查看旅行计划，重新安排某一天的行程，以及根据偏好推荐目的地。生成计划需要一到几分钟。"),
+	)
+	s.registerTools()
+	return s
+}
+
+// ServeStdio 通过标准输入输出提供服务，整个进程代表令牌所属的用户，启动前验证令牌，
+// 之后每次调用工具时重新验证，令牌被吊销或过期后拒绝调用。ctx结束时返回
+func (s *Server) ServeStdio(ctx context.Context, token string, in io.Reader, out io.Writer) error {
+	userID, err := s.tokens.ValidateAPIToken(ctx, token)
+	if err != nil {
+		return fmt.Errorf("验证API令牌失败: %w", err)
+	}
+	logger.Infof("MCP服务器通过stdio提供服务, 用户: %s", userID)
+
+	stdio := server.NewStdioServer(s.mcp)
+	stdio.SetContextFunc(func(ctx context.Context) context.Context {
+		return withToken(withUserID(ctx, userID), token)
+	})
+	err = stdio.Listen(ctx, in, out)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// ServeSSE 通过SSE提供服务，baseURL是客户端访问本服务的地址，为空时由监听地址得出，只指定端口时使用localhost。
+// 每个请求都需要在Authorization请求头中带上API令牌，ctx结束时关闭所有会话并返回
+func (s *Server) ServeSSE(ctx context.Context, addr, baseURL string) error {
+	if baseURL == "" {
+		baseURL = "http://" + addr
+		if strings.HasPrefix(addr, ":") {
+			baseURL = "http://localhost" + addr
+		}
+	}
+	httpServer := &http.Server{Addr: addr}
+	sse := server.NewSSEServer(s.mcp,
+		server.WithBaseURL(baseURL),
+		server.WithHTTPServer(httpServer),
+		server.WithSSEContextFunc(func(ctx context.Context, r *http.Request) context.Context {
+			// 消息在返回202之后才处理，此时请求的context已经结束，不能让它取消生成
+			return context.WithoutCancel(ctx)
+		}),
+	)
+	httpServer.Handler = s.requireToken(sse)
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Infof("MCP服务器通过SSE提供服务, 监听 %s, SSE地址 %s", addr, baseURL+sse.CompleteSsePath())
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("Shutting down MCP server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sse.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// requireToken 验证请求中的API令牌，失败时返回401，成功时把用户ID放入请求的context
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			http.Error(w, "缺少API令牌", http.StatusUnauthorized)
+			return
+		}
+		userID, err := s.tokens.ValidateAPIToken(r.Context(), token)
+		if err != nil {
+			if !errors.Is(err, services.ErrInvalidAPIToken) {
+				logger.Errorf("验证API令牌失败: %v", err)
+			}
+			http.Error(w, "无效的API令牌", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withUserID(r.Context(), userID)))
+	})
+}
+
+// authenticated 拒绝没有用户的工具调用，并在context中附加调用来源，用于记录模型用量。
+// stdio模式的令牌只在启动时传入一次，每次调用前重新验证；SSE模式的每个请求已经由requireToken验证
+func (s *Server) authenticated(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		userID := userIDFromContext(ctx)
+		if userID == "" {
+			return mcp.NewToolResultError("未认证，请使用API令牌连接"), nil
+		}
+		if token := tokenFromContext(ctx); token != "" {
+			if _, err := s.tokens.ValidateAPIToken(ctx, token); err != nil {
+				if !errors.Is(err, services.ErrInvalidAPIToken) {
+					logger.Errorf("验证API令牌失败: %v", err)
+					return mcp.NewToolResultError("验证API令牌失败，请稍后重试"), nil
+				}
+				return mcp.NewToolResultError("API令牌已被吊销或过期，请使用新的令牌重新连接"), nil
+			}
+		}
+		ctx = services.WithCallInfo(ctx, userID, endpointPrefix+request.Params.Name)
+		return next(ctx, request)
+	}
+}
+
+// userIDKey context中保存用户ID的键
+type userIDKey struct{}
+
+// withUserID 在context中附加用户ID
+func withUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// userIDFromContext 获取context中的用户ID，不存在时返回空字符串
+func userIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey{}).(string)
+	return userID
+}
+
+// tokenKey context中保存需要在调用工具时重新验证的API令牌的键
+type tokenKey struct{}
+
+// withToken 在context中附加API令牌
+func withToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// tokenFromContext 获取context中的API令牌，不存在时返回空字符串
+func tokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(tokenKey{}).(string)
+	return token
+}
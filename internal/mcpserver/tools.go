@@ -0,0 +1,302 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"personatrip/internal/models"
+	"personatrip/internal/services"
+	"personatrip/internal/utils/logger"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// dateLayout 工具参数中日期的格式
+const dateLayout = "2006-01-02"
+
+// preferenceOptions 旅行偏好参数，生成计划和推荐目的地共用
+var preferenceOptions = []mcp.ToolOption{
+	mcp.WithString("budget", mcp.Description("预算等级"), mcp.Enum("经济", "中等", "豪华")),
+	mcp.WithArray("travel_style", mcp.Description("旅行风格，如休闲、探险、文化"), mcp.Items(map[string]interface{}{"type": "string"})),
+	mcp.WithArray("accommodation", mcp.Description("住宿偏好，如酒店、民宿"), mcp.Items(map[string]interface{}{"type": "string"})),
+	mcp.WithArray("transportation", mcp.Description("交通偏好，如公共交通、自驾"), mcp.Items(map[string]interface{}{"type": "string"})),
+	mcp.WithArray("activities", mcp.Description("活动偏好，如博物馆、徒步"), mcp.Items(map[string]interface{}{"type": "string"})),
+	mcp.WithArray("food_preferences", mcp.Description("饮食偏好，如当地小吃、素食"), mcp.Items(map[string]interface{}{"type": "string"})),
+}
+
+// generateTripPlanArgs generate_trip_plan的参数
+type generateTripPlanArgs struct {
+	Destination     string   `json:"destination"`
+	StartDate       string   `json:"start_date"`
+	EndDate         string   `json:"end_date"`
+	Budget          string   `json:"budget"`
+	TravelStyle     []string `json:"travel_style"`
+	Accommodation   []string `json:"accommodation"`
+	Transportation  []string `json:"transportation"`
+	Activities      []string `json:"activities"`
+	FoodPreferences []string `json:"food_preferences"`
+	SpecialRequests string   `json:"special_requests"`
+}
+
+// tripSummary list_my_trips返回的计划摘要
+type tripSummary struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Destination string    `json:"destination"`
+	StartDate   string    `json:"start_date"`
+	EndDate     string    `json:"end_date"`
+	Days        int       `json:"days"`
+	IsPublic    bool      `json:"is_public"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// regenerateDayArgs regenerate_day的参数
+type regenerateDayArgs struct {
+	TripID       string `json:"trip_id"`
+	Day          int    `json:"day"`
+	Instructions string `json:"instructions"`
+}
+
+// regenerateDayResult regenerate_day的结果，只返回重新生成的一天和重新计算的预算
+type regenerateDayResult struct {
+	TripID string         `json:"trip_id"`
+	Day    models.TripDay `json:"day"`
+	Budget models.Budget  `json:"budget"`
+}
+
+// registerTools 注册所有工具
+func (s *Server) registerTools() {
+	s.mcp.AddTool(mcp.NewTool("generate_trip_plan", append([]mcp.ToolOption{
+		mcp.WithDescription("根据目的地、日期和偏好生成详细的旅行计划，并保存到当前用户的计划中。返回保存后的完整计划，其中id可用于get_trip和regenerate_day"),
+		mcp.WithString("destination", mcp.Required(), mcp.Description("目的地，如“京都”")),
+		mcp.WithString("start_date", mcp.Required(), mcp.Description("开始日期，格式为YYYY-MM-DD")),
+		mcp.WithString("end_date", mcp.Required(), mcp.Description("结束日期，格式为YYYY-MM-DD")),
+		mcp.WithString("special_requests", mcp.Description("特殊要求")),
+	}, preferenceOptions...)...), s.generateTripPlan)
+
+	s.mcp.AddTool(mcp.NewTool("list_my_trips",
+		mcp.WithDescription("列出当前用户保存的所有旅行计划的摘要"),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{ReadOnlyHint: true}),
+	), s.listMyTrips)
+
+	s.mcp.AddTool(mcp.NewTool("get_trip",
+		mcp.WithDescription("获取旅行计划的完整内容，只能获取自己的或公开的计划"),
+		mcp.WithString("trip_id", mcp.Required(), mcp.Description("旅行计划ID")),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{ReadOnlyHint: true}),
+	), s.getTrip)
+
+	s.mcp.AddTool(mcp.NewTool("recommend_destinations", append([]mcp.ToolOption{
+		mcp.WithDescription("根据旅行偏好推荐目的地"),
+		mcp.WithToolAnnotation(mcp.ToolAnnotation{ReadOnlyHint: true}),
+	}, preferenceOptions...)...), s.recommendDestinations)
+
+	s.mcp.AddTool(mcp.NewTool("regenerate_day",
+		mcp.WithDescription("按要求重新安排自己的旅行计划中的某一天，沿用生成计划时的预算和偏好，保存修改后的计划并重新计算预算。返回新的当天行程和预算"),
+		mcp.WithString("trip_id", mcp.Required(), mcp.Description("旅行计划ID")),
+		mcp.WithNumber("day", mcp.Required(), mcp.Min(1), mcp.Description("第几天，从1开始")),
+		mcp.WithString("instructions", mcp.Description("对这一天的修改要求，如“换成室内活动”，为空时只要求与原安排不同")),
+	), s.regenerateDay)
+}
+
+// generateTripPlan 生成并保存旅行计划
+func (s *Server) generateTripPlan(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args generateTripPlanArgs
+	if err := bindArguments(request, &args); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if args.Destination == "" {
+		return mcp.NewToolResultError("destination不能为空"), nil
+	}
+	startDate, err := time.Parse(dateLayout, args.StartDate)
+	if err != nil {
+		return mcp.NewToolResultError("start_date的格式应为YYYY-MM-DD"), nil
+	}
+	endDate, err := time.Parse(dateLayout, args.EndDate)
+	if err != nil {
+		return mcp.NewToolResultError("end_date的格式应为YYYY-MM-DD"), nil
+	}
+	if startDate.After(endDate) {
+		return mcp.NewToolResultError("开始日期不能晚于结束日期"), nil
+	}
+	userID, err := primitive.ObjectIDFromHex(userIDFromContext(ctx))
+	if err != nil {
+		return mcp.NewToolResultError("无效的用户ID"), nil
+	}
+
+	req := &models.PlanRequest{
+		Destination:     args.Destination,
+		StartDate:       startDate,
+		EndDate:         endDate,
+		Budget:          args.Budget,
+		TravelStyle:     args.TravelStyle,
+		Accommodation:   args.Accommodation,
+		Transportation:  args.Transportation,
+		Activities:      args.Activities,
+		FoodPreferences: args.FoodPreferences,
+		SpecialRequests: args.SpecialRequests,
+	}
+	logger.Infof("收到MCP旅行计划请求: %+v", *req)
+	plan, err := s.planner.GenerateTripPlan(ctx, req)
+	if err != nil {
+		logger.Errorf("生成旅行计划失败: %v", err)
+		return modelError("生成旅行计划失败", err), nil
+	}
+
+	plan.UserID = userID
+	if plan.Title == "" {
+		plan.Title = req.Destination + " Trip " + time.Now().Format("2006-01-02")
+	}
+	saved, err := s.trips.CreateTripPlan(ctx, plan)
+	if err != nil {
+		logger.Errorf("保存旅行计划失败: %v", err)
+		return mcp.NewToolResultError("保存旅行计划失败"), nil
+	}
+	logger.Infof("成功通过MCP生成旅行计划, ID: %s", saved.ID.Hex())
+	return jsonResult(saved)
+}
+
+// listMyTrips 列出当前用户的旅行计划摘要
+func (s *Server) listMyTrips(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	userID, err := primitive.ObjectIDFromHex(userIDFromContext(ctx))
+	if err != nil {
+		return mcp.NewToolResultError("无效的用户ID"), nil
+	}
+	plans, err := s.trips.GetTripPlansByUserID(ctx, userID)
+	if err != nil {
+		logger.Errorf("获取用户旅行计划失败: %v", err)
+		return mcp.NewToolResultError("获取旅行计划失败"), nil
+	}
+
+	summaries := make([]tripSummary, 0, len(plans))
+	for _, plan := range plans {
+		summaries = append(summaries, tripSummary{
+			ID:          plan.ID.Hex(),
+			Title:       plan.Title,
+			Destination: plan.Destination,
+			StartDate:   plan.StartDate,
+			EndDate:     plan.EndDate,
+			Days:        len(plan.Days),
+			IsPublic:    plan.IsPublic,
+			CreatedAt:   plan.CreatedAt,
+		})
+	}
+	return jsonResult(summaries)
+}
+
+// getTrip 获取自己的或公开的旅行计划
+func (s *Server) getTrip(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args struct {
+		TripID string `json:"trip_id"`
+	}
+	if err := bindArguments(request, &args); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	plan, result := s.findTrip(ctx, args.TripID, false)
+	if result != nil {
+		return result, nil
+	}
+	return jsonResult(plan)
+}
+
+// recommendDestinations 根据偏好推荐目的地
+func (s *Server) recommendDestinations(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var preferences models.UserPreferences
+	if err := bindArguments(request, &preferences); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	recommendations, err := s.planner.GenerateDestinationRecommendations(ctx, &preferences)
+	if err != nil {
+		logger.Errorf("生成目的地推荐失败: %v", err)
+		return modelError("生成目的地推荐失败", err), nil
+	}
+	return jsonResult(recommendations)
+}
+
+// regenerateDay 重新生成自己的计划中的某一天并保存
+func (s *Server) regenerateDay(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var args regenerateDayArgs
+	if err := bindArguments(request, &args); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	plan, result := s.findTrip(ctx, args.TripID, true)
+	if result != nil {
+		return result, nil
+	}
+
+	updated, err := s.planner.RegenerateTripDay(ctx, plan, args.Day, args.Instructions)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTripDay) {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		logger.Errorf("重新生成第%d天行程失败: %v", args.Day, err)
+		return modelError("重新生成行程失败", err), nil
+	}
+	if err := s.trips.UpdateTripPlan(ctx, updated); err != nil {
+		logger.Errorf("更新旅行计划失败: %v", err)
+		return mcp.NewToolResultError("更新旅行计划失败"), nil
+	}
+	if s.experiments != nil {
+		if err := s.experiments.RecordPlanEdited(ctx, plan); err != nil {
+			logger.Errorf("记录实验计划修改失败: %v", err)
+		}
+	}
+
+	return jsonResult(&regenerateDayResult{
+		TripID: updated.ID.Hex(),
+		Day:    updated.Days[args.Day-1],
+		Budget: updated.Budget,
+	})
+}
+
+// findTrip 获取旅行计划，own为true时只能获取自己的计划，否则也可以获取公开的计划。
+// 失败时返回给客户端的错误结果
+func (s *Server) findTrip(ctx context.Context, tripID string, own bool) (*models.TripPlan, *mcp.CallToolResult) {
+	id, err := primitive.ObjectIDFromHex(tripID)
+	if err != nil {
+		return nil, mcp.NewToolResultError("无效的trip_id")
+	}
+	plan, err := s.trips.GetTripPlanByID(ctx, id)
+	if err != nil {
+		return nil, mcp.NewToolResultError("旅行计划未找到")
+	}
+	isOwner := plan.UserID.Hex() == userIDFromContext(ctx)
+	if !isOwner && (own || !plan.IsPublic) {
+		// 不区分不存在和无权访问，避免泄露其他用户的计划ID
+		return nil, mcp.NewToolResultError("旅行计划未找到")
+	}
+	return plan, nil
+}
+
+// bindArguments 把工具参数解析到out
+func bindArguments(request mcp.CallToolRequest, out interface{}) error {
+	data, err := json.Marshal(request.Params.Arguments)
+	if err != nil {
+		return fmt.Errorf("无效的参数: %v", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("无效的参数: %v", err)
+	}
+	return nil
+}
+
+// jsonResult 把结果编码为JSON文本
+func jsonResult(v interface{}) (*mcp.CallToolResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// modelError 调用模型失败时的结果，模型繁忙时附上繁忙的原因，其中包含多久后重试
+func modelError(message string, err error) *mcp.CallToolResult {
+	var busy *services.ModelBusyError
+	if errors.As(err, &busy) {
+		return mcp.NewToolResultError(fmt.Sprintf("%s: %s", message, busy.Error()))
+	}
+	return mcp.NewToolResultError(message)
+}
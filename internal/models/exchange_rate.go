@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ExchangeRateBaseCurrency 汇率表的基准货币，表中每种货币的汇率都是相对它的
+const ExchangeRateBaseCurrency = "CNY"
+
+// ExchangeRate 管理员维护的汇率，智能体换算货币时使用，不需要访问网络
+type ExchangeRate struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Currency  string    `json:"currency" gorm:"size:3;uniqueIndex;not null"` // ISO 4217货币代码，如USD
+	Rate      float64   `json:"rate" gorm:"not null"`                        // 1单位该货币折合多少基准货币
+	Source    string    `json:"source" gorm:"size:200"`                      // 汇率来源，如"中国银行 2025-04-21 中间价"
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ExchangeRateRequest 设置汇率请求
+type ExchangeRateRequest struct {
+	Rate   float64 `json:"rate" binding:"required,gt=0"`
+	Source string  `json:"source" binding:"max=200"`
+}
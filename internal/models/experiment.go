@@ -0,0 +1,106 @@
+package models
+
+import "time"
+
+// 实验状态
+const (
+	ExperimentDraft   = "draft"   // 已创建，尚未开始
+	ExperimentRunning = "running" // 运行中，同一时间只有一个实验运行
+	ExperimentStopped = "stopped" // 已停止，保留数据用于报告
+)
+
+// Experiment 在线上流量中对比模型配置和提示词版本的A/B实验
+type Experiment struct {
+	ID          uint                `json:"id" gorm:"primaryKey"`
+	Name        string              `json:"name" gorm:"size:100;not null"`
+	Description string              `json:"description" gorm:"size:255"`
+	Status      string              `json:"status" gorm:"size:20;not null;index"`
+	Variants    []ExperimentVariant `json:"variants" gorm:"foreignKey:ExperimentID"`
+	StartedAt   *time.Time          `json:"started_at"` // 最近一次开始的时间
+	StoppedAt   *time.Time          `json:"stopped_at"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// ExperimentVariant 实验中的一个分组，由模型配置和提示词版本组成
+type ExperimentVariant struct {
+	ID            uint   `json:"id" gorm:"primaryKey"`
+	ExperimentID  uint   `json:"experiment_id" gorm:"index;not null"`
+	Name          string `json:"name" gorm:"size:100;not null"`
+	ModelConfigID uint   `json:"model_config_id" gorm:"not null"`
+	PromptVersion int    `json:"prompt_version"` // trip_plan提示词模板的版本，0表示使用当前启用的版本
+	Weight        int    `json:"weight" gorm:"not null"`
+}
+
+// ExperimentCreateRequest 创建实验的请求
+type ExperimentCreateRequest struct {
+	Name        string                     `json:"name" binding:"required"`
+	Description string                     `json:"description"`
+	Variants    []ExperimentVariantRequest `json:"variants" binding:"required,min=2,dive"`
+}
+
+// ExperimentVariantRequest 创建实验时的分组定义
+type ExperimentVariantRequest struct {
+	Name          string `json:"name" binding:"required"`
+	ModelConfigID uint   `json:"model_config_id" binding:"required"`
+	PromptVersion int    `json:"prompt_version" binding:"min=0"`
+	Weight        int    `json:"weight" binding:"required,min=1"` // 流量权重，按权重占比分配用户
+}
+
+// ExperimentExposure 记录一次分配到实验分组的旅行计划生成
+type ExperimentExposure struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ExperimentID uint      `json:"experiment_id" gorm:"index"`
+	VariantID    uint      `json:"variant_id" gorm:"index"`
+	UserID       string    `json:"user_id" gorm:"size:50"`
+	Success      bool      `json:"success"`
+	FellBack     bool      `json:"fell_back"`  // 分组的模型配置失败，由故障切换链中的其他配置生成
+	LatencyMs    int64     `json:"latency_ms"` // 整个生成过程的耗时（毫秒）
+	TotalTokens  int       `json:"total_tokens"`
+	Cost         float64   `json:"cost"`
+	Edited       bool      `json:"edited"`  // 用户修改过生成的计划
+	Deleted      bool      `json:"deleted"` // 用户删除了生成的计划
+	CreatedAt    time.Time `json:"created_at" gorm:"index"`
+}
+
+// ExperimentAssignment 用户在运行中实验里分到的分组
+type ExperimentAssignment struct {
+	Experiment *Experiment
+	Variant    *ExperimentVariant
+}
+
+// ExperimentTag 标记旅行计划由哪个实验分组生成
+type ExperimentTag struct {
+	ExperimentID uint   `json:"experiment_id" bson:"experiment_id"`
+	VariantID    uint   `json:"variant_id" bson:"variant_id"`
+	VariantName  string `json:"variant_name" bson:"variant_name"`
+	ExposureID   uint   `json:"exposure_id" bson:"exposure_id"` // 对应的生成记录，用于统计修改和删除
+}
+
+// ExperimentVariantReport 实验分组的效果统计
+type ExperimentVariantReport struct {
+	VariantID     uint    `json:"variant_id"`
+	Name          string  `json:"name"`
+	ModelConfigID uint    `json:"model_config_id"`
+	PromptVersion int     `json:"prompt_version"`
+	Weight        int     `json:"weight"`
+	Exposures     int64   `json:"exposures"` // 生成次数
+	Successes     int64   `json:"successes"`
+	Fallbacks     int64   `json:"fallbacks"`
+	Edits         int64   `json:"edits"`
+	Deletes       int64   `json:"deletes"`
+	TotalTokens   int64   `json:"total_tokens"`
+	TotalCost     float64 `json:"total_cost"`
+	AvgLatencyMs  float64 `json:"avg_latency_ms"`
+	SuccessRate   float64 `json:"success_rate"`  // 成功次数/生成次数
+	FallbackRate  float64 `json:"fallback_rate"` // 故障切换次数/成功次数
+	AvgCost       float64 `json:"avg_cost"`      // 每个成功生成的计划的平均费用
+	EditRate      float64 `json:"edit_rate"`     // 被修改的计划/成功次数
+	DeleteRate    float64 `json:"delete_rate"`   // 被删除的计划/成功次数
+}
+
+// ExperimentReport 实验报告
+type ExperimentReport struct {
+	Experiment *Experiment               `json:"experiment"`
+	Variants   []ExperimentVariantReport `json:"variants"`
+}
@@ -0,0 +1,231 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"personatrip/internal/secrets"
+	"personatrip/pkg/mcp/providers/generic"
+)
+
+// MCP提供者的来源
+const (
+	MCPSourceConfig   = "config"   // 内置的高德地图或MCP_SERVERS_FILE中声明的服务器
+	MCPSourceDatabase = "database" // 管理员通过接口添加的服务器
+)
+
+// MCP提供者的连接状态
+const (
+	MCPStatusConnected    = "connected"    // 已连接，工具可用
+	MCPStatusDegraded     = "degraded"     // 已连接但探测失败，正在自动重启，生成时不使用它的工具
+	MCPStatusFailed       = "failed"       // 初始化失败，可以重启
+	MCPStatusDisconnected = "disconnected" // 数据库中的服务器尚未连接，服务启动时在后台连接
+)
+
+// MCPServer 管理员添加的MCP服务器，重启服务后自动连接
+type MCPServer struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"size:100;uniqueIndex;not null"`
+	Transport string    `json:"transport" gorm:"size:20;not null"`
+	Command   string    `json:"command,omitempty" gorm:"size:255"`
+	Args      string    `json:"-" gorm:"type:text"` // JSON数组
+	Env       string    `json:"-" gorm:"type:text"` // 信封加密后的JSON对象，可能包含密钥
+	URL       string    `json:"url,omitempty" gorm:"size:500"`
+	Headers   string    `json:"-" gorm:"type:text"` // 信封加密后的JSON对象，可能包含密钥
+	Timeout   string    `json:"timeout,omitempty" gorm:"size:20"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetServerConfig 保存服务器配置，环境变量和请求头加密保存
+func (m *MCPServer) SetServerConfig(cfg generic.ServerConfig) error {
+	args, err := json.Marshal(cfg.Args)
+	if err != nil {
+		return err
+	}
+	env, err := encryptJSON(cfg.Env)
+	if err != nil {
+		return err
+	}
+	headers, err := encryptJSON(cfg.Headers)
+	if err != nil {
+		return err
+	}
+
+	m.Name = cfg.Name
+	m.Transport = cfg.Transport
+	m.Command = cfg.Command
+	m.Args = string(args)
+	m.Env = env
+	m.URL = cfg.URL
+	m.Headers = headers
+	m.Timeout = cfg.Timeout
+	return nil
+}
+
+// ServerConfig 返回解密后的服务器配置
+func (m *MCPServer) ServerConfig() (generic.ServerConfig, error) {
+	cfg := generic.ServerConfig{
+		Name:      m.Name,
+		Transport: m.Transport,
+		Command:   m.Command,
+		URL:       m.URL,
+		Timeout:   m.Timeout,
+	}
+	if m.Args != "" {
+		if err := json.Unmarshal([]byte(m.Args), &cfg.Args); err != nil {
+			return cfg, fmt.Errorf("解析MCP服务器%s的参数失败: %w", m.Name, err)
+		}
+	}
+	if err := decryptJSON(m.Env, &cfg.Env); err != nil {
+		return cfg, fmt.Errorf("解密MCP服务器%s的环境变量失败: %w", m.Name, err)
+	}
+	if err := decryptJSON(m.Headers, &cfg.Headers); err != nil {
+		return cfg, fmt.Errorf("解密MCP服务器%s的请求头失败: %w", m.Name, err)
+	}
+	return cfg, nil
+}
+
+// encryptJSON 把map编码为JSON后加密，map为空时返回空字符串
+func encryptJSON(m map[string]string) (string, error) {
+	if len(m) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return secrets.Default().Encrypt(string(data))
+}
+
+// decryptJSON 解密encryptJSON的结果
+func decryptJSON(value string, m *map[string]string) error {
+	if value == "" {
+		return nil
+	}
+	data, err := secrets.Default().Decrypt(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), m)
+}
+
+// MCPServerCreateRequest 添加MCP服务器的请求，字段含义与MCP_SERVERS_FILE中的服务器相同
+type MCPServerCreateRequest struct {
+	Name      string            `json:"name" binding:"required"`
+	Transport string            `json:"transport" binding:"required,oneof=stdio sse http"`
+	Command   string            `json:"command"`
+	Args      []string          `json:"args"`
+	Env       map[string]string `json:"env"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	Timeout   string            `json:"timeout"`
+}
+
+// ToServerConfig 转换为服务器配置
+func (r *MCPServerCreateRequest) ToServerConfig() generic.ServerConfig {
+	return generic.ServerConfig{
+		Name:      r.Name,
+		Transport: r.Transport,
+		Command:   r.Command,
+		Args:      r.Args,
+		Env:       r.Env,
+		URL:       r.URL,
+		Headers:   r.Headers,
+		Timeout:   r.Timeout,
+	}
+}
+
+// MCPProviderInfo MCP提供者的配置、连接状态和工具
+type MCPProviderInfo struct {
+	Name      string            `json:"name"`
+	Source    string            `json:"source"`              // config或database
+	ServerID  uint              `json:"server_id,omitempty"` // 来源为database时对应的MCPServer
+	Status    string            `json:"status"`              // connected、degraded、failed或disconnected
+	Error     string            `json:"error,omitempty"`     // 初始化或探测失败的错误
+	Transport string            `json:"transport,omitempty"`
+	Command   string            `json:"command,omitempty"`
+	Args      []string          `json:"args,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`     // 值只返回指纹
+	URL       string            `json:"url,omitempty"`     // 查询参数的值只返回指纹，密码替换为xxxxx
+	Headers   map[string]string `json:"headers,omitempty"` // 值只返回指纹
+	Timeout   string            `json:"timeout,omitempty"`
+	Tools     []MCPToolInfo     `json:"tools"`
+}
+
+// SetServerConfig 填入服务器配置，环境变量、请求头和URL查询参数的值替换为指纹
+func (i *MCPProviderInfo) SetServerConfig(cfg generic.ServerConfig) {
+	i.Transport = cfg.Transport
+	i.Command = cfg.Command
+	i.Args = cfg.Args
+	i.Env = fingerprintValues(cfg.Env)
+	i.URL = maskURL(cfg.URL)
+	i.Headers = fingerprintValues(cfg.Headers)
+	i.Timeout = cfg.Timeout
+}
+
+// fingerprintValues 把map的值替换为指纹
+func fingerprintValues(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	masked := make(map[string]string, len(m))
+	for k, v := range m {
+		masked[k] = secrets.Fingerprint(v)
+	}
+	return masked
+}
+
+// maskURL 把URL中查询参数的值替换为指纹、密码替换为xxxxx，SSE和HTTP服务器的密钥常放在查询参数中。
+// 无法解析的URL整体替换为指纹
+func maskURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return secrets.Fingerprint(raw)
+	}
+	query := u.Query()
+	u.RawQuery = ""
+	u.ForceQuery = false
+	masked := u.Redacted()
+	if len(query) == 0 {
+		return masked
+	}
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range query[key] {
+			params = append(params, key+"="+secrets.Fingerprint(value))
+		}
+	}
+	// 片段在Redacted的结果末尾，查询参数需要插在片段之前
+	fragment := ""
+	if u.Fragment != "" {
+		fragment = "#" + u.EscapedFragment()
+		masked = strings.TrimSuffix(masked, fragment)
+	}
+	return masked + "?" + strings.Join(params, "&") + fragment
+}
+
+// MCPToolInfo MCP工具的名称、描述和参数
+type MCPToolInfo struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	InputSchema interface{} `json:"input_schema,omitempty"` // 参数的JSON Schema
+}
+
+// MCPToolCallRequest 手动调用MCP工具的请求
+type MCPToolCallRequest struct {
+	Arguments map[string]interface{} `json:"arguments"`
+}
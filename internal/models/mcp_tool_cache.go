@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MCPToolCacheEntry 持久化的MCP工具调用结果，过期后由MongoDB的TTL索引自动删除
+type MCPToolCacheEntry struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Key       string             `json:"key" bson:"key"` // 提供者、工具名称和归一化参数的SHA-256摘要
+	Provider  string             `json:"provider" bson:"provider"`
+	Tool      string             `json:"tool" bson:"tool"`
+	Arguments string             `json:"arguments" bson:"arguments"` // 归一化的参数JSON
+	Result    string             `json:"-" bson:"result"`            // 调用结果(CallToolResult)的JSON
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+}
+
+// MCPToolCacheStats MCP工具调用结果缓存的命中统计，从服务启动开始计算
+type MCPToolCacheStats struct {
+	Persistent bool                    `json:"persistent"`  // 是否同时保存到MongoDB
+	Entries    int                     `json:"entries"`     // 内存中的结果数量
+	MaxEntries int                     `json:"max_entries"` // 内存中最多保存的结果数量
+	Hits       int64                   `json:"hits"`
+	StoreHits  int64                   `json:"store_hits"` // 内存未命中、从MongoDB命中的次数，包含在hits中
+	Misses     int64                   `json:"misses"`
+	Evictions  int64                   `json:"evictions"` // 内存已满时淘汰的结果数量
+	HitRate    float64                 `json:"hit_rate"`  // 命中次数/(命中次数+未命中次数)
+	Since      time.Time               `json:"since"`
+	Tools      []MCPToolCacheToolStats `json:"tools"` // 按提供者和工具名称排序
+}
+
+// MCPToolCacheToolStats 一个工具的缓存命中统计
+type MCPToolCacheToolStats struct {
+	Provider  string  `json:"provider"`
+	Tool      string  `json:"tool"`
+	TTL       string  `json:"ttl"` // 该工具的缓存有效期
+	Hits      int64   `json:"hits"`
+	StoreHits int64   `json:"store_hits"`
+	Misses    int64   `json:"misses"`
+	HitRate   float64 `json:"hit_rate"`
+}
@@ -1,139 +1,229 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
+	"personatrip/internal/secrets"
 	"personatrip/pkg/einosdk"
 )
 
 // ModelConfig 表示大模型配置
 type ModelConfig struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	Name        string    `json:"name" gorm:"size:100;not null"`
-	ModelType   string    `json:"model_type" gorm:"size:50;not null"` // openai, ollama, ark, mock
-	ModelName   string    `json:"model_name" gorm:"size:100;not null"`
-	ApiKey      string    `json:"api_key,omitempty" gorm:"size:255"`
-	BaseUrl     string    `json:"base_url,omitempty" gorm:"size:255"`
-	IsActive    bool      `json:"is_active" gorm:"default:false"`
-	Temperature float32   `json:"temperature" gorm:"default:0.7"`
-	MaxTokens   int       `json:"max_tokens" gorm:"default:2000"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                uint              `json:"id" gorm:"primaryKey"`
+	Name              string            `json:"name" gorm:"size:100;not null"`
+	ModelType         string            `json:"model_type" gorm:"size:50;not null"` // 已注册的提供者类型，见einosdk.ProviderTypes
+	ModelName         string            `json:"model_name" gorm:"size:100;not null"`
+	ApiKey            string            `json:"-" gorm:"size:1024"`                           // 信封加密后的API密钥，只在创建einosdk客户端时解密
+	ApiKeyFingerprint string            `json:"api_key_fingerprint,omitempty" gorm:"size:64"` // 密钥的掩码指纹，用于区分配置的是哪个密钥
+	BaseUrl           string            `json:"base_url,omitempty" gorm:"size:255"`
+	IsActive          bool              `json:"is_active" gorm:"default:false"`
+	Priority          int               `json:"priority" gorm:"default:0"` // 故障切换优先级，大于0时加入备用链，数值越小越优先
+	Temperature       float32           `json:"temperature" gorm:"default:0.7"`
+	MaxTokens         int               `json:"max_tokens" gorm:"default:2000"`
+	PromptPrice       float64           `json:"prompt_price" gorm:"default:0"`                // 每百万输入token的价格
+	CompletionPrice   float64           `json:"completion_price" gorm:"default:0"`            // 每百万输出token的价格
+	MaxConcurrency    int               `json:"max_concurrency" gorm:"default:0"`             // 同时进行的最大调用数，0表示不限制
+	RequestsPerMinute int               `json:"requests_per_minute" gorm:"default:0"`         // 每分钟最多开始的调用数，0表示不限制
+	HealthStatus      ModelHealthStatus `json:"health_status" gorm:"size:20;default:unknown"` // 后台探测得到的健康状态，见ModelHealthService
+	HealthCheckedAt   *time.Time        `json:"health_checked_at,omitempty"`
+	HealthLatencyMs   int64             `json:"health_latency_ms"`
+	HealthError       string            `json:"health_error,omitempty" gorm:"size:500"` // 最近一次探测失败的错误
+	HealthFailures    int               `json:"health_failures" gorm:"default:0"`       // 连续探测失败的次数
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
 }
 
-// ToEinoModelType 将字符串类型转换为einosdk.ModelType
+// CalculateCost 根据token用量和单价计算费用
+func (m *ModelConfig) CalculateCost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*m.PromptPrice + float64(completionTokens)*m.CompletionPrice) / 1e6
+}
+
+// ToEinoModelType 将字符串类型转换为einosdk.ModelType，未注册的类型回退为模拟模型
 func (m *ModelConfig) ToEinoModelType() einosdk.ModelType {
-	switch m.ModelType {
-	case "openai":
-		return einosdk.ModelTypeOpenAI
-	case "ollama":
-		return einosdk.ModelTypeOllama
-	case "ark":
-		return einosdk.ModelTypeArk
-	case "mock":
-		return einosdk.ModelTypeMock
-	default:
+	modelType, ok := einosdk.ParseModelType(m.ModelType)
+	if !ok {
 		return einosdk.ModelTypeMock
 	}
+	return modelType
+}
+
+// IsDown 是否被探测为不可用
+func (m *ModelConfig) IsDown() bool {
+	return m.HealthStatus == ModelHealthDown
+}
+
+// SetApiKey 加密并保存API密钥，同时记录密钥的指纹，apiKey为空时清除密钥
+func (m *ModelConfig) SetApiKey(apiKey string) error {
+	encrypted, err := secrets.Default().Encrypt(apiKey)
+	if err != nil {
+		return fmt.Errorf("加密API密钥失败: %w", err)
+	}
+	m.ApiKey = encrypted
+	m.ApiKeyFingerprint = secrets.Fingerprint(apiKey)
+	return nil
 }
 
-// GetEinoOptions 获取Eino客户端选项
-func (m *ModelConfig) GetEinoOptions() []einosdk.ClientOption {
+// GetEinoOptions 获取Eino客户端选项，API密钥在这里解密
+func (m *ModelConfig) GetEinoOptions() ([]einosdk.ClientOption, error) {
 	options := []einosdk.ClientOption{
 		einosdk.WithModel(m.ModelName),
 	}
 
 	if m.ApiKey != "" {
-		options = append(options, einosdk.WithAPIKey(m.ApiKey))
+		apiKey, err := secrets.Default().Decrypt(m.ApiKey)
+		if err != nil {
+			return nil, fmt.Errorf("解密模型配置%s的API密钥失败: %w", m.Name, err)
+		}
+		options = append(options, einosdk.WithAPIKey(apiKey))
 	}
 
 	if m.BaseUrl != "" {
 		options = append(options, einosdk.WithBaseURL(m.BaseUrl))
 	}
 
-	return options
+	return options, nil
+}
+
+// NewEinoClient 使用该配置创建Eino客户端
+func (m *ModelConfig) NewEinoClient() (*einosdk.Client, error) {
+	options, err := m.GetEinoOptions()
+	if err != nil {
+		return nil, err
+	}
+	return einosdk.NewClient(m.ToEinoModelType(), options...), nil
 }
 
 // ModelConfigResponse 是模型配置的响应格式
 type ModelConfigResponse struct {
-	ID          uint    `json:"id"`
-	Name        string  `json:"name"`
-	ModelType   string  `json:"model_type"`
-	ModelName   string  `json:"model_name"`
-	BaseUrl     string  `json:"base_url,omitempty"`
-	IsActive    bool    `json:"is_active"`
-	Temperature float32 `json:"temperature"`
-	MaxTokens   int     `json:"max_tokens"`
+	ID                uint              `json:"id"`
+	Name              string            `json:"name"`
+	ModelType         string            `json:"model_type"`
+	ModelName         string            `json:"model_name"`
+	ApiKeyFingerprint string            `json:"api_key_fingerprint,omitempty"`
+	BaseUrl           string            `json:"base_url,omitempty"`
+	IsActive          bool              `json:"is_active"`
+	Priority          int               `json:"priority"`
+	Temperature       float32           `json:"temperature"`
+	MaxTokens         int               `json:"max_tokens"`
+	PromptPrice       float64           `json:"prompt_price"`
+	CompletionPrice   float64           `json:"completion_price"`
+	MaxConcurrency    int               `json:"max_concurrency"`
+	RequestsPerMinute int               `json:"requests_per_minute"`
+	HealthStatus      ModelHealthStatus `json:"health_status"`
+	HealthCheckedAt   *time.Time        `json:"health_checked_at,omitempty"`
+	HealthLatencyMs   int64             `json:"health_latency_ms"`
+	HealthError       string            `json:"health_error,omitempty"`
 }
 
 // ToResponse 将ModelConfig转换为ModelConfigResponse
 func (m *ModelConfig) ToResponse() ModelConfigResponse {
 	return ModelConfigResponse{
-		ID:          m.ID,
-		Name:        m.Name,
-		ModelType:   m.ModelType,
-		ModelName:   m.ModelName,
-		BaseUrl:     m.BaseUrl,
-		IsActive:    m.IsActive,
-		Temperature: m.Temperature,
-		MaxTokens:   m.MaxTokens,
+		ID:                m.ID,
+		Name:              m.Name,
+		ModelType:         m.ModelType,
+		ModelName:         m.ModelName,
+		ApiKeyFingerprint: m.ApiKeyFingerprint,
+		BaseUrl:           m.BaseUrl,
+		IsActive:          m.IsActive,
+		Priority:          m.Priority,
+		Temperature:       m.Temperature,
+		MaxTokens:         m.MaxTokens,
+		PromptPrice:       m.PromptPrice,
+		CompletionPrice:   m.CompletionPrice,
+		MaxConcurrency:    m.MaxConcurrency,
+		RequestsPerMinute: m.RequestsPerMinute,
+		HealthStatus:      m.HealthStatus,
+		HealthCheckedAt:   m.HealthCheckedAt,
+		HealthLatencyMs:   m.HealthLatencyMs,
+		HealthError:       m.HealthError,
 	}
 }
 
 // ModelConfigCreateRequest 是创建模型配置的请求格式
 type ModelConfigCreateRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	ModelType   string  `json:"model_type" binding:"required,oneof=openai ollama ark mock"`
-	ModelName   string  `json:"model_name" binding:"required"`
-	ApiKey      string  `json:"api_key"`
-	BaseUrl     string  `json:"base_url"`
-	IsActive    bool    `json:"is_active"`
-	Temperature float32 `json:"temperature"`
-	MaxTokens   int     `json:"max_tokens"`
+	Name              string  `json:"name" binding:"required"`
+	ModelType         string  `json:"model_type" binding:"required,model_type"`
+	ModelName         string  `json:"model_name" binding:"required"`
+	ApiKey            string  `json:"api_key"`
+	BaseUrl           string  `json:"base_url"`
+	IsActive          bool    `json:"is_active"`
+	Priority          int     `json:"priority" binding:"min=0"`
+	Temperature       float32 `json:"temperature"`
+	MaxTokens         int     `json:"max_tokens"`
+	PromptPrice       float64 `json:"prompt_price" binding:"min=0"`
+	CompletionPrice   float64 `json:"completion_price" binding:"min=0"`
+	MaxConcurrency    int     `json:"max_concurrency" binding:"min=0"`
+	RequestsPerMinute int     `json:"requests_per_minute" binding:"min=0"`
 }
 
 // ModelConfigUpdateRequest 是更新模型配置的请求格式
 type ModelConfigUpdateRequest struct {
-	Name        string  `json:"name"`
-	ModelType   string  `json:"model_type" binding:"omitempty,oneof=openai ollama ark mock"`
-	ModelName   string  `json:"model_name"`
-	ApiKey      string  `json:"api_key"`
-	BaseUrl     string  `json:"base_url"`
-	IsActive    bool    `json:"is_active"`
-	Temperature float32 `json:"temperature"`
-	MaxTokens   int     `json:"max_tokens"`
+	Name              string   `json:"name"`
+	ModelType         string   `json:"model_type" binding:"omitempty,model_type"`
+	ModelName         string   `json:"model_name"`
+	ApiKey            string   `json:"api_key"`
+	BaseUrl           string   `json:"base_url"`
+	IsActive          bool     `json:"is_active"`
+	Priority          *int     `json:"priority" binding:"omitempty,min=0"` // 为空时不修改，设为0表示移出备用链
+	Temperature       float32  `json:"temperature"`
+	MaxTokens         int      `json:"max_tokens"`
+	PromptPrice       *float64 `json:"prompt_price" binding:"omitempty,min=0"` // 为空时不修改
+	CompletionPrice   *float64 `json:"completion_price" binding:"omitempty,min=0"`
+	MaxConcurrency    *int     `json:"max_concurrency" binding:"omitempty,min=0"` // 为空时不修改，设为0表示不限制
+	RequestsPerMinute *int     `json:"requests_per_minute" binding:"omitempty,min=0"`
 }
 
 // ModelConfigListItem 模型配置列表项
 type ModelConfigListItem struct {
-	ID          uint    `json:"id"`
-	Name        string  `json:"name"`
-	ModelType   string  `json:"model_type"`
-	ModelName   string  `json:"model_name"`
-	BaseUrl     string  `json:"base_url,omitempty"`
-	IsActive    bool    `json:"is_active"`
-	Temperature float32 `json:"temperature"`
-	MaxTokens   int     `json:"max_tokens"`
+	ID                uint              `json:"id"`
+	Name              string            `json:"name"`
+	ModelType         string            `json:"model_type"`
+	ModelName         string            `json:"model_name"`
+	ApiKeyFingerprint string            `json:"api_key_fingerprint,omitempty"`
+	BaseUrl           string            `json:"base_url,omitempty"`
+	IsActive          bool              `json:"is_active"`
+	Priority          int               `json:"priority"`
+	Temperature       float32           `json:"temperature"`
+	MaxTokens         int               `json:"max_tokens"`
+	PromptPrice       float64           `json:"prompt_price"`
+	CompletionPrice   float64           `json:"completion_price"`
+	MaxConcurrency    int               `json:"max_concurrency"`
+	RequestsPerMinute int               `json:"requests_per_minute"`
+	HealthStatus      ModelHealthStatus `json:"health_status"`
+	HealthCheckedAt   *time.Time        `json:"health_checked_at,omitempty"`
+	HealthLatencyMs   int64             `json:"health_latency_ms"`
+	HealthError       string            `json:"health_error,omitempty"`
 }
 
 // ToListItem 转换为列表项
 func (m *ModelConfig) ToListItem() ModelConfigListItem {
 	return ModelConfigListItem{
-		ID:          m.ID,
-		Name:        m.Name,
-		ModelType:   m.ModelType,
-		ModelName:   m.ModelName,
-		BaseUrl:     m.BaseUrl,
-		IsActive:    m.IsActive,
-		Temperature: m.Temperature,
-		MaxTokens:   m.MaxTokens,
+		ID:                m.ID,
+		Name:              m.Name,
+		ModelType:         m.ModelType,
+		ModelName:         m.ModelName,
+		ApiKeyFingerprint: m.ApiKeyFingerprint,
+		BaseUrl:           m.BaseUrl,
+		IsActive:          m.IsActive,
+		Priority:          m.Priority,
+		Temperature:       m.Temperature,
+		MaxTokens:         m.MaxTokens,
+		PromptPrice:       m.PromptPrice,
+		CompletionPrice:   m.CompletionPrice,
+		MaxConcurrency:    m.MaxConcurrency,
+		RequestsPerMinute: m.RequestsPerMinute,
+		HealthStatus:      m.HealthStatus,
+		HealthCheckedAt:   m.HealthCheckedAt,
+		HealthLatencyMs:   m.HealthLatencyMs,
+		HealthError:       m.HealthError,
 	}
 }
 
 // CreateModelConfigRequest 创建模型配置请求
 type CreateModelConfigRequest struct {
 	Name        string  `json:"name" binding:"required"`
-	ModelType   string  `json:"model_type" binding:"required,oneof=openai ollama ark mock"`
+	ModelType   string  `json:"model_type" binding:"required,model_type"`
 	ModelName   string  `json:"model_name" binding:"required"`
 	ApiKey      string  `json:"api_key"`
 	BaseUrl     string  `json:"base_url"`
@@ -145,7 +235,7 @@ type CreateModelConfigRequest struct {
 // UpdateModelConfigRequest 更新模型配置请求
 type UpdateModelConfigRequest struct {
 	Name        string  `json:"name"`
-	ModelType   string  `json:"model_type" binding:"omitempty,oneof=openai ollama ark mock"`
+	ModelType   string  `json:"model_type" binding:"omitempty,model_type"`
 	ModelName   string  `json:"model_name"`
 	ApiKey      string  `json:"api_key"`
 	BaseUrl     string  `json:"base_url"`
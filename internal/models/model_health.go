@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// ModelHealthStatus 模型配置的健康状态
+type ModelHealthStatus string
+
+const (
+	ModelHealthUnknown  ModelHealthStatus = "unknown"  // 还没有探测过，或配置修改后尚未重新探测
+	ModelHealthHealthy  ModelHealthStatus = "healthy"  // 最近的探测都成功且延迟正常
+	ModelHealthDegraded ModelHealthStatus = "degraded" // 延迟过高，或最近有探测失败
+	ModelHealthDown     ModelHealthStatus = "down"     // 连续多次探测失败，故障切换时跳过
+)
+
+// ModelHealthCheck 一次模型配置探测的结果
+type ModelHealthCheck struct {
+	ID            uint              `json:"id" gorm:"primaryKey"`
+	ModelConfigID uint              `json:"model_config_id" gorm:"index"`
+	Status        ModelHealthStatus `json:"status" gorm:"size:20"` // 本次探测后配置的健康状态
+	Success       bool              `json:"success"`
+	LatencyMs     int64             `json:"latency_ms"`
+	Error         string            `json:"error,omitempty" gorm:"size:500"`
+	CreatedAt     time.Time         `json:"created_at" gorm:"index"`
+}
+
+// ModelHealthQuery 探测历史的查询条件
+type ModelHealthQuery struct {
+	Limit int `form:"limit" binding:"omitempty,min=1,max=500"` // 返回最近的记录数，默认50
+}
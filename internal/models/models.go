@@ -29,7 +29,7 @@ type UserPreferences struct {
 
 // DestinationInfo 目的地详细信息
 type DestinationInfo struct {
-	Name            string `json:"name" bson:"name"`
+	Name            string `json:"name" bson:"name" jsonschema:"required"`
 	Country         string `json:"country" bson:"country"`
 	Language        string `json:"language" bson:"language"`
 	Currency        string `json:"currency" bson:"currency"`
@@ -118,20 +118,21 @@ type Hospital struct {
 }
 
 // TripPlan 旅行计划模型
+// jsonschema标签用于生成约束大模型输出的JSON Schema："-"表示字段由服务端填充，"required"表示模型必须输出
 type TripPlan struct {
-	ID                     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	UserID                 primitive.ObjectID `json:"user_id" bson:"user_id"`
-	Title                  string             `json:"title" bson:"title"`
-	Destination            string             `json:"destination" bson:"destination"`
-	DestinationInfo        DestinationInfo    `json:"destination_info" bson:"destination_info"`
-	StartDate              string             `json:"start_date" bson:"start_date"`
-	EndDate                string             `json:"end_date" bson:"end_date"`
+	ID                     primitive.ObjectID `json:"id" bson:"_id,omitempty" jsonschema:"-"`
+	UserID                 primitive.ObjectID `json:"user_id" bson:"user_id" jsonschema:"-"`
+	Title                  string             `json:"title" bson:"title" jsonschema:"required"`
+	Destination            string             `json:"destination" bson:"destination" jsonschema:"-"`
+	DestinationInfo        DestinationInfo    `json:"destination_info" bson:"destination_info" jsonschema:"required"`
+	StartDate              string             `json:"start_date" bson:"start_date" jsonschema:"-"`
+	EndDate                string             `json:"end_date" bson:"end_date" jsonschema:"-"`
 	TravelInfo             TravelInfo         `json:"travel_info" bson:"travel_info"`
 	WeatherForecast        WeatherForecast    `json:"weather_forecast" bson:"weather_forecast"`
 	PackingList            PackingList        `json:"packing_list" bson:"packing_list"`
 	EmergencyContacts      EmergencyContacts  `json:"emergency_contacts" bson:"emergency_contacts"`
-	Days                   []TripDay          `json:"days" bson:"days"`
-	Budget                 Budget             `json:"budget" bson:"budget"`
+	Days                   []TripDay          `json:"days" bson:"days" jsonschema:"required"`
+	Budget                 Budget             `json:"budget" bson:"budget" jsonschema:"required"`
 	LocalAttractions       []LocalAttraction  `json:"local_attractions" bson:"local_attractions"`
 	LocalCuisine           []LocalCuisine     `json:"local_cuisine" bson:"local_cuisine"`
 	Shopping               Shopping           `json:"shopping" bson:"shopping"`
@@ -139,17 +140,37 @@ type TripPlan struct {
 	PracticalInfo          PracticalInfo      `json:"practical_information" bson:"practical_information"`
 	Notes                  string             `json:"notes" bson:"notes"`
 	SuggestedModifications string             `json:"suggested_modifications" bson:"suggested_modifications"`
-	IsPublic               bool               `json:"is_public" bson:"is_public"`
-	CreatedAt              time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt              time.Time          `json:"updated_at" bson:"updated_at"`
+	IsPublic               bool               `json:"is_public" bson:"is_public" jsonschema:"-"`
+	Generation             *GenerationInfo    `json:"generation,omitempty" bson:"generation,omitempty" jsonschema:"-"`
+	Request                *PlanRequest       `json:"request,omitempty" bson:"request,omitempty" jsonschema:"-"` // 生成时的请求，重新安排某一天时沿用其中的偏好
+	CreatedAt              time.Time          `json:"created_at" bson:"created_at" jsonschema:"-"`
+	UpdatedAt              time.Time          `json:"updated_at" bson:"updated_at" jsonschema:"-"`
+}
+
+// GenerationInfo 记录实际生成旅行计划的模型
+type GenerationInfo struct {
+	ModelConfigID    uint                `json:"model_config_id" bson:"model_config_id"`
+	ModelConfigName  string              `json:"model_config_name" bson:"model_config_name"`
+	ModelType        string              `json:"model_type" bson:"model_type"`
+	ModelName        string              `json:"model_name" bson:"model_name"`
+	Attempts         int                 `json:"attempts" bson:"attempts"`           // 尝试过的模型配置数量，包含成功的一次，分阶段生成时为各阶段的最大值
+	PromptTokens     int                 `json:"prompt_tokens" bson:"prompt_tokens"` // 成功那次生成的token用量，智能体多步调用或分阶段生成时为各步之和
+	CompletionTokens int                 `json:"completion_tokens" bson:"completion_tokens"`
+	TotalTokens      int                 `json:"total_tokens" bson:"total_tokens"`
+	Cost             float64             `json:"cost" bson:"cost"`                                 // 按模型配置单价计算的费用
+	PromptVersion    int                 `json:"prompt_version" bson:"prompt_version"`             // 所用trip_plan提示词模板的版本，0表示内置模板；分阶段生成时为trip_skeleton模板的版本
+	Stages           int                 `json:"stages,omitempty" bson:"stages,omitempty"`         // 分阶段生成时调用模型的阶段数，一次生成完整计划时为0
+	Cached           bool                `json:"cached" bson:"cached"`                             // 是否命中缓存，命中时以上信息为最初生成该计划时的记录
+	Experiment       *ExperimentTag      `json:"experiment,omitempty" bson:"experiment,omitempty"` // 分配到A/B实验分组时的分组信息
+	TraceID          *primitive.ObjectID `json:"trace_id,omitempty" bson:"trace_id,omitempty"`     // 生成过程记录的ID，命中缓存时为最初生成时的记录
 }
 
 // TripDay 旅行日程
 type TripDay struct {
-	Day            int              `json:"day" bson:"day"`
-	Date           string           `json:"date" bson:"date"`
+	Day            int              `json:"day" bson:"day" jsonschema:"required"`
+	Date           string           `json:"date" bson:"date" jsonschema:"required"`
 	Weather        DayWeather       `json:"weather" bson:"weather"`
-	Activities     []Activity       `json:"activities" bson:"activities"`
+	Activities     []Activity       `json:"activities" bson:"activities" jsonschema:"required"`
 	Meals          []Meal           `json:"meals" bson:"meals"`
 	Accommodation  Accommodation    `json:"accommodation" bson:"accommodation"`
 	Transportation []Transportation `json:"transportation" bson:"transportation"`
@@ -177,7 +198,7 @@ type Transportation struct {
 
 // Activity 活动项目
 type Activity struct {
-	Name            string   `json:"name" bson:"name"`
+	Name            string   `json:"name" bson:"name" jsonschema:"required"`
 	Type            string   `json:"type" bson:"type"` // 景点、体验、交通等
 	Location        Location `json:"location" bson:"location"`
 	StartTime       string   `json:"start_time" bson:"start_time"`
@@ -198,7 +219,7 @@ type Activity struct {
 
 // Meal 餐饮
 type Meal struct {
-	Type            string   `json:"type" bson:"type"` // 早餐、午餐、晚餐、小吃
+	Type            string   `json:"type" bson:"type" jsonschema:"required"` // 早餐、午餐、晚餐、小吃
 	Venue           string   `json:"venue" bson:"venue"`
 	Cuisine         string   `json:"cuisine" bson:"cuisine"`
 	Description     string   `json:"description" bson:"description"`
@@ -246,9 +267,9 @@ type Coordinates struct {
 
 // Budget 预算
 type Budget struct {
-	Currency       string        `json:"currency" bson:"currency"`
+	Currency       string        `json:"currency" bson:"currency" jsonschema:"required"`
 	ExchangeRate   string        `json:"exchange_rate" bson:"exchange_rate"`
-	TotalEstimate  float64       `json:"total_estimate" bson:"total_estimate"`
+	TotalEstimate  float64       `json:"total_estimate" bson:"total_estimate" jsonschema:"required"`
 	Accommodation  float64       `json:"accommodation" bson:"accommodation"`
 	Transportation float64       `json:"transportation" bson:"transportation"`
 	Food           float64       `json:"food" bson:"food"`
@@ -358,14 +379,14 @@ type Communication struct {
 
 // PlanRequest 创建旅行计划的请求
 type PlanRequest struct {
-	Destination     string    `json:"destination" binding:"required"`
-	StartDate       time.Time `json:"start_date" binding:"required"`
-	EndDate         time.Time `json:"end_date" binding:"required"`
-	Budget          string    `json:"budget"`           // 预算等级: 经济、中等、豪华
-	TravelStyle     []string  `json:"travel_style"`     // 旅行风格
-	Accommodation   []string  `json:"accommodation"`    // 住宿偏好
-	Transportation  []string  `json:"transportation"`   // 交通偏好
-	Activities      []string  `json:"activities"`       // 活动偏好
-	FoodPreferences []string  `json:"food_preferences"` // 饮食偏好
-	SpecialRequests string    `json:"special_requests"` // 特殊要求
+	Destination     string    `json:"destination" bson:"destination" binding:"required"`
+	StartDate       time.Time `json:"start_date" bson:"start_date" binding:"required"`
+	EndDate         time.Time `json:"end_date" bson:"end_date" binding:"required"`
+	Budget          string    `json:"budget" bson:"budget"`                     // 预算等级: 经济、中等、豪华
+	TravelStyle     []string  `json:"travel_style" bson:"travel_style"`         // 旅行风格
+	Accommodation   []string  `json:"accommodation" bson:"accommodation"`       // 住宿偏好
+	Transportation  []string  `json:"transportation" bson:"transportation"`     // 交通偏好
+	Activities      []string  `json:"activities" bson:"activities"`             // 活动偏好
+	FoodPreferences []string  `json:"food_preferences" bson:"food_preferences"` // 饮食偏好
+	SpecialRequests string    `json:"special_requests" bson:"special_requests"` // 特殊要求
 }
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PlanCacheKey 归一化后的旅行计划请求，内容相同的请求共用一份缓存
+type PlanCacheKey struct {
+	Destination     string   `json:"destination" bson:"destination"`   // 去除首尾空白并转为小写
+	Days            int      `json:"days" bson:"days"`                 // 行程天数，包含首尾两天
+	Budget          string   `json:"budget" bson:"budget"`             // 归一化的预算等级: economy、mid、luxury
+	TravelStyle     []string `json:"travel_style" bson:"travel_style"` // 以下偏好均已去重并排序
+	Accommodation   []string `json:"accommodation" bson:"accommodation"`
+	Transportation  []string `json:"transportation" bson:"transportation"`
+	Activities      []string `json:"activities" bson:"activities"`
+	FoodPreferences []string `json:"food_preferences" bson:"food_preferences"`
+	SpecialRequests string   `json:"special_requests" bson:"special_requests"`
+}
+
+// PlanCacheEntry 缓存的旅行计划，过期后由MongoDB的TTL索引自动删除
+type PlanCacheEntry struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Hash      string             `json:"hash" bson:"hash"` // PlanCacheKey的SHA-256摘要
+	Key       PlanCacheKey       `json:"key" bson:"key"`
+	Plan      TripPlan           `json:"-" bson:"plan"`
+	StartDate time.Time          `json:"start_date" bson:"start_date"` // 生成该计划时请求的开始日期
+	Hits      int                `json:"hits" bson:"hits"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+}
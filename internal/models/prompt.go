@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 提示词模板名称，每个名称对应代码中的一个调用位置
+const (
+	PromptTripPlan        = "trip_plan"                   // 生成旅行计划
+	PromptRecommendations = "destination_recommendations" // 生成目的地推荐
+	PromptAgentSystem     = "agent_system"                // 智能体的系统提示词
+	PromptTripSkeleton    = "trip_skeleton"               // 分阶段生成：行程框架和每天的主题
+	PromptTripDay         = "trip_day"                    // 分阶段生成：单日详细行程
+	PromptTripEssentials  = "trip_essentials"             // 分阶段生成：签证、天气、行李、紧急联系等出行信息
+	PromptTripLocalGuide  = "trip_local_guide"            // 分阶段生成：景点、美食、购物和文化活动
+)
+
+// PromptTemplate 提示词模板的一个版本，内容为text/template模板
+type PromptTemplate struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"size:100;not null;uniqueIndex:idx_prompt_name_version"`
+	Version     int       `json:"version" gorm:"not null;uniqueIndex:idx_prompt_name_version"` // 同名模板内从1开始递增，删除的版本号不再使用
+	Content     string    `json:"content" gorm:"type:text;not null"`
+	Description string    `json:"description" gorm:"size:255"`
+	IsActive    bool      `json:"is_active" gorm:"default:false"` // 同名模板中只有一个版本启用
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// DeletedAt 删除时间，删除的版本只做标记，保留版本号和唯一索引
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// PromptTemplateCreateRequest 创建提示词模板新版本的请求
+type PromptTemplateCreateRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Content     string `json:"content" binding:"required"`
+	Description string `json:"description"`
+	Activate    bool   `json:"activate"` // 创建后立即启用
+}
+
+// PromptPreviewRequest 预览提示词渲染结果的请求，ID和Content都为空时使用该名称当前启用的版本
+type PromptPreviewRequest struct {
+	Name    string          `json:"name" binding:"required"`
+	ID      uint            `json:"id"`      // 预览已保存的版本
+	Content string          `json:"content"` // 预览未保存的模板内容
+	Data    json.RawMessage `json:"data"`    // 模板数据，字段见提示词定义中的变量
+}
+
+// PromptPreviewResponse 提示词渲染结果
+type PromptPreviewResponse struct {
+	Version  int    `json:"version"` // 预览未保存的内容时为0
+	Rendered string `json:"rendered"`
+}
+
+// PromptVariable 提示词模板可以使用的变量
+type PromptVariable struct {
+	Name        string `json:"name"` // 模板中的写法，如 .Destination
+	Description string `json:"description"`
+}
+
+// PromptDefinition 描述一个提示词模板名称及其可用变量
+type PromptDefinition struct {
+	Name           string           `json:"name"`
+	Description    string           `json:"description"`
+	Variables      []PromptVariable `json:"variables"`
+	DefaultContent string           `json:"default_content"` // 没有启用的版本时使用的内置模板
+	ActiveVersion  int              `json:"active_version"`  // 当前启用的版本，0表示使用内置模板
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"personatrip/pkg/einosdk"
+)
+
+// GenerationTrace 一次生成旅行计划的完整过程，包括每次模型调用的步骤、推理内容和工具调用，
+// 用于排查生成质量问题，过期后由MongoDB的TTL索引自动删除
+type GenerationTrace struct {
+	ID          primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	PlanID      *primitive.ObjectID `json:"plan_id,omitempty" bson:"plan_id,omitempty"` // 生成成功时为计划的ID
+	UserID      string              `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Endpoint    string              `json:"endpoint" bson:"endpoint"`
+	Destination string              `json:"destination" bson:"destination"`
+	Request     PlanRequest         `json:"request" bson:"request"`
+	Success     bool                `json:"success" bson:"success"`
+	Error       string              `json:"error,omitempty" bson:"error,omitempty"`
+	Calls       []TraceCall         `json:"calls,omitempty" bson:"calls"` // 按开始时间排序，包含故障切换、修正和分阶段生成的每次调用
+	LatencyMs   int64               `json:"latency_ms" bson:"latency_ms"`
+	CreatedAt   time.Time           `json:"created_at" bson:"created_at"`
+	ExpiresAt   time.Time           `json:"expires_at" bson:"expires_at"`
+}
+
+// TraceCall 生成过程中使用一个模型配置的一次调用
+type TraceCall struct {
+	Stage           string              `json:"stage,omitempty" bson:"stage,omitempty"` // 分阶段生成时的阶段名称
+	ModelConfigID   uint                `json:"model_config_id" bson:"model_config_id"`
+	ModelConfigName string              `json:"model_config_name" bson:"model_config_name"`
+	ModelType       string              `json:"model_type" bson:"model_type"`
+	ModelName       string              `json:"model_name" bson:"model_name"`
+	Repair          int                 `json:"repair,omitempty" bson:"repair,omitempty"` // 第几次修正，首次生成为0
+	Prompt          string              `json:"prompt" bson:"prompt"`
+	Steps           []einosdk.TraceStep `json:"steps" bson:"steps"`                     // 智能体的每一步模型调用和工具调用
+	Output          string              `json:"output" bson:"output"`                   // 模型的最终消息
+	Error           string              `json:"error,omitempty" bson:"error,omitempty"` // 调用失败或输出未通过校验的原因
+	Usage           einosdk.TokenUsage  `json:"usage" bson:"usage"`
+	StartedAt       time.Time           `json:"started_at" bson:"started_at"`
+	LatencyMs       int64               `json:"latency_ms" bson:"latency_ms"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TripJobStatus 旅行计划生成任务状态
+type TripJobStatus string
+
+const (
+	TripJobQueued    TripJobStatus = "queued"    // 已提交，等待执行
+	TripJobRunning   TripJobStatus = "running"   // 正在生成
+	TripJobSucceeded TripJobStatus = "succeeded" // 生成成功，计划已保存
+	TripJobFailed    TripJobStatus = "failed"    // 生成失败
+)
+
+// TripJob 异步生成旅行计划的任务
+type TripJob struct {
+	ID         primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID     primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	Status     TripJobStatus       `json:"status" bson:"status"`
+	Request    PlanRequest         `json:"request" bson:"request"`
+	Error      string              `json:"error,omitempty" bson:"error,omitempty"`
+	PlanID     *primitive.ObjectID `json:"plan_id,omitempty" bson:"plan_id,omitempty"`
+	Attempts   int                 `json:"attempts" bson:"attempts"`
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at" bson:"updated_at"`
+	StartedAt  *time.Time          `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	FinishedAt *time.Time          `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+}
+
+// IsFinished 任务是否已结束（成功或失败）
+func (j *TripJob) IsFinished() bool {
+	return j.Status == TripJobSucceeded || j.Status == TripJobFailed
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// UsageRecord 记录一次大模型调用的token用量和费用
+type UsageRecord struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	UserID           string    `json:"user_id" gorm:"size:50;index"` // 发起请求的用户，匿名请求为空
+	ModelConfigID    uint      `json:"model_config_id" gorm:"index"`
+	ModelType        string    `json:"model_type" gorm:"size:50"`
+	ModelName        string    `json:"model_name" gorm:"size:100"`
+	Endpoint         string    `json:"endpoint" gorm:"size:100"` // 触发调用的接口或任务
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	Cost             float64   `json:"cost"`       // 按模型配置中的单价计算的费用
+	LatencyMs        int64     `json:"latency_ms"` // 调用耗时（毫秒）
+	Success          bool      `json:"success"`
+	CreatedAt        time.Time `json:"created_at" gorm:"index"`
+}
+
+// UsageSummary 按某个维度汇总的用量和费用
+type UsageSummary struct {
+	Key              string  `json:"key"`            // 用户ID、模型配置ID或日期
+	Name             string  `json:"name,omitempty"` // 按模型汇总时为模型配置名称
+	Calls            int64   `json:"calls"`
+	FailedCalls      int64   `json:"failed_calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+}
+
+// UsageQuery 用量统计的查询条件
+type UsageQuery struct {
+	From time.Time `form:"from" time_format:"2006-01-02"` // 起始日期（包含）
+	To   time.Time `form:"to" time_format:"2006-01-02"`   // 结束日期（包含）
+}
@@ -42,3 +42,34 @@ type LoginResponse struct {
 type TokenClaims struct {
 	UserID uint `json:"user_id"`
 }
+
+// APITokenPrefix 用户API令牌的前缀，便于识别和扫描泄露的令牌
+const APITokenPrefix = "pt_"
+
+// UserAPIToken 用户的API令牌，供MCP客户端等程序代表用户调用，只保存令牌的SHA-256摘要
+type UserAPIToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     string     `json:"user_id" gorm:"size:100;not null;index"`
+	Name       string     `json:"name" gorm:"size:100;not null"`
+	TokenHash  string     `json:"-" gorm:"size:64;not null;unique"`
+	Hint       string     `json:"hint" gorm:"size:20"` // 令牌的前几位，用于区分令牌
+	ExpiresAt  *time.Time `json:"expires_at"`          // 为空时不过期
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (t *UserAPIToken) TableName() string {
+	return "user_api_tokens"
+}
+
+// APITokenCreateRequest 创建API令牌请求
+type APITokenCreateRequest struct {
+	Name          string `json:"name" binding:"required,max=100"`
+	ExpiresInDays int    `json:"expires_in_days" binding:"min=0,max=3650"` // 有效天数，为0时不过期
+}
+
+// APITokenCreateResponse 创建API令牌响应，令牌明文只在创建时返回一次
+type APITokenCreateResponse struct {
+	Token string       `json:"token"`
+	Info  UserAPIToken `json:"info"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"personatrip/pkg/einosdk"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// RegisterValidations 向gin的校验器注册自定义校验规则
+// model_type: 模型类型必须是einosdk中已注册的提供者
+func RegisterValidations() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil
+	}
+	return v.RegisterValidation("model_type", func(fl validator.FieldLevel) bool {
+		_, ok := einosdk.ParseModelType(fl.Field().String())
+		return ok
+	})
+}
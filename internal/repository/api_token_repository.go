@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"personatrip/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// APITokenRepository 定义用户API令牌仓库接口
+type APITokenRepository interface {
+	Create(ctx context.Context, token *models.UserAPIToken) error
+	GetByHash(ctx context.Context, hash string) (*models.UserAPIToken, error)
+	ListByUserID(ctx context.Context, userID string) ([]models.UserAPIToken, error)
+	Delete(ctx context.Context, userID string, id uint) error
+	UpdateLastUsed(ctx context.Context, id uint, usedAt time.Time) error
+}
+
+// GormAPITokenRepository 是使用GORM实现的用户API令牌仓库
+type GormAPITokenRepository struct {
+	db *gorm.DB
+}
+
+// NewGormAPITokenRepository 创建新的GORM用户API令牌仓库
+func NewGormAPITokenRepository(db *gorm.DB) APITokenRepository {
+	return &GormAPITokenRepository{db: db}
+}
+
+// Create 保存API令牌
+func (r *GormAPITokenRepository) Create(ctx context.Context, token *models.UserAPIToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// GetByHash 根据令牌摘要获取API令牌
+func (r *GormAPITokenRepository) GetByHash(ctx context.Context, hash string) (*models.UserAPIToken, error) {
+	var token models.UserAPIToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListByUserID 获取用户的所有API令牌，最新创建的在前
+func (r *GormAPITokenRepository) ListByUserID(ctx context.Context, userID string) ([]models.UserAPIToken, error) {
+	var tokens []models.UserAPIToken
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Delete 删除用户的API令牌，令牌不存在或不属于该用户时返回ErrNotFound
+func (r *GormAPITokenRepository) Delete(ctx context.Context, userID string, id uint) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.UserAPIToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateLastUsed 更新API令牌的最后使用时间
+func (r *GormAPITokenRepository) UpdateLastUsed(ctx context.Context, id uint, usedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.UserAPIToken{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
@@ -18,23 +18,44 @@ type Database interface {
 	UserRepo() UserRepository
 	AdminRepo() AdminRepository
 	ModelConfigRepo() ModelConfigRepository
+	UsageRepo() UsageRepository
+	PromptRepo() PromptTemplateRepository
+	ExperimentRepo() ExperimentRepository
+	ModelHealthRepo() ModelHealthRepository
+	MCPServerRepo() MCPServerRepository
+	APITokenRepo() APITokenRepository
+	ExchangeRateRepo() ExchangeRateRepository
 }
 
 // GormDatabase 实现了Database接口的MySQL(GORM)版本
 type GormDatabase struct {
-	DB              *gorm.DB
-	userRepo        UserRepository
-	adminRepo       AdminRepository
-	modelConfigRepo ModelConfigRepository
+	DB               *gorm.DB
+	userRepo         UserRepository
+	adminRepo        AdminRepository
+	modelConfigRepo  ModelConfigRepository
+	usageRepo        UsageRepository
+	promptRepo       PromptTemplateRepository
+	experimentRepo   ExperimentRepository
+	modelHealthRepo  ModelHealthRepository
+	mcpServerRepo    MCPServerRepository
+	apiTokenRepo     APITokenRepository
+	exchangeRateRepo ExchangeRateRepository
 }
 
 // NewGormDatabase 创建一个新的GORM数据库实例,新加入的模型必须修改的地方
 func NewGormDatabase(db *gorm.DB) Database {
 	return &GormDatabase{
-		DB:              db,
-		userRepo:        NewGormUserRepository(db),
-		adminRepo:       NewGormAdminRepository(db),
-		modelConfigRepo: NewGormModelConfigRepository(db),
+		DB:               db,
+		userRepo:         NewGormUserRepository(db),
+		adminRepo:        NewGormAdminRepository(db),
+		modelConfigRepo:  NewGormModelConfigRepository(db),
+		usageRepo:        NewGormUsageRepository(db),
+		promptRepo:       NewGormPromptTemplateRepository(db),
+		experimentRepo:   NewGormExperimentRepository(db),
+		modelHealthRepo:  NewGormModelHealthRepository(db),
+		mcpServerRepo:    NewGormMCPServerRepository(db),
+		apiTokenRepo:     NewGormAPITokenRepository(db),
+		exchangeRateRepo: NewGormExchangeRateRepository(db),
 	}
 }
 
@@ -66,3 +87,38 @@ func (g *GormDatabase) AdminRepo() AdminRepository {
 func (g *GormDatabase) ModelConfigRepo() ModelConfigRepository {
 	return g.modelConfigRepo
 }
+
+// UsageRepo 返回用量记录仓库
+func (g *GormDatabase) UsageRepo() UsageRepository {
+	return g.usageRepo
+}
+
+// PromptRepo 返回提示词模板仓库
+func (g *GormDatabase) PromptRepo() PromptTemplateRepository {
+	return g.promptRepo
+}
+
+// ExperimentRepo 返回A/B实验仓库
+func (g *GormDatabase) ExperimentRepo() ExperimentRepository {
+	return g.experimentRepo
+}
+
+// ModelHealthRepo 返回模型配置探测历史仓库
+func (g *GormDatabase) ModelHealthRepo() ModelHealthRepository {
+	return g.modelHealthRepo
+}
+
+// MCPServerRepo 返回MCP服务器仓库
+func (g *GormDatabase) MCPServerRepo() MCPServerRepository {
+	return g.mcpServerRepo
+}
+
+// APITokenRepo 返回用户API令牌仓库
+func (g *GormDatabase) APITokenRepo() APITokenRepository {
+	return g.apiTokenRepo
+}
+
+// ExchangeRateRepo 返回汇率仓库
+func (g *GormDatabase) ExchangeRateRepo() ExchangeRateRepository {
+	return g.exchangeRateRepo
+}
@@ -5,4 +5,6 @@ import "errors"
 // 常见错误定义
 var (
 	ErrNotFound = errors.New("resource not found")
+	// ErrPromptTemplateActive 启用中的提示词模板版本不能删除
+	ErrPromptTemplateActive = errors.New("cannot delete active prompt template")
 )
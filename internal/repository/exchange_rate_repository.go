@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	"personatrip/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExchangeRateRepository 定义汇率仓库接口
+type ExchangeRateRepository interface {
+	GetAll(ctx context.Context) ([]models.ExchangeRate, error)
+	GetByCurrency(ctx context.Context, currency string) (*models.ExchangeRate, error)
+	Upsert(ctx context.Context, rate *models.ExchangeRate) error
+	Delete(ctx context.Context, currency string) error
+}
+
+// GormExchangeRateRepository 是使用GORM实现的汇率仓库
+type GormExchangeRateRepository struct {
+	db *gorm.DB
+}
+
+// NewGormExchangeRateRepository 创建新的GORM汇率仓库
+func NewGormExchangeRateRepository(db *gorm.DB) ExchangeRateRepository {
+	return &GormExchangeRateRepository{db: db}
+}
+
+// GetAll 获取所有汇率，按货币代码排列
+func (r *GormExchangeRateRepository) GetAll(ctx context.Context) ([]models.ExchangeRate, error) {
+	var rates []models.ExchangeRate
+	if err := r.db.WithContext(ctx).Order("currency ASC").Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// GetByCurrency 根据货币代码获取汇率
+func (r *GormExchangeRateRepository) GetByCurrency(ctx context.Context, currency string) (*models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	if err := r.db.WithContext(ctx).Where("currency = ?", currency).First(&rate).Error; err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// Upsert 保存汇率，货币已存在时更新汇率和来源
+func (r *GormExchangeRateRepository) Upsert(ctx context.Context, rate *models.ExchangeRate) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+	}).Create(rate).Error
+}
+
+// Delete 删除货币的汇率，不存在时返回ErrNotFound
+func (r *GormExchangeRateRepository) Delete(ctx context.Context, currency string) error {
+	result := r.db.WithContext(ctx).Where("currency = ?", currency).Delete(&models.ExchangeRate{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
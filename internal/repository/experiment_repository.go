@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"personatrip/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ExperimentRepository 定义A/B实验仓库接口
+type ExperimentRepository interface {
+	Create(ctx context.Context, experiment *models.Experiment) error
+	Delete(ctx context.Context, id uint) error
+	GetByID(ctx context.Context, id uint) (*models.Experiment, error)
+	List(ctx context.Context) ([]models.Experiment, error)
+	GetRunning(ctx context.Context) (*models.Experiment, error)
+	Start(ctx context.Context, id uint) error
+	Stop(ctx context.Context, id uint) error
+	CreateExposure(ctx context.Context, exposure *models.ExperimentExposure) error
+	MarkExposureEdited(ctx context.Context, id uint) error
+	MarkExposureDeleted(ctx context.Context, id uint) error
+	SummarizeVariants(ctx context.Context, experimentID uint) ([]models.ExperimentVariantReport, error)
+}
+
+// experimentSummaryColumns 按分组汇总生成记录的统计列
+const experimentSummaryColumns = "COUNT(*) AS exposures, " +
+	"SUM(CASE WHEN success THEN 1 ELSE 0 END) AS successes, " +
+	"SUM(CASE WHEN success AND fell_back THEN 1 ELSE 0 END) AS fallbacks, " +
+	"SUM(CASE WHEN edited THEN 1 ELSE 0 END) AS edits, " +
+	"SUM(CASE WHEN deleted THEN 1 ELSE 0 END) AS deletes, " +
+	"SUM(total_tokens) AS total_tokens, " +
+	"SUM(cost) AS total_cost, " +
+	"AVG(latency_ms) AS avg_latency_ms"
+
+// GormExperimentRepository 是使用GORM实现的A/B实验仓库
+type GormExperimentRepository struct {
+	db *gorm.DB
+}
+
+// NewGormExperimentRepository 创建新的GORM A/B实验仓库
+func NewGormExperimentRepository(db *gorm.DB) ExperimentRepository {
+	return &GormExperimentRepository{db: db}
+}
+
+// Create 创建实验及其分组
+func (r *GormExperimentRepository) Create(ctx context.Context, experiment *models.Experiment) error {
+	return r.db.WithContext(ctx).Create(experiment).Error
+}
+
+// Delete 删除实验及其分组和生成记录
+func (r *GormExperimentRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("experiment_id = ?", id).Delete(&models.ExperimentExposure{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("experiment_id = ?", id).Delete(&models.ExperimentVariant{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Experiment{}, id).Error
+	})
+}
+
+// GetByID 根据ID获取实验及其分组
+func (r *GormExperimentRepository) GetByID(ctx context.Context, id uint) (*models.Experiment, error) {
+	var experiment models.Experiment
+	if err := r.withVariants(ctx).First(&experiment, id).Error; err != nil {
+		return nil, err
+	}
+	return &experiment, nil
+}
+
+// List 获取所有实验，按创建时间倒序排列
+func (r *GormExperimentRepository) List(ctx context.Context) ([]models.Experiment, error) {
+	var experiments []models.Experiment
+	if err := r.withVariants(ctx).Order("id DESC").Find(&experiments).Error; err != nil {
+		return nil, err
+	}
+	return experiments, nil
+}
+
+// GetRunning 获取运行中的实验
+func (r *GormExperimentRepository) GetRunning(ctx context.Context) (*models.Experiment, error) {
+	var experiment models.Experiment
+	if err := r.withVariants(ctx).Where("status = ?", models.ExperimentRunning).First(&experiment).Error; err != nil {
+		return nil, err
+	}
+	return &experiment, nil
+}
+
+// Start 开始指定实验，其他运行中的实验自动停止
+func (r *GormExperimentRepository) Start(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.Experiment{}, id).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&models.Experiment{}).
+			Where("status = ? AND id <> ?", models.ExperimentRunning, id).
+			Updates(map[string]interface{}{"status": models.ExperimentStopped, "stopped_at": now}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Experiment{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"status": models.ExperimentRunning, "started_at": now}).Error
+	})
+}
+
+// Stop 停止指定实验
+func (r *GormExperimentRepository) Stop(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Model(&models.Experiment{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": models.ExperimentStopped, "stopped_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CreateExposure 保存一条生成记录
+func (r *GormExperimentRepository) CreateExposure(ctx context.Context, exposure *models.ExperimentExposure) error {
+	return r.db.WithContext(ctx).Create(exposure).Error
+}
+
+// MarkExposureEdited 标记生成的计划被用户修改
+func (r *GormExperimentRepository) MarkExposureEdited(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&models.ExperimentExposure{}).Where("id = ?", id).Update("edited", true).Error
+}
+
+// MarkExposureDeleted 标记生成的计划被用户删除
+func (r *GormExperimentRepository) MarkExposureDeleted(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&models.ExperimentExposure{}).Where("id = ?", id).Update("deleted", true).Error
+}
+
+// SummarizeVariants 按分组汇总实验的生成记录，只填充计数和合计列
+func (r *GormExperimentRepository) SummarizeVariants(ctx context.Context, experimentID uint) ([]models.ExperimentVariantReport, error) {
+	var summaries []models.ExperimentVariantReport
+	err := r.db.WithContext(ctx).
+		Model(&models.ExperimentExposure{}).
+		Select("variant_id, "+experimentSummaryColumns).
+		Where("experiment_id = ?", experimentID).
+		Group("variant_id").
+		Scan(&summaries).Error
+	return summaries, err
+}
+
+// withVariants 构建预加载分组的查询
+func (r *GormExperimentRepository) withVariants(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	})
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+
+	"personatrip/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// MCPServerRepository 定义MCP服务器仓库接口
+type MCPServerRepository interface {
+	Create(ctx context.Context, server *models.MCPServer) error
+	Delete(ctx context.Context, id uint) error
+	GetByName(ctx context.Context, name string) (*models.MCPServer, error)
+	GetAll(ctx context.Context) ([]models.MCPServer, error)
+	UpdateSecrets(ctx context.Context, id uint, env, headers string) error
+}
+
+// GormMCPServerRepository 是使用GORM实现的MCP服务器仓库
+type GormMCPServerRepository struct {
+	db *gorm.DB
+}
+
+// NewGormMCPServerRepository 创建新的GORM MCP服务器仓库
+func NewGormMCPServerRepository(db *gorm.DB) MCPServerRepository {
+	return &GormMCPServerRepository{db: db}
+}
+
+// Create 保存MCP服务器
+func (r *GormMCPServerRepository) Create(ctx context.Context, server *models.MCPServer) error {
+	return r.db.WithContext(ctx).Create(server).Error
+}
+
+// Delete 删除MCP服务器
+func (r *GormMCPServerRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.MCPServer{}, id).Error
+}
+
+// GetByName 根据名称获取MCP服务器
+func (r *GormMCPServerRepository) GetByName(ctx context.Context, name string) (*models.MCPServer, error) {
+	var server models.MCPServer
+	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&server).Error; err != nil {
+		return nil, err
+	}
+	return &server, nil
+}
+
+// GetAll 获取所有MCP服务器，按名称排列
+func (r *GormMCPServerRepository) GetAll(ctx context.Context) ([]models.MCPServer, error) {
+	var servers []models.MCPServer
+	if err := r.db.WithContext(ctx).Order("name ASC").Find(&servers).Error; err != nil {
+		return nil, err
+	}
+	return servers, nil
+}
+
+// UpdateSecrets 只更新加密后的环境变量和请求头，用于轮换主密钥
+func (r *GormMCPServerRepository) UpdateSecrets(ctx context.Context, id uint, env, headers string) error {
+	return r.db.WithContext(ctx).Model(&models.MCPServer{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"env":     env,
+		"headers": headers,
+	}).Error
+}
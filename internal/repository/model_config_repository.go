@@ -17,6 +17,9 @@ type ModelConfigRepository interface {
 	GetAll(ctx context.Context) ([]models.ModelConfig, error)
 	GetActive(ctx context.Context) (*models.ModelConfig, error)
 	SetActive(ctx context.Context, id uint) error
+	GetFallbacks(ctx context.Context) ([]models.ModelConfig, error)
+	UpdateAPIKey(ctx context.Context, id uint, apiKey, fingerprint string) error
+	UpdateHealth(ctx context.Context, config *models.ModelConfig) error
 }
 
 // GormModelConfigRepository 是使用GORM实现的模型配置仓库
@@ -117,6 +120,37 @@ func (r *GormModelConfigRepository) SetActive(ctx context.Context, id uint) erro
 	return r.db.WithContext(ctx).Model(&models.ModelConfig{}).Where("id = ?", id).Update("is_active", true).Error
 }
 
+// GetFallbacks 获取加入备用链的非活跃配置，按优先级升序排列
+func (r *GormModelConfigRepository) GetFallbacks(ctx context.Context) ([]models.ModelConfig, error) {
+	var configs []models.ModelConfig
+	if err := r.db.WithContext(ctx).
+		Where("is_active = ? AND priority > ?", false, 0).
+		Order("priority ASC").Order("id ASC").
+		Find(&configs).Error; err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// UpdateAPIKey 只更新加密后的API密钥和指纹，用于轮换主密钥
+func (r *GormModelConfigRepository) UpdateAPIKey(ctx context.Context, id uint, apiKey, fingerprint string) error {
+	return r.db.WithContext(ctx).Model(&models.ModelConfig{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"api_key":             apiKey,
+		"api_key_fingerprint": fingerprint,
+	}).Error
+}
+
+// UpdateHealth 只更新探测得到的健康状态，不覆盖管理员同时做的修改
+func (r *GormModelConfigRepository) UpdateHealth(ctx context.Context, config *models.ModelConfig) error {
+	return r.db.WithContext(ctx).Model(&models.ModelConfig{}).Where("id = ?", config.ID).Updates(map[string]interface{}{
+		"health_status":     config.HealthStatus,
+		"health_checked_at": config.HealthCheckedAt,
+		"health_latency_ms": config.HealthLatencyMs,
+		"health_error":      config.HealthError,
+		"health_failures":   config.HealthFailures,
+	}).Error
+}
+
 // deactivateAll 将所有配置设为非活跃
 func (r *GormModelConfigRepository) deactivateAll(ctx context.Context) error {
 	return r.db.WithContext(ctx).Model(&models.ModelConfig{}).Where("is_active = ?", true).Update("is_active", false).Error
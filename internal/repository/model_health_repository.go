@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"personatrip/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ModelHealthRepository 定义模型配置探测历史仓库接口
+type ModelHealthRepository interface {
+	Create(ctx context.Context, check *models.ModelHealthCheck) error
+	ListByModel(ctx context.Context, modelConfigID uint, limit int) ([]models.ModelHealthCheck, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// GormModelHealthRepository 是使用GORM实现的探测历史仓库
+type GormModelHealthRepository struct {
+	db *gorm.DB
+}
+
+// NewGormModelHealthRepository 创建新的GORM探测历史仓库
+func NewGormModelHealthRepository(db *gorm.DB) ModelHealthRepository {
+	return &GormModelHealthRepository{db: db}
+}
+
+// Create 保存一次探测结果
+func (r *GormModelHealthRepository) Create(ctx context.Context, check *models.ModelHealthCheck) error {
+	return r.db.WithContext(ctx).Create(check).Error
+}
+
+// ListByModel 获取模型配置最近的limit次探测结果，按时间倒序排列
+func (r *GormModelHealthRepository) ListByModel(ctx context.Context, modelConfigID uint, limit int) ([]models.ModelHealthCheck, error) {
+	var checks []models.ModelHealthCheck
+	if err := r.db.WithContext(ctx).
+		Where("model_config_id = ?", modelConfigID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&checks).Error; err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+// DeleteBefore 删除before之前的探测结果，返回删除的数量
+func (r *GormModelHealthRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&models.ModelHealthCheck{})
+	return result.RowsAffected, result.Error
+}
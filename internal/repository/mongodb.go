@@ -2,9 +2,9 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -18,6 +18,10 @@ type MongoDB struct {
 	database  *mongo.Database
 	users     *mongo.Collection
 	tripPlans *mongo.Collection
+	tripJobs  *mongo.Collection
+	planCache *mongo.Collection
+	traces    *mongo.Collection
+	toolCache *mongo.Collection
 }
 
 // NewMongoDB 创建新的MongoDB存储实例
@@ -41,12 +45,49 @@ func NewMongoDB(uri string) (*MongoDB, error) {
 	database := client.Database("personatrip")
 	users := database.Collection("users")
 	tripPlans := database.Collection("trip_plans")
+	tripJobs := database.Collection("trip_jobs")
+	planCache := database.Collection("trip_plan_cache")
+	traces := database.Collection("generation_traces")
+	toolCache := database.Collection("mcp_tool_cache")
+
+	// 缓存按摘要唯一，过期时间到达后由TTL索引自动删除
+	_, err = planCache.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 按计划和用户查询记录，保存时间到达后由TTL索引自动删除
+	_, err = traces.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "plan_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// MCP工具调用结果按键唯一，按提供者和工具批量删除，过期后由TTL索引自动删除
+	_, err = toolCache.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "provider", Value: 1}, {Key: "tool", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	return &MongoDB{
 		client:    client,
 		database:  database,
 		users:     users,
 		tripPlans: tripPlans,
+		tripJobs:  tripJobs,
+		planCache: planCache,
+		traces:    traces,
+		toolCache: toolCache,
 	}, nil
 }
 
@@ -99,9 +140,11 @@ func (m *MongoDB) UpdateUser(ctx context.Context, user *models.User) error {
 	return err
 }
 
-// CreateTripPlan 创建旅行计划
-func (m *MongoDB) CreateTripPlan(ctx *gin.Context, plan *models.TripPlan) (*models.TripPlan, error) {
-	plan.ID = primitive.NewObjectID()
+// CreateTripPlan 创建旅行计划，生成时已分配ID的计划沿用该ID
+func (m *MongoDB) CreateTripPlan(ctx context.Context, plan *models.TripPlan) (*models.TripPlan, error) {
+	if plan.ID.IsZero() {
+		plan.ID = primitive.NewObjectID()
+	}
 	plan.CreatedAt = time.Now()
 	plan.UpdatedAt = time.Now()
 
@@ -114,7 +157,7 @@ func (m *MongoDB) CreateTripPlan(ctx *gin.Context, plan *models.TripPlan) (*mode
 }
 
 // GetTripPlanByID 通过ID获取旅行计划
-func (m *MongoDB) GetTripPlanByID(ctx *gin.Context, id primitive.ObjectID) (*models.TripPlan, error) {
+func (m *MongoDB) GetTripPlanByID(ctx context.Context, id primitive.ObjectID) (*models.TripPlan, error) {
 	var plan models.TripPlan
 	err := m.tripPlans.FindOne(ctx, bson.M{"_id": id}).Decode(&plan)
 	if err != nil {
@@ -125,7 +168,7 @@ func (m *MongoDB) GetTripPlanByID(ctx *gin.Context, id primitive.ObjectID) (*mod
 }
 
 // GetTripPlansByUserID 获取用户的所有旅行计划
-func (m *MongoDB) GetTripPlansByUserID(ctx *gin.Context, userID primitive.ObjectID) ([]*models.TripPlan, error) {
+func (m *MongoDB) GetTripPlansByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.TripPlan, error) {
 	cursor, err := m.tripPlans.Find(ctx, bson.M{"user_id": userID})
 	if err != nil {
 		return nil, err
@@ -141,7 +184,7 @@ func (m *MongoDB) GetTripPlansByUserID(ctx *gin.Context, userID primitive.Object
 }
 
 // UpdateTripPlan 更新旅行计划
-func (m *MongoDB) UpdateTripPlan(ctx *gin.Context, plan *models.TripPlan) error {
+func (m *MongoDB) UpdateTripPlan(ctx context.Context, plan *models.TripPlan) error {
 	plan.UpdatedAt = time.Now()
 
 	_, err := m.tripPlans.ReplaceOne(ctx, bson.M{"_id": plan.ID}, plan)
@@ -149,7 +192,250 @@ func (m *MongoDB) UpdateTripPlan(ctx *gin.Context, plan *models.TripPlan) error
 }
 
 // DeleteTripPlan 删除旅行计划
-func (m *MongoDB) DeleteTripPlan(ctx *gin.Context, id primitive.ObjectID) error {
+func (m *MongoDB) DeleteTripPlan(ctx context.Context, id primitive.ObjectID) error {
 	_, err := m.tripPlans.DeleteOne(ctx, bson.M{"_id": id})
 	return err
 }
+
+// CreateTripJob 创建旅行计划生成任务
+func (m *MongoDB) CreateTripJob(ctx context.Context, job *models.TripJob) error {
+	job.ID = primitive.NewObjectID()
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = time.Now()
+
+	_, err := m.tripJobs.InsertOne(ctx, job)
+	return err
+}
+
+// GetTripJobByID 通过ID获取旅行计划生成任务
+func (m *MongoDB) GetTripJobByID(ctx context.Context, id primitive.ObjectID) (*models.TripJob, error) {
+	var job models.TripJob
+	err := m.tripJobs.FindOne(ctx, bson.M{"_id": id}).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// GetTripJobsByStatus 获取处于指定状态的所有任务，按创建时间排序
+func (m *MongoDB) GetTripJobsByStatus(ctx context.Context, statuses ...models.TripJobStatus) ([]*models.TripJob, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := m.tripJobs.Find(ctx, bson.M{"status": bson.M{"$in": statuses}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []*models.TripJob
+	if err = cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// UpdateTripJob 更新旅行计划生成任务
+func (m *MongoDB) UpdateTripJob(ctx context.Context, job *models.TripJob) error {
+	job.UpdatedAt = time.Now()
+
+	_, err := m.tripJobs.ReplaceOne(ctx, bson.M{"_id": job.ID}, job)
+	return err
+}
+
+// ClaimTripJob 原子地把queued状态的任务改为running并增加执行次数，返回领取后的任务。
+// 任务不是queued状态（已被其他worker或实例领取、已经结束）时返回mongo.ErrNoDocuments
+func (m *MongoDB) ClaimTripJob(ctx context.Context, id primitive.ObjectID) (*models.TripJob, error) {
+	now := time.Now()
+	filter := bson.M{"_id": id, "status": models.TripJobQueued}
+	update := bson.M{
+		"$set": bson.M{"status": models.TripJobRunning, "started_at": now, "updated_at": now},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var job models.TripJob
+	if err := m.tripJobs.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// RequeueStaleTripJob 把updatedBefore之后没有更新过的queued或running任务重置为queued并更新时间，
+// 返回是否由本次调用重置，多个实例同时重置同一个任务时只有一个成功
+func (m *MongoDB) RequeueStaleTripJob(ctx context.Context, id primitive.ObjectID, updatedBefore time.Time) (bool, error) {
+	filter := bson.M{
+		"_id":        id,
+		"status":     bson.M{"$in": []models.TripJobStatus{models.TripJobQueued, models.TripJobRunning}},
+		"updated_at": bson.M{"$lt": updatedBefore},
+	}
+	update := bson.M{"$set": bson.M{"status": models.TripJobQueued, "updated_at": time.Now()}}
+	result, err := m.tripJobs.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// GetPlanCacheEntry 通过摘要获取未过期的缓存，TTL索引的清理存在延迟，因此再按过期时间过滤
+func (m *MongoDB) GetPlanCacheEntry(ctx context.Context, hash string) (*models.PlanCacheEntry, error) {
+	var entry models.PlanCacheEntry
+	filter := bson.M{"hash": hash, "expires_at": bson.M{"$gt": time.Now()}}
+	err := m.planCache.FindOne(ctx, filter).Decode(&entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// SavePlanCacheEntry 保存缓存，同一摘要的旧缓存会被替换
+func (m *MongoDB) SavePlanCacheEntry(ctx context.Context, entry *models.PlanCacheEntry) error {
+	entry.ID = primitive.NilObjectID
+	opts := options.Replace().SetUpsert(true)
+	_, err := m.planCache.ReplaceOne(ctx, bson.M{"hash": entry.Hash}, entry, opts)
+	return err
+}
+
+// IncrementPlanCacheHits 缓存命中次数加一
+func (m *MongoDB) IncrementPlanCacheHits(ctx context.Context, hash string) error {
+	_, err := m.planCache.UpdateOne(ctx, bson.M{"hash": hash}, bson.M{"$inc": bson.M{"hits": 1}})
+	return err
+}
+
+// ListPlanCacheEntries 获取缓存列表，destination不为空时只返回该目的地的缓存，按创建时间倒序
+func (m *MongoDB) ListPlanCacheEntries(ctx context.Context, destination string, limit int64) ([]*models.PlanCacheEntry, error) {
+	filter := bson.M{}
+	if destination != "" {
+		filter["key.destination"] = destination
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"plan": 0})
+	cursor, err := m.planCache.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*models.PlanCacheEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// DeletePlanCacheEntry 删除指定摘要的缓存，不存在时返回ErrNotFound
+func (m *MongoDB) DeletePlanCacheEntry(ctx context.Context, hash string) error {
+	result, err := m.planCache.DeleteOne(ctx, bson.M{"hash": hash})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeletePlanCacheEntries 批量删除缓存，destination为空时清空全部缓存，返回删除的数量
+func (m *MongoDB) DeletePlanCacheEntries(ctx context.Context, destination string) (int64, error) {
+	filter := bson.M{}
+	if destination != "" {
+		filter["key.destination"] = destination
+	}
+	result, err := m.planCache.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// GetMCPToolCacheEntry 通过键获取未过期的MCP工具调用结果，TTL索引的清理存在延迟，因此再按过期时间过滤
+func (m *MongoDB) GetMCPToolCacheEntry(ctx context.Context, key string) (*models.MCPToolCacheEntry, error) {
+	var entry models.MCPToolCacheEntry
+	filter := bson.M{"key": key, "expires_at": bson.M{"$gt": time.Now()}}
+	err := m.toolCache.FindOne(ctx, filter).Decode(&entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// SaveMCPToolCacheEntry 保存MCP工具调用结果，同一键的旧结果会被替换
+func (m *MongoDB) SaveMCPToolCacheEntry(ctx context.Context, entry *models.MCPToolCacheEntry) error {
+	entry.ID = primitive.NilObjectID
+	opts := options.Replace().SetUpsert(true)
+	_, err := m.toolCache.ReplaceOne(ctx, bson.M{"key": entry.Key}, entry, opts)
+	return err
+}
+
+// DeleteMCPToolCacheEntries 批量删除MCP工具调用结果，provider和tool为空时不按其过滤，返回删除的数量
+func (m *MongoDB) DeleteMCPToolCacheEntries(ctx context.Context, provider, tool string) (int64, error) {
+	filter := bson.M{}
+	if provider != "" {
+		filter["provider"] = provider
+	}
+	if tool != "" {
+		filter["tool"] = tool
+	}
+	result, err := m.toolCache.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// SaveGenerationTrace 保存生成过程记录
+func (m *MongoDB) SaveGenerationTrace(ctx context.Context, trace *models.GenerationTrace) error {
+	if trace.ID.IsZero() {
+		trace.ID = primitive.NewObjectID()
+	}
+
+	_, err := m.traces.InsertOne(ctx, trace)
+	return err
+}
+
+// GetGenerationTrace 通过ID获取生成过程记录，不存在时返回ErrNotFound
+func (m *MongoDB) GetGenerationTrace(ctx context.Context, id primitive.ObjectID) (*models.GenerationTrace, error) {
+	var trace models.GenerationTrace
+	err := m.traces.FindOne(ctx, bson.M{"_id": id}).Decode(&trace)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &trace, nil
+}
+
+// ListGenerationTraces 获取最近的生成过程记录，不包含每次调用的详情，按创建时间倒序；
+// userID不为空时只返回该用户的记录，failedOnly为true时只返回失败的记录
+func (m *MongoDB) ListGenerationTraces(ctx context.Context, userID string, failedOnly bool, limit int64) ([]*models.GenerationTrace, error) {
+	filter := bson.M{}
+	if userID != "" {
+		filter["user_id"] = userID
+	}
+	if failedOnly {
+		filter["success"] = false
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"calls": 0})
+	cursor, err := m.traces.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var traces []*models.GenerationTrace
+	if err = cursor.All(ctx, &traces); err != nil {
+		return nil, err
+	}
+
+	return traces, nil
+}
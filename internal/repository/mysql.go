@@ -51,6 +51,15 @@ func autoMigrate(db *gorm.DB) error {
 		&models.UserMySQL{},
 		&models.Admin{},
 		&models.ModelConfig{},
+		&models.ModelHealthCheck{},
+		&models.UsageRecord{},
+		&models.PromptTemplate{},
+		&models.Experiment{},
+		&models.ExperimentVariant{},
+		&models.ExperimentExposure{},
+		&models.MCPServer{},
+		&models.UserAPIToken{},
+		&models.ExchangeRate{},
 	)
 	return err
 }
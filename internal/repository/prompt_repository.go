@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+
+	"personatrip/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// PromptTemplateRepository 定义提示词模板仓库接口
+type PromptTemplateRepository interface {
+	Create(ctx context.Context, template *models.PromptTemplate) error
+	Delete(ctx context.Context, id uint) error
+	GetByID(ctx context.Context, id uint) (*models.PromptTemplate, error)
+	List(ctx context.Context, name string) ([]models.PromptTemplate, error)
+	GetActive(ctx context.Context, name string) (*models.PromptTemplate, error)
+	GetByVersion(ctx context.Context, name string, version int) (*models.PromptTemplate, error)
+	GetLatestVersion(ctx context.Context, name string) (int, error)
+	SetActive(ctx context.Context, id uint) error
+}
+
+// GormPromptTemplateRepository 是使用GORM实现的提示词模板仓库
+type GormPromptTemplateRepository struct {
+	db *gorm.DB
+}
+
+// NewGormPromptTemplateRepository 创建新的GORM提示词模板仓库
+func NewGormPromptTemplateRepository(db *gorm.DB) PromptTemplateRepository {
+	return &GormPromptTemplateRepository{db: db}
+}
+
+// Create 创建提示词模板版本
+func (r *GormPromptTemplateRepository) Create(ctx context.Context, template *models.PromptTemplate) error {
+	return r.db.WithContext(ctx).Create(template).Error
+}
+
+// Delete 标记删除提示词模板版本，启用中的版本不允许删除，返回ErrPromptTemplateActive
+func (r *GormPromptTemplateRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Where("is_active = ?", false).Delete(&models.PromptTemplate{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrPromptTemplateActive
+	}
+	return nil
+}
+
+// GetByID 根据ID获取提示词模板版本
+func (r *GormPromptTemplateRepository) GetByID(ctx context.Context, id uint) (*models.PromptTemplate, error) {
+	var template models.PromptTemplate
+	if err := r.db.WithContext(ctx).First(&template, id).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// List 获取提示词模板版本列表，name不为空时只返回该名称的版本，按名称和版本倒序排列
+func (r *GormPromptTemplateRepository) List(ctx context.Context, name string) ([]models.PromptTemplate, error) {
+	query := r.db.WithContext(ctx)
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+
+	var templates []models.PromptTemplate
+	if err := query.Order("name ASC").Order("version DESC").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// GetActive 获取指定名称当前启用的版本
+func (r *GormPromptTemplateRepository) GetActive(ctx context.Context, name string) (*models.PromptTemplate, error) {
+	var template models.PromptTemplate
+	if err := r.db.WithContext(ctx).Where("name = ? AND is_active = ?", name, true).First(&template).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetByVersion 获取指定名称的指定版本
+func (r *GormPromptTemplateRepository) GetByVersion(ctx context.Context, name string, version int) (*models.PromptTemplate, error) {
+	var template models.PromptTemplate
+	if err := r.db.WithContext(ctx).Where("name = ? AND version = ?", name, version).First(&template).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetLatestVersion 获取指定名称的最大版本号，包括已删除的版本，没有任何版本时返回0
+func (r *GormPromptTemplateRepository) GetLatestVersion(ctx context.Context, name string) (int, error) {
+	var version int
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.PromptTemplate{}).
+		Where("name = ?", name).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&version).Error
+	return version, err
+}
+
+// SetActive 启用指定版本，同名的其他版本设为非启用
+func (r *GormPromptTemplateRepository) SetActive(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var template models.PromptTemplate
+		if err := tx.First(&template, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PromptTemplate{}).
+			Where("name = ? AND is_active = ?", template.Name, true).
+			Update("is_active", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.PromptTemplate{}).Where("id = ?", id).Update("is_active", true).Error
+	})
+}
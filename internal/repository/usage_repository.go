@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"personatrip/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// UsageRepository 定义大模型用量记录仓库接口
+type UsageRepository interface {
+	Create(ctx context.Context, record *models.UsageRecord) error
+	SummarizeByUser(ctx context.Context, from, to time.Time) ([]models.UsageSummary, error)
+	SummarizeByModel(ctx context.Context, from, to time.Time) ([]models.UsageSummary, error)
+	SummarizeByDay(ctx context.Context, from, to time.Time) ([]models.UsageSummary, error)
+}
+
+// usageSummaryColumns 汇总查询共用的统计列
+const usageSummaryColumns = "COUNT(*) AS calls, " +
+	"SUM(CASE WHEN usage_records.success THEN 0 ELSE 1 END) AS failed_calls, " +
+	"SUM(usage_records.prompt_tokens) AS prompt_tokens, " +
+	"SUM(usage_records.completion_tokens) AS completion_tokens, " +
+	"SUM(usage_records.total_tokens) AS total_tokens, " +
+	"SUM(usage_records.cost) AS cost, " +
+	"AVG(usage_records.latency_ms) AS avg_latency_ms"
+
+// GormUsageRepository 是使用GORM实现的用量记录仓库
+type GormUsageRepository struct {
+	db *gorm.DB
+}
+
+// NewGormUsageRepository 创建新的GORM用量记录仓库
+func NewGormUsageRepository(db *gorm.DB) UsageRepository {
+	return &GormUsageRepository{db: db}
+}
+
+// Create 保存一条用量记录
+func (r *GormUsageRepository) Create(ctx context.Context, record *models.UsageRecord) error {
+	return r.db.WithContext(ctx).Create(record).Error
+}
+
+// SummarizeByUser 按用户汇总[from, to)区间内的用量，按费用降序排列
+func (r *GormUsageRepository) SummarizeByUser(ctx context.Context, from, to time.Time) ([]models.UsageSummary, error) {
+	var summaries []models.UsageSummary
+	err := r.between(ctx, from, to).
+		Select("usage_records.user_id AS `key`, " + usageSummaryColumns).
+		Group("usage_records.user_id").
+		Order("cost DESC").
+		Scan(&summaries).Error
+	return summaries, err
+}
+
+// SummarizeByModel 按模型配置汇总[from, to)区间内的用量，按费用降序排列
+func (r *GormUsageRepository) SummarizeByModel(ctx context.Context, from, to time.Time) ([]models.UsageSummary, error) {
+	var summaries []models.UsageSummary
+	err := r.between(ctx, from, to).
+		Select("CAST(usage_records.model_config_id AS CHAR) AS `key`, MAX(model_configs.name) AS name, " + usageSummaryColumns).
+		Joins("LEFT JOIN model_configs ON model_configs.id = usage_records.model_config_id").
+		Group("usage_records.model_config_id").
+		Order("cost DESC").
+		Scan(&summaries).Error
+	return summaries, err
+}
+
+// SummarizeByDay 按天汇总[from, to)区间内的用量，按日期升序排列
+func (r *GormUsageRepository) SummarizeByDay(ctx context.Context, from, to time.Time) ([]models.UsageSummary, error) {
+	var summaries []models.UsageSummary
+	err := r.between(ctx, from, to).
+		Select("DATE_FORMAT(usage_records.created_at, '%Y-%m-%d') AS `key`, " + usageSummaryColumns).
+		Group("`key`").
+		Order("`key` ASC").
+		Scan(&summaries).Error
+	return summaries, err
+}
+
+// between 构建限定时间区间的查询
+func (r *GormUsageRepository) between(ctx context.Context, from, to time.Time) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Model(&models.UsageRecord{}).
+		Where("usage_records.created_at >= ? AND usage_records.created_at < ?", from, to)
+}
@@ -0,0 +1,318 @@
+package secrets
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"personatrip/internal/config"
+)
+
+// 加密后的值的格式为 enc:v1:<主密钥ID>:<被主密钥加密的数据密钥>:<被数据密钥加密的明文>，
+// 两段密文都使用AES-256-GCM并以随机nonce开头，经base64编码。轮换主密钥时只需重新加密数据密钥
+const (
+	encryptedPrefix = "enc:v1:"
+	masterKeySize   = 32
+	dataKeySize     = 32
+)
+
+var (
+	// ErrUnknownMasterKey 加密该值的主密钥不在当前的主密钥列表中
+	ErrUnknownMasterKey = errors.New("未找到加密该值的主密钥")
+
+	// ErrMalformed 加密后的值格式错误
+	ErrMalformed = errors.New("加密数据格式错误")
+)
+
+// Keyring 保存主密钥，第一个主密钥用于加密，其余的只用于解密轮换前加密的值。
+// 没有主密钥时不加密，密钥以明文保存
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// MasterKey 一个主密钥
+type MasterKey struct {
+	ID  string
+	Key []byte // 32字节，用于AES-256
+}
+
+// NewKeyring 创建密钥环，keys中的第一个为当前主密钥
+func NewKeyring(keys []MasterKey) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte, len(keys))}
+	for i, key := range keys {
+		if key.ID == "" || strings.Contains(key.ID, ":") {
+			return nil, fmt.Errorf("主密钥ID不能为空或包含冒号: %q", key.ID)
+		}
+		if len(key.Key) != masterKeySize {
+			return nil, fmt.Errorf("主密钥%s的长度为%d字节，需要%d字节", key.ID, len(key.Key), masterKeySize)
+		}
+		if _, ok := k.keys[key.ID]; ok {
+			return nil, fmt.Errorf("主密钥ID重复: %s", key.ID)
+		}
+		k.keys[key.ID] = key.Key
+		if i == 0 {
+			k.primary = key.ID
+		}
+	}
+	return k, nil
+}
+
+// LoadKeyring 从配置加载主密钥。SecretConfig.MasterKeys为逗号分隔的"ID:base64密钥"列表，
+// MasterKeyFile为每行一个"ID:base64密钥"的文件，两者同时配置时先使用环境变量中的密钥。
+// 生产环境必须配置主密钥
+func LoadKeyring(cfg *config.SecretConfig, environment string) (*Keyring, error) {
+	var entries []string
+	if cfg != nil {
+		entries = append(entries, strings.Split(cfg.MasterKeys, ",")...)
+		if cfg.MasterKeyFile != "" {
+			lines, err := readKeyFile(cfg.MasterKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, lines...)
+		}
+	}
+
+	var keys []MasterKey
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("主密钥格式应为ID:base64密钥")
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("主密钥%s不是有效的base64: %w", id, err)
+		}
+		keys = append(keys, MasterKey{ID: strings.TrimSpace(id), Key: key})
+	}
+	if len(keys) == 0 && environment == "production" {
+		return nil, errors.New("生产环境必须配置SECRET_MASTER_KEYS或SECRET_MASTER_KEY_FILE")
+	}
+	return NewKeyring(keys)
+}
+
+// readKeyFile 读取主密钥文件，忽略空行和以#开头的注释行
+func readKeyFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取主密钥文件失败: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// Enabled 是否配置了主密钥
+func (k *Keyring) Enabled() bool {
+	return k != nil && k.primary != ""
+}
+
+// PrimaryKeyID 返回当前主密钥的ID，没有主密钥时返回空字符串
+func (k *Keyring) PrimaryKeyID() string {
+	if k == nil {
+		return ""
+	}
+	return k.primary
+}
+
+// Encrypt 用随机数据密钥加密明文，再用当前主密钥加密数据密钥。没有主密钥或明文为空时原样返回
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if !k.Enabled() || plaintext == "" {
+		return plaintext, nil
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	sealedData, err := seal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	sealedKey, err := seal(k.keys[k.primary], dataKey)
+	if err != nil {
+		return "", err
+	}
+	return format(k.primary, sealedKey, sealedData), nil
+}
+
+// Decrypt 解密Encrypt的结果，未加密的值原样返回
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	keyID, sealedKey, sealedData, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := k.openDataKey(keyID, sealedKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataKey, sealedData)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap 用当前主密钥重新加密值的数据密钥，明文部分的密文不变；未加密的值直接用当前主密钥加密。
+// 返回新的值和是否有变化，已使用当前主密钥或没有主密钥时不变
+func (k *Keyring) Rewrap(value string) (string, bool, error) {
+	if !k.Enabled() || value == "" {
+		return value, false, nil
+	}
+	if !IsEncrypted(value) {
+		encrypted, err := k.Encrypt(value)
+		return encrypted, err == nil, err
+	}
+
+	keyID, sealedKey, sealedData, err := parse(value)
+	if err != nil {
+		return "", false, err
+	}
+	if keyID == k.primary {
+		return value, false, nil
+	}
+	dataKey, err := k.openDataKey(keyID, sealedKey)
+	if err != nil {
+		return "", false, err
+	}
+	sealedKey, err = seal(k.keys[k.primary], dataKey)
+	if err != nil {
+		return "", false, err
+	}
+	return format(k.primary, sealedKey, sealedData), true, nil
+}
+
+// openDataKey 用指定的主密钥解密数据密钥
+func (k *Keyring) openDataKey(keyID string, sealedKey []byte) ([]byte, error) {
+	var masterKey []byte
+	if k != nil {
+		masterKey = k.keys[keyID]
+	}
+	if masterKey == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMasterKey, keyID)
+	}
+	dataKey, err := open(masterKey, sealedKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return dataKey, nil
+}
+
+// IsEncrypted 值是否为Encrypt的结果
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// Fingerprint 返回可以展示给管理员的密钥指纹：只保留末4位的掩码和SHA-256摘要的前8位，
+// 用于区分配置的是哪个密钥而不泄露密钥本身
+func Fingerprint(plaintext string) string {
+	if plaintext == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(plaintext))
+	masked := "****"
+	if runes := []rune(plaintext); len(runes) >= 12 {
+		masked += string(runes[len(runes)-4:])
+	}
+	return masked + " sha256:" + hex.EncodeToString(sum[:4])
+}
+
+// format 拼接加密后的值
+func format(keyID string, sealedKey, sealedData []byte) string {
+	return encryptedPrefix + keyID + ":" + base64.StdEncoding.EncodeToString(sealedKey) + ":" + base64.StdEncoding.EncodeToString(sealedData)
+}
+
+// parse 拆分加密后的值
+func parse(value string) (string, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if !IsEncrypted(value) || len(parts) != 3 {
+		return "", nil, nil, ErrMalformed
+	}
+	sealedKey, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	sealedData, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	return parts[0], sealedKey, sealedData, nil
+}
+
+// seal 用AES-256-GCM加密，结果以随机nonce开头
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open 解密seal的结果
+func open(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("密文过短")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+// newGCM 创建AES-GCM加密器
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultKeyring = &Keyring{}
+)
+
+// SetDefault 设置全局密钥环，启动时根据配置调用一次
+func SetDefault(k *Keyring) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultKeyring = k
+}
+
+// Default 返回全局密钥环，未设置时返回不加密的空密钥环
+func Default() *Keyring {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultKeyring
+}
@@ -0,0 +1,243 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testKey 返回由同一字节填充的32字节主密钥
+func testKey(id string, b byte) MasterKey {
+	return MasterKey{ID: id, Key: bytes.Repeat([]byte{b}, masterKeySize)}
+}
+
+// mustKeyring 创建密钥环，失败时终止测试
+func mustKeyring(t *testing.T, keys ...MasterKey) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(keys)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return k
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	keyring := mustKeyring(t, testKey("k1", 1))
+	tests := []struct {
+		name      string
+		plaintext string
+		encrypted bool
+	}{
+		{name: "ascii", plaintext: "sk-test-1234567890", encrypted: true},
+		{name: "unicode", plaintext: "高德地图密钥", encrypted: true},
+		{name: "contains separator", plaintext: "a:b:c", encrypted: true},
+		{name: "empty", plaintext: "", encrypted: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := keyring.Encrypt(tt.plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if IsEncrypted(encrypted) != tt.encrypted {
+				t.Fatalf("IsEncrypted(%q) = %v, want %v", encrypted, !tt.encrypted, tt.encrypted)
+			}
+			if tt.encrypted && strings.Contains(encrypted, tt.plaintext) {
+				t.Fatalf("encrypted value contains the plaintext: %q", encrypted)
+			}
+			decrypted, err := keyring.Decrypt(encrypted)
+			if err != nil {
+				t.Fatalf("Decrypt: %v", err)
+			}
+			if decrypted != tt.plaintext {
+				t.Fatalf("Decrypt = %q, want %q", decrypted, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestEncryptUsesRandomNonce(t *testing.T) {
+	keyring := mustKeyring(t, testKey("k1", 1))
+	first, err := keyring.Encrypt("same")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := keyring.Encrypt("same")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatal("encrypting the same plaintext twice returned the same value")
+	}
+}
+
+func TestRewrapAcrossRotation(t *testing.T) {
+	oldKeyring := mustKeyring(t, testKey("old", 1))
+	rotated := mustKeyring(t, testKey("new", 2), testKey("old", 1))
+	newOnly := mustKeyring(t, testKey("new", 2))
+
+	encrypted, err := oldKeyring.Encrypt("api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newlyEncrypted, err := rotated.Encrypt("api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		changed bool
+	}{
+		{name: "old primary key", value: encrypted, changed: true},
+		{name: "current primary key", value: newlyEncrypted, changed: false},
+		{name: "plaintext legacy value", value: "api-key", changed: true},
+		{name: "empty", value: "", changed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rewrapped, changed, err := rotated.Rewrap(tt.value)
+			if err != nil {
+				t.Fatalf("Rewrap: %v", err)
+			}
+			if changed != tt.changed {
+				t.Fatalf("Rewrap changed = %v, want %v", changed, tt.changed)
+			}
+			if !changed && rewrapped != tt.value {
+				t.Fatalf("unchanged value was modified: %q", rewrapped)
+			}
+			if tt.value == "" {
+				return
+			}
+			if !strings.HasPrefix(rewrapped, encryptedPrefix+"new:") {
+				t.Fatalf("rewrapped value not under the new primary key: %q", rewrapped)
+			}
+			// 去掉旧主密钥后仍然可以解密
+			decrypted, err := newOnly.Decrypt(rewrapped)
+			if err != nil {
+				t.Fatalf("Decrypt with new key only: %v", err)
+			}
+			if decrypted != "api-key" {
+				t.Fatalf("Decrypt = %q, want %q", decrypted, "api-key")
+			}
+		})
+	}
+}
+
+func TestRewrapKeepsDataCiphertext(t *testing.T) {
+	oldKeyring := mustKeyring(t, testKey("old", 1))
+	rotated := mustKeyring(t, testKey("new", 2), testKey("old", 1))
+
+	encrypted, err := oldKeyring.Encrypt("api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewrapped, _, err := rotated.Rewrap(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, oldData, err := parse(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, newData, err := parse(rewrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(oldData, newData) {
+		t.Fatal("Rewrap re-encrypted the data instead of only the data key")
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	keyring := mustKeyring(t, testKey("k1", 1))
+	other := mustKeyring(t, testKey("k2", 2))
+	sameIDOtherKey := mustKeyring(t, testKey("k1", 3))
+
+	encrypted, err := keyring.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID, sealedKey, sealedData, err := parse(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte{}, sealedData...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	tests := []struct {
+		name    string
+		keyring *Keyring
+		value   string
+		want    error
+	}{
+		{name: "unknown key id", keyring: other, value: encrypted, want: ErrUnknownMasterKey},
+		{name: "no master keys", keyring: &Keyring{}, value: encrypted, want: ErrUnknownMasterKey},
+		{name: "nil keyring", keyring: nil, value: encrypted, want: ErrUnknownMasterKey},
+		{name: "wrong key for id", keyring: sameIDOtherKey, value: encrypted, want: ErrMalformed},
+		{name: "tampered data", keyring: keyring, value: format(keyID, sealedKey, tampered), want: ErrMalformed},
+		{name: "missing parts", keyring: keyring, value: encryptedPrefix + "k1:abc", want: ErrMalformed},
+		{name: "invalid base64", keyring: keyring, value: encryptedPrefix + "k1:!!!:!!!", want: ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.keyring.Decrypt(tt.value); !errors.Is(err, tt.want) {
+				t.Fatalf("Decrypt error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaintextLegacyValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyring *Keyring
+	}{
+		{name: "with master key", keyring: mustKeyring(t, testKey("k1", 1))},
+		{name: "without master key", keyring: mustKeyring(t)},
+		{name: "nil keyring", keyring: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, value := range []string{"", "sk-plain", "enc-not-really"} {
+				decrypted, err := tt.keyring.Decrypt(value)
+				if err != nil {
+					t.Fatalf("Decrypt(%q): %v", value, err)
+				}
+				if decrypted != value {
+					t.Fatalf("Decrypt(%q) = %q, want the value unchanged", value, decrypted)
+				}
+			}
+		})
+	}
+
+	// 没有主密钥时不加密，也不改写
+	disabled := mustKeyring(t)
+	if encrypted, err := disabled.Encrypt("sk-plain"); err != nil || encrypted != "sk-plain" {
+		t.Fatalf("Encrypt without master key = %q, %v", encrypted, err)
+	}
+	if value, changed, err := disabled.Rewrap("sk-plain"); err != nil || changed || value != "sk-plain" {
+		t.Fatalf("Rewrap without master key = %q, %v, %v", value, changed, err)
+	}
+}
+
+func TestNewKeyringValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []MasterKey
+	}{
+		{name: "empty id", keys: []MasterKey{testKey("", 1)}},
+		{name: "id with colon", keys: []MasterKey{testKey("a:b", 1)}},
+		{name: "short key", keys: []MasterKey{{ID: "k1", Key: []byte("short")}}},
+		{name: "duplicate id", keys: []MasterKey{testKey("k1", 1), testKey("k1", 2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeyring(tt.keys); err == nil {
+				t.Fatal("NewKeyring succeeded, want error")
+			}
+		})
+	}
+}
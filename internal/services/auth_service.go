@@ -1,16 +1,29 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gorm.io/gorm"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"personatrip/internal/models"
 	"personatrip/internal/repository"
+	"personatrip/internal/utils/logger"
 )
 
+// ErrInvalidAPIToken API令牌不存在或已过期
+var ErrInvalidAPIToken = errors.New("无效的API令牌")
+
+// apiTokenTouchInterval 记录API令牌最后使用时间的最小间隔，SSE模式下每个请求都会验证令牌
+const apiTokenTouchInterval = time.Minute
+
 // AuthService 处理用户认证
 type AuthService struct {
 	db         repository.Database
@@ -124,3 +137,65 @@ func (s *AuthService) GetUserFromToken(ctx *gin.Context, tokenString string) (*m
 	// 获取用户
 	return s.db.UserRepo().GetUserByID(ctx, userID)
 }
+
+// CreateAPIToken 为用户创建API令牌，令牌明文只在返回值中出现一次，数据库只保存摘要
+func (s *AuthService) CreateAPIToken(ctx context.Context, userID string, req *models.APITokenCreateRequest) (*models.APITokenCreateResponse, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("生成API令牌失败: %w", err)
+	}
+	plain := models.APITokenPrefix + hex.EncodeToString(secret)
+
+	token := &models.UserAPIToken{
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: hashAPIToken(plain),
+		Hint:      plain[:len(models.APITokenPrefix)+6],
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := s.db.APITokenRepo().Create(ctx, token); err != nil {
+		return nil, fmt.Errorf("保存API令牌失败: %w", err)
+	}
+	return &models.APITokenCreateResponse{Token: plain, Info: *token}, nil
+}
+
+// ListAPITokens 获取用户的所有API令牌，不包含令牌明文
+func (s *AuthService) ListAPITokens(ctx context.Context, userID string) ([]models.UserAPIToken, error) {
+	return s.db.APITokenRepo().ListByUserID(ctx, userID)
+}
+
+// DeleteAPIToken 吊销用户的API令牌，令牌不存在或不属于该用户时返回repository.ErrNotFound
+func (s *AuthService) DeleteAPIToken(ctx context.Context, userID string, id uint) error {
+	return s.db.APITokenRepo().Delete(ctx, userID, id)
+}
+
+// ValidateAPIToken 验证API令牌并返回所属用户的ID，同时记录令牌的最后使用时间。
+// 最后使用时间只在距上次记录超过apiTokenTouchInterval时更新，更新失败不影响验证结果
+func (s *AuthService) ValidateAPIToken(ctx context.Context, plain string) (string, error) {
+	token, err := s.db.APITokenRepo().GetByHash(ctx, hashAPIToken(plain))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrInvalidAPIToken
+		}
+		return "", err
+	}
+	now := time.Now()
+	if token.ExpiresAt != nil && !now.Before(*token.ExpiresAt) {
+		return "", ErrInvalidAPIToken
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval {
+		if err := s.db.APITokenRepo().UpdateLastUsed(ctx, token.ID, now); err != nil {
+			logger.Warnf("更新API令牌 %d 的最后使用时间失败: %v", token.ID, err)
+		}
+	}
+	return token.UserID, nil
+}
+
+// hashAPIToken 计算API令牌的SHA-256摘要
+func hashAPIToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,23 @@
+package services
+
+import "context"
+
+// callInfoKey 是调用信息在context中的键
+type callInfoKey struct{}
+
+// CallInfo 描述一次大模型调用的来源，用于用量记录
+type CallInfo struct {
+	UserID   string // 发起请求的用户ID，匿名请求为空
+	Endpoint string // 触发调用的接口路径或任务名称
+}
+
+// WithCallInfo 在context中附加调用来源
+func WithCallInfo(ctx context.Context, userID, endpoint string) context.Context {
+	return context.WithValue(ctx, callInfoKey{}, CallInfo{UserID: userID, Endpoint: endpoint})
+}
+
+// CallInfoFromContext 获取context中的调用来源，不存在时返回空值
+func CallInfoFromContext(ctx context.Context) CallInfo {
+	info, _ := ctx.Value(callInfoKey{}).(CallInfo)
+	return info
+}
@@ -3,118 +3,119 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/cloudwego/eino/components/tool"
 	"github.com/mark3labs/mcp-go/mcp"
 	iconfig "personatrip/internal/config"
 	"personatrip/internal/models"
+	"personatrip/internal/toolpolicy"
+	"personatrip/internal/traveltools"
+	"personatrip/internal/utils/logger"
 	"personatrip/pkg/einosdk"
 	pkgmcp "personatrip/pkg/mcp"
 )
 
-// EinoService 是大模型服务的实现
+var (
+	// ErrAllModelsFailed 故障切换链中的所有模型配置都生成失败
+	ErrAllModelsFailed = errors.New("所有模型配置均生成失败")
+
+	// errUnparseableOutput 模型输出无法解析，换一个模型重试
+	errUnparseableOutput = errors.New("模型输出无法解析")
+)
+
+// mcpReadyTimeout 生成旅行计划时等待MCP客户端初始化的最长时间，超时后不使用工具生成
+const mcpReadyTimeout = 10 * time.Second
+
+// EinoService 是大模型服务的实现，可以被多个请求同时使用
 type EinoService struct {
-	client         *einosdk.Client
-	configService  ModelConfigService
-	activeConfig   *models.ModelConfig
-	defaultOptions *einosdk.GenerateTextRequest
-	mcpClient      *pkgmcp.Client
+	configService   ModelConfigService
+	clients         *ModelClients               // 按模型配置缓存的客户端
+	active          atomic.Pointer[activeModel] // 当前激活的模型配置及其客户端，RefreshModelConfig时整体替换
+	mcp             *mcpConnection              // 为空时不使用MCP工具
+	usageService    UsageService                // 为空时不记录用量
+	promptService   PromptService               // 为空时使用内置提示词模板
+	experiments     ExperimentService           // 为空时不进行A/B实验
+	planCache       *PlanCacheService           // 为空时不使用缓存
+	traces          *TraceService               // 为空时不记录生成过程
+	limiters        *ModelLimiters              // 为空时不限制模型配置的并发数和每分钟请求数
+	attemptTimeout  time.Duration               // 单个模型配置的最长生成时间，为0时不限制
+	maxRepairs      int                         // 输出未通过校验时要求模型修正的最多次数
+	pipelineMinDays int                         // 行程天数达到该值时分阶段并行生成，为0时不分阶段
+	toolPolicies    toolpolicy.Policies         // 各场景智能体可以使用的工具和调用限制
+	localTools      []tool.BaseTool             // 进程内运行的旅行工具，不需要网络，与MCP工具一起使用
 }
 
-// NewEinoService 创建新的Eino服务实例
-func NewEinoService(configService ModelConfigService) *EinoService {
+// NewEinoService 创建新的Eino服务实例，MCP客户端在后台初始化。exchangeRates为空时智能体不能换算货币
+func NewEinoService(configService ModelConfigService, usageService UsageService, promptService PromptService, experiments ExperimentService, planCache *PlanCacheService, traces *TraceService, exchangeRates traveltools.RateSource, llmConfig *iconfig.LLMConfig) *EinoService {
 	service := &EinoService{
-		configService: configService,
-		defaultOptions: &einosdk.GenerateTextRequest{
-			MaxTokens:   8000,
-			Temperature: 0.7,
-		},
+		configService:   configService,
+		clients:         NewModelClients(),
+		usageService:    usageService,
+		promptService:   promptService,
+		experiments:     experiments,
+		planCache:       planCache,
+		traces:          traces,
+		limiters:        NewModelLimiters(llmConfig.QueueTimeout),
+		attemptTimeout:  llmConfig.AttemptTimeout,
+		maxRepairs:      llmConfig.MaxRepairs,
+		pipelineMinDays: llmConfig.PipelineMinDays,
+		localTools:      traveltools.New(exchangeRates),
 	}
 
+	toolPolicies, err := toolpolicy.Load(llmConfig.ToolPolicyFile)
+	if err != nil {
+		logger.Errorf("加载工具策略失败: %v，使用默认策略", err)
+		toolPolicies = toolpolicy.DefaultPolicies()
+	}
+	service.toolPolicies = toolPolicies
+
 	// 初始化时尝试加载激活的模型配置
-	service.RefreshModelConfig(context.Background())
+	if err := service.RefreshModelConfig(context.Background()); err != nil {
+		logger.Warnf("加载激活的模型配置失败: %v", err)
+	}
 
 	// 初始化MCP客户端
-	go func() {
-		// 加载配置
-		cfg, err := iconfig.Load()
-		if err != nil {
-			fmt.Printf("加载配置失败: %v，使用默认值初始化MCP客户端\n", err)
-			client, err := pkgmcp.InitMCPClient(context.Background(), nil)
-			if err != nil {
-				fmt.Printf("初始化MCP客户端失败: %v\n", err)
-				return
-			}
-			service.mcpClient = client
-			return
-		}
-
-		// 使用配置初始化MCP客户端
-		opts := pkgmcp.NewInitOptionsFromConfig(cfg)
-		client, err := pkgmcp.InitMCPClient(context.Background(), opts)
-		if err != nil {
-			fmt.Printf("初始化MCP客户端失败: %v\n", err)
-			return
-		}
-		service.mcpClient = client
-	}()
+	cfg, err := iconfig.Load()
+	if err != nil {
+		logger.Warnf("加载配置失败: %v，使用默认值初始化MCP客户端", err)
+	}
+	mcpOptions, err := pkgmcp.NewInitOptionsFromConfig(cfg)
+	if err != nil {
+		logger.Errorf("加载MCP服务器配置失败: %v，只使用内置的MCP提供者", err)
+	}
+	service.mcp = startMCPConnection(mcpOptions)
 
 	return service
 }
 
-// NewEinoServiceWithConfig 根据指定的模型配置创建新的Eino服务实例
-func NewEinoServiceWithConfig(config *models.ModelConfig) *EinoService {
-	service := &EinoService{
-		activeConfig: config,
-		defaultOptions: &einosdk.GenerateTextRequest{
-			MaxTokens:   config.MaxTokens,
-			Temperature: config.Temperature,
-		},
+// NewEinoServiceWithConfig 根据指定的模型配置创建临时的Eino服务实例，用于测试该配置，不初始化MCP客户端
+func NewEinoServiceWithConfig(config *models.ModelConfig) (*EinoService, error) {
+	service := &EinoService{clients: NewModelClients()}
+	client, err := service.clients.Get(config)
+	if err != nil {
+		return nil, err
 	}
-
-	// 创建Eino客户端
-	service.client = einosdk.NewClient(
-		config.ToEinoModelType(),
-		config.GetEinoOptions()...,
-	)
-
-	// 初始化MCP客户端
-	go func() {
-		// 加载配置
-
-		cfg, err := iconfig.Load()
-		if err != nil {
-			fmt.Printf("加载配置失败: %v，使用默认值初始化MCP客户端\n", err)
-			client, err := pkgmcp.InitMCPClient(context.Background(), nil)
-			if err != nil {
-				fmt.Printf("初始化MCP客户端失败: %v\n", err)
-				return
-			}
-			service.mcpClient = client
-			return
-		}
-
-		// 使用配置初始化MCP客户端
-		opts := pkgmcp.NewInitOptionsFromConfig(cfg)
-		client, err := pkgmcp.InitMCPClient(context.Background(), opts)
-		if err != nil {
-			fmt.Printf("初始化MCP客户端失败: %v\n", err)
-			return
-		}
-		service.mcpClient = client
-	}()
-
-	return service
+	service.active.Store(&activeModel{config: *config, client: client})
+	return service, nil
 }
 
-// TestGenerateText 测试生成文本
+// TestGenerateText 使用激活的模型配置生成文本
 func (s *EinoService) TestGenerateText(ctx context.Context, prompt string) (string, error) {
+	active := s.active.Load()
+	if active == nil {
+		return "", errors.New("没有激活的模型配置")
+	}
+
 	// 调用Eino API
-	response, err := s.client.GenerateText(ctx, &einosdk.GenerateTextRequest{
+	response, err := active.client.GenerateText(ctx, &einosdk.GenerateTextRequest{
 		Prompt:      prompt,
-		MaxTokens:   s.defaultOptions.MaxTokens,
-		Temperature: s.defaultOptions.Temperature,
+		MaxTokens:   active.config.MaxTokens,
+		Temperature: active.config.Temperature,
 	})
 	if err != nil {
 		return "", err
@@ -123,511 +124,683 @@ func (s *EinoService) TestGenerateText(ctx context.Context, prompt string) (stri
 	return response.Text, nil
 }
 
-// RefreshModelConfig 刷新模型配置
+// RefreshModelConfig 在模型配置变化后重新加载：连接参数变化的配置在下次使用时重新创建客户端，
+// 并替换激活的模型配置快照。正在进行的生成继续使用原来的客户端
 func (s *EinoService) RefreshModelConfig(ctx context.Context) error {
+	configs, err := s.configService.GetAllModelConfigs(ctx)
+	if err != nil {
+		return err
+	}
+	s.clients.Reload(configs)
+
 	// 从数据库获取激活的模型配置
 	config, err := s.configService.GetActiveModelConfig(ctx)
 	if err != nil {
-		// 如果没有激活的配置，使用Mock模型
-		s.client = einosdk.NewClient(einosdk.ModelTypeArk)
 		return err
 	}
+	client, err := s.clients.Get(config)
+	if err != nil {
+		return err
+	}
+	s.active.Store(&activeModel{config: *config, client: client})
 
-	// 更新激活的配置
-	s.activeConfig = config
-
-	// 更新默认选项
-	s.defaultOptions.MaxTokens = config.MaxTokens
-	s.defaultOptions.Temperature = config.Temperature
+	return nil
+}
 
-	// 创建Eino客户端
-	s.client = einosdk.NewClient(
-		config.ToEinoModelType(),
-		config.GetEinoOptions()...,
-	)
+// MCPReady MCP客户端是否已初始化成功
+func (s *EinoService) MCPReady() bool {
+	return s.mcp.Ready()
+}
 
-	return nil
+// MCPClient 等待MCP客户端初始化完成并返回，ctx结束前未完成时返回ErrMCPNotReady
+func (s *EinoService) MCPClient(ctx context.Context) (*pkgmcp.Client, error) {
+	return s.mcp.Wait(ctx)
 }
 
 // GenerateTripPlan 根据用户请求生成旅行计划
 func (s *EinoService) GenerateTripPlan(ctx context.Context, req *models.PlanRequest) (*models.TripPlan, error) {
-	// 刷新模型配置，确保使用最新的配置
-	err := s.RefreshModelConfig(ctx)
+	return s.generateTripPlan(ctx, req, nil)
+}
+
+// GenerateTripPlanStream 以流式方式生成旅行计划，模型增量输出和工具调用事件通过handler回调
+func (s *EinoService) GenerateTripPlanStream(ctx context.Context, req *models.PlanRequest, handler einosdk.StreamHandler) (*models.TripPlan, error) {
+	return s.generateTripPlan(ctx, req, handler)
+}
+
+// generateTripPlan 生成旅行计划，handler不为空时使用流式调用。相似的请求命中缓存时直接返回缓存的计划，
+// 分配到A/B实验分组的用户不使用缓存，由分组指定的模型配置和提示词版本生成。
+// 行程天数达到pipelineMinDays时分阶段并行生成，实验分组指定了trip_plan提示词版本时总是一次生成完整计划。
+// 调用模型的过程保存为生成过程记录，与生成的计划互相关联
+func (s *EinoService) generateTripPlan(ctx context.Context, req *models.PlanRequest, handler einosdk.StreamHandler) (*models.TripPlan, error) {
+	assignment := s.assignExperiment(ctx)
+	if assignment == nil {
+		if plan := s.planCache.Lookup(ctx, req); plan != nil {
+			return plan, nil
+		}
+	}
+
+	var preferredConfigID uint
+	promptVersion := 0
+	if assignment != nil {
+		preferredConfigID = assignment.Variant.ModelConfigID
+		promptVersion = assignment.Variant.PromptVersion
+	}
+
+	chain, err := s.modelChain(ctx, preferredConfigID)
 	if err != nil {
 		return nil, err
 	}
 
-	// 构建提示词
-	prompt := buildTripPlanPrompt(req)
-	rTools, err := s.mcpClient.GetToolsByProviderNameList(ctx, []string{pkgmcp.ProviderAMap})
-	// 调用Eino API
-	response, err := s.client.GenerateText(ctx, &einosdk.GenerateTextRequest{
-		Prompt:      prompt,
-		MaxTokens:   8000,
-		Temperature: s.defaultOptions.Temperature,
-		Tools:       rTools,
-	})
+	ctx, recorder := s.traces.Start(ctx, req)
+	start := time.Now()
+	plan, generation, err := s.generatePlan(ctx, req, promptVersion, chain, handler)
+	tag := s.recordExposure(ctx, assignment, generation, time.Since(start))
 	if err != nil {
+		s.traces.Finish(ctx, recorder, nil, err)
 		return nil, fmt.Errorf("failed to generate trip plan: %w", err)
 	}
-	plan, err := parseTripPlanResponse(response.Text)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse trip plan: %w", err)
-	}
 
 	// 填充请求中的基本信息
 	plan.Destination = req.Destination
 	plan.StartDate = req.StartDate.String()
 	plan.EndDate = req.EndDate.String()
+	plan.Request = copyPlanRequest(req)
+	plan.Generation = generation
+	plan.Generation.Experiment = tag
+	s.traces.Finish(ctx, recorder, plan, nil)
+
+	if assignment == nil {
+		s.planCache.Store(ctx, req, plan)
+	}
 
 	return plan, nil
 }
 
-// 构建旅行计划的提示词
-func buildTripPlanPrompt(req *models.PlanRequest) string {
-	return fmt.Sprintf(`
-你是一个专业的旅游规划助手。请为以下旅行需求创建一个详细的旅行计划:
-
-目的地: %s
-开始日期: %s
-结束日期: %s
-预算: %s
-旅行风格: %v
-住宿偏好: %v
-交通偏好: %v
-活动偏好: %v
-饮食偏好: %v
-特殊要求: %s
-
-请提供一个包含以下内容的详细旅行计划:
-1. 每天的行程安排，包括景点、活动、餐饮和住宿
-2. 每个活动的大致时间安排
-3. 每个活动和住宿的估计费用
-4. 交通建议
-5. 当地特色美食推荐
-6. 天气信息和穿衣建议
-7. 当地文化和习俗提示
-8. 安全和健康建议
-9. 必备物品清单
-10. 紧急联系信息
-
-请以JSON格式返回，格式如下:
-{
-  "title": "旅行计划标题",
-  "destination_info": {
-    "name": "目的地名称",
-    "country": "国家",
-    "language": "当地语言",
-    "currency": "当地货币",
-    "time_zone": "时区",
-    "best_time_to_visit": "最佳旅游时间"
-  },
-  "travel_info": {
-    "visa_required": true/false,
-    "visa_tips": "签证信息",
-    "passport_validity": "护照有效期要求",
-    "vaccination_required": ["疫苗1", "疫苗2"],
-    "local_customs": "当地习俗简介",
-    "etiquette_tips": "礼仪提示",
-    "safety_tips": "安全提示",
-    "health_tips": "健康建议",
-    "electrical_socket_type": "电源插座类型",
-    "internet_availability": "网络可用性说明",
-    "language_phrases": [
-      {"phrase": "你好", "pronunciation": "Ni Hao", "meaning": "Hello"}
-    ]
-  },
-  "weather_forecast": {
-    "climate_overview": "季节性气候概况",
-    "daily_forecast": [
-      {
-        "date": "YYYY-MM-DD",
-        "temperature": {
-          "min": 最低温度,
-          "max": 最高温度,
-          "unit": "摄氏/华氏"
-        },
-        "conditions": "天气状况",
-        "precipitation_chance": 降水几率,
-        "clothing_suggestions": ["穿衣建议1", "穿衣建议2"]
-      }
-    ]
-  },
-  "packing_list": {
-    "essentials": ["必备物品1", "必备物品2"],
-    "clothing": ["衣物1", "衣物2"],
-    "toiletries": ["洗漱用品1", "洗漱用品2"],
-    "electronics": ["电子设备1", "电子设备2"],
-    "documents": ["文档1", "文档2"],
-    "other": ["其他物品1", "其他物品2"]
-  },
-  "emergency_contacts": {
-    "local_emergency": "当地紧急电话",
-    "police": "警察电话",
-    "ambulance": "救护车电话",
-    "fire": "消防电话",
-    "embassy": "使馆信息",
-    "hospitals": [
-      {
-        "name": "医院名称",
-        "address": "地址",
-        "phone": "电话",
-        "has_english_speaking_staff": true/false
-      }
-    ]
-  },
-  "days": [
-    {
-      "day": 1,
-      "date": "YYYY-MM-DD",
-      "weather": {
-        "temperature": {
-          "morning": 早晨温度,
-          "day": 白天温度,
-          "evening": 傍晚温度,
-          "unit": "摄氏/华氏"
-        },
-        "conditions": "天气状况",
-        "clothing_suggestion": "穿衣建议"
-      },
-      "activities": [
-        {
-          "name": "活动名称",
-          "type": "活动类型",
-          "location": {
-            "name": "地点名称",
-            "address": "地址",
-            "city": "城市",
-            "country": "国家",
-            "coordinates": {
-              "latitude": 纬度,
-              "longitude": 经度
-            }
-          },
-          "start_time": "HH:MM",
-          "end_time": "HH:MM",
-          "description": "活动描述",
-          "cost": 费用数值,
-          "booking_required": true/false,
-          "booking_tips": "预订提示",
-          "crowd_level": "人群水平预期",
-          "suitable_weather": "适合的天气条件",
-          "indoor_outdoor": "室内/室外",
-          "accessibility": "无障碍设施情况",
-          "rating": 评分,
-          "photos": ["照片URL1", "照片URL2"],
-          "tips": ["小贴士1", "小贴士2"]
-        }
-      ],
-      "meals": [
-        {
-          "type": "餐食类型",
-          "venue": "餐厅名称",
-          "cuisine": "菜系",
-          "description": "描述",
-          "specialties": ["特色菜1", "特色菜2"],
-          "dietary_options": ["素食", "无麸质"],
-          "address": "地址",
-          "booking_required": true/false,
-          "cost": 费用数值,
-          "tips": "用餐提示"
-        }
-      ],
-      "accommodation": {
-        "name": "住宿名称",
-        "type": "住宿类型",
-        "address": "地址",
-        "description": "描述",
-        "amenities": ["设施1", "设施2"],
-        "check_in": "入住时间",
-        "check_out": "退房时间",
-        "cost": 费用数值,
-        "booking_reference": "预订参考信息",
-        "contact": "联系方式",
-        "nearest_landmarks": ["地标1", "地标2"],
-        "transportation_options": ["交通选项1", "交通选项2"]
-      },
-      "transportation": [
-        {
-          "type": "交通类型",
-          "from": "出发地",
-          "to": "目的地",
-          "departure_time": "出发时间",
-          "arrival_time": "到达时间",
-          "cost": 费用数值,
-          "booking_reference": "预订参考信息",
-          "notes": "交通备注"
-        }
-      ],
-      "tips": ["当天提示1", "当天提示2"]
-    }
-  ],
-  "budget": {
-    "currency": "货币",
-    "exchange_rate": "汇率",
-    "total_estimate": 总预算,
-    "accommodation": 住宿预算,
-    "transportation": 交通预算,
-    "food": 餐饮预算,
-    "activities": 活动预算,
-    "shopping": 购物预算,
-    "other": 其他预算,
-    "daily_breakdown": [
-      {
-        "day": 1,
-        "date": "YYYY-MM-DD",
-        "total": 当天总花费,
-        "details": {
-          "accommodation": 住宿费用,
-          "transportation": 交通费用,
-          "food": 餐饮费用,
-          "activities": 活动费用,
-          "other": 其他费用
-        }
-      }
-    ],
-    "payment_tips": {
-      "credit_cards_accepted": true/false,
-      "atm_availability": "ATM可用性",
-      "tipping_culture": "小费文化",
-      "recommended_payment_methods": ["建议支付方式1", "建议支付方式2"]
-    }
-  },
-  "local_attractions": [
-    {
-      "name": "景点名称",
-      "category": "景点类别",
-      "description": "描述",
-      "must_see": true/false,
-      "address": "地址",
-      "opening_hours": "开放时间",
-      "cost": 费用数值,
-      "time_required": "建议游览时间",
-      "best_time_to_visit": "最佳游览时间",
-      "tips": ["小贴士1", "小贴士2"]
-    }
-  ],
-  "local_cuisine": [
-    {
-      "name": "美食名称",
-      "description": "描述",
-      "must_try": true/false,
-      "where_to_find": ["地点1", "地点2"],
-      "price_range": "价格范围",
-      "photos": ["照片URL1", "照片URL2"]
-    }
-  ],
-  "shopping": {
-    "recommended_items": ["推荐购买物品1", "推荐购买物品2"],
-    "markets_and_malls": [
-      {
-        "name": "商场/市场名称",
-        "type": "类型",
-        "address": "地址",
-        "specialty": "特色",
-        "opening_hours": "营业时间"
-      }
-    ],
-    "souvenirs": ["纪念品1", "纪念品2"]
-  },
-  "cultural_events": [
-    {
-      "name": "文化活动名称",
-      "date": "日期",
-      "description": "描述",
-      "location": "地点",
-      "cost": 费用数值,
-      "tips": "参与提示"
-    }
-  ],
-  "practical_information": {
-    "local_transportation": {
-      "options": ["选项1", "选项2"],
-      "recommended": "推荐方式",
-      "cost": "费用信息",
-      "passes": "交通通行证信息",
-      "apps": ["推荐应用1", "推荐应用2"]
-    },
-    "communication": {
-      "local_sim": "当地SIM卡信息",
-      "wifi_availability": "WiFi可用性",
-      "useful_apps": ["有用的应用1", "有用的应用2"]
-    }
-  },
-  "notes": "额外注意事项",
-  "suggested_modifications": "根据天气或其他因素可能需要的计划调整建议"
-}
-`,
-		req.Destination,
-		req.StartDate,
-		req.EndDate,
-		req.Budget,
-		req.TravelStyle,
-		req.Accommodation,
-		req.Transportation,
-		req.Activities,
-		req.FoodPreferences,
-		req.SpecialRequests,
-	)
-}
-
-// 解析大模型返回的旅行计划
-func parseTripPlanResponse(response string) (*models.TripPlan, error) {
-	var plan models.TripPlan
+// GenerateTripPlanWithConfig 只使用指定的模型配置生成旅行计划，不使用缓存、A/B实验和故障切换，用于离线评测。
+// promptVersion为0时使用当前启用的提示词，并与正常流程一样按行程天数决定是否分阶段生成
+func (s *EinoService) GenerateTripPlanWithConfig(ctx context.Context, req *models.PlanRequest, config *models.ModelConfig, promptVersion int) (*models.TripPlan, error) {
+	plan, generation, err := s.generatePlan(ctx, req, promptVersion, []models.ModelConfig{*config}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate trip plan: %w", err)
+	}
+
+	plan.Destination = req.Destination
+	plan.StartDate = req.StartDate.String()
+	plan.EndDate = req.EndDate.String()
+	plan.Request = copyPlanRequest(req)
+	plan.Generation = generation
+	return plan, nil
+}
+
+// generatePlan 按故障切换链生成旅行计划，行程天数达到pipelineMinDays时分阶段并行生成，
+// 指定了trip_plan提示词版本时总是一次生成完整计划
+func (s *EinoService) generatePlan(ctx context.Context, req *models.PlanRequest, promptVersion int, chain []models.ModelConfig, handler einosdk.StreamHandler) (*models.TripPlan, *models.GenerationInfo, error) {
+	if promptVersion == 0 && s.pipelineMinDays > 0 && TripDays(req.StartDate, req.EndDate) >= s.pipelineMinDays {
+		return s.generateStaged(ctx, req, chain, handler)
+	}
+	return s.generateComplete(ctx, req, promptVersion, chain, handler)
+}
 
-	// 尝试直接解析JSON
-	err := json.Unmarshal([]byte(response), &plan)
+// generateComplete 使用trip_plan提示词一次生成完整的旅行计划，promptVersion为0时使用当前启用的版本
+func (s *EinoService) generateComplete(ctx context.Context, req *models.PlanRequest, promptVersion int, chain []models.ModelConfig, handler einosdk.StreamHandler) (*models.TripPlan, *models.GenerationInfo, error) {
+	prompt, promptVersion, err := s.renderPromptVersion(ctx, models.PromptTripPlan, promptVersion, NewTripPlanPromptData(req))
 	if err != nil {
-		// 如果直接解析失败，尝试提取JSON部分
-		jsonStart := strings.Index(response, "{")
-		if jsonStart >= 0 {
-			// 寻找匹配的大括号结束位置
-			braceCount := 0
-			jsonEnd := -1
-
-			for i := jsonStart; i < len(response); i++ {
-				if response[i] == '{' {
-					braceCount++
-				} else if response[i] == '}' {
-					braceCount--
-					if braceCount == 0 {
-						jsonEnd = i + 1
-						break
-					}
-				}
-			}
+		return nil, nil, err
+	}
+	systemPrompt, _, err := s.renderPrompt(ctx, models.PromptAgentSystem, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	responseSchema, err := tripPlanResponseSchema()
+	if err != nil {
+		return nil, nil, err
+	}
+	textReq := &einosdk.GenerateTextRequest{
+		Prompt:         prompt,
+		SystemPrompt:   systemPrompt,
+		MaxTokens:      8000,
+		Tools:          s.agentTools(ctx, toolpolicy.UseCasePlan),
+		ResponseSchema: responseSchema,
+	}
+
+	// 依次尝试故障切换链中的模型，直到输出通过Schema校验
+	var plan *models.TripPlan
+	generation, err := s.generateWithFailover(ctx, chain, textReq, handler, func(text string) error {
+		var err error
+		plan, err = parseTripPlanResponse(text)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	generation.PromptVersion = promptVersion
+	return plan, generation, nil
+}
+
+// assignExperiment 为当前用户分配运行中实验的分组，没有实验或查询失败时返回nil，按正常流程生成
+func (s *EinoService) assignExperiment(ctx context.Context) *models.ExperimentAssignment {
+	if s.experiments == nil {
+		return nil
+	}
+
+	assignment, err := s.experiments.Assign(ctx, CallInfoFromContext(ctx).UserID)
+	if err != nil {
+		logger.Errorf("分配实验分组失败，使用默认配置生成: %v", err)
+		return nil
+	}
+	return assignment
+}
+
+// recordExposure 保存实验分组的生成记录，返回写入计划的分组标记；未分配分组时返回nil
+func (s *EinoService) recordExposure(ctx context.Context, assignment *models.ExperimentAssignment, generation *models.GenerationInfo, latency time.Duration) *models.ExperimentTag {
+	if assignment == nil {
+		return nil
+	}
+
+	exposure := &models.ExperimentExposure{
+		ExperimentID: assignment.Experiment.ID,
+		VariantID:    assignment.Variant.ID,
+		UserID:       CallInfoFromContext(ctx).UserID,
+		Success:      generation != nil,
+		LatencyMs:    latency.Milliseconds(),
+	}
+	if generation != nil {
+		exposure.FellBack = generation.ModelConfigID != assignment.Variant.ModelConfigID
+		exposure.TotalTokens = generation.TotalTokens
+		exposure.Cost = generation.Cost
+	}
+
+	// 请求可能已被取消，生成记录仍然需要保存
+	if err := s.experiments.RecordExposure(context.WithoutCancel(ctx), exposure); err != nil {
+		logger.Errorf("保存实验生成记录失败: %v", err)
+	}
+
+	return &models.ExperimentTag{
+		ExperimentID: assignment.Experiment.ID,
+		VariantID:    assignment.Variant.ID,
+		VariantName:  assignment.Variant.Name,
+		ExposureID:   exposure.ID,
+	}
+}
+
+// agentTools 获取智能体在该场景中可以使用的工具：按场景的策略筛选进程内的旅行工具和MCP工具，
+// 不包括被标记为degraded的提供者的工具。调用次数和截止时间在generateWithConfig每次调用模型时重新计算。
+// MCP客户端仍在初始化时最多等待mcpReadyTimeout，未就绪或获取失败时只使用进程内的工具
+func (s *EinoService) agentTools(ctx context.Context, useCase string) []tool.BaseTool {
+	policy := s.toolPolicies.Get(useCase)
+	if policy.DeniesAll() {
+		return nil
+	}
+
+	// 进程内的工具在前，与MCP工具重名时优先使用
+	tools := append([]tool.BaseTool{}, s.localTools...)
+	waitCtx, cancel := context.WithTimeout(ctx, mcpReadyTimeout)
+	defer cancel()
+	client, err := s.mcp.Wait(waitCtx)
+	if err != nil {
+		logger.Warnf("不使用MCP工具生成: %v", err)
+	} else {
+		mcpTools, err := client.GetToolsByProviderNameList(ctx, client.HealthyProviderNames())
+		if err != nil {
+			logger.Errorf("获取MCP工具失败: %v", err)
+		}
+		tools = append(tools, mcpTools...)
+	}
+	tools, err = policy.Filter(ctx, uniqueTools(ctx, tools))
+	if err != nil {
+		logger.Errorf("应用%s场景的工具策略失败，不使用工具生成: %v", useCase, err)
+		return nil
+	}
+	return tools
+}
+
+// uniqueTools 去掉同名的工具，智能体按名称调用工具，同名工具只保留先出现的一个
+func uniqueTools(ctx context.Context, tools []tool.BaseTool) []tool.BaseTool {
+	seen := make(map[string]bool, len(tools))
+	unique := make([]tool.BaseTool, 0, len(tools))
+	for _, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			logger.Errorf("获取工具信息失败: %v", err)
+			continue
+		}
+		if seen[info.Name] {
+			logger.Warnf("忽略重名的工具: %s", info.Name)
+			continue
+		}
+		seen[info.Name] = true
+		unique = append(unique, t)
+	}
+	return unique
+}
 
-			if jsonEnd > jsonStart {
-				jsonStr := response[jsonStart:jsonEnd]
-				// 清理JSON字符串，替换中文标点等
-				jsonStr = cleanJSONString(jsonStr)
-
-				// 处理JSON中destination字段映射到DestinationInfo
-				var rawJSON map[string]interface{}
-				if err := json.Unmarshal([]byte(jsonStr), &rawJSON); err == nil {
-					if dest, ok := rawJSON["destination"].(map[string]interface{}); ok {
-						// 将destination对象内容复制到destination_info
-						rawJSON["destination_info"] = dest
-
-						// 保存原始destination字符串值
-						if name, ok := dest["name"].(string); ok {
-							rawJSON["destination"] = name
-						}
-
-						// 重新序列化修正后的JSON
-						if newJSON, err := json.Marshal(rawJSON); err == nil {
-							jsonStr = string(newJSON)
-						}
-					}
-				}
-
-				err = json.Unmarshal([]byte(jsonStr), &plan)
-				if err == nil {
-					return &plan, nil
-				}
+// renderPrompt 渲染提示词模板，返回渲染结果和模板版本，未配置提示词服务时使用内置模板
+func (s *EinoService) renderPrompt(ctx context.Context, name string, data interface{}) (string, int, error) {
+	if s.promptService == nil {
+		text, err := renderBuiltinPrompt(name, data)
+		return text, 0, err
+	}
+	return s.promptService.Render(ctx, name, data)
+}
+
+// renderPromptVersion 使用指定版本渲染提示词模板，version为0时使用当前启用的版本
+func (s *EinoService) renderPromptVersion(ctx context.Context, name string, version int, data interface{}) (string, int, error) {
+	if version == 0 || s.promptService == nil {
+		return s.renderPrompt(ctx, name, data)
+	}
+	return s.promptService.RenderVersion(ctx, name, version, data)
+}
+
+// modelChain 获取故障切换链，preferredID不为0时把该配置放在最前面，其余配置作为备用
+func (s *EinoService) modelChain(ctx context.Context, preferredID uint) ([]models.ModelConfig, error) {
+	chain, err := s.configService.GetFallbackChain(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取模型配置失败: %w", err)
+	}
+	if preferredID == 0 {
+		return chain, nil
+	}
+
+	preferred, err := s.configService.GetModelConfigByID(ctx, preferredID)
+	if err != nil {
+		return nil, fmt.Errorf("获取模型配置失败: %w", err)
+	}
+	if preferred.IsDown() {
+		logger.Warnf("模型配置 %s(ID: %d) 已被探测为不可用，使用故障切换链", preferred.Name, preferred.ID)
+		return chain, nil
+	}
+	result := []models.ModelConfig{*preferred}
+	for _, config := range chain {
+		if config.ID != preferredID {
+			result = append(result, config)
+		}
+	}
+	return result, nil
+}
+
+// generateWithFailover 按故障切换链依次调用模型，accept用于校验并解析输出。
+// 超时、5xx等暂时性错误、排队超时或输出无法解析时切换到下一个配置，其他错误直接返回。
+// 所有配置都排队超时时返回重试时间最短的*ModelBusyError
+func (s *EinoService) generateWithFailover(ctx context.Context, chain []models.ModelConfig, textReq *einosdk.GenerateTextRequest, handler einosdk.StreamHandler, accept func(text string) error) (*models.GenerationInfo, error) {
+	var lastErr error
+	var busyErr *ModelBusyError
+	allBusy := true
+	for i := range chain {
+		config := &chain[i]
+		if i > 0 && handler != nil {
+			handler(&einosdk.StreamEvent{
+				Type:    einosdk.StreamEventFailover,
+				Content: fmt.Sprintf("切换到备用模型配置: %s", config.Name),
+			})
+		}
+
+		usage, err := s.generateWithConfig(ctx, config, textReq, handler, accept)
+		if err == nil {
+			return &models.GenerationInfo{
+				ModelConfigID:    config.ID,
+				ModelConfigName:  config.Name,
+				ModelType:        config.ModelType,
+				ModelName:        config.ModelName,
+				Attempts:         i + 1,
+				PromptTokens:     usage.PromptTokens,
+				CompletionTokens: usage.CompletionTokens,
+				TotalTokens:      usage.TotalTokens,
+				Cost:             config.CalculateCost(usage.PromptTokens, usage.CompletionTokens),
+			}, nil
+		}
+
+		// 调用方取消或超过总时长时不再尝试
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		var busy *ModelBusyError
+		if errors.As(err, &busy) {
+			if busyErr == nil || busy.RetryAfter < busyErr.RetryAfter {
+				busyErr = busy
+			}
+		} else {
+			allBusy = false
+			if !errors.Is(err, errUnparseableOutput) && !einosdk.IsTransient(err) {
+				return nil, err
 			}
 		}
+		logger.Errorf("模型配置 %s(ID: %d) 生成失败，尝试下一个配置: %v", config.Name, config.ID, err)
+		lastErr = err
+	}
 
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	if allBusy && busyErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAllModelsFailed, busyErr)
+	}
+	return nil, fmt.Errorf("%w: %v", ErrAllModelsFailed, lastErr)
+}
+
+// generateWithConfig 使用指定的模型配置生成一次并记录用量，超过attemptTimeout视为超时，排队时间不计入。
+// 输出未通过accept校验时最多修正maxRepairs次，修正的用量计入本次调用
+func (s *EinoService) generateWithConfig(ctx context.Context, config *models.ModelConfig, textReq *einosdk.GenerateTextRequest, handler einosdk.StreamHandler, accept func(text string) error) (einosdk.TokenUsage, error) {
+	release, err := s.limiters.Acquire(ctx, config)
+	if err != nil {
+		return einosdk.TokenUsage{}, err
+	}
+	defer release()
+
+	attemptCtx := ctx
+	if s.attemptTimeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, s.attemptTimeout)
+		defer cancel()
+	}
+
+	client, err := s.clients.Get(config)
+	if err != nil {
+		return einosdk.TokenUsage{}, err
+	}
+	req := *textReq
+	req.Temperature = config.Temperature
+	req.Tools = toolpolicy.StartRun(textReq.Tools)
+
+	start := time.Now()
+	callCtx, call := startTraceCall(attemptCtx, config, &req, 0)
+	var response *einosdk.GenerateTextResponse
+	if handler != nil {
+		response, err = client.StreamText(callCtx, &req, handler)
+	} else {
+		response, err = client.GenerateText(callCtx, &req)
+	}
+
+	var usage einosdk.TokenUsage
+	if err != nil {
+		call.end(nil, err)
+	} else {
+		usage = response.Usage
+		acceptErr := accept(response.Text)
+		call.end(response, acceptErr)
+
+		var repairUsage einosdk.TokenUsage
+		repairUsage, err = s.repairOutput(attemptCtx, client, config, &req, response.Text, acceptErr, handler, accept)
+		usage.Merge(repairUsage)
+	}
+	s.recordUsage(ctx, config, usage, time.Since(start), err == nil)
+
+	return usage, err
+}
+
+// repairOutput 输出未通过校验时把输出和校验错误err发回给模型修正，直到通过或用完修正次数
+func (s *EinoService) repairOutput(ctx context.Context, client *einosdk.Client, config *models.ModelConfig, req *einosdk.GenerateTextRequest, text string, err error, handler einosdk.StreamHandler, accept func(text string) error) (einosdk.TokenUsage, error) {
+	var usage einosdk.TokenUsage
+	for repair := 1; err != nil && repair <= s.maxRepairs; repair++ {
+		logger.Infof("模型输出未通过校验，第%d次修正: %v", repair, err)
+		if handler != nil {
+			handler(&einosdk.StreamEvent{
+				Type:    einosdk.StreamEventRepair,
+				Content: fmt.Sprintf("输出未通过校验，第%d次修正", repair),
+				Error:   err.Error(),
+			})
+		}
+
+		repairReq := buildRepairRequest(req, text, err)
+		callCtx, call := startTraceCall(ctx, config, repairReq, repair)
+		response, genErr := client.GenerateText(callCtx, repairReq)
+		if genErr != nil {
+			call.end(nil, genErr)
+			return usage, genErr
+		}
+		usage.Merge(response.Usage)
+		text = response.Text
+		err = accept(text)
+		call.end(response, err)
+	}
+	if err != nil {
+		return usage, fmt.Errorf("%w: %v", errUnparseableOutput, err)
+	}
+	return usage, nil
+}
+
+// buildRepairRequest 构建修正请求，不绑定工具，只要求模型按校验错误修正上一次的输出
+func buildRepairRequest(req *einosdk.GenerateTextRequest, output string, err error) *einosdk.GenerateTextRequest {
+	problems := []string{err.Error()}
+	var validationErr *PlanValidationError
+	if errors.As(err, &validationErr) {
+		problems = validationErr.Problems
+	}
+
+	prompt := fmt.Sprintf(`你上一次的输出没有通过校验，错误如下:
+- %s
+
+请修正以上错误，其余内容保持不变，只返回修正后的完整JSON，不要包含任何其他文字。
+
+上一次的输出:
+%s
+`, strings.Join(problems, "\n- "), output)
+
+	return &einosdk.GenerateTextRequest{
+		Prompt:         prompt,
+		SystemPrompt:   req.SystemPrompt,
+		MaxTokens:      req.MaxTokens,
+		Temperature:    req.Temperature,
+		JSONMode:       req.JSONMode,
+		ResponseSchema: req.ResponseSchema,
+	}
+}
+
+// recordUsage 保存一次模型调用的用量记录，失败只记录日志
+func (s *EinoService) recordUsage(ctx context.Context, config *models.ModelConfig, usage einosdk.TokenUsage, latency time.Duration, success bool) {
+	if s.usageService == nil {
+		return
+	}
+
+	// 请求可能已被取消，用量仍然需要保存
+	if err := s.usageService.RecordUsage(context.WithoutCancel(ctx), newUsageRecord(ctx, config, usage, latency, success)); err != nil {
+		logger.Errorf("保存模型用量记录失败: %v", err)
+	}
+}
+
+// parseTripPlanResponse 从模型输出中提取旅行计划JSON，通过Schema校验后解析
+func parseTripPlanResponse(response string) (*models.TripPlan, error) {
+	jsonStr, err := extractJSONObject(response)
+	if err != nil {
+		return nil, err
 	}
 
-	// 检查是否需要处理destination字段
 	var rawJSON map[string]interface{}
-	if json.Unmarshal([]byte(response), &rawJSON) == nil {
-		if dest, ok := rawJSON["destination"].(map[string]interface{}); ok {
-			// 如果destination是对象而不是字符串，则需要转换
-			if name, ok := dest["name"].(string); ok {
-				plan.Destination = name
-			}
+	if err := json.Unmarshal([]byte(jsonStr), &rawJSON); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
 
-			// 将destination对象映射到DestinationInfo
-			destJSON, _ := json.Marshal(dest)
-			json.Unmarshal(destJSON, &plan.DestinationInfo)
+	// 部分模型把目的地信息输出为destination对象，映射到destination_info
+	if dest, ok := rawJSON["destination"].(map[string]interface{}); ok {
+		if _, exists := rawJSON["destination_info"]; !exists {
+			rawJSON["destination_info"] = dest
 		}
+		delete(rawJSON, "destination")
 	}
 
+	data, err := json.Marshal(rawJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	if err := validateTripPlanJSON(data); err != nil {
+		return nil, err
+	}
+
+	var plan models.TripPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
 	return &plan, nil
 }
 
-// cleanJSONString 尝试修复常见的JSON格式问题
+// extractJSONObject 取出模型输出中的第一个完整JSON对象，无法解析时先修复常见的格式问题再重试
+func extractJSONObject(response string) (string, error) {
+	response = strings.TrimSpace(response)
+	if json.Valid([]byte(response)) {
+		return response, nil
+	}
+
+	jsonStart := strings.Index(response, "{")
+	if jsonStart < 0 {
+		return "", fmt.Errorf("failed to parse JSON response: 输出中没有JSON对象")
+	}
+
+	candidate := matchJSONObject(response[jsonStart:])
+	if json.Valid([]byte(candidate)) {
+		return candidate, nil
+	}
+
+	candidate = matchJSONObject(cleanJSONString(response[jsonStart:]))
+	var probe interface{}
+	if err := json.Unmarshal([]byte(candidate), &probe); err != nil {
+		return "", fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	return candidate, nil
+}
+
+// matchJSONObject 返回以"{"开头的文本中与之匹配的完整对象，字符串中的大括号不参与匹配；
+// 没有找到匹配的结束位置时返回原文本
+func matchJSONObject(text string) string {
+	depth := 0
+	inString := false
+	escaped := false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return text[:i+1]
+			}
+		}
+	}
+	return text
+}
+
+// structuralPunctuation 模型在JSON结构位置上误用的全角标点
+var structuralPunctuation = map[rune]rune{
+	'\uff0c': ',', // 中文逗号
+	'\u3001': ',', // 中文顿号
+	'\uff1a': ':', // 中文冒号
+	'\uff5b': '{', // 全角左大括号
+	'\uff5d': '}', // 全角右大括号
+	'\uff3b': '[', // 全角左方括号
+	'\uff3d': ']', // 全角右方括号
+	'\u3010': '[', // 中文左方括号
+	'\u3011': ']', // 中文右方括号
+	'\uff0d': '-', // 全角减号
+}
+
+// stringQuotes 可以作为字符串定界符的全角引号及其对应的结束引号
+var stringQuotes = map[rune]rune{
+	'\u201c': '\u201d', // 中文双引号
+	'\u201d': '\u201d',
+	'\u2018': '\u2019', // 中文单引号
+	'\u300c': '\u300d', // 直角引号
+}
+
+// cleanJSONString 修复模型输出中常见的JSON格式问题：字符串外的全角标点和全角引号定界符，
+// 以及字符串内未转义的控制字符。字符串内容中的中文标点保持原样
 func cleanJSONString(jsonStr string) string {
-	// 替换非标准引号 (使用Unicode码点)
-	jsonStr = strings.ReplaceAll(jsonStr, "\u201c", "\"") // 左双引号
-	jsonStr = strings.ReplaceAll(jsonStr, "\u201d", "\"") // 右双引号
-	jsonStr = strings.ReplaceAll(jsonStr, "\u2018", "\"") // 左单引号
-	jsonStr = strings.ReplaceAll(jsonStr, "\u2019", "\"") // 右单引号
-
-	// 替换中文标点符号
-	jsonStr = strings.ReplaceAll(jsonStr, "\uff0c", ",")  // 中文逗号
-	jsonStr = strings.ReplaceAll(jsonStr, "\uff1a", ":")  // 中文冒号
-	jsonStr = strings.ReplaceAll(jsonStr, "\uff1b", ";")  // 中文分号
-	jsonStr = strings.ReplaceAll(jsonStr, "\u3001", ",")  // 中文顿号
-	jsonStr = strings.ReplaceAll(jsonStr, "\uff01", "!")  // 中文感叹号
-	jsonStr = strings.ReplaceAll(jsonStr, "\uff1f", "?")  // 中文问号
-	jsonStr = strings.ReplaceAll(jsonStr, "\u3002", ".")  // 中文句号
-	jsonStr = strings.ReplaceAll(jsonStr, "\u300c", "\"") // 中文左引号
-	jsonStr = strings.ReplaceAll(jsonStr, "\u300d", "\"") // 中文右引号
-	jsonStr = strings.ReplaceAll(jsonStr, "\uff08", "(")  // 中文左括号
-	jsonStr = strings.ReplaceAll(jsonStr, "\uff09", ")")  // 中文右括号
-	jsonStr = strings.ReplaceAll(jsonStr, "\u3010", "[")  // 中文左方括号
-	jsonStr = strings.ReplaceAll(jsonStr, "\u3011", "]")  // 中文右方括号
-	jsonStr = strings.ReplaceAll(jsonStr, "\u3008", "<")  // 中文左尖括号
-	jsonStr = strings.ReplaceAll(jsonStr, "\u3009", ">")  // 中文右尖括号
-	jsonStr = strings.ReplaceAll(jsonStr, "\uff0b", "+")  // 中文加号
-	jsonStr = strings.ReplaceAll(jsonStr, "\uff0d", "-")  // 中文减号
-	jsonStr = strings.ReplaceAll(jsonStr, "\uff0f", "/")  // 中文斜杠
-	jsonStr = strings.ReplaceAll(jsonStr, "\uff3c", "\\") // 中文反斜杠
-
-	// 移除特殊控制字符
-	var cleanBytes []byte
-	for _, c := range []byte(jsonStr) {
-		if c >= 32 && c <= 126 || c >= 128 {
-			cleanBytes = append(cleanBytes, c)
+	var b strings.Builder
+	b.Grow(len(jsonStr))
+
+	inString := false
+	escaped := false
+	closeQuote := '"' // 当前字符串的结束引号
+	for _, r := range jsonStr {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+				b.WriteRune(r)
+			case r == '\\':
+				escaped = true
+				b.WriteRune(r)
+			case r == closeQuote || r == '"':
+				// 以全角引号开始的字符串也接受ASCII引号结束，模型经常混用
+				inString = false
+				b.WriteRune('"')
+			case r == '\n':
+				b.WriteString(`\n`)
+			case r == '\r':
+				b.WriteString(`\r`)
+			case r == '\t':
+				b.WriteString(`\t`)
+			case r < 0x20:
+				// 其他控制字符直接移除
+			default:
+				b.WriteRune(r)
+			}
+			continue
+		}
+
+		if r == '"' {
+			inString = true
+			closeQuote = '"'
+			b.WriteRune(r)
+			continue
+		}
+		if quote, ok := stringQuotes[r]; ok {
+			inString = true
+			closeQuote = quote
+			b.WriteRune('"')
+			continue
 		}
+		if mapped, ok := structuralPunctuation[r]; ok {
+			b.WriteRune(mapped)
+			continue
+		}
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' {
+			continue
+		}
+		b.WriteRune(r)
 	}
 
-	return string(cleanBytes)
+	return b.String()
 }
 
 // GenerateDestinationRecommendations 根据用户偏好生成目的地推荐
 func (s *EinoService) GenerateDestinationRecommendations(ctx context.Context, preferences *models.UserPreferences) ([]string, error) {
-	// 构建提示词
-	prompt := fmt.Sprintf(`
-基于以下用户偏好，推荐5个最适合的旅游目的地:
-
-旅行风格: %v
-预算: %s
-住宿偏好: %v
-交通偏好: %v
-活动偏好: %v
-饮食偏好: %v
-
-请以JSON数组格式返回5个推荐目的地，每个目的地包含名称和简短理由。
-`,
-		preferences.TravelStyle,
-		preferences.Budget,
-		preferences.Accommodation,
-		preferences.Transportation,
-		preferences.Activities,
-		preferences.FoodPreferences,
-	)
+	// 使用当前启用的模板构建提示词
+	prompt, _, err := s.renderPrompt(ctx, models.PromptRecommendations, preferences)
+	if err != nil {
+		return nil, err
+	}
+	systemPrompt, _, err := s.renderPrompt(ctx, models.PromptAgentSystem, nil)
+	if err != nil {
+		return nil, err
+	}
 
-	// 调用Eino API
-	response, err := s.client.GenerateText(ctx, &einosdk.GenerateTextRequest{
-		Model:       "eino-large", // 使用适当的模型名称
-		Prompt:      prompt,
-		MaxTokens:   2000,
-		Temperature: 0.7,
-	})
+	chain, err := s.modelChain(ctx, 0)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate recommendations: %w", err)
+		return nil, err
 	}
 
-	// 解析响应
+	// 调用Eino API，解析失败时切换到备用模型
 	var recommendations []string
-	err = json.Unmarshal([]byte(response.Text), &recommendations)
+	_, err = s.generateWithFailover(ctx, chain, &einosdk.GenerateTextRequest{
+		Prompt:       prompt,
+		SystemPrompt: systemPrompt,
+		MaxTokens:    2000,
+		Tools:        s.agentTools(ctx, toolpolicy.UseCaseRecommendations),
+	}, nil, func(text string) error {
+		return json.Unmarshal([]byte(text), &recommendations)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse recommendations: %w", err)
+		return nil, fmt.Errorf("failed to generate recommendations: %w", err)
 	}
 
 	return recommendations, nil
@@ -635,10 +808,11 @@ func (s *EinoService) GenerateDestinationRecommendations(ctx context.Context, pr
 
 // CallMCPTool 调用指定提供者的指定工具
 func (s *EinoService) CallMCPTool(ctx context.Context, providerName, toolName string, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
-	if s.mcpClient == nil {
-		return nil, fmt.Errorf("MCP客户端未初始化")
+	client, err := s.mcp.Wait(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return s.mcpClient.CallTool(ctx, providerName, toolName, arguments)
+	return client.CallTool(ctx, providerName, toolName, arguments)
 }
 
 // Close 关闭服务实例并释放资源
@@ -646,10 +820,8 @@ func (s *EinoService) Close() error {
 	var errs []error
 
 	// 关闭MCP客户端
-	if s.mcpClient != nil {
-		if err := s.mcpClient.Close(); err != nil {
-			errs = append(errs, fmt.Errorf("关闭MCP客户端错误: %w", err))
-		}
+	if err := s.mcp.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("关闭MCP客户端错误: %w", err))
 	}
 
 	// 这里可以添加其他资源的关闭逻辑
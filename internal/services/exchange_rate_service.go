@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"personatrip/internal/models"
+	"personatrip/internal/repository"
+)
+
+// ErrInvalidExchangeRate 无效的汇率
+var ErrInvalidExchangeRate = errors.New("无效的汇率")
+
+// currencyCodePattern ISO 4217货币代码
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ExchangeRateService 管理员维护的汇率表，智能体的货币换算工具从这里读取汇率
+type ExchangeRateService struct {
+	db repository.Database
+}
+
+// NewExchangeRateService 创建新的汇率服务
+func NewExchangeRateService(db repository.Database) *ExchangeRateService {
+	return &ExchangeRateService{db: db}
+}
+
+// ListExchangeRates 获取所有汇率，不包括基准货币
+func (s *ExchangeRateService) ListExchangeRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	return s.db.ExchangeRateRepo().GetAll(ctx)
+}
+
+// SetExchangeRate 设置货币的汇率，货币代码不区分大小写
+func (s *ExchangeRateService) SetExchangeRate(ctx context.Context, currency string, req *models.ExchangeRateRequest) (*models.ExchangeRate, error) {
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	if req.Rate <= 0 {
+		return nil, fmt.Errorf("%w: 汇率必须大于0", ErrInvalidExchangeRate)
+	}
+
+	rate := &models.ExchangeRate{
+		Currency: currency,
+		Rate:     req.Rate,
+		Source:   strings.TrimSpace(req.Source),
+	}
+	if err := s.db.ExchangeRateRepo().Upsert(ctx, rate); err != nil {
+		return nil, err
+	}
+	return s.db.ExchangeRateRepo().GetByCurrency(ctx, currency)
+}
+
+// DeleteExchangeRate 删除货币的汇率，不存在时返回repository.ErrNotFound
+func (s *ExchangeRateService) DeleteExchangeRate(ctx context.Context, currency string) error {
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return err
+	}
+	return s.db.ExchangeRateRepo().Delete(ctx, currency)
+}
+
+// normalizeCurrency 转为大写并校验货币代码，基准货币的汇率固定为1，不能设置
+func normalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if !currencyCodePattern.MatchString(currency) {
+		return "", fmt.Errorf("%w: 货币代码必须是3个字母的ISO 4217代码", ErrInvalidExchangeRate)
+	}
+	if currency == models.ExchangeRateBaseCurrency {
+		return "", fmt.Errorf("%w: %s是基准货币，汇率固定为1", ErrInvalidExchangeRate, currency)
+	}
+	return currency, nil
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+
+	"personatrip/internal/models"
+	"personatrip/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidExperiment 实验定义无效
+	ErrInvalidExperiment = errors.New("无效的实验")
+
+	// ErrExperimentNotFound 实验不存在
+	ErrExperimentNotFound = errors.New("实验不存在")
+)
+
+// ExperimentService 定义A/B实验服务接口
+type ExperimentService interface {
+	CreateExperiment(ctx context.Context, req *models.ExperimentCreateRequest) (*models.Experiment, error)
+	DeleteExperiment(ctx context.Context, id uint) error
+	GetExperimentByID(ctx context.Context, id uint) (*models.Experiment, error)
+	ListExperiments(ctx context.Context) ([]models.Experiment, error)
+	StartExperiment(ctx context.Context, id uint) error
+	StopExperiment(ctx context.Context, id uint) error
+	GetReport(ctx context.Context, id uint) (*models.ExperimentReport, error)
+	Assign(ctx context.Context, userID string) (*models.ExperimentAssignment, error)
+	RecordExposure(ctx context.Context, exposure *models.ExperimentExposure) error
+	RecordPlanEdited(ctx context.Context, plan *models.TripPlan) error
+	RecordPlanDeleted(ctx context.Context, plan *models.TripPlan) error
+}
+
+// ExperimentServiceImpl 是A/B实验服务的实现
+type ExperimentServiceImpl struct {
+	db repository.Database
+}
+
+// NewExperimentService 创建新的A/B实验服务
+func NewExperimentService(db repository.Database) ExperimentService {
+	return &ExperimentServiceImpl{db: db}
+}
+
+// CreateExperiment 创建实验，分组引用的模型配置和提示词版本必须存在
+func (s *ExperimentServiceImpl) CreateExperiment(ctx context.Context, req *models.ExperimentCreateRequest) (*models.Experiment, error) {
+	experiment := &models.Experiment{
+		Name:        req.Name,
+		Description: req.Description,
+		Status:      models.ExperimentDraft,
+	}
+
+	names := make(map[string]bool, len(req.Variants))
+	for _, v := range req.Variants {
+		if names[v.Name] {
+			return nil, fmt.Errorf("%w: 分组名称重复: %s", ErrInvalidExperiment, v.Name)
+		}
+		names[v.Name] = true
+
+		if _, err := s.db.ModelConfigRepo().GetByID(ctx, v.ModelConfigID); err != nil {
+			return nil, fmt.Errorf("%w: 分组%s的模型配置%d不存在", ErrInvalidExperiment, v.Name, v.ModelConfigID)
+		}
+		if v.PromptVersion > 0 {
+			if _, err := s.db.PromptRepo().GetByVersion(ctx, models.PromptTripPlan, v.PromptVersion); err != nil {
+				return nil, fmt.Errorf("%w: 分组%s的提示词版本%d不存在", ErrInvalidExperiment, v.Name, v.PromptVersion)
+			}
+		}
+
+		experiment.Variants = append(experiment.Variants, models.ExperimentVariant{
+			Name:          v.Name,
+			ModelConfigID: v.ModelConfigID,
+			PromptVersion: v.PromptVersion,
+			Weight:        v.Weight,
+		})
+	}
+
+	if err := s.db.ExperimentRepo().Create(ctx, experiment); err != nil {
+		return nil, err
+	}
+	return experiment, nil
+}
+
+// DeleteExperiment 删除实验及其数据，运行中的实验需要先停止
+func (s *ExperimentServiceImpl) DeleteExperiment(ctx context.Context, id uint) error {
+	experiment, err := s.GetExperimentByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if experiment.Status == models.ExperimentRunning {
+		return fmt.Errorf("%w: 运行中的实验不能删除", ErrInvalidExperiment)
+	}
+	return s.db.ExperimentRepo().Delete(ctx, id)
+}
+
+// GetExperimentByID 根据ID获取实验
+func (s *ExperimentServiceImpl) GetExperimentByID(ctx context.Context, id uint) (*models.Experiment, error) {
+	experiment, err := s.db.ExperimentRepo().GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExperimentNotFound
+	}
+	return experiment, err
+}
+
+// ListExperiments 获取所有实验
+func (s *ExperimentServiceImpl) ListExperiments(ctx context.Context) ([]models.Experiment, error) {
+	return s.db.ExperimentRepo().List(ctx)
+}
+
+// StartExperiment 开始实验，同一时间只有一个实验运行，其他运行中的实验会被停止
+func (s *ExperimentServiceImpl) StartExperiment(ctx context.Context, id uint) error {
+	err := s.db.ExperimentRepo().Start(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrExperimentNotFound
+	}
+	return err
+}
+
+// StopExperiment 停止实验，之后的生成恢复使用活跃的模型配置和提示词版本
+func (s *ExperimentServiceImpl) StopExperiment(ctx context.Context, id uint) error {
+	err := s.db.ExperimentRepo().Stop(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrExperimentNotFound
+	}
+	return err
+}
+
+// GetReport 按分组统计实验的成功率、耗时、费用以及用户修改和删除计划的比例
+func (s *ExperimentServiceImpl) GetReport(ctx context.Context, id uint) (*models.ExperimentReport, error) {
+	experiment, err := s.GetExperimentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	summaries, err := s.db.ExperimentRepo().SummarizeVariants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	byVariant := make(map[uint]models.ExperimentVariantReport, len(summaries))
+	for _, summary := range summaries {
+		byVariant[summary.VariantID] = summary
+	}
+
+	report := &models.ExperimentReport{Experiment: experiment}
+	for _, variant := range experiment.Variants {
+		row := byVariant[variant.ID]
+		row.VariantID = variant.ID
+		row.Name = variant.Name
+		row.ModelConfigID = variant.ModelConfigID
+		row.PromptVersion = variant.PromptVersion
+		row.Weight = variant.Weight
+		row.SuccessRate = ratio(row.Successes, row.Exposures)
+		row.FallbackRate = ratio(row.Fallbacks, row.Successes)
+		row.EditRate = ratio(row.Edits, row.Successes)
+		row.DeleteRate = ratio(row.Deletes, row.Successes)
+		if row.Successes > 0 {
+			row.AvgCost = row.TotalCost / float64(row.Successes)
+		}
+		report.Variants = append(report.Variants, row)
+	}
+	return report, nil
+}
+
+// Assign 为用户分配运行中实验的分组，没有运行中的实验时返回nil。
+// 同一用户在同一实验中总是分到同一分组，匿名请求随机分配
+func (s *ExperimentServiceImpl) Assign(ctx context.Context, userID string) (*models.ExperimentAssignment, error) {
+	experiment, err := s.db.ExperimentRepo().GetRunning(ctx)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	variant := pickVariant(experiment, userID)
+	if variant == nil {
+		return nil, nil
+	}
+	return &models.ExperimentAssignment{Experiment: experiment, Variant: variant}, nil
+}
+
+// RecordExposure 保存一次分配到实验分组的生成记录
+func (s *ExperimentServiceImpl) RecordExposure(ctx context.Context, exposure *models.ExperimentExposure) error {
+	return s.db.ExperimentRepo().CreateExposure(ctx, exposure)
+}
+
+// RecordPlanEdited 用户修改了实验生成的计划时更新生成记录，其他计划忽略
+func (s *ExperimentServiceImpl) RecordPlanEdited(ctx context.Context, plan *models.TripPlan) error {
+	if id := exposureID(plan); id != 0 {
+		return s.db.ExperimentRepo().MarkExposureEdited(ctx, id)
+	}
+	return nil
+}
+
+// RecordPlanDeleted 用户删除了实验生成的计划时更新生成记录，其他计划忽略
+func (s *ExperimentServiceImpl) RecordPlanDeleted(ctx context.Context, plan *models.TripPlan) error {
+	if id := exposureID(plan); id != 0 {
+		return s.db.ExperimentRepo().MarkExposureDeleted(ctx, id)
+	}
+	return nil
+}
+
+// pickVariant 按权重选择分组，用户ID不为空时由实验ID和用户ID的哈希决定，保证分组稳定
+func pickVariant(experiment *models.Experiment, userID string) *models.ExperimentVariant {
+	total := 0
+	for _, variant := range experiment.Variants {
+		total += variant.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	var point int
+	if userID == "" {
+		point = rand.Intn(total)
+	} else {
+		h := fnv.New32a()
+		h.Write([]byte(strconv.FormatUint(uint64(experiment.ID), 10) + ":" + userID))
+		point = int(h.Sum32() % uint32(total))
+	}
+
+	for i := range experiment.Variants {
+		point -= experiment.Variants[i].Weight
+		if point < 0 {
+			return &experiment.Variants[i]
+		}
+	}
+	return nil
+}
+
+// exposureID 获取计划对应的实验生成记录ID，不是实验生成的计划返回0
+func exposureID(plan *models.TripPlan) uint {
+	if plan == nil || plan.Generation == nil || plan.Generation.Experiment == nil {
+		return 0
+	}
+	return plan.Generation.Experiment.ExposureID
+}
+
+// ratio 计算比例，分母为0时返回0
+func ratio(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
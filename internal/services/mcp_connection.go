@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"personatrip/internal/utils/logger"
+	pkgmcp "personatrip/pkg/mcp"
+)
+
+// ErrMCPNotReady MCP客户端尚未初始化完成、初始化失败或未启用
+var ErrMCPNotReady = errors.New("MCP客户端未就绪")
+
+// mcpConnection 在后台初始化MCP客户端。初始化结束前调用方可以等待，也可以不使用工具直接生成，
+// 初始化结束后client和err不再修改
+type mcpConnection struct {
+	ready  chan struct{} // 初始化结束（成功或失败）后关闭
+	client *pkgmcp.Client
+	err    error
+	cancel context.CancelFunc
+}
+
+// startMCPConnection 开始在后台初始化MCP客户端
+func startMCPConnection(opts *pkgmcp.InitOptions) *mcpConnection {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &mcpConnection{
+		ready:  make(chan struct{}),
+		cancel: cancel,
+	}
+
+	go func() {
+		defer close(c.ready)
+		client, err := pkgmcp.InitMCPClient(ctx, opts)
+		if err != nil {
+			logger.Errorf("初始化MCP客户端失败: %v", err)
+			// 部分提供者可能已经连接，需要关闭
+			if client != nil {
+				client.Close()
+			}
+			c.err = err
+			return
+		}
+		c.client = client
+	}()
+	return c
+}
+
+// Ready 是否已初始化成功，不等待
+func (c *mcpConnection) Ready() bool {
+	if c == nil {
+		return false
+	}
+	select {
+	case <-c.ready:
+		return c.err == nil
+	default:
+		return false
+	}
+}
+
+// Wait 等待初始化结束并返回MCP客户端，ctx结束、初始化失败或未启用MCP时返回ErrMCPNotReady
+func (c *mcpConnection) Wait(ctx context.Context) (*pkgmcp.Client, error) {
+	if c == nil {
+		return nil, fmt.Errorf("%w: 未启用MCP", ErrMCPNotReady)
+	}
+	select {
+	case <-c.ready:
+		if c.err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMCPNotReady, c.err)
+		}
+		return c.client, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: 等待初始化超时", ErrMCPNotReady)
+	}
+}
+
+// Close 中止尚未完成的初始化，关闭已初始化的MCP客户端
+func (c *mcpConnection) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.cancel()
+	<-c.ready
+	if c.client == nil {
+		return nil
+	}
+	return c.client.Close()
+}
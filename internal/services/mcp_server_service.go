@@ -0,0 +1,381 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"gorm.io/gorm"
+
+	"personatrip/internal/models"
+	"personatrip/internal/repository"
+	"personatrip/internal/secrets"
+	"personatrip/internal/utils/logger"
+	pkgmcp "personatrip/pkg/mcp"
+	"personatrip/pkg/mcp/providers/generic"
+)
+
+var (
+	// ErrMCPProviderNotFound MCP提供者不存在
+	ErrMCPProviderNotFound = pkgmcp.ErrProviderNotFound
+
+	// ErrMCPProviderExists 已有同名的MCP提供者
+	ErrMCPProviderExists = errors.New("MCP提供者已存在")
+
+	// ErrInvalidMCPServer MCP服务器配置无效
+	ErrInvalidMCPServer = errors.New("无效的MCP服务器配置")
+
+	// ErrMCPConnectFailed 连接MCP服务器失败
+	ErrMCPConnectFailed = errors.New("连接MCP服务器失败")
+)
+
+// MCPClientSource 提供初始化完成的MCP客户端
+type MCPClientSource interface {
+	MCPClient(ctx context.Context) (*pkgmcp.Client, error)
+}
+
+// MCPServerService 在运行时管理MCP提供者：查看提供者和工具、添加和删除服务器、重启提供者和手动调用工具。
+// 管理员添加的服务器保存在MySQL中，服务启动时在后台连接，与配置文件中的服务器同名时替换后者
+type MCPServerService struct {
+	db     repository.Database
+	source MCPClientSource
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewMCPServerService 创建新的MCP服务器管理服务，需要调用Start连接数据库中的服务器
+func NewMCPServerService(db repository.Database, source MCPClientSource) *MCPServerService {
+	return &MCPServerService{
+		db:     db,
+		source: source,
+	}
+}
+
+// Start 在后台等待MCP客户端初始化完成，然后连接数据库中的所有服务器
+func (s *MCPServerService) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.connectAll(ctx)
+	}()
+}
+
+// Stop 停止连接数据库中的服务器并等待正在进行的连接结束
+func (s *MCPServerService) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// connectAll 并行连接数据库中的所有服务器，失败只记录日志
+func (s *MCPServerService) connectAll(ctx context.Context) {
+	client, err := s.source.MCPClient(ctx)
+	if err != nil {
+		logger.Warnf("不连接数据库中的MCP服务器: %v", err)
+		return
+	}
+	servers, err := s.db.MCPServerRepo().GetAll(ctx)
+	if err != nil {
+		logger.Errorf("获取MCP服务器失败: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := range servers {
+		cfg, err := servers[i].ServerConfig()
+		if err != nil {
+			logger.Errorf("%v", err)
+			continue
+		}
+		wg.Add(1)
+		go func(cfg generic.ServerConfig) {
+			defer wg.Done()
+			if err := client.ConnectProvider(ctx, cfg.Name, generic.NewProvider(cfg)); err != nil {
+				logger.Warnf("连接数据库中的MCP服务器失败: %v", err)
+				return
+			}
+			logger.Infof("已连接数据库中的MCP服务器: %s", cfg.Name)
+		}(cfg)
+	}
+	wg.Wait()
+}
+
+// List 获取所有MCP提供者的配置、状态和工具，按名称排列
+func (s *MCPServerService) List(ctx context.Context) ([]models.MCPProviderInfo, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	servers, err := s.db.MCPServerRepo().GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]*models.MCPServer, len(servers))
+	for i := range servers {
+		stored[servers[i].Name] = &servers[i]
+	}
+	failures := client.FailedProviders()
+
+	names := client.ProviderNames()
+	for name := range failures {
+		names = append(names, name)
+	}
+	for name := range stored {
+		if !client.HasProvider(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	infos := make([]models.MCPProviderInfo, 0, len(names))
+	for _, name := range names {
+		infos = append(infos, s.describe(ctx, client, name, stored[name], failures[name]))
+	}
+	return infos, nil
+}
+
+// Get 获取一个MCP提供者的配置、状态和工具
+func (s *MCPServerService) Get(ctx context.Context, name string) (*models.MCPProviderInfo, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	server, err := s.storedServer(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil && !client.HasProvider(name) {
+		return nil, fmt.Errorf("%w: %s", ErrMCPProviderNotFound, name)
+	}
+
+	info := s.describe(ctx, client, name, server, client.FailedProviders()[name])
+	return &info, nil
+}
+
+// Create 保存MCP服务器并立即连接。连接失败时服务器仍然保存，返回的状态为failed，可以之后重启
+func (s *MCPServerService) Create(ctx context.Context, req *models.MCPServerCreateRequest) (*models.MCPProviderInfo, error) {
+	cfg := req.ToServerConfig()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMCPServer, err)
+	}
+
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.storedServer(ctx, cfg.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil || client.HasProvider(cfg.Name) {
+		return nil, fmt.Errorf("%w: %s", ErrMCPProviderExists, cfg.Name)
+	}
+
+	server := &models.MCPServer{}
+	if err := server.SetServerConfig(cfg); err != nil {
+		return nil, err
+	}
+	if err := s.db.MCPServerRepo().Create(ctx, server); err != nil {
+		return nil, err
+	}
+
+	if err := client.ConnectProvider(ctx, cfg.Name, generic.NewProvider(cfg)); err != nil {
+		logger.Warnf("连接新添加的MCP服务器失败: %v", err)
+	}
+	return s.Get(ctx, cfg.Name)
+}
+
+// Delete 断开MCP提供者并删除数据库中的服务器。内置和配置文件中的提供者只在本次运行中移除，重启服务后恢复
+func (s *MCPServerService) Delete(ctx context.Context, name string) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	server, err := s.storedServer(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if server != nil {
+		if err := s.db.MCPServerRepo().Delete(ctx, server.ID); err != nil {
+			return err
+		}
+	}
+	if err := client.RemoveProvider(name); err != nil {
+		if server != nil && errors.Is(err, pkgmcp.ErrProviderNotFound) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// Restart 重新连接MCP提供者，数据库中的服务器按保存的配置连接。
+// 重启失败时已连接的提供者保持不变，返回ErrMCPConnectFailed和提供者当前的状态
+func (s *MCPServerService) Restart(ctx context.Context, name string) (*models.MCPProviderInfo, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	server, err := s.storedServer(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if server != nil {
+		cfg, cfgErr := server.ServerConfig()
+		if cfgErr != nil {
+			return nil, cfgErr
+		}
+		err = client.ConnectProvider(ctx, name, generic.NewProvider(cfg))
+	} else {
+		err = client.RestartProvider(ctx, name)
+		if errors.Is(err, pkgmcp.ErrProviderNotFound) {
+			return nil, err
+		}
+	}
+
+	info, getErr := s.Get(ctx, name)
+	if getErr != nil {
+		return nil, getErr
+	}
+	if err != nil {
+		return info, fmt.Errorf("%w: %v", ErrMCPConnectFailed, err)
+	}
+	return info, nil
+}
+
+// CallTool 手动调用MCP提供者的工具
+func (s *MCPServerService) CallTool(ctx context.Context, name, toolName string, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.CallTool(ctx, name, toolName, arguments)
+}
+
+// RotateSecrets 用当前主密钥重新加密所有MCP服务器的环境变量和请求头，返回更新的服务器数
+func (s *MCPServerService) RotateSecrets(ctx context.Context) (int, error) {
+	servers, err := s.db.MCPServerRepo().GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	keyring := secrets.Default()
+	updated := 0
+	for _, server := range servers {
+		env, envChanged, err := keyring.Rewrap(server.Env)
+		if err != nil {
+			return updated, fmt.Errorf("轮换MCP服务器%s的环境变量失败: %w", server.Name, err)
+		}
+		headers, headersChanged, err := keyring.Rewrap(server.Headers)
+		if err != nil {
+			return updated, fmt.Errorf("轮换MCP服务器%s的请求头失败: %w", server.Name, err)
+		}
+		if !envChanged && !headersChanged {
+			continue
+		}
+		if err := s.db.MCPServerRepo().UpdateSecrets(ctx, server.ID, env, headers); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// client 等待MCP客户端初始化完成，最多等待mcpReadyTimeout
+func (s *MCPServerService) client(ctx context.Context) (*pkgmcp.Client, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, mcpReadyTimeout)
+	defer cancel()
+	return s.source.MCPClient(waitCtx)
+}
+
+// storedServer 获取数据库中的同名服务器，不存在时返回nil
+func (s *MCPServerService) storedServer(ctx context.Context, name string) (*models.MCPServer, error) {
+	server, err := s.db.MCPServerRepo().GetByName(ctx, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return server, err
+}
+
+// describe 汇总提供者的配置、状态和工具，server为空表示不是数据库中的服务器
+func (s *MCPServerService) describe(ctx context.Context, client *pkgmcp.Client, name string, server *models.MCPServer, failure error) models.MCPProviderInfo {
+	info := models.MCPProviderInfo{
+		Name:   name,
+		Source: models.MCPSourceConfig,
+		Tools:  []models.MCPToolInfo{},
+	}
+	if server != nil {
+		info.Source = models.MCPSourceDatabase
+		info.ServerID = server.ID
+	}
+
+	if cfg, ok := client.ProviderConfig(name); ok {
+		info.SetServerConfig(cfg)
+	} else if server != nil {
+		cfg, err := server.ServerConfig()
+		if err != nil {
+			info.Error = err.Error()
+		}
+		info.SetServerConfig(cfg)
+	}
+
+	degraded, isDegraded := client.DegradedProviders()[name]
+	switch {
+	case failure != nil:
+		info.Status = models.MCPStatusFailed
+		info.Error = failure.Error()
+	case !client.IsConnected(name):
+		info.Status = models.MCPStatusDisconnected
+	default:
+		info.Status = models.MCPStatusConnected
+		if isDegraded {
+			info.Status = models.MCPStatusDegraded
+			info.Error = degraded.Error()
+		}
+		tools, err := mcpToolInfos(ctx, client, name)
+		if err != nil {
+			info.Error = err.Error()
+		}
+		info.Tools = tools
+	}
+	return info
+}
+
+// mcpToolInfos 获取提供者所有工具的名称、描述和参数的JSON Schema
+func mcpToolInfos(ctx context.Context, client *pkgmcp.Client, name string) ([]models.MCPToolInfo, error) {
+	tools, err := client.GetTools(ctx, name)
+	if err != nil {
+		return []models.MCPToolInfo{}, err
+	}
+
+	infos := make([]models.MCPToolInfo, 0, len(tools))
+	for _, t := range tools {
+		toolInfo, err := t.Info(ctx)
+		if err != nil {
+			return infos, err
+		}
+		info := models.MCPToolInfo{
+			Name:        toolInfo.Name,
+			Description: toolInfo.Desc,
+		}
+		if toolInfo.ParamsOneOf != nil {
+			inputSchema, err := toolInfo.ParamsOneOf.ToOpenAPIV3()
+			if err != nil {
+				return infos, err
+			}
+			info.InputSchema = inputSchema
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
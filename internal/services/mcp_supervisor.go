@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"personatrip/internal/config"
+	"personatrip/internal/utils/logger"
+	pkgmcp "personatrip/pkg/mcp"
+)
+
+// mcpRestartState 一个需要自动重启的提供者的重启进度
+type mcpRestartState struct {
+	attempts    int       // 连续重启失败的次数
+	nextAttempt time.Time // 下一次重启的时间
+}
+
+// MCPSupervisor 定期探测所有已连接的MCP服务器，探测失败的提供者被标记为degraded，
+// 生成旅行计划时不使用它们的工具；degraded和初始化失败的提供者按指数退避自动重启
+type MCPSupervisor struct {
+	source MCPClientSource
+	cfg    *config.MCPConfig
+
+	restarts map[string]*mcpRestartState // 只在后台goroutine中访问
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewMCPSupervisor 创建新的MCP服务器监督服务，需要调用Start启动
+func NewMCPSupervisor(source MCPClientSource, cfg *config.MCPConfig) *MCPSupervisor {
+	if cfg == nil {
+		cfg = &config.MCPConfig{}
+	}
+	if cfg.PingTimeout <= 0 {
+		cfg.PingTimeout = 5 * time.Second
+	}
+	if cfg.RestartBackoff <= 0 {
+		cfg.RestartBackoff = 5 * time.Second
+	}
+	if cfg.MaxRestartBackoff < cfg.RestartBackoff {
+		cfg.MaxRestartBackoff = cfg.RestartBackoff
+	}
+
+	return &MCPSupervisor{
+		source:   source,
+		cfg:      cfg,
+		restarts: make(map[string]*mcpRestartState),
+	}
+}
+
+// Start 在后台等待MCP客户端初始化完成，之后每隔PingInterval探测一次所有提供者
+func (s *MCPSupervisor) Start(ctx context.Context) {
+	if s.cfg.PingInterval <= 0 {
+		logger.Info("未启用MCP服务器探测和自动重启")
+		return
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		client, err := s.source.MCPClient(ctx)
+		if err != nil {
+			logger.Warnf("不探测MCP服务器: %v", err)
+			return
+		}
+		s.run(ctx, client)
+	}()
+	logger.Infof("MCP服务器探测已启动, 间隔: %s", s.cfg.PingInterval)
+}
+
+// Stop 停止探测并等待正在进行的探测和重启结束
+func (s *MCPSupervisor) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// run 探测到期时探测所有提供者，重启到期时重启不健康的提供者，然后等待到下一个到期时间
+func (s *MCPSupervisor) run(ctx context.Context, client *pkgmcp.Client) {
+	nextPing := time.Now()
+	for {
+		if !time.Now().Before(nextPing) {
+			s.pingAll(ctx, client)
+			nextPing = time.Now().Add(s.cfg.PingInterval)
+		}
+		s.syncRestarts(client)
+		s.restartDue(ctx, client)
+
+		wake := nextPing
+		for _, state := range s.restarts {
+			if state.nextAttempt.Before(wake) {
+				wake = state.nextAttempt
+			}
+		}
+		timer := time.NewTimer(time.Until(wake))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// pingAll 并行探测所有已连接的提供者，失败时标记为degraded，成功时清除标记
+func (s *MCPSupervisor) pingAll(ctx context.Context, client *pkgmcp.Client) {
+	degraded := client.DegradedProviders()
+
+	var wg sync.WaitGroup
+	for _, name := range client.ProviderNames() {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, s.cfg.PingTimeout)
+			defer cancel()
+			err := client.PingProvider(pingCtx, name)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				if _, ok := degraded[name]; !ok {
+					logger.Warnf("MCP提供者 %s 探测失败，标记为degraded: %v", name, err)
+				}
+				client.MarkDegraded(name, err)
+				return
+			}
+			if _, ok := degraded[name]; ok {
+				logger.Infof("MCP提供者 %s 探测恢复正常", name)
+				client.MarkHealthy(name)
+			}
+		}(name)
+	}
+	wg.Wait()
+}
+
+// syncRestarts 让重启进度与提供者的状态一致：新出现的degraded或初始化失败的提供者立即重启，
+// 已经恢复、被管理员重启或删除的提供者不再重启
+func (s *MCPSupervisor) syncRestarts(client *pkgmcp.Client) {
+	unhealthy := client.DegradedProviders()
+	for name, err := range client.FailedProviders() {
+		unhealthy[name] = err
+	}
+
+	for name := range s.restarts {
+		if _, ok := unhealthy[name]; !ok {
+			delete(s.restarts, name)
+		}
+	}
+	for name := range unhealthy {
+		if _, ok := s.restarts[name]; !ok {
+			s.restarts[name] = &mcpRestartState{nextAttempt: time.Now()}
+		}
+	}
+}
+
+// restartDue 并行重启到期的提供者，失败时按指数退避安排下一次重启
+func (s *MCPSupervisor) restartDue(ctx context.Context, client *pkgmcp.Client) {
+	now := time.Now()
+	var wg sync.WaitGroup
+	for name, state := range s.restarts {
+		if state.nextAttempt.After(now) {
+			continue
+		}
+		wg.Add(1)
+		go func(name string, state *mcpRestartState) {
+			defer wg.Done()
+			if err := client.RestartProvider(ctx, name); err != nil {
+				state.attempts++
+				state.nextAttempt = time.Now().Add(s.backoff(state.attempts))
+				if ctx.Err() == nil {
+					logger.Warnf("自动重启MCP提供者 %s 失败(第%d次)，%s后重试: %v", name, state.attempts, s.backoff(state.attempts), err)
+				}
+				return
+			}
+			state.attempts = 0
+			logger.Infof("已自动重启MCP提供者 %s", name)
+		}(name, state)
+	}
+	wg.Wait()
+}
+
+// backoff 连续失败attempts次后的等待时间，从RestartBackoff开始翻倍，不超过MaxRestartBackoff
+func (s *MCPSupervisor) backoff(attempts int) time.Duration {
+	d := s.cfg.RestartBackoff
+	for i := 1; i < attempts && d < s.cfg.MaxRestartBackoff; i++ {
+		d *= 2
+	}
+	if d > s.cfg.MaxRestartBackoff {
+		d = s.cfg.MaxRestartBackoff
+	}
+	return d
+}
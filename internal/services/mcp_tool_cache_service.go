@@ -0,0 +1,354 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"personatrip/internal/config"
+	"personatrip/internal/models"
+	"personatrip/internal/utils/logger"
+)
+
+// mcpToolCacheStoreTimeout 读写MongoDB中缓存的最长时间，超时按未命中处理，不拖慢工具调用
+const mcpToolCacheStoreTimeout = 2 * time.Second
+
+// MCPToolCacheRepository MCP工具调用结果缓存的存储接口
+type MCPToolCacheRepository interface {
+	GetMCPToolCacheEntry(ctx context.Context, key string) (*models.MCPToolCacheEntry, error)
+	SaveMCPToolCacheEntry(ctx context.Context, entry *models.MCPToolCacheEntry) error
+	DeleteMCPToolCacheEntries(ctx context.Context, provider, tool string) (int64, error)
+}
+
+// mcpToolCacheItem 内存中缓存的一个结果
+type mcpToolCacheItem struct {
+	key       string
+	provider  string
+	tool      string
+	result    string
+	expiresAt time.Time
+}
+
+// mcpToolKey 统计命中次数的工具
+type mcpToolKey struct {
+	provider string
+	tool     string
+}
+
+// mcpToolCounters 一个工具的命中次数
+type mcpToolCounters struct {
+	hits      int64
+	storeHits int64
+	misses    int64
+}
+
+// MCPToolCacheService 按工具名称和归一化的参数缓存MCP工具的调用结果，同一地点的地理编码、POI搜索等
+// 在不同的计划之间复用，减少高德地图等服务的配额消耗和延迟。结果保存在内存的LRU中，可以同时保存到MongoDB
+type MCPToolCacheService struct {
+	source MCPClientSource
+	repo   MCPToolCacheRepository // 为空时只缓存在内存中
+	cfg    *config.MCPToolCacheConfig
+
+	mu        sync.Mutex
+	items     map[string]*list.Element // 值为*mcpToolCacheItem
+	lru       *list.List               // 最近使用的结果在前
+	counters  map[mcpToolKey]*mcpToolCounters
+	evictions int64
+	since     time.Time
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewMCPToolCacheService 创建新的MCP工具调用结果缓存服务，需要调用Start才会用于MCP客户端
+func NewMCPToolCacheService(source MCPClientSource, repo MCPToolCacheRepository, cfg *config.MCPToolCacheConfig) *MCPToolCacheService {
+	if cfg == nil {
+		cfg = &config.MCPToolCacheConfig{}
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 1000
+	}
+	if !cfg.Persist {
+		repo = nil
+	}
+
+	return &MCPToolCacheService{
+		source:   source,
+		repo:     repo,
+		cfg:      cfg,
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+		counters: make(map[mcpToolKey]*mcpToolCounters),
+		since:    time.Now(),
+	}
+}
+
+// Enabled 是否有需要缓存的工具
+func (s *MCPToolCacheService) Enabled() bool {
+	if s.cfg.DefaultTTL > 0 {
+		return true
+	}
+	for _, ttl := range s.cfg.ToolTTLs {
+		if ttl > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Start 在后台等待MCP客户端初始化完成，之后智能体和管理接口的工具调用都先查缓存
+func (s *MCPToolCacheService) Start(ctx context.Context) {
+	if !s.Enabled() {
+		logger.Info("未启用MCP工具调用结果缓存")
+		return
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		client, err := s.source.MCPClient(ctx)
+		if err != nil {
+			logger.Warnf("不缓存MCP工具调用结果: %v", err)
+			return
+		}
+		client.SetToolCache(s)
+	}()
+	logger.Infof("MCP工具调用结果缓存已启用, 内存容量: %d, 保存到MongoDB: %t", s.cfg.MaxEntries, s.repo != nil)
+}
+
+// Stop 停止等待MCP客户端
+func (s *MCPToolCacheService) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// ttl 工具的缓存有效期，单独配置的有效期优先
+func (s *MCPToolCacheService) ttl(tool string) time.Duration {
+	if ttl, ok := s.cfg.ToolTTLs[tool]; ok {
+		return ttl
+	}
+	return s.cfg.DefaultTTL
+}
+
+// Get 返回未过期的结果，内存中未命中时再查MongoDB，不缓存的工具不计入统计
+func (s *MCPToolCacheService) Get(ctx context.Context, provider, tool string, arguments map[string]interface{}) (string, bool) {
+	if s.ttl(tool) <= 0 {
+		return "", false
+	}
+	key, _, err := mcpToolCacheKey(provider, tool, arguments)
+	if err != nil {
+		return "", false
+	}
+
+	s.mu.Lock()
+	if elem, ok := s.items[key]; ok {
+		item := elem.Value.(*mcpToolCacheItem)
+		if time.Now().Before(item.expiresAt) {
+			s.lru.MoveToFront(elem)
+			s.counter(provider, tool).hits++
+			s.mu.Unlock()
+			return item.result, true
+		}
+		s.lru.Remove(elem)
+		delete(s.items, key)
+	}
+	s.mu.Unlock()
+
+	if s.repo != nil {
+		storeCtx, cancel := context.WithTimeout(ctx, mcpToolCacheStoreTimeout)
+		entry, err := s.repo.GetMCPToolCacheEntry(storeCtx, key)
+		cancel()
+		if err == nil {
+			s.mu.Lock()
+			s.put(&mcpToolCacheItem{key: key, provider: provider, tool: tool, result: entry.Result, expiresAt: entry.ExpiresAt})
+			counter := s.counter(provider, tool)
+			counter.hits++
+			counter.storeHits++
+			s.mu.Unlock()
+			return entry.Result, true
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			logger.Errorf("查询MCP工具调用结果缓存失败: %v", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.counter(provider, tool).misses++
+	s.mu.Unlock()
+	return "", false
+}
+
+// Set 缓存调用成功的结果，失败只记录日志
+func (s *MCPToolCacheService) Set(ctx context.Context, provider, tool string, arguments map[string]interface{}, result string) {
+	ttl := s.ttl(tool)
+	if ttl <= 0 {
+		return
+	}
+	key, normalized, err := mcpToolCacheKey(provider, tool, arguments)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	s.put(&mcpToolCacheItem{key: key, provider: provider, tool: tool, result: result, expiresAt: now.Add(ttl)})
+	s.mu.Unlock()
+
+	if s.repo != nil {
+		entry := &models.MCPToolCacheEntry{
+			Key:       key,
+			Provider:  provider,
+			Tool:      tool,
+			Arguments: normalized,
+			Result:    result,
+			CreatedAt: now,
+			ExpiresAt: now.Add(ttl),
+		}
+		storeCtx, cancel := context.WithTimeout(ctx, mcpToolCacheStoreTimeout)
+		defer cancel()
+		if err := s.repo.SaveMCPToolCacheEntry(storeCtx, entry); err != nil {
+			logger.Errorf("保存MCP工具调用结果缓存失败: %v", err)
+		}
+	}
+}
+
+// put 保存结果并移到最前，超出容量时淘汰最久未使用的结果，调用方需要持有锁
+func (s *MCPToolCacheService) put(item *mcpToolCacheItem) {
+	if elem, ok := s.items[item.key]; ok {
+		elem.Value = item
+		s.lru.MoveToFront(elem)
+		return
+	}
+	s.items[item.key] = s.lru.PushFront(item)
+	for s.lru.Len() > s.cfg.MaxEntries {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.items, oldest.Value.(*mcpToolCacheItem).key)
+		s.evictions++
+	}
+}
+
+// counter 返回工具的命中次数，调用方需要持有锁
+func (s *MCPToolCacheService) counter(provider, tool string) *mcpToolCounters {
+	key := mcpToolKey{provider: provider, tool: tool}
+	counter, ok := s.counters[key]
+	if !ok {
+		counter = &mcpToolCounters{}
+		s.counters[key] = counter
+	}
+	return counter
+}
+
+// Stats 返回服务启动以来的命中统计
+func (s *MCPToolCacheService) Stats() *models.MCPToolCacheStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := &models.MCPToolCacheStats{
+		Persistent: s.repo != nil,
+		Entries:    s.lru.Len(),
+		MaxEntries: s.cfg.MaxEntries,
+		Evictions:  s.evictions,
+		Since:      s.since,
+		Tools:      make([]models.MCPToolCacheToolStats, 0, len(s.counters)),
+	}
+	for key, counter := range s.counters {
+		stats.Hits += counter.hits
+		stats.StoreHits += counter.storeHits
+		stats.Misses += counter.misses
+		stats.Tools = append(stats.Tools, models.MCPToolCacheToolStats{
+			Provider:  key.provider,
+			Tool:      key.tool,
+			TTL:       s.ttl(key.tool).String(),
+			Hits:      counter.hits,
+			StoreHits: counter.storeHits,
+			Misses:    counter.misses,
+			HitRate:   hitRate(counter.hits, counter.misses),
+		})
+	}
+	stats.HitRate = hitRate(stats.Hits, stats.Misses)
+	sort.Slice(stats.Tools, func(i, j int) bool {
+		if stats.Tools[i].Provider != stats.Tools[j].Provider {
+			return stats.Tools[i].Provider < stats.Tools[j].Provider
+		}
+		return stats.Tools[i].Tool < stats.Tools[j].Tool
+	})
+	return stats
+}
+
+// Clear 删除缓存的结果，provider和tool为空时不按其过滤，返回删除的数量。
+// 保存到MongoDB时返回MongoDB中删除的数量，否则返回内存中删除的数量
+func (s *MCPToolCacheService) Clear(ctx context.Context, provider, tool string) (int64, error) {
+	var deleted int64
+	s.mu.Lock()
+	for elem := s.lru.Front(); elem != nil; {
+		next := elem.Next()
+		item := elem.Value.(*mcpToolCacheItem)
+		if (provider == "" || item.provider == provider) && (tool == "" || item.tool == tool) {
+			s.lru.Remove(elem)
+			delete(s.items, item.key)
+			deleted++
+		}
+		elem = next
+	}
+	s.mu.Unlock()
+
+	if s.repo == nil {
+		return deleted, nil
+	}
+	return s.repo.DeleteMCPToolCacheEntries(ctx, provider, tool)
+}
+
+// mcpToolCacheKey 计算缓存键：参数中的字符串去掉首尾空白并合并连续空白，值为null的字段视为未传，
+// JSON对象按键排序，内容相同的参数得到相同的键。同时返回归一化的参数JSON
+func mcpToolCacheKey(provider, tool string, arguments map[string]interface{}) (string, string, error) {
+	data, err := json.Marshal(normalizeArgument(arguments))
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(provider + "\x00" + tool + "\x00" + string(data)))
+	return hex.EncodeToString(sum[:]), string(data), nil
+}
+
+// normalizeArgument 递归归一化参数值
+func normalizeArgument(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return strings.Join(strings.Fields(v), " ")
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if item != nil {
+				normalized[key] = normalizeArgument(item)
+			}
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, item := range v {
+			normalized[i] = normalizeArgument(item)
+		}
+		return normalized
+	default:
+		return v
+	}
+}
+
+// hitRate 命中次数占查询次数的比例，没有查询时为0
+func hitRate(hits, misses int64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
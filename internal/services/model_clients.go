@@ -0,0 +1,104 @@
+package services
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"personatrip/internal/models"
+	"personatrip/pkg/einosdk"
+)
+
+// clientKey 决定einosdk客户端连接参数的配置字段，这些字段不变时复用已创建的客户端
+type clientKey struct {
+	modelType string
+	modelName string
+	apiKey    string // 加密后的值，轮换主密钥后也会重新创建客户端
+	baseURL   string
+}
+
+// keyOf 返回模型配置的连接参数
+func keyOf(config *models.ModelConfig) clientKey {
+	return clientKey{
+		modelType: config.ModelType,
+		modelName: config.ModelName,
+		apiKey:    config.ApiKey,
+		baseURL:   config.BaseUrl,
+	}
+}
+
+// modelClient 用一个模型配置创建的客户端，创建后不再修改，可以被多个请求同时使用
+type modelClient struct {
+	key    clientKey
+	client *einosdk.Client
+}
+
+// activeModel 当前激活的模型配置及其客户端的快照，创建后不再修改，刷新时整体替换
+type activeModel struct {
+	config models.ModelConfig
+	client *einosdk.Client
+}
+
+// ModelClients 按模型配置ID缓存einosdk客户端。缓存是不可变的快照，读取不加锁；
+// 配置的连接参数变化时创建新客户端，复制出新快照后原子替换
+type ModelClients struct {
+	mu       sync.Mutex // 串行化快照的替换
+	snapshot atomic.Pointer[map[uint]*modelClient]
+}
+
+// NewModelClients 创建空的客户端缓存
+func NewModelClients() *ModelClients {
+	m := &ModelClients{}
+	m.snapshot.Store(&map[uint]*modelClient{})
+	return m
+}
+
+// Get 返回模型配置对应的客户端，尚未创建或连接参数已变化时创建新客户端并加入缓存
+func (m *ModelClients) Get(config *models.ModelConfig) (*einosdk.Client, error) {
+	if client := m.lookup(config); client != nil {
+		return client, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// 等待锁期间可能已被其他请求创建
+	if client := m.lookup(config); client != nil {
+		return client, nil
+	}
+
+	client, err := config.NewEinoClient()
+	if err != nil {
+		return nil, err
+	}
+	current := *m.snapshot.Load()
+	next := make(map[uint]*modelClient, len(current)+1)
+	for id, c := range current {
+		next[id] = c
+	}
+	next[config.ID] = &modelClient{key: keyOf(config), client: client}
+	m.snapshot.Store(&next)
+	return client, nil
+}
+
+// Reload 用最新的配置列表重建缓存：连接参数未变的配置沿用原客户端，已删除的配置被移除，
+// 其余配置在下次使用时创建客户端
+func (m *ModelClients) Reload(configs []models.ModelConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := *m.snapshot.Load()
+	next := make(map[uint]*modelClient, len(configs))
+	for i := range configs {
+		if c, ok := current[configs[i].ID]; ok && c.key == keyOf(&configs[i]) {
+			next[configs[i].ID] = c
+		}
+	}
+	m.snapshot.Store(&next)
+}
+
+// lookup 在当前快照中查找连接参数相同的客户端
+func (m *ModelClients) lookup(config *models.ModelConfig) *einosdk.Client {
+	if c, ok := (*m.snapshot.Load())[config.ID]; ok && c.key == keyOf(config) {
+		return c.client
+	}
+	return nil
+}
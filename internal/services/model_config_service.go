@@ -2,9 +2,21 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"personatrip/internal/models"
 	"personatrip/internal/repository"
+	"personatrip/internal/secrets"
+	"personatrip/pkg/einosdk"
+)
+
+var (
+	// ErrInvalidModelConfig 模型配置未通过提供者校验
+	ErrInvalidModelConfig = errors.New("无效的模型配置")
+
+	// ErrModelConfigDown 模型配置被后台探测标记为不可用
+	ErrModelConfigDown = errors.New("模型配置已被探测为不可用")
 )
 
 // ModelConfigService 定义模型配置服务接口
@@ -15,7 +27,9 @@ type ModelConfigService interface {
 	GetModelConfigByID(ctx context.Context, id uint) (*models.ModelConfig, error)
 	GetAllModelConfigs(ctx context.Context) ([]models.ModelConfig, error)
 	GetActiveModelConfig(ctx context.Context) (*models.ModelConfig, error)
-	SetActiveModelConfig(ctx context.Context, id uint) error
+	SetActiveModelConfig(ctx context.Context, id uint, force bool) error
+	GetFallbackChain(ctx context.Context) ([]models.ModelConfig, error)
+	RotateSecrets(ctx context.Context) (int, error)
 }
 
 // ModelConfigServiceImpl 是模型配置服务的实现
@@ -31,14 +45,18 @@ func NewModelConfigService(db repository.Database) ModelConfigService {
 // CreateModelConfig 创建新的模型配置
 func (s *ModelConfigServiceImpl) CreateModelConfig(ctx context.Context, req *models.ModelConfigCreateRequest) (*models.ModelConfig, error) {
 	config := &models.ModelConfig{
-		Name:        req.Name,
-		ModelType:   req.ModelType,
-		ModelName:   req.ModelName,
-		ApiKey:      req.ApiKey,
-		BaseUrl:     req.BaseUrl,
-		IsActive:    req.IsActive,
-		Temperature: req.Temperature,
-		MaxTokens:   req.MaxTokens,
+		Name:              req.Name,
+		ModelType:         req.ModelType,
+		ModelName:         req.ModelName,
+		BaseUrl:           req.BaseUrl,
+		IsActive:          req.IsActive,
+		Priority:          req.Priority,
+		Temperature:       req.Temperature,
+		MaxTokens:         req.MaxTokens,
+		PromptPrice:       req.PromptPrice,
+		CompletionPrice:   req.CompletionPrice,
+		MaxConcurrency:    req.MaxConcurrency,
+		RequestsPerMinute: req.RequestsPerMinute,
 	}
 
 	// 设置默认值
@@ -49,6 +67,14 @@ func (s *ModelConfigServiceImpl) CreateModelConfig(ctx context.Context, req *mod
 		config.MaxTokens = 2000
 	}
 
+	if err := config.SetApiKey(req.ApiKey); err != nil {
+		return nil, err
+	}
+
+	if err := validateModelConfig(config); err != nil {
+		return nil, err
+	}
+
 	if err := s.db.ModelConfigRepo().Create(ctx, config); err != nil {
 		return nil, err
 	}
@@ -62,6 +88,9 @@ func (s *ModelConfigServiceImpl) UpdateModelConfig(ctx context.Context, id uint,
 		return nil, err
 	}
 
+	// 连接参数变化后之前的探测结果不再有效
+	connection := [4]string{config.ModelType, config.ModelName, config.ApiKey, config.BaseUrl}
+
 	// 更新字段
 	if req.Name != "" {
 		config.Name = req.Name
@@ -73,12 +102,29 @@ func (s *ModelConfigServiceImpl) UpdateModelConfig(ctx context.Context, id uint,
 		config.ModelName = req.ModelName
 	}
 	if req.ApiKey != "" {
-		config.ApiKey = req.ApiKey
+		if err := config.SetApiKey(req.ApiKey); err != nil {
+			return nil, err
+		}
 	}
 	if req.BaseUrl != "" {
 		config.BaseUrl = req.BaseUrl
 	}
 	config.IsActive = req.IsActive
+	if req.Priority != nil {
+		config.Priority = *req.Priority
+	}
+	if req.PromptPrice != nil {
+		config.PromptPrice = *req.PromptPrice
+	}
+	if req.CompletionPrice != nil {
+		config.CompletionPrice = *req.CompletionPrice
+	}
+	if req.MaxConcurrency != nil {
+		config.MaxConcurrency = *req.MaxConcurrency
+	}
+	if req.RequestsPerMinute != nil {
+		config.RequestsPerMinute = *req.RequestsPerMinute
+	}
 	if req.Temperature != 0 {
 		config.Temperature = req.Temperature
 	}
@@ -86,6 +132,16 @@ func (s *ModelConfigServiceImpl) UpdateModelConfig(ctx context.Context, id uint,
 		config.MaxTokens = req.MaxTokens
 	}
 
+	if err := validateModelConfig(config); err != nil {
+		return nil, err
+	}
+
+	if connection != [4]string{config.ModelType, config.ModelName, config.ApiKey, config.BaseUrl} {
+		config.HealthStatus = models.ModelHealthUnknown
+		config.HealthFailures = 0
+		config.HealthError = ""
+	}
+
 	if err := s.db.ModelConfigRepo().Update(ctx, config); err != nil {
 		return nil, err
 	}
@@ -112,7 +168,100 @@ func (s *ModelConfigServiceImpl) GetActiveModelConfig(ctx context.Context) (*mod
 	return s.db.ModelConfigRepo().GetActive(ctx)
 }
 
-// SetActiveModelConfig 设置指定ID的配置为活跃
-func (s *ModelConfigServiceImpl) SetActiveModelConfig(ctx context.Context, id uint) error {
+// SetActiveModelConfig 设置指定ID的配置为活跃，被探测为不可用的配置只有force为true时才能激活
+func (s *ModelConfigServiceImpl) SetActiveModelConfig(ctx context.Context, id uint, force bool) error {
+	config, err := s.db.ModelConfigRepo().GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if config.IsDown() && !force {
+		return fmt.Errorf("%w: %s", ErrModelConfigDown, config.HealthError)
+	}
 	return s.db.ModelConfigRepo().SetActive(ctx, id)
 }
+
+// GetFallbackChain 获取故障切换链：活跃配置在前，其后是按优先级排列的备用配置
+func (s *ModelConfigServiceImpl) GetFallbackChain(ctx context.Context) ([]models.ModelConfig, error) {
+	active, err := s.db.ModelConfigRepo().GetActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fallbacks, err := s.db.ModelConfigRepo().GetFallbacks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := make([]models.ModelConfig, 0, len(fallbacks)+1)
+	chain = append(chain, *active)
+	for _, config := range fallbacks {
+		if config.ID != active.ID {
+			chain = append(chain, config)
+		}
+	}
+	return skipDownConfigs(chain), nil
+}
+
+// skipDownConfigs 去掉被探测为不可用的配置，所有配置都不可用时保留原链，仍然尝试生成
+func skipDownConfigs(chain []models.ModelConfig) []models.ModelConfig {
+	available := make([]models.ModelConfig, 0, len(chain))
+	for _, config := range chain {
+		if !config.IsDown() {
+			available = append(available, config)
+		}
+	}
+	if len(available) == 0 {
+		return chain
+	}
+	return available
+}
+
+// RotateSecrets 用当前主密钥重新加密所有模型配置的API密钥，并加密轮换前以明文保存的密钥，返回更新的配置数。
+// 启动时调用，更换主密钥后旧主密钥只需保留到轮换完成
+func (s *ModelConfigServiceImpl) RotateSecrets(ctx context.Context) (int, error) {
+	configs, err := s.db.ModelConfigRepo().GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	keyring := secrets.Default()
+	updated := 0
+	for i := range configs {
+		config := &configs[i]
+		if config.ApiKey == "" {
+			continue
+		}
+		fingerprint := config.ApiKeyFingerprint
+		if fingerprint == "" && !secrets.IsEncrypted(config.ApiKey) {
+			fingerprint = secrets.Fingerprint(config.ApiKey)
+		}
+		apiKey, changed, err := keyring.Rewrap(config.ApiKey)
+		if err != nil {
+			return updated, fmt.Errorf("轮换模型配置%s(ID: %d)的API密钥失败: %w", config.Name, config.ID, err)
+		}
+		if !changed && fingerprint == config.ApiKeyFingerprint {
+			continue
+		}
+		if err := s.db.ModelConfigRepo().UpdateAPIKey(ctx, config.ID, apiKey, fingerprint); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// validateModelConfig 使用对应提供者校验模型配置
+func validateModelConfig(config *models.ModelConfig) error {
+	modelType, ok := einosdk.ParseModelType(config.ModelType)
+	if !ok {
+		return fmt.Errorf("%w: 不支持的模型类型 %s", ErrInvalidModelConfig, config.ModelType)
+	}
+	options, err := config.GetEinoOptions()
+	if err != nil {
+		return err
+	}
+	if err := einosdk.ValidateConfig(modelType, options...); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidModelConfig, err)
+	}
+	return nil
+}
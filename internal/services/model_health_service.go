@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"personatrip/internal/config"
+	"personatrip/internal/models"
+	"personatrip/internal/repository"
+	"personatrip/internal/utils/logger"
+	"personatrip/pkg/einosdk"
+)
+
+const (
+	// healthCheckEndpoint 探测产生的模型调用在用量记录中的来源
+	healthCheckEndpoint = "health_check"
+	// healthCheckPrompt 探测使用的提示词，只要求模型回复很短的内容
+	healthCheckPrompt = "这是一次连通性检查，请只回复OK"
+	// healthCheckMaxTokens 探测时模型最多输出的token数
+	healthCheckMaxTokens = 16
+	// healthRecentWindow 探测成功时检查最近多少次探测，其中有失败时标记为degraded
+	healthRecentWindow = 5
+	// defaultHealthHistoryLimit 未指定时返回的探测历史数量
+	defaultHealthHistoryLimit = 50
+	// healthErrorMaxLength 保存的错误信息的最大字符数
+	healthErrorMaxLength = 500
+)
+
+// ModelHealthService 定期用很短的提示词探测所有模型配置，记录延迟、错误和健康状态历史。
+// 连续失败的配置被标记为down，故障切换时跳过
+type ModelHealthService struct {
+	db           repository.Database
+	usageService UsageService // 为空时不记录探测的用量
+	cfg          *config.HealthCheckConfig
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewModelHealthService 创建新的模型配置探测服务，需要调用Start启动后台探测
+func NewModelHealthService(db repository.Database, usageService UsageService, cfg *config.HealthCheckConfig) *ModelHealthService {
+	if cfg == nil {
+		cfg = &config.HealthCheckConfig{}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.DownThreshold <= 0 {
+		cfg.DownThreshold = 3
+	}
+
+	return &ModelHealthService{
+		db:           db,
+		usageService: usageService,
+		cfg:          cfg,
+	}
+}
+
+// Start 启动后台探测，启动后立即探测一次，之后每隔Interval探测所有模型配置
+func (s *ModelHealthService) Start(ctx context.Context) {
+	if s.cfg.Interval <= 0 {
+		logger.Info("未启用模型配置后台探测")
+		return
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			s.ProbeAll(ctx)
+			s.cleanup(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	logger.Infof("模型配置后台探测已启动, 间隔: %s", s.cfg.Interval)
+}
+
+// Stop 停止后台探测并等待正在进行的探测结束
+func (s *ModelHealthService) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// ProbeAll 并行探测所有模型配置，失败只记录日志
+func (s *ModelHealthService) ProbeAll(ctx context.Context) {
+	configs, err := s.db.ModelConfigRepo().GetAll(ctx)
+	if err != nil {
+		logger.Errorf("获取模型配置失败，跳过本轮探测: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := range configs {
+		wg.Add(1)
+		go func(config *models.ModelConfig) {
+			defer wg.Done()
+			if _, err := s.Probe(ctx, config); err != nil && ctx.Err() == nil {
+				logger.Errorf("保存模型配置 %s(ID: %d) 的探测结果失败: %v", config.Name, config.ID, err)
+			}
+		}(&configs[i])
+	}
+	wg.Wait()
+}
+
+// Probe 探测一个模型配置，更新其健康状态并保存探测结果。
+// 探测失败记录在结果中，只有保存失败时返回错误
+func (s *ModelHealthService) Probe(ctx context.Context, config *models.ModelConfig) (*models.ModelHealthCheck, error) {
+	latency, probeErr := s.ping(ctx, config)
+	if probeErr != nil && ctx.Err() != nil {
+		// 服务停止导致的失败不计入健康状态
+		return nil, ctx.Err()
+	}
+
+	check := &models.ModelHealthCheck{
+		ModelConfigID: config.ID,
+		Success:       probeErr == nil,
+		LatencyMs:     latency.Milliseconds(),
+	}
+	if probeErr != nil {
+		check.Error = truncateRunes(probeErr.Error(), healthErrorMaxLength)
+	}
+
+	previous := config.HealthStatus
+	now := time.Now()
+	config.HealthCheckedAt = &now
+	config.HealthLatencyMs = check.LatencyMs
+	if check.Success {
+		config.HealthFailures = 0
+		config.HealthError = ""
+		config.HealthStatus = models.ModelHealthHealthy
+		if (s.cfg.DegradedLatency > 0 && latency >= s.cfg.DegradedLatency) || s.recentlyFailed(ctx, config.ID) {
+			config.HealthStatus = models.ModelHealthDegraded
+		}
+	} else {
+		config.HealthFailures++
+		config.HealthError = check.Error
+		config.HealthStatus = models.ModelHealthDegraded
+		if config.HealthFailures >= s.cfg.DownThreshold {
+			config.HealthStatus = models.ModelHealthDown
+		}
+	}
+	check.Status = config.HealthStatus
+
+	if err := s.db.ModelHealthRepo().Create(ctx, check); err != nil {
+		return nil, err
+	}
+	if err := s.db.ModelConfigRepo().UpdateHealth(ctx, config); err != nil {
+		return nil, err
+	}
+
+	if previous != config.HealthStatus {
+		if config.HealthStatus == models.ModelHealthHealthy {
+			logger.Infof("模型配置 %s(ID: %d) 的健康状态: %s -> %s", config.Name, config.ID, previous, config.HealthStatus)
+		} else {
+			logger.Warnf("模型配置 %s(ID: %d) 的健康状态: %s -> %s, 错误: %s", config.Name, config.ID, previous, config.HealthStatus, config.HealthError)
+		}
+	}
+	return check, nil
+}
+
+// History 获取模型配置最近的探测结果，按时间倒序排列
+func (s *ModelHealthService) History(ctx context.Context, id uint, query *models.ModelHealthQuery) ([]models.ModelHealthCheck, error) {
+	limit := defaultHealthHistoryLimit
+	if query != nil && query.Limit > 0 {
+		limit = query.Limit
+	}
+	return s.db.ModelHealthRepo().ListByModel(ctx, id, limit)
+}
+
+// ping 用探测提示词调用一次模型并记录用量，返回耗时
+func (s *ModelHealthService) ping(ctx context.Context, config *models.ModelConfig) (time.Duration, error) {
+	client, err := config.NewEinoClient()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(WithCallInfo(ctx, "", healthCheckEndpoint), s.cfg.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	response, err := client.GenerateText(ctx, &einosdk.GenerateTextRequest{
+		Prompt:    healthCheckPrompt,
+		MaxTokens: healthCheckMaxTokens,
+	})
+	latency := time.Since(start)
+	if err == nil && strings.TrimSpace(response.Text) == "" {
+		err = errors.New("模型返回了空内容")
+	}
+
+	if s.usageService != nil {
+		var usage einosdk.TokenUsage
+		if response != nil {
+			usage = response.Usage
+		}
+		if recordErr := s.usageService.RecordUsage(context.WithoutCancel(ctx), newUsageRecord(ctx, config, usage, latency, err == nil)); recordErr != nil {
+			logger.Errorf("保存模型用量记录失败: %v", recordErr)
+		}
+	}
+	return latency, err
+}
+
+// recentlyFailed 最近的探测中是否有失败
+func (s *ModelHealthService) recentlyFailed(ctx context.Context, id uint) bool {
+	checks, err := s.db.ModelHealthRepo().ListByModel(ctx, id, healthRecentWindow-1)
+	if err != nil {
+		logger.Errorf("获取模型配置(ID: %d)的探测历史失败: %v", id, err)
+		return false
+	}
+	for _, check := range checks {
+		if !check.Success {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanup 删除超过保存时间的探测历史
+func (s *ModelHealthService) cleanup(ctx context.Context) {
+	if s.cfg.Retention <= 0 {
+		return
+	}
+	deleted, err := s.db.ModelHealthRepo().DeleteBefore(ctx, time.Now().Add(-s.cfg.Retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Errorf("清理模型配置探测历史失败: %v", err)
+		}
+		return
+	}
+	if deleted > 0 {
+		logger.Debugf("已清理 %d 条过期的模型配置探测历史", deleted)
+	}
+}
+
+// truncateRunes 把文本截断到最多n个字符
+func truncateRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"personatrip/internal/models"
+)
+
+// defaultCallDuration 还没有完成的调用时用于估算重试时间的单次调用耗时
+const defaultCallDuration = 30 * time.Second
+
+// ModelBusyError 模型配置达到并发数或每分钟请求数限制，排队超时
+type ModelBusyError struct {
+	ModelConfigID   uint
+	ModelConfigName string
+	RetryAfter      time.Duration // 预计可以重试的等待时间
+}
+
+// Error 返回错误信息
+func (e *ModelBusyError) Error() string {
+	return fmt.Sprintf("模型配置 %s 繁忙，请在%d秒后重试", e.ModelConfigName, e.RetryAfterSeconds())
+}
+
+// RetryAfterSeconds 返回向上取整的重试等待秒数，至少为1
+func (e *ModelBusyError) RetryAfterSeconds() int {
+	seconds := int(math.Ceil(e.RetryAfter.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// ModelLimiters 按模型配置限制同时进行的调用数和每分钟开始的调用数，
+// 超出限制的调用按到达顺序排队，排队超过timeout时返回*ModelBusyError
+type ModelLimiters struct {
+	mu       sync.Mutex
+	limiters map[uint]*modelLimiter
+	timeout  time.Duration // 排队等待的最长时间，为0时不排队
+}
+
+// NewModelLimiters 创建模型配置限流器
+func NewModelLimiters(timeout time.Duration) *ModelLimiters {
+	return &ModelLimiters{
+		limiters: make(map[uint]*modelLimiter),
+		timeout:  timeout,
+	}
+}
+
+// Acquire 申请一次调用名额，返回的release必须在调用结束后执行。
+// 限制值每次从config读取，修改模型配置后立即生效；没有限制时立即返回
+func (l *ModelLimiters) Acquire(ctx context.Context, config *models.ModelConfig) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	limiter := l.get(config)
+	if limiter == nil {
+		return func() {}, nil
+	}
+	return limiter.acquire(ctx, l.timeout, config)
+}
+
+// get 获取模型配置的限流器并更新限制值，配置没有限制且还没有限流器时返回nil
+func (l *ModelLimiters) get(config *models.ModelConfig) *modelLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limiter, ok := l.limiters[config.ID]
+	if !ok {
+		if config.MaxConcurrency <= 0 && config.RequestsPerMinute <= 0 {
+			return nil
+		}
+		limiter = &modelLimiter{}
+		l.limiters[config.ID] = limiter
+	}
+	limiter.setLimits(config.MaxConcurrency, config.RequestsPerMinute)
+	return limiter
+}
+
+// modelLimiter 单个模型配置的限流器
+type modelLimiter struct {
+	mu                sync.Mutex
+	maxConcurrency    int
+	requestsPerMinute int
+	active            int              // 进行中的调用数
+	starts            []time.Time      // 最近一分钟内开始的调用时间，按时间顺序
+	queue             []*limiterWaiter // 等待中的调用，按到达顺序
+	avgDuration       time.Duration    // 调用耗时的指数移动平均
+	timer             *time.Timer      // 等待每分钟请求数窗口滑动后唤醒排队的调用
+}
+
+// limiterWaiter 排队中的调用
+type limiterWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// setLimits 更新限制值，放宽限制后唤醒排队的调用
+func (m *modelLimiter) setLimits(maxConcurrency, requestsPerMinute int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxConcurrency = maxConcurrency
+	m.requestsPerMinute = requestsPerMinute
+	m.dispatch(time.Now())
+}
+
+// acquire 有空闲名额且没有更早的排队调用时立即返回，否则排队等待
+func (m *modelLimiter) acquire(ctx context.Context, timeout time.Duration, config *models.ModelConfig) (func(), error) {
+	m.mu.Lock()
+	now := time.Now()
+	if len(m.queue) == 0 && m.canStart(now) {
+		m.start(now)
+		m.mu.Unlock()
+		return m.releaseFunc(now), nil
+	}
+	if timeout <= 0 {
+		err := m.busyError(config, now)
+		m.mu.Unlock()
+		return nil, err
+	}
+	waiter := &limiterWaiter{ready: make(chan struct{})}
+	m.queue = append(m.queue, waiter)
+	m.schedule(now)
+	m.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var ctxErr error
+	select {
+	case <-waiter.ready:
+		return m.releaseFunc(time.Now()), nil
+	case <-ctx.Done():
+		ctxErr = ctx.Err()
+	case <-timer.C:
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// 超时的同时获得了名额
+	if waiter.granted {
+		return m.releaseFunc(time.Now()), nil
+	}
+	m.remove(waiter)
+	if ctxErr != nil {
+		return nil, ctxErr
+	}
+	return nil, m.busyError(config, time.Now())
+}
+
+// releaseFunc 返回只执行一次的释放函数，释放时记录调用耗时并唤醒排队的调用
+func (m *modelLimiter) releaseFunc(started time.Time) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			now := time.Now()
+			m.active--
+			elapsed := now.Sub(started)
+			if m.avgDuration == 0 {
+				m.avgDuration = elapsed
+			} else {
+				m.avgDuration = (m.avgDuration*4 + elapsed) / 5
+			}
+			m.dispatch(now)
+		})
+	}
+}
+
+// canStart 判断当前是否可以开始一次调用，调用方需持有锁
+func (m *modelLimiter) canStart(now time.Time) bool {
+	m.prune(now)
+	if m.maxConcurrency > 0 && m.active >= m.maxConcurrency {
+		return false
+	}
+	if m.requestsPerMinute > 0 && len(m.starts) >= m.requestsPerMinute {
+		return false
+	}
+	return true
+}
+
+// start 占用一个名额，调用方需持有锁
+func (m *modelLimiter) start(now time.Time) {
+	m.active++
+	m.starts = append(m.starts, now)
+}
+
+// prune 移除一分钟之前开始的调用记录，调用方需持有锁
+func (m *modelLimiter) prune(now time.Time) {
+	cutoff := now.Add(-time.Minute)
+	i := 0
+	for i < len(m.starts) && !m.starts[i].After(cutoff) {
+		i++
+	}
+	m.starts = m.starts[i:]
+}
+
+// dispatch 按到达顺序唤醒可以开始的排队调用，调用方需持有锁
+func (m *modelLimiter) dispatch(now time.Time) {
+	for len(m.queue) > 0 && m.canStart(now) {
+		waiter := m.queue[0]
+		m.queue = m.queue[1:]
+		m.start(now)
+		waiter.granted = true
+		close(waiter.ready)
+	}
+	m.schedule(now)
+}
+
+// schedule 排队的调用被每分钟请求数限制时，在最早的调用记录过期后重新唤醒，调用方需持有锁
+func (m *modelLimiter) schedule(now time.Time) {
+	if m.timer != nil || len(m.queue) == 0 || m.requestsPerMinute <= 0 || len(m.starts) < m.requestsPerMinute {
+		return
+	}
+	m.timer = time.AfterFunc(m.starts[0].Add(time.Minute).Sub(now), func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.timer = nil
+		m.dispatch(time.Now())
+	})
+}
+
+// remove 从队列中移除超时或取消的调用，调用方需持有锁
+func (m *modelLimiter) remove(waiter *limiterWaiter) {
+	for i, w := range m.queue {
+		if w == waiter {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// busyError 根据排队长度、每分钟请求数窗口和平均调用耗时估算重试时间，调用方需持有锁
+func (m *modelLimiter) busyError(config *models.ModelConfig, now time.Time) *ModelBusyError {
+	m.prune(now)
+	ahead := len(m.queue)
+
+	var wait time.Duration
+	if m.requestsPerMinute > 0 && len(m.starts) >= m.requestsPerMinute {
+		wait = m.starts[0].Add(time.Minute).Sub(now)
+		// 排在前面的调用会占用之后窗口的名额
+		wait += time.Duration(ahead/m.requestsPerMinute) * time.Minute
+	}
+	if m.maxConcurrency > 0 && m.active >= m.maxConcurrency {
+		avg := m.avgDuration
+		if avg == 0 {
+			avg = defaultCallDuration
+		}
+		if d := avg * time.Duration(ahead+1) / time.Duration(m.maxConcurrency); d > wait {
+			wait = d
+		}
+	}
+
+	return &ModelBusyError{
+		ModelConfigID:   config.ID,
+		ModelConfigName: config.Name,
+		RetryAfter:      wait,
+	}
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"personatrip/internal/models"
+)
+
+// acquireResult 排队调用的结果
+type acquireResult struct {
+	release func()
+	err     error
+}
+
+// acquireAsync 在后台申请名额
+func acquireAsync(l *ModelLimiters, ctx context.Context, config *models.ModelConfig) <-chan acquireResult {
+	done := make(chan acquireResult, 1)
+	go func() {
+		release, err := l.Acquire(ctx, config)
+		done <- acquireResult{release: release, err: err}
+	}()
+	return done
+}
+
+// queueLen 返回排队中的调用数
+func queueLen(l *ModelLimiters, id uint) int {
+	l.mu.Lock()
+	limiter := l.limiters[id]
+	l.mu.Unlock()
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	return len(limiter.queue)
+}
+
+// waitQueueLen 等待排队的调用数达到n
+func waitQueueLen(t *testing.T, l *ModelLimiters, id uint, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for queueLen(l, id) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("queue length = %d, want %d", queueLen(l, id), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// mustAcquire 立即获得名额，失败时终止测试
+func mustAcquire(t *testing.T, l *ModelLimiters, config *models.ModelConfig) func() {
+	t.Helper()
+	release, err := l.Acquire(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	return release
+}
+
+// receive 等待排队调用的结果
+func receive(t *testing.T, done <-chan acquireResult) acquireResult {
+	t.Helper()
+	select {
+	case result := <-done:
+		return result
+	case <-time.After(2 * time.Second):
+		t.Fatal("queued call was not granted")
+		return acquireResult{}
+	}
+}
+
+func TestModelLimitersUnlimited(t *testing.T) {
+	l := NewModelLimiters(0)
+	config := &models.ModelConfig{ID: 1, Name: "unlimited"}
+	for i := 0; i < 10; i++ {
+		mustAcquire(t, l, config)
+	}
+	if len(l.limiters) != 0 {
+		t.Fatal("created a limiter for a config without limits")
+	}
+
+	var nilLimiters *ModelLimiters
+	if _, err := nilLimiters.Acquire(context.Background(), config); err != nil {
+		t.Fatalf("nil limiters: %v", err)
+	}
+}
+
+func TestModelLimitersNoQueue(t *testing.T) {
+	l := NewModelLimiters(0)
+	config := &models.ModelConfig{ID: 1, Name: "busy", MaxConcurrency: 1}
+	release := mustAcquire(t, l, config)
+
+	_, err := l.Acquire(context.Background(), config)
+	var busy *ModelBusyError
+	if !errors.As(err, &busy) {
+		t.Fatalf("Acquire error = %v, want *ModelBusyError", err)
+	}
+	if busy.ModelConfigID != 1 || busy.RetryAfter != defaultCallDuration {
+		t.Fatalf("busy error = %+v, want retry after %s", busy, defaultCallDuration)
+	}
+
+	// 重复释放不会多出名额
+	release()
+	release()
+	mustAcquire(t, l, config)
+	if _, err := l.Acquire(context.Background(), config); err == nil {
+		t.Fatal("releasing twice freed two slots")
+	}
+}
+
+func TestModelLimitersOrderUnderContention(t *testing.T) {
+	l := NewModelLimiters(5 * time.Second)
+	config := &models.ModelConfig{ID: 1, Name: "serial", MaxConcurrency: 1}
+	release := mustAcquire(t, l, config)
+
+	const waiters = 5
+	results := make([]<-chan acquireResult, waiters)
+	for i := range results {
+		results[i] = acquireAsync(l, context.Background(), config)
+		// 等前一个调用排上队再发起下一个，保证到达顺序
+		waitQueueLen(t, l, config.ID, i+1)
+	}
+
+	release()
+	for i, done := range results {
+		result := receive(t, done)
+		if result.err != nil {
+			t.Fatalf("waiter %d: %v", i, result.err)
+		}
+		// 只有一个名额，后面的调用还在排队说明是按顺序放行的
+		if got := queueLen(l, config.ID); got != waiters-i-1 {
+			t.Fatalf("after granting waiter %d, queue length = %d, want %d", i, got, waiters-i-1)
+		}
+		for j := i + 1; j < waiters; j++ {
+			select {
+			case <-results[j]:
+				t.Fatalf("waiter %d granted before waiter %d released", j, i)
+			default:
+			}
+		}
+		result.release()
+	}
+}
+
+func TestModelLimitersTimeoutRemovesWaiter(t *testing.T) {
+	l := NewModelLimiters(20 * time.Millisecond)
+	config := &models.ModelConfig{ID: 1, Name: "slow", MaxConcurrency: 1}
+	release := mustAcquire(t, l, config)
+
+	_, err := l.Acquire(context.Background(), config)
+	var busy *ModelBusyError
+	if !errors.As(err, &busy) {
+		t.Fatalf("Acquire error = %v, want *ModelBusyError", err)
+	}
+	if got := queueLen(l, config.ID); got != 0 {
+		t.Fatalf("timed out waiter still queued, queue length = %d", got)
+	}
+
+	// 取消的调用同样移出队列，并返回ctx的错误
+	ctx, cancel := context.WithCancel(context.Background())
+	done := acquireAsync(l, ctx, config)
+	waitQueueLen(t, l, config.ID, 1)
+	cancel()
+	if result := receive(t, done); !errors.Is(result.err, context.Canceled) {
+		t.Fatalf("canceled Acquire error = %v, want context.Canceled", result.err)
+	}
+	if got := queueLen(l, config.ID); got != 0 {
+		t.Fatalf("canceled waiter still queued, queue length = %d", got)
+	}
+
+	// 释放后名额没有被已离开的调用占用
+	release()
+	mustAcquire(t, l, config)
+}
+
+func TestModelLimitersGrantDuringTimeout(t *testing.T) {
+	l := NewModelLimiters(20 * time.Millisecond)
+	config := &models.ModelConfig{ID: 1, Name: "race", MaxConcurrency: 1}
+	mustAcquire(t, l, config)
+
+	done := acquireAsync(l, context.Background(), config)
+	waitQueueLen(t, l, config.ID, 1)
+	limiter := l.limiters[config.ID]
+
+	// 持有锁直到排队的调用超时，再在锁内释放名额，使名额在超时处理之前交给排队的调用
+	limiter.mu.Lock()
+	time.Sleep(60 * time.Millisecond)
+	limiter.active--
+	limiter.dispatch(time.Now())
+	limiter.mu.Unlock()
+
+	result := receive(t, done)
+	if result.err != nil {
+		t.Fatalf("waiter granted during timeout got error: %v", result.err)
+	}
+	limiter.mu.Lock()
+	active := limiter.active
+	limiter.mu.Unlock()
+	if active != 1 {
+		t.Fatalf("active = %d, want 1 held by the granted waiter", active)
+	}
+
+	// 名额归还后可以再次获得
+	result.release()
+	mustAcquire(t, l, config)
+}
+
+func TestModelLimitersLoosenLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  models.ModelConfig
+		loosen  func(config *models.ModelConfig)
+		waiters int
+	}{
+		{
+			name:    "raise concurrency",
+			config:  models.ModelConfig{ID: 1, Name: "concurrency", MaxConcurrency: 1},
+			loosen:  func(config *models.ModelConfig) { config.MaxConcurrency = 3 },
+			waiters: 2,
+		},
+		{
+			name:    "remove requests per minute",
+			config:  models.ModelConfig{ID: 2, Name: "rpm", RequestsPerMinute: 1},
+			loosen:  func(config *models.ModelConfig) { config.RequestsPerMinute = 0 },
+			waiters: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewModelLimiters(5 * time.Second)
+			config := tt.config
+			release := mustAcquire(t, l, &config)
+			defer release()
+
+			results := make([]<-chan acquireResult, tt.waiters)
+			for i := range results {
+				results[i] = acquireAsync(l, context.Background(), &config)
+				waitQueueLen(t, l, config.ID, i+1)
+			}
+
+			// 修改配置后下一次Acquire读取新的限制并唤醒排队的调用
+			loosened := config
+			tt.loosen(&loosened)
+			l.get(&loosened)
+			for i, done := range results {
+				result := receive(t, done)
+				if result.err != nil {
+					t.Fatalf("waiter %d: %v", i, result.err)
+				}
+				defer result.release()
+			}
+		})
+	}
+}
+
+func TestModelBusyErrorRetryAfter(t *testing.T) {
+	tests := []struct {
+		retryAfter time.Duration
+		want       int
+	}{
+		{retryAfter: 0, want: 1},
+		{retryAfter: 300 * time.Millisecond, want: 1},
+		{retryAfter: 1500 * time.Millisecond, want: 2},
+		{retryAfter: 30 * time.Second, want: 30},
+	}
+	for _, tt := range tests {
+		if got := (&ModelBusyError{RetryAfter: tt.retryAfter}).RetryAfterSeconds(); got != tt.want {
+			t.Fatalf("RetryAfterSeconds(%s) = %d, want %d", tt.retryAfter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"personatrip/internal/config"
+	"personatrip/internal/models"
+	"personatrip/internal/utils/logger"
+)
+
+// planCacheListLimit 管理接口一次返回的缓存数量上限
+const planCacheListLimit = 200
+
+// budgetTiers 预算等级的常见写法到归一化等级的映射
+var budgetTiers = map[string]string{
+	"经济": "economy", "economy": "economy", "budget": "economy", "low": "economy", "cheap": "economy",
+	"中等": "mid", "mid": "mid", "medium": "mid", "moderate": "mid", "standard": "mid",
+	"豪华": "luxury", "luxury": "luxury", "high": "luxury", "premium": "luxury",
+}
+
+// PlanCacheRepository 旅行计划缓存的存储接口
+type PlanCacheRepository interface {
+	GetPlanCacheEntry(ctx context.Context, hash string) (*models.PlanCacheEntry, error)
+	SavePlanCacheEntry(ctx context.Context, entry *models.PlanCacheEntry) error
+	IncrementPlanCacheHits(ctx context.Context, hash string) error
+	ListPlanCacheEntries(ctx context.Context, destination string, limit int64) ([]*models.PlanCacheEntry, error)
+	DeletePlanCacheEntry(ctx context.Context, hash string) error
+	DeletePlanCacheEntries(ctx context.Context, destination string) (int64, error)
+}
+
+// PlanCacheService 按归一化的请求缓存生成的旅行计划，相似的请求直接复用而不再调用模型
+type PlanCacheService struct {
+	repo PlanCacheRepository
+	cfg  *config.PlanCacheConfig
+}
+
+// NewPlanCacheService 创建新的旅行计划缓存服务
+func NewPlanCacheService(repo PlanCacheRepository, cfg *config.PlanCacheConfig) *PlanCacheService {
+	if cfg == nil {
+		cfg = &config.PlanCacheConfig{}
+	}
+	return &PlanCacheService{
+		repo: repo,
+		cfg:  cfg,
+	}
+}
+
+// Enabled 是否启用缓存，服务为空或有效期为0时不启用
+func (s *PlanCacheService) Enabled() bool {
+	return s != nil && s.cfg.TTL > 0
+}
+
+// Lookup 查找与请求匹配的缓存，命中时返回按请求开始日期重新排期的计划；未命中或查询失败时返回nil
+func (s *PlanCacheService) Lookup(ctx context.Context, req *models.PlanRequest) *models.TripPlan {
+	if !s.Enabled() {
+		return nil
+	}
+
+	hash := hashPlanCacheKey(NormalizePlanRequest(req))
+	entry, err := s.repo.GetPlanCacheEntry(ctx, hash)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			logger.Errorf("查询旅行计划缓存失败: %v", err)
+		}
+		return nil
+	}
+	if err := s.repo.IncrementPlanCacheHits(ctx, hash); err != nil {
+		logger.Errorf("更新旅行计划缓存命中次数失败: %v", err)
+	}
+
+	logger.Infof("命中旅行计划缓存: %s", hash)
+	plan := &entry.Plan
+	redatePlan(plan, req, entry.StartDate)
+	if plan.Generation == nil {
+		plan.Generation = &models.GenerationInfo{}
+	}
+	plan.Generation.Cached = true
+	return plan
+}
+
+// Store 缓存新生成的旅行计划，失败只记录日志
+func (s *PlanCacheService) Store(ctx context.Context, req *models.PlanRequest, plan *models.TripPlan) {
+	if !s.Enabled() {
+		return
+	}
+
+	key := NormalizePlanRequest(req)
+	now := time.Now()
+	entry := &models.PlanCacheEntry{
+		Hash:      hashPlanCacheKey(key),
+		Key:       key,
+		Plan:      *plan,
+		StartDate: req.StartDate,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.cfg.TTL),
+	}
+	// 命中缓存时作为新计划保存，不沿用预先分配的ID
+	entry.Plan.ID = primitive.NilObjectID
+	if err := s.repo.SavePlanCacheEntry(ctx, entry); err != nil {
+		logger.Errorf("保存旅行计划缓存失败: %v", err)
+	}
+}
+
+// List 获取缓存列表，destination不为空时只返回该目的地的缓存
+func (s *PlanCacheService) List(ctx context.Context, destination string) ([]*models.PlanCacheEntry, error) {
+	return s.repo.ListPlanCacheEntries(ctx, normalizeText(destination), planCacheListLimit)
+}
+
+// Invalidate 删除指定摘要的缓存
+func (s *PlanCacheService) Invalidate(ctx context.Context, hash string) error {
+	return s.repo.DeletePlanCacheEntry(ctx, hash)
+}
+
+// InvalidateAll 删除指定目的地的全部缓存，destination为空时清空所有缓存
+func (s *PlanCacheService) InvalidateAll(ctx context.Context, destination string) (int64, error) {
+	return s.repo.DeletePlanCacheEntries(ctx, normalizeText(destination))
+}
+
+// NormalizePlanRequest 归一化旅行计划请求：目的地、预算和偏好统一大小写和写法，偏好去重排序，日期只保留天数
+func NormalizePlanRequest(req *models.PlanRequest) models.PlanCacheKey {
+	budget := normalizeText(req.Budget)
+	if tier, ok := budgetTiers[budget]; ok {
+		budget = tier
+	}
+
+	return models.PlanCacheKey{
+		Destination:     normalizeText(req.Destination),
+		Days:            TripDays(req.StartDate, req.EndDate),
+		Budget:          budget,
+		TravelStyle:     normalizeList(req.TravelStyle),
+		Accommodation:   normalizeList(req.Accommodation),
+		Transportation:  normalizeList(req.Transportation),
+		Activities:      normalizeList(req.Activities),
+		FoodPreferences: normalizeList(req.FoodPreferences),
+		SpecialRequests: normalizeText(req.SpecialRequests),
+	}
+}
+
+// hashPlanCacheKey 计算归一化请求的SHA-256摘要
+func hashPlanCacheKey(key models.PlanCacheKey) string {
+	data, _ := json.Marshal(key)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeText 去除首尾空白，合并连续空白并转为小写
+func normalizeText(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+// normalizeList 归一化每一项后去掉空项和重复项，并排序
+func normalizeList(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		item = normalizeText(item)
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		result = append(result, item)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// TripDays 计算行程天数，只按日期计算，包含首尾两天
+func TripDays(start, end time.Time) int {
+	return int(dateOnly(end).Sub(dateOnly(start)).Hours()/24) + 1
+}
+
+// dateOnly 去掉时间部分，只保留日期
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// redatePlan 将缓存的计划按请求的开始日期重新排期，每天的日期由第几天推算。
+// 天气预报只对生成时的日期有效，开始日期与cachedStart不同时去掉每天的天气，月份不同时同时去掉气候概况
+func redatePlan(plan *models.TripPlan, req *models.PlanRequest, cachedStart time.Time) {
+	start := dateOnly(req.StartDate)
+	dayDate := func(day, index int) string {
+		if day <= 0 {
+			day = index + 1
+		}
+		return start.AddDate(0, 0, day-1).Format("2006-01-02")
+	}
+
+	for i := range plan.Days {
+		plan.Days[i].Date = dayDate(plan.Days[i].Day, i)
+	}
+	for i := range plan.Budget.DailyBreakdown {
+		plan.Budget.DailyBreakdown[i].Date = dayDate(plan.Budget.DailyBreakdown[i].Day, i)
+	}
+	if cached := dateOnly(cachedStart); !cached.Equal(start) {
+		plan.WeatherForecast.DailyForecast = []models.DailyForecast{}
+		for i := range plan.Days {
+			plan.Days[i].Weather = models.DayWeather{}
+		}
+		if cached.Month() != start.Month() {
+			plan.WeatherForecast.ClimateOverview = ""
+		}
+	}
+
+	plan.Destination = req.Destination
+	plan.StartDate = req.StartDate.String()
+	plan.EndDate = req.EndDate.String()
+	plan.Request = copyPlanRequest(req)
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"personatrip/internal/models"
+)
+
+// date 返回UTC零点的日期
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestNormalizePlanRequest(t *testing.T) {
+	base := models.PlanRequest{
+		Destination: "Tokyo",
+		StartDate:   date(2025, 5, 1),
+		EndDate:     date(2025, 5, 3),
+		Budget:      "中等",
+		TravelStyle: []string{"文化", "美食"},
+	}
+	key := NormalizePlanRequest(&base)
+
+	tests := []struct {
+		name string
+		req  models.PlanRequest
+		same bool
+	}{
+		{name: "case and whitespace", same: true, req: models.PlanRequest{
+			Destination: "  TOKYO ", StartDate: base.StartDate, EndDate: base.EndDate, Budget: "Medium", TravelStyle: []string{"美食", " 文化", "美食"},
+		}},
+		{name: "different dates with same length", same: true, req: models.PlanRequest{
+			Destination: "tokyo", StartDate: date(2025, 11, 10), EndDate: date(2025, 11, 12), Budget: "mid", TravelStyle: []string{"文化", "美食"},
+		}},
+		{name: "time of day ignored", same: true, req: models.PlanRequest{
+			Destination: "tokyo", StartDate: date(2025, 5, 1).Add(22 * time.Hour), EndDate: date(2025, 5, 3).Add(time.Hour), Budget: "中等", TravelStyle: []string{"文化", "美食"},
+		}},
+		{name: "different length", same: false, req: models.PlanRequest{
+			Destination: "tokyo", StartDate: base.StartDate, EndDate: date(2025, 5, 4), Budget: "中等", TravelStyle: []string{"文化", "美食"},
+		}},
+		{name: "different budget tier", same: false, req: models.PlanRequest{
+			Destination: "tokyo", StartDate: base.StartDate, EndDate: base.EndDate, Budget: "luxury", TravelStyle: []string{"文化", "美食"},
+		}},
+		{name: "different special requests", same: false, req: models.PlanRequest{
+			Destination: "tokyo", StartDate: base.StartDate, EndDate: base.EndDate, Budget: "中等", TravelStyle: []string{"文化", "美食"}, SpecialRequests: "带小孩",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := NormalizePlanRequest(&tt.req)
+			if same := hashPlanCacheKey(other) == hashPlanCacheKey(key); same != tt.same {
+				t.Fatalf("same hash = %v, want %v\nkey:   %+v\nother: %+v", same, tt.same, key, other)
+			}
+		})
+	}
+
+	if key.Days != 3 || key.Budget != "mid" || !reflect.DeepEqual(key.TravelStyle, []string{"文化", "美食"}) {
+		t.Fatalf("NormalizePlanRequest = %+v", key)
+	}
+	if unknown := NormalizePlanRequest(&models.PlanRequest{Budget: " Backpacker "}); unknown.Budget != "backpacker" {
+		t.Fatalf("unknown budget normalized to %q, want backpacker", unknown.Budget)
+	}
+}
+
+// cachedPlan 返回5月1日开始的两天计划，带有天气
+func cachedPlan() *models.TripPlan {
+	weather := models.DayWeather{Conditions: "晴"}
+	return &models.TripPlan{
+		Days: []models.TripDay{
+			{Day: 1, Date: "2025-05-01", Weather: weather},
+			{Day: 2, Date: "2025-05-02", Weather: weather},
+		},
+		Budget: models.Budget{DailyBreakdown: []models.DailyBudget{{Day: 1}, {Day: 2}}},
+		WeatherForecast: models.WeatherForecast{
+			ClimateOverview: "五月温暖",
+			DailyForecast:   []models.DailyForecast{{Date: "2025-05-01"}, {Date: "2025-05-02"}},
+		},
+	}
+}
+
+func TestRedatePlan(t *testing.T) {
+	cachedStart := date(2025, 5, 1)
+	tests := []struct {
+		name          string
+		start         time.Time
+		dates         []string
+		keepsWeather  bool
+		keepsOverview bool
+	}{
+		{name: "same start date", start: date(2025, 5, 1), dates: []string{"2025-05-01", "2025-05-02"}, keepsWeather: true, keepsOverview: true},
+		{name: "same month", start: date(2025, 5, 20), dates: []string{"2025-05-20", "2025-05-21"}, keepsWeather: false, keepsOverview: true},
+		{name: "different month", start: date(2025, 12, 31), dates: []string{"2025-12-31", "2026-01-01"}, keepsWeather: false, keepsOverview: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := cachedPlan()
+			req := &models.PlanRequest{Destination: "Tokyo", StartDate: tt.start, EndDate: tt.start.AddDate(0, 0, 1)}
+			redatePlan(plan, req, cachedStart)
+
+			for i, day := range plan.Days {
+				if day.Date != tt.dates[i] || plan.Budget.DailyBreakdown[i].Date != tt.dates[i] {
+					t.Fatalf("day %d dated %s / %s, want %s", i+1, day.Date, plan.Budget.DailyBreakdown[i].Date, tt.dates[i])
+				}
+				if hasWeather := day.Weather.Conditions != ""; hasWeather != tt.keepsWeather {
+					t.Fatalf("day %d weather kept = %v, want %v", i+1, hasWeather, tt.keepsWeather)
+				}
+			}
+			if kept := len(plan.WeatherForecast.DailyForecast) > 0; kept != tt.keepsWeather {
+				t.Fatalf("daily forecast kept = %v, want %v", kept, tt.keepsWeather)
+			}
+			if tt.keepsWeather && plan.WeatherForecast.DailyForecast[1].Date != tt.dates[1] {
+				t.Fatalf("forecast dated %s, want %s", plan.WeatherForecast.DailyForecast[1].Date, tt.dates[1])
+			}
+			if kept := plan.WeatherForecast.ClimateOverview != ""; kept != tt.keepsOverview {
+				t.Fatalf("climate overview kept = %v, want %v", kept, tt.keepsOverview)
+			}
+			if plan.Request == nil || plan.Request.Destination != "Tokyo" {
+				t.Fatalf("plan request = %+v, want a copy of the request", plan.Request)
+			}
+		})
+	}
+}
@@ -0,0 +1,615 @@
+package services
+
+// 以下为内置的提示词模板，数据库中没有启用的版本时使用，版本号记为0
+
+// builtinTripPlanPrompt 生成旅行计划的提示词模板，数据为TripPlanPromptData
+const builtinTripPlanPrompt = `
+你是一个专业的旅游规划助手。请为以下旅行需求创建一个详细的旅行计划:
+
+目的地: {{.Destination}}
+开始日期: {{.StartDate}}
+结束日期: {{.EndDate}}
+行程天数: {{.Days}}
+预算: {{.Budget}}
+旅行风格: {{.TravelStyle}}
+住宿偏好: {{.Accommodation}}
+交通偏好: {{.Transportation}}
+活动偏好: {{.Activities}}
+饮食偏好: {{.FoodPreferences}}
+特殊要求: {{.SpecialRequests}}
+
+请提供一个包含以下内容的详细旅行计划:
+1. 每天的行程安排，包括景点、活动、餐饮和住宿
+2. 每个活动的大致时间安排
+3. 每个活动和住宿的估计费用
+4. 交通建议
+5. 当地特色美食推荐
+6. 天气信息和穿衣建议
+7. 当地文化和习俗提示
+8. 安全和健康建议
+9. 必备物品清单
+10. 紧急联系信息
+
+汇率、时差、日期对应的星期和节假日请使用工具查询，不要自行估计。
+
+请以JSON格式返回，格式如下:
+{
+  "title": "旅行计划标题",
+  "destination_info": {
+    "name": "目的地名称",
+    "country": "国家",
+    "language": "当地语言",
+    "currency": "当地货币",
+    "time_zone": "时区",
+    "best_time_to_visit": "最佳旅游时间"
+  },
+  "travel_info": {
+    "visa_required": true/false,
+    "visa_tips": "签证信息",
+    "passport_validity": "护照有效期要求",
+    "vaccination_required": ["疫苗1", "疫苗2"],
+    "local_customs": "当地习俗简介",
+    "etiquette_tips": "礼仪提示",
+    "safety_tips": "安全提示",
+    "health_tips": "健康建议",
+    "electrical_socket_type": "电源插座类型",
+    "internet_availability": "网络可用性说明",
+    "language_phrases": [
+      {"phrase": "你好", "pronunciation": "Ni Hao", "meaning": "Hello"}
+    ]
+  },
+  "weather_forecast": {
+    "climate_overview": "季节性气候概况",
+    "daily_forecast": [
+      {
+        "date": "YYYY-MM-DD",
+        "temperature": {
+          "min": 最低温度,
+          "max": 最高温度,
+          "unit": "摄氏/华氏"
+        },
+        "conditions": "天气状况",
+        "precipitation_chance": 降水几率,
+        "clothing_suggestions": ["穿衣建议1", "穿衣建议2"]
+      }
+    ]
+  },
+  "packing_list": {
+    "essentials": ["必备物品1", "必备物品2"],
+    "clothing": ["衣物1", "衣物2"],
+    "toiletries": ["洗漱用品1", "洗漱用品2"],
+    "electronics": ["电子设备1", "电子设备2"],
+    "documents": ["文档1", "文档2"],
+    "other": ["其他物品1", "其他物品2"]
+  },
+  "emergency_contacts": {
+    "local_emergency": "当地紧急电话",
+    "police": "警察电话",
+    "ambulance": "救护车电话",
+    "fire": "消防电话",
+    "embassy": "使馆信息",
+    "hospitals": [
+      {
+        "name": "医院名称",
+        "address": "地址",
+        "phone": "电话",
+        "has_english_speaking_staff": true/false
+      }
+    ]
+  },
+  "days": [
+    {
+      "day": 1,
+      "date": "YYYY-MM-DD",
+      "weather": {
+        "temperature": {
+          "morning": 早晨温度,
+          "day": 白天温度,
+          "evening": 傍晚温度,
+          "unit": "摄氏/华氏"
+        },
+        "conditions": "天气状况",
+        "clothing_suggestion": "穿衣建议"
+      },
+      "activities": [
+        {
+          "name": "活动名称",
+          "type": "活动类型",
+          "location": {
+            "name": "地点名称",
+            "address": "地址",
+            "city": "城市",
+            "country": "国家",
+            "coordinates": {
+              "latitude": 纬度,
+              "longitude": 经度
+            }
+          },
+          "start_time": "HH:MM",
+          "end_time": "HH:MM",
+          "description": "活动描述",
+          "cost": 费用数值,
+          "booking_required": true/false,
+          "booking_tips": "预订提示",
+          "crowd_level": "人群水平预期",
+          "suitable_weather": "适合的天气条件",
+          "indoor_outdoor": "室内/室外",
+          "accessibility": "无障碍设施情况",
+          "rating": 评分,
+          "photos": ["照片URL1", "照片URL2"],
+          "tips": ["小贴士1", "小贴士2"]
+        }
+      ],
+      "meals": [
+        {
+          "type": "餐食类型",
+          "venue": "餐厅名称",
+          "cuisine": "菜系",
+          "description": "描述",
+          "specialties": ["特色菜1", "特色菜2"],
+          "dietary_options": ["素食", "无麸质"],
+          "address": "地址",
+          "booking_required": true/false,
+          "cost": 费用数值,
+          "tips": "用餐提示"
+        }
+      ],
+      "accommodation": {
+        "name": "住宿名称",
+        "type": "住宿类型",
+        "address": "地址",
+        "description": "描述",
+        "amenities": ["设施1", "设施2"],
+        "check_in": "入住时间",
+        "check_out": "退房时间",
+        "cost": 费用数值,
+        "booking_reference": "预订参考信息",
+        "contact": "联系方式",
+        "nearest_landmarks": ["地标1", "地标2"],
+        "transportation_options": ["交通选项1", "交通选项2"]
+      },
+      "transportation": [
+        {
+          "type": "交通类型",
+          "from": "出发地",
+          "to": "目的地",
+          "departure_time": "出发时间",
+          "arrival_time": "到达时间",
+          "cost": 费用数值,
+          "booking_reference": "预订参考信息",
+          "notes": "交通备注"
+        }
+      ],
+      "tips": ["当天提示1", "当天提示2"]
+    }
+  ],
+  "budget": {
+    "currency": "货币",
+    "exchange_rate": "汇率",
+    "total_estimate": 总预算,
+    "accommodation": 住宿预算,
+    "transportation": 交通预算,
+    "food": 餐饮预算,
+    "activities": 活动预算,
+    "shopping": 购物预算,
+    "other": 其他预算,
+    "daily_breakdown": [
+      {
+        "day": 1,
+        "date": "YYYY-MM-DD",
+        "total": 当天总花费,
+        "details": {
+          "accommodation": 住宿费用,
+          "transportation": 交通费用,
+          "food": 餐饮费用,
+          "activities": 活动费用,
+          "other": 其他费用
+        }
+      }
+    ],
+    "payment_tips": {
+      "credit_cards_accepted": true/false,
+      "atm_availability": "ATM可用性",
+      "tipping_culture": "小费文化",
+      "recommended_payment_methods": ["建议支付方式1", "建议支付方式2"]
+    }
+  },
+  "local_attractions": [
+    {
+      "name": "景点名称",
+      "category": "景点类别",
+      "description": "描述",
+      "must_see": true/false,
+      "address": "地址",
+      "opening_hours": "开放时间",
+      "cost": 费用数值,
+      "time_required": "建议游览时间",
+      "best_time_to_visit": "最佳游览时间",
+      "tips": ["小贴士1", "小贴士2"]
+    }
+  ],
+  "local_cuisine": [
+    {
+      "name": "美食名称",
+      "description": "描述",
+      "must_try": true/false,
+      "where_to_find": ["地点1", "地点2"],
+      "price_range": "价格范围",
+      "photos": ["照片URL1", "照片URL2"]
+    }
+  ],
+  "shopping": {
+    "recommended_items": ["推荐购买物品1", "推荐购买物品2"],
+    "markets_and_malls": [
+      {
+        "name": "商场/市场名称",
+        "type": "类型",
+        "address": "地址",
+        "specialty": "特色",
+        "opening_hours": "营业时间"
+      }
+    ],
+    "souvenirs": ["纪念品1", "纪念品2"]
+  },
+  "cultural_events": [
+    {
+      "name": "文化活动名称",
+      "date": "日期",
+      "description": "描述",
+      "location": "地点",
+      "cost": 费用数值,
+      "tips": "参与提示"
+    }
+  ],
+  "practical_information": {
+    "local_transportation": {
+      "options": ["选项1", "选项2"],
+      "recommended": "推荐方式",
+      "cost": "费用信息",
+      "passes": "交通通行证信息",
+      "apps": ["推荐应用1", "推荐应用2"]
+    },
+    "communication": {
+      "local_sim": "当地SIM卡信息",
+      "wifi_availability": "WiFi可用性",
+      "useful_apps": ["有用的应用1", "有用的应用2"]
+    }
+  },
+  "notes": "额外注意事项",
+  "suggested_modifications": "根据天气或其他因素可能需要的计划调整建议"
+}
+`
+
+// builtinTripSkeletonPrompt 分阶段生成的行程框架提示词模板，数据为TripPlanPromptData
+const builtinTripSkeletonPrompt = `
+你是一个专业的旅游规划助手。请为以下旅行需求规划整体行程框架，之后会按照框架逐天生成详细行程:
+
+目的地: {{.Destination}}
+开始日期: {{.StartDate}}
+结束日期: {{.EndDate}}
+行程天数: {{.Days}}
+预算: {{.Budget}}
+旅行风格: {{.TravelStyle}}
+住宿偏好: {{.Accommodation}}
+交通偏好: {{.Transportation}}
+活动偏好: {{.Activities}}
+饮食偏好: {{.FoodPreferences}}
+特殊要求: {{.SpecialRequests}}
+
+请确定每天的主题、主要游览区域和主要景点，相邻两天的区域尽量连贯，不同日期的景点不要重复。
+days必须包含{{.Days}}天，day从1开始。
+每天是星期几、是否遇上目的地的节假日请使用工具查询，节假日期间的热门景点需要错峰安排或提前预约。
+
+请以JSON格式返回，格式如下:
+{
+  "title": "旅行计划标题",
+  "destination_info": {
+    "name": "目的地名称",
+    "country": "国家",
+    "language": "当地语言",
+    "currency": "当地货币",
+    "time_zone": "时区",
+    "best_time_to_visit": "最佳旅游时间"
+  },
+  "currency": "计划中所有费用使用的货币代码，如CNY",
+  "days": [
+    {
+      "day": 1,
+      "theme": "当天主题",
+      "area": "主要游览区域",
+      "highlights": ["主要景点1", "主要景点2"]
+    }
+  ],
+  "notes": "额外注意事项"
+}
+`
+
+// builtinTripDayPrompt 分阶段生成的单日行程提示词模板，数据为TripDayPromptData
+const builtinTripDayPrompt = `
+你是一个专业的旅游规划助手。请按照已确定的行程框架，为旅行计划《{{.Title}}》生成第{{.Day}}天的详细行程:
+
+目的地: {{.Destination}}
+当天日期: {{.Date}}
+当天主题: {{.Theme}}
+主要区域: {{.Area}}
+主要景点: {{.Highlights}}
+预算: {{.Budget}}
+旅行风格: {{.TravelStyle}}
+住宿偏好: {{.Accommodation}}
+交通偏好: {{.Transportation}}
+活动偏好: {{.Activities}}
+饮食偏好: {{.FoodPreferences}}
+特殊要求: {{.SpecialRequests}}
+
+整个行程的安排如下，请与前后两天衔接，不要安排其他日期的主要景点:
+{{.Outline}}
+
+请只返回这一天的行程，所有费用使用{{.Currency}}，以JSON格式返回，格式如下:
+{
+  "day": {{.Day}},
+  "date": "{{.Date}}",
+  "weather": {
+    "temperature": {
+      "morning": 早晨温度,
+      "day": 白天温度,
+      "evening": 傍晚温度,
+      "unit": "摄氏/华氏"
+    },
+    "conditions": "天气状况",
+    "clothing_suggestion": "穿衣建议"
+  },
+  "activities": [
+    {
+      "name": "活动名称",
+      "type": "活动类型",
+      "location": {
+        "name": "地点名称",
+        "address": "地址",
+        "city": "城市",
+        "country": "国家",
+        "coordinates": {
+          "latitude": 纬度,
+          "longitude": 经度
+        }
+      },
+      "start_time": "HH:MM",
+      "end_time": "HH:MM",
+      "description": "活动描述",
+      "cost": 费用数值,
+      "booking_required": true/false,
+      "booking_tips": "预订提示",
+      "crowd_level": "人群水平预期",
+      "suitable_weather": "适合的天气条件",
+      "indoor_outdoor": "室内/室外",
+      "accessibility": "无障碍设施情况",
+      "rating": 评分,
+      "tips": ["小贴士1", "小贴士2"]
+    }
+  ],
+  "meals": [
+    {
+      "type": "餐食类型",
+      "venue": "餐厅名称",
+      "cuisine": "菜系",
+      "description": "描述",
+      "specialties": ["特色菜1", "特色菜2"],
+      "address": "地址",
+      "booking_required": true/false,
+      "cost": 费用数值,
+      "tips": "用餐提示"
+    }
+  ],
+  "accommodation": {
+    "name": "住宿名称",
+    "type": "住宿类型",
+    "address": "地址",
+    "description": "描述",
+    "amenities": ["设施1", "设施2"],
+    "check_in": "入住时间",
+    "check_out": "退房时间",
+    "cost": 当晚费用数值,
+    "contact": "联系方式",
+    "nearest_landmarks": ["地标1", "地标2"]
+  },
+  "transportation": [
+    {
+      "type": "交通类型",
+      "from": "出发地",
+      "to": "目的地",
+      "departure_time": "出发时间",
+      "arrival_time": "到达时间",
+      "cost": 费用数值,
+      "notes": "交通备注"
+    }
+  ],
+  "tips": ["当天提示1", "当天提示2"]
+}
+`
+
+// builtinTripEssentialsPrompt 分阶段生成的出行信息提示词模板，数据为TripSectionPromptData
+const builtinTripEssentialsPrompt = `
+你是一个专业的旅游规划助手。旅行计划《{{.Title}}》的行程已经确定，请补充出行前需要了解的信息:
+
+目的地: {{.Destination}}
+开始日期: {{.StartDate}}
+结束日期: {{.EndDate}}
+行程天数: {{.Days}}
+预算: {{.Budget}}
+旅行风格: {{.TravelStyle}}
+住宿偏好: {{.Accommodation}}
+交通偏好: {{.Transportation}}
+活动偏好: {{.Activities}}
+饮食偏好: {{.FoodPreferences}}
+特殊要求: {{.SpecialRequests}}
+
+行程安排:
+{{.Outline}}
+
+汇率请使用工具查询，不要自行估计。
+
+请以JSON格式返回，费用使用{{.Currency}}，格式如下:
+{
+  "travel_info": {
+    "visa_required": true/false,
+    "visa_tips": "签证信息",
+    "passport_validity": "护照有效期要求",
+    "vaccination_required": ["疫苗1", "疫苗2"],
+    "local_customs": "当地习俗简介",
+    "etiquette_tips": "礼仪提示",
+    "safety_tips": "安全提示",
+    "health_tips": "健康建议",
+    "electrical_socket_type": "电源插座类型",
+    "internet_availability": "网络可用性说明",
+    "language_phrases": [
+      {"phrase": "你好", "pronunciation": "Ni Hao", "meaning": "Hello"}
+    ]
+  },
+  "weather_forecast": {
+    "climate_overview": "季节性气候概况",
+    "daily_forecast": [
+      {
+        "date": "YYYY-MM-DD",
+        "temperature": {
+          "min": 最低温度,
+          "max": 最高温度,
+          "unit": "摄氏/华氏"
+        },
+        "conditions": "天气状况",
+        "precipitation_chance": 降水几率,
+        "clothing_suggestions": ["穿衣建议1", "穿衣建议2"]
+      }
+    ]
+  },
+  "packing_list": {
+    "essentials": ["必备物品1", "必备物品2"],
+    "clothing": ["衣物1", "衣物2"],
+    "toiletries": ["洗漱用品1", "洗漱用品2"],
+    "electronics": ["电子设备1", "电子设备2"],
+    "documents": ["文档1", "文档2"],
+    "other": ["其他物品1", "其他物品2"]
+  },
+  "emergency_contacts": {
+    "local_emergency": "当地紧急电话",
+    "police": "警察电话",
+    "ambulance": "救护车电话",
+    "fire": "消防电话",
+    "embassy": "使馆信息",
+    "hospitals": [
+      {
+        "name": "医院名称",
+        "address": "地址",
+        "phone": "电话",
+        "has_english_speaking_staff": true/false
+      }
+    ]
+  },
+  "practical_information": {
+    "local_transportation": {
+      "options": ["选项1", "选项2"],
+      "recommended": "推荐方式",
+      "cost": "费用信息",
+      "passes": "交通通行证信息",
+      "apps": ["推荐应用1", "推荐应用2"]
+    },
+    "communication": {
+      "local_sim": "当地SIM卡信息",
+      "wifi_availability": "WiFi可用性",
+      "useful_apps": ["有用的应用1", "有用的应用2"]
+    }
+  },
+  "exchange_rate": "汇率",
+  "payment_tips": {
+    "credit_cards_accepted": true/false,
+    "atm_availability": "ATM可用性",
+    "tipping_culture": "小费文化",
+    "recommended_payment_methods": ["建议支付方式1", "建议支付方式2"]
+  }
+}
+`
+
+// builtinTripLocalGuidePrompt 分阶段生成的当地指南提示词模板，数据为TripSectionPromptData
+const builtinTripLocalGuidePrompt = `
+你是一个专业的旅游规划助手。旅行计划《{{.Title}}》的行程已经确定，请补充目的地的当地指南:
+
+目的地: {{.Destination}}
+开始日期: {{.StartDate}}
+结束日期: {{.EndDate}}
+行程天数: {{.Days}}
+预算: {{.Budget}}
+旅行风格: {{.TravelStyle}}
+住宿偏好: {{.Accommodation}}
+交通偏好: {{.Transportation}}
+活动偏好: {{.Activities}}
+饮食偏好: {{.FoodPreferences}}
+特殊要求: {{.SpecialRequests}}
+
+行程安排:
+{{.Outline}}
+
+请推荐行程之外也值得了解的景点、当地美食、购物去处和旅行期间的文化活动，并给出根据天气或其他因素可能需要的计划调整建议。
+请以JSON格式返回，费用使用{{.Currency}}，格式如下:
+{
+  "local_attractions": [
+    {
+      "name": "景点名称",
+      "category": "景点类别",
+      "description": "描述",
+      "must_see": true/false,
+      "address": "地址",
+      "opening_hours": "开放时间",
+      "cost": 费用数值,
+      "time_required": "建议游览时间",
+      "best_time_to_visit": "最佳游览时间",
+      "tips": ["小贴士1", "小贴士2"]
+    }
+  ],
+  "local_cuisine": [
+    {
+      "name": "美食名称",
+      "description": "描述",
+      "must_try": true/false,
+      "where_to_find": ["地点1", "地点2"],
+      "price_range": "价格范围"
+    }
+  ],
+  "shopping": {
+    "recommended_items": ["推荐购买物品1", "推荐购买物品2"],
+    "markets_and_malls": [
+      {
+        "name": "商场/市场名称",
+        "type": "类型",
+        "address": "地址",
+        "specialty": "特色",
+        "opening_hours": "营业时间"
+      }
+    ],
+    "souvenirs": ["纪念品1", "纪念品2"]
+  },
+  "shopping_budget": 购物预算数值,
+  "cultural_events": [
+    {
+      "name": "文化活动名称",
+      "date": "日期",
+      "description": "描述",
+      "location": "地点",
+      "cost": 费用数值,
+      "tips": "参与提示"
+    }
+  ],
+  "suggested_modifications": "根据天气或其他因素可能需要的计划调整建议"
+}
+`
+
+// builtinRecommendationPrompt 生成目的地推荐的提示词模板，数据为models.UserPreferences
+const builtinRecommendationPrompt = `
+基于以下用户偏好，推荐5个最适合的旅游目的地:
+
+旅行风格: {{.TravelStyle}}
+预算: {{.Budget}}
+住宿偏好: {{.Accommodation}}
+交通偏好: {{.Transportation}}
+活动偏好: {{.Activities}}
+饮食偏好: {{.FoodPreferences}}
+
+请以JSON数组格式返回5个推荐目的地，每个目的地包含名称和简短理由。
+`
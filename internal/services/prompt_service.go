@@ -0,0 +1,437 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/template"
+
+	"gorm.io/gorm"
+	"personatrip/internal/models"
+	"personatrip/internal/repository"
+	"personatrip/internal/utils/logger"
+	"personatrip/pkg/einosdk"
+)
+
+var (
+	// ErrInvalidPromptTemplate 提示词模板名称未知或模板内容无法解析、渲染
+	ErrInvalidPromptTemplate = errors.New("无效的提示词模板")
+
+	// ErrPromptTemplateNotFound 提示词模板版本不存在
+	ErrPromptTemplateNotFound = errors.New("提示词模板不存在")
+
+	// ErrPromptTemplateInUse 提示词模板版本正在启用或被运行中的实验使用，不能删除
+	ErrPromptTemplateInUse = errors.New("提示词模板正在使用")
+)
+
+// TripPlanPromptData 旅行计划提示词模板的数据
+type TripPlanPromptData struct {
+	Destination     string   `json:"destination"`
+	StartDate       string   `json:"start_date"` // 格式为YYYY-MM-DD
+	EndDate         string   `json:"end_date"`
+	Days            int      `json:"days"`
+	Budget          string   `json:"budget"`
+	TravelStyle     []string `json:"travel_style"`
+	Accommodation   []string `json:"accommodation"`
+	Transportation  []string `json:"transportation"`
+	Activities      []string `json:"activities"`
+	FoodPreferences []string `json:"food_preferences"`
+	SpecialRequests string   `json:"special_requests"`
+}
+
+// NewTripPlanPromptData 由旅行计划请求构建模板数据
+func NewTripPlanPromptData(req *models.PlanRequest) *TripPlanPromptData {
+	return &TripPlanPromptData{
+		Destination:     req.Destination,
+		StartDate:       req.StartDate.Format("2006-01-02"),
+		EndDate:         req.EndDate.Format("2006-01-02"),
+		Days:            TripDays(req.StartDate, req.EndDate),
+		Budget:          req.Budget,
+		TravelStyle:     req.TravelStyle,
+		Accommodation:   req.Accommodation,
+		Transportation:  req.Transportation,
+		Activities:      req.Activities,
+		FoodPreferences: req.FoodPreferences,
+		SpecialRequests: req.SpecialRequests,
+	}
+}
+
+// TripDayPromptData 分阶段生成单日行程的提示词模板数据
+type TripDayPromptData struct {
+	TripPlanPromptData
+	Title      string   `json:"title"`
+	Currency   string   `json:"currency"`
+	Outline    string   `json:"outline"` // 整个行程每天的主题，每行一天
+	Day        int      `json:"day"`
+	Date       string   `json:"date"` // 格式为YYYY-MM-DD
+	Theme      string   `json:"theme"`
+	Area       string   `json:"area"`
+	Highlights []string `json:"highlights"`
+}
+
+// TripSectionPromptData 分阶段生成出行信息和当地指南的提示词模板数据
+type TripSectionPromptData struct {
+	TripPlanPromptData
+	Title    string `json:"title"`
+	Currency string `json:"currency"`
+	Outline  string `json:"outline"`
+}
+
+// promptDefinition 一个提示词模板名称的内置内容和数据类型
+type promptDefinition struct {
+	description string
+	variables   []models.PromptVariable
+	builtin     string
+	newData     func() interface{} // 创建空的模板数据，用于校验模板和解析预览数据
+}
+
+// preferenceVariables 用户偏好相关的模板变量
+var preferenceVariables = []models.PromptVariable{
+	{Name: ".Budget", Description: "预算等级"},
+	{Name: ".TravelStyle", Description: "旅行风格列表"},
+	{Name: ".Accommodation", Description: "住宿偏好列表"},
+	{Name: ".Transportation", Description: "交通偏好列表"},
+	{Name: ".Activities", Description: "活动偏好列表"},
+	{Name: ".FoodPreferences", Description: "饮食偏好列表"},
+}
+
+// tripRequestVariables 旅行计划请求相关的模板变量
+var tripRequestVariables = append([]models.PromptVariable{
+	{Name: ".Destination", Description: "目的地"},
+	{Name: ".StartDate", Description: "开始日期，格式为YYYY-MM-DD"},
+	{Name: ".EndDate", Description: "结束日期，格式为YYYY-MM-DD"},
+	{Name: ".Days", Description: "行程天数"},
+	{Name: ".SpecialRequests", Description: "特殊要求"},
+}, preferenceVariables...)
+
+// outlineVariables 行程框架阶段生成的模板变量，用于分阶段生成的后续阶段
+var outlineVariables = []models.PromptVariable{
+	{Name: ".Title", Description: "旅行计划标题"},
+	{Name: ".Currency", Description: "费用使用的货币"},
+	{Name: ".Outline", Description: "整个行程每天的主题，每行一天"},
+}
+
+// promptDefinitions 所有可以在后台管理的提示词模板
+var promptDefinitions = map[string]*promptDefinition{
+	models.PromptTripPlan: {
+		description: "一次生成完整旅行计划的用户提示词",
+		variables:   tripRequestVariables,
+		builtin:     builtinTripPlanPrompt,
+		newData:     func() interface{} { return &TripPlanPromptData{} },
+	},
+	models.PromptTripSkeleton: {
+		description: "分阶段生成旅行计划的第一阶段：行程框架和每天的主题",
+		variables:   tripRequestVariables,
+		builtin:     builtinTripSkeletonPrompt,
+		newData:     func() interface{} { return &TripPlanPromptData{} },
+	},
+	models.PromptTripDay: {
+		description: "分阶段生成旅行计划：按行程框架生成单日详细行程，各天并行生成",
+		variables: append(append([]models.PromptVariable{
+			{Name: ".Day", Description: "第几天"},
+			{Name: ".Date", Description: "当天日期，格式为YYYY-MM-DD"},
+			{Name: ".Theme", Description: "当天主题"},
+			{Name: ".Area", Description: "当天主要游览区域"},
+			{Name: ".Highlights", Description: "当天主要景点列表"},
+		}, outlineVariables...), tripRequestVariables...),
+		builtin: builtinTripDayPrompt,
+		newData: func() interface{} { return &TripDayPromptData{} },
+	},
+	models.PromptTripEssentials: {
+		description: "分阶段生成旅行计划：签证、天气、行李清单、紧急联系和支付等出行信息",
+		variables:   append(append([]models.PromptVariable{}, outlineVariables...), tripRequestVariables...),
+		builtin:     builtinTripEssentialsPrompt,
+		newData:     func() interface{} { return &TripSectionPromptData{} },
+	},
+	models.PromptTripLocalGuide: {
+		description: "分阶段生成旅行计划：当地景点、美食、购物和文化活动",
+		variables:   append(append([]models.PromptVariable{}, outlineVariables...), tripRequestVariables...),
+		builtin:     builtinTripLocalGuidePrompt,
+		newData:     func() interface{} { return &TripSectionPromptData{} },
+	},
+	models.PromptRecommendations: {
+		description: "生成目的地推荐的用户提示词",
+		variables:   preferenceVariables,
+		builtin:     builtinRecommendationPrompt,
+		newData:     func() interface{} { return &models.UserPreferences{} },
+	},
+	models.PromptAgentSystem: {
+		description: "智能体的系统提示词，没有变量",
+		variables:   []models.PromptVariable{},
+		builtin:     einosdk.DefaultSystemPrompt,
+		newData:     func() interface{} { return nil },
+	},
+}
+
+// PromptService 定义提示词模板服务接口
+type PromptService interface {
+	GetDefinitions(ctx context.Context) ([]models.PromptDefinition, error)
+	CreatePromptTemplate(ctx context.Context, req *models.PromptTemplateCreateRequest) (*models.PromptTemplate, error)
+	DeletePromptTemplate(ctx context.Context, id uint) error
+	GetPromptTemplateByID(ctx context.Context, id uint) (*models.PromptTemplate, error)
+	ListPromptTemplates(ctx context.Context, name string) ([]models.PromptTemplate, error)
+	SetActivePromptTemplate(ctx context.Context, id uint) error
+	Preview(ctx context.Context, req *models.PromptPreviewRequest) (*models.PromptPreviewResponse, error)
+	Render(ctx context.Context, name string, data interface{}) (string, int, error)
+	RenderVersion(ctx context.Context, name string, version int, data interface{}) (string, int, error)
+}
+
+// PromptServiceImpl 是提示词模板服务的实现
+type PromptServiceImpl struct {
+	db repository.Database
+}
+
+// NewPromptService 创建新的提示词模板服务
+func NewPromptService(db repository.Database) PromptService {
+	return &PromptServiceImpl{db: db}
+}
+
+// GetDefinitions 获取所有提示词模板名称、可用变量和当前启用的版本
+func (s *PromptServiceImpl) GetDefinitions(ctx context.Context) ([]models.PromptDefinition, error) {
+	names := []string{
+		models.PromptTripPlan,
+		models.PromptTripSkeleton,
+		models.PromptTripDay,
+		models.PromptTripEssentials,
+		models.PromptTripLocalGuide,
+		models.PromptRecommendations,
+		models.PromptAgentSystem,
+	}
+	definitions := make([]models.PromptDefinition, 0, len(names))
+	for _, name := range names {
+		def := promptDefinitions[name]
+		definition := models.PromptDefinition{
+			Name:           name,
+			Description:    def.description,
+			Variables:      def.variables,
+			DefaultContent: def.builtin,
+		}
+
+		active, err := s.db.PromptRepo().GetActive(ctx, name)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if active != nil {
+			definition.ActiveVersion = active.Version
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions, nil
+}
+
+// CreatePromptTemplate 创建提示词模板的新版本，版本号在同名模板内递增，已删除的版本号不会重复使用
+func (s *PromptServiceImpl) CreatePromptTemplate(ctx context.Context, req *models.PromptTemplateCreateRequest) (*models.PromptTemplate, error) {
+	if _, err := parsePromptTemplate(req.Name, req.Content); err != nil {
+		return nil, err
+	}
+
+	latest, err := s.db.PromptRepo().GetLatestVersion(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	prompt := &models.PromptTemplate{
+		Name:        req.Name,
+		Version:     latest + 1,
+		Content:     req.Content,
+		Description: req.Description,
+	}
+	if err := s.db.PromptRepo().Create(ctx, prompt); err != nil {
+		return nil, err
+	}
+
+	if req.Activate {
+		if err := s.db.PromptRepo().SetActive(ctx, prompt.ID); err != nil {
+			return nil, err
+		}
+		prompt.IsActive = true
+	}
+	return prompt, nil
+}
+
+// DeletePromptTemplate 删除提示词模板版本，启用中的版本和运行中的实验使用的版本不能删除
+func (s *PromptServiceImpl) DeletePromptTemplate(ctx context.Context, id uint) error {
+	prompt, err := s.GetPromptTemplateByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if prompt.Name == models.PromptTripPlan {
+		experiment, err := s.db.ExperimentRepo().GetRunning(ctx)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if experiment != nil {
+			for _, variant := range experiment.Variants {
+				if variant.PromptVersion == prompt.Version {
+					return fmt.Errorf("%w: 运行中的实验%s的分组%s使用版本%d", ErrPromptTemplateInUse, experiment.Name, variant.Name, prompt.Version)
+				}
+			}
+		}
+	}
+
+	err = s.db.PromptRepo().Delete(ctx, id)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrPromptTemplateNotFound
+	case errors.Is(err, repository.ErrPromptTemplateActive):
+		return fmt.Errorf("%w: 版本%d正在启用", ErrPromptTemplateInUse, prompt.Version)
+	}
+	return err
+}
+
+// GetPromptTemplateByID 根据ID获取提示词模板版本
+func (s *PromptServiceImpl) GetPromptTemplateByID(ctx context.Context, id uint) (*models.PromptTemplate, error) {
+	prompt, err := s.db.PromptRepo().GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPromptTemplateNotFound
+	}
+	return prompt, err
+}
+
+// ListPromptTemplates 获取提示词模板版本列表，name不为空时只返回该名称的版本
+func (s *PromptServiceImpl) ListPromptTemplates(ctx context.Context, name string) ([]models.PromptTemplate, error) {
+	return s.db.PromptRepo().List(ctx, name)
+}
+
+// SetActivePromptTemplate 启用指定版本，之后的生成立即使用该版本
+func (s *PromptServiceImpl) SetActivePromptTemplate(ctx context.Context, id uint) error {
+	err := s.db.PromptRepo().SetActive(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrPromptTemplateNotFound
+	}
+	return err
+}
+
+// Preview 使用给定的数据渲染模板，可以预览已保存的版本、未保存的内容或当前启用的版本
+func (s *PromptServiceImpl) Preview(ctx context.Context, req *models.PromptPreviewRequest) (*models.PromptPreviewResponse, error) {
+	def, ok := promptDefinitions[req.Name]
+	if !ok {
+		return nil, fmt.Errorf("%w: 未知的模板名称 %s", ErrInvalidPromptTemplate, req.Name)
+	}
+
+	version := 0
+	content := req.Content
+	switch {
+	case req.ID != 0:
+		prompt, err := s.GetPromptTemplateByID(ctx, req.ID)
+		if err != nil {
+			return nil, err
+		}
+		if prompt.Name != req.Name {
+			return nil, fmt.Errorf("%w: 版本%d不属于模板%s", ErrInvalidPromptTemplate, req.ID, req.Name)
+		}
+		version, content = prompt.Version, prompt.Content
+	case content == "":
+		var err error
+		content, version, err = s.activeContent(ctx, req.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data := def.newData()
+	if len(req.Data) > 0 && data != nil {
+		if err := json.Unmarshal(req.Data, data); err != nil {
+			return nil, fmt.Errorf("%w: 无效的模板数据: %v", ErrInvalidPromptTemplate, err)
+		}
+	}
+
+	rendered, err := renderPromptTemplate(req.Name, content, data)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PromptPreviewResponse{Version: version, Rendered: rendered}, nil
+}
+
+// Render 使用当前启用的版本渲染提示词，返回渲染结果和版本号，没有启用的版本时使用内置模板，版本号为0
+func (s *PromptServiceImpl) Render(ctx context.Context, name string, data interface{}) (string, int, error) {
+	content, version, err := s.activeContent(ctx, name)
+	if err != nil {
+		return "", 0, err
+	}
+
+	rendered, err := renderPromptTemplate(name, content, data)
+	if err != nil {
+		return "", 0, err
+	}
+	return rendered, version, nil
+}
+
+// RenderVersion 使用指定版本渲染提示词，version为0时使用当前启用的版本
+func (s *PromptServiceImpl) RenderVersion(ctx context.Context, name string, version int, data interface{}) (string, int, error) {
+	if version == 0 {
+		return s.Render(ctx, name, data)
+	}
+
+	prompt, err := s.db.PromptRepo().GetByVersion(ctx, name, version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", 0, fmt.Errorf("%w: %s版本%d", ErrPromptTemplateNotFound, name, version)
+		}
+		return "", 0, err
+	}
+
+	rendered, err := renderPromptTemplate(name, prompt.Content, data)
+	if err != nil {
+		return "", 0, err
+	}
+	return rendered, version, nil
+}
+
+// activeContent 获取当前启用版本的内容，查询失败时记录日志并使用内置模板，避免影响生成
+func (s *PromptServiceImpl) activeContent(ctx context.Context, name string) (string, int, error) {
+	def, ok := promptDefinitions[name]
+	if !ok {
+		return "", 0, fmt.Errorf("%w: 未知的模板名称 %s", ErrInvalidPromptTemplate, name)
+	}
+
+	active, err := s.db.PromptRepo().GetActive(ctx, name)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Errorf("获取启用的提示词模板 %s 失败，使用内置模板: %v", name, err)
+		}
+		return def.builtin, 0, nil
+	}
+	return active.Content, active.Version, nil
+}
+
+// renderBuiltinPrompt 使用内置模板渲染提示词，未配置提示词服务时使用
+func renderBuiltinPrompt(name string, data interface{}) (string, error) {
+	def, ok := promptDefinitions[name]
+	if !ok {
+		return "", fmt.Errorf("%w: 未知的模板名称 %s", ErrInvalidPromptTemplate, name)
+	}
+	return renderPromptTemplate(name, def.builtin, data)
+}
+
+// parsePromptTemplate 解析模板，并用空数据试渲染一次，提前发现引用了不存在的变量等错误
+func parsePromptTemplate(name, content string) (*template.Template, error) {
+	def, ok := promptDefinitions[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: 未知的模板名称 %s", ErrInvalidPromptTemplate, name)
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPromptTemplate, err)
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, def.newData()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPromptTemplate, err)
+	}
+	return tmpl, nil
+}
+
+// renderPromptTemplate 解析并渲染模板
+func renderPromptTemplate(name, content string, data interface{}) (string, error) {
+	tmpl, err := parsePromptTemplate(name, content)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPromptTemplate, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"personatrip/internal/config"
+	"personatrip/internal/models"
+	"personatrip/internal/repository"
+	"personatrip/internal/utils/logger"
+	"personatrip/pkg/einosdk"
+)
+
+const (
+	// traceListLimit 管理接口一次返回的记录数量上限
+	traceListLimit = 100
+	// traceMaxText 记录中单个文本字段保存的最大字符数，避免工具返回的大段结果超过文档大小限制
+	traceMaxText = 32 * 1024
+)
+
+// GenerationTraceRepository 生成过程记录的存储接口
+type GenerationTraceRepository interface {
+	SaveGenerationTrace(ctx context.Context, trace *models.GenerationTrace) error
+	GetGenerationTrace(ctx context.Context, id primitive.ObjectID) (*models.GenerationTrace, error)
+	ListGenerationTraces(ctx context.Context, userID string, failedOnly bool, limit int64) ([]*models.GenerationTrace, error)
+	GetTripPlanByID(ctx context.Context, id primitive.ObjectID) (*models.TripPlan, error)
+}
+
+// TraceService 保存每次生成旅行计划的模型调用、推理内容和工具调用，供管理员排查生成质量问题
+type TraceService struct {
+	repo GenerationTraceRepository
+	cfg  *config.TraceConfig
+}
+
+// NewTraceService 创建新的生成过程记录服务
+func NewTraceService(repo GenerationTraceRepository, cfg *config.TraceConfig) *TraceService {
+	if cfg == nil {
+		cfg = &config.TraceConfig{}
+	}
+	return &TraceService{
+		repo: repo,
+		cfg:  cfg,
+	}
+}
+
+// Enabled 是否记录生成过程，服务为空或保存时间为0时不记录
+func (s *TraceService) Enabled() bool {
+	return s != nil && s.cfg.TTL > 0
+}
+
+// Start 开始记录一次旅行计划生成，返回附加了记录器的context；未启用时返回原context和nil
+func (s *TraceService) Start(ctx context.Context, req *models.PlanRequest) (context.Context, *traceRecorder) {
+	if !s.Enabled() {
+		return ctx, nil
+	}
+	recorder := &traceRecorder{
+		request: *req,
+		started: time.Now(),
+	}
+	return context.WithValue(ctx, traceRecorderKey{}, recorder), recorder
+}
+
+// Finish 保存生成过程记录，失败只记录日志。生成成功时预先为计划分配ID，
+// 记录和计划互相关联，保存计划时沿用该ID
+func (s *TraceService) Finish(ctx context.Context, recorder *traceRecorder, plan *models.TripPlan, err error) {
+	if !s.Enabled() || recorder == nil {
+		return
+	}
+
+	info := CallInfoFromContext(ctx)
+	now := time.Now()
+	trace := &models.GenerationTrace{
+		ID:          primitive.NewObjectID(),
+		UserID:      info.UserID,
+		Endpoint:    info.Endpoint,
+		Destination: recorder.request.Destination,
+		Request:     recorder.request,
+		Success:     err == nil,
+		Calls:       recorder.finishedCalls(),
+		LatencyMs:   now.Sub(recorder.started).Milliseconds(),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.cfg.TTL),
+	}
+	if err != nil {
+		trace.Error = err.Error()
+	}
+	if plan != nil {
+		if plan.ID.IsZero() {
+			plan.ID = primitive.NewObjectID()
+		}
+		trace.PlanID = &plan.ID
+		if plan.Generation != nil {
+			plan.Generation.TraceID = &trace.ID
+		}
+	}
+
+	// 请求可能已被取消，记录仍然需要保存
+	if err := s.repo.SaveGenerationTrace(context.WithoutCancel(ctx), trace); err != nil {
+		logger.Errorf("保存生成过程记录失败: %v", err)
+		if plan != nil && plan.Generation != nil {
+			plan.Generation.TraceID = nil
+		}
+	}
+}
+
+// Get 获取生成过程记录
+func (s *TraceService) Get(ctx context.Context, id primitive.ObjectID) (*models.GenerationTrace, error) {
+	return s.repo.GetGenerationTrace(ctx, id)
+}
+
+// GetByPlanID 获取生成旅行计划时的记录，命中缓存的计划返回最初生成时的记录，
+// 计划不存在或没有记录时返回repository.ErrNotFound
+func (s *TraceService) GetByPlanID(ctx context.Context, planID primitive.ObjectID) (*models.GenerationTrace, error) {
+	plan, err := s.repo.GetTripPlanByID(ctx, planID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	if plan.Generation == nil || plan.Generation.TraceID == nil {
+		return nil, repository.ErrNotFound
+	}
+	return s.repo.GetGenerationTrace(ctx, *plan.Generation.TraceID)
+}
+
+// List 获取最近的记录，不包含每次调用的详情；userID不为空时只返回该用户的记录，failedOnly为true时只返回失败的记录
+func (s *TraceService) List(ctx context.Context, userID string, failedOnly bool) ([]*models.GenerationTrace, error) {
+	return s.repo.ListGenerationTraces(ctx, userID, failedOnly, traceListLimit)
+}
+
+// traceRecorderKey 是生成过程记录器在context中的键
+type traceRecorderKey struct{}
+
+// traceStageKey 是分阶段生成时阶段名称在context中的键
+type traceStageKey struct{}
+
+// traceRecorder 收集一次旅行计划生成中的每次模型调用，并行的阶段可以同时写入
+type traceRecorder struct {
+	request models.PlanRequest
+	started time.Time
+
+	mu    sync.Mutex
+	calls []*tracedCall
+}
+
+// tracedCall 正在记录的一次模型调用
+type tracedCall struct {
+	recorder *traceRecorder
+	trace    *einosdk.Trace
+	call     models.TraceCall
+}
+
+// withTraceStage 在context中附加阶段名称，之后的模型调用记录在该阶段下
+func withTraceStage(ctx context.Context, stage string) context.Context {
+	return context.WithValue(ctx, traceStageKey{}, stage)
+}
+
+// startTraceCall 开始记录一次模型调用，返回的context用于调用模型；ctx中没有记录器时返回原context和nil
+func startTraceCall(ctx context.Context, config *models.ModelConfig, req *einosdk.GenerateTextRequest, repair int) (context.Context, *tracedCall) {
+	recorder, _ := ctx.Value(traceRecorderKey{}).(*traceRecorder)
+	if recorder == nil {
+		return ctx, nil
+	}
+
+	stage, _ := ctx.Value(traceStageKey{}).(string)
+	call := &tracedCall{
+		recorder: recorder,
+		trace:    einosdk.NewTrace(),
+		call: models.TraceCall{
+			Stage:           stage,
+			ModelConfigID:   config.ID,
+			ModelConfigName: config.Name,
+			ModelType:       config.ModelType,
+			ModelName:       config.ModelName,
+			Repair:          repair,
+			Prompt:          req.Prompt,
+			StartedAt:       time.Now(),
+		},
+	}
+	recorder.mu.Lock()
+	recorder.calls = append(recorder.calls, call)
+	recorder.mu.Unlock()
+	return einosdk.WithTrace(ctx, call.trace), call
+}
+
+// end 结束记录，err为调用失败或输出未通过校验的原因
+func (c *tracedCall) end(response *einosdk.GenerateTextResponse, err error) {
+	if c == nil {
+		return
+	}
+	c.recorder.mu.Lock()
+	defer c.recorder.mu.Unlock()
+	c.call.LatencyMs = time.Since(c.call.StartedAt).Milliseconds()
+	if response != nil {
+		c.call.Output = response.Text
+		c.call.Usage = response.Usage
+	}
+	if err != nil {
+		c.call.Error = err.Error()
+	}
+}
+
+// finishedCalls 返回按开始时间排序的调用，文本字段超过traceMaxText时截断
+func (r *traceRecorder) finishedCalls() []models.TraceCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calls := make([]models.TraceCall, 0, len(r.calls))
+	for _, c := range r.calls {
+		call := c.call
+		call.Prompt = truncateTraceText(call.Prompt)
+		call.Output = truncateTraceText(call.Output)
+		call.Steps = c.trace.Steps()
+		for i := range call.Steps {
+			step := &call.Steps[i]
+			step.Content = truncateTraceText(step.Content)
+			step.Reasoning = truncateTraceText(step.Reasoning)
+			step.Arguments = truncateTraceText(step.Arguments)
+			step.Result = truncateTraceText(step.Result)
+		}
+		calls = append(calls, call)
+	}
+	sort.SliceStable(calls, func(i, j int) bool {
+		return calls[i].StartedAt.Before(calls[j].StartedAt)
+	})
+	return calls
+}
+
+// truncateTraceText 截断超过traceMaxText个字符的文本
+func truncateTraceText(text string) string {
+	if len(text) <= traceMaxText {
+		return text
+	}
+	runes := []rune(text)
+	if len(runes) <= traceMaxText {
+		return text
+	}
+	return string(runes[:traceMaxText]) + "...(已截断)"
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"personatrip/internal/config"
+	"personatrip/internal/models"
+	"personatrip/internal/utils/logger"
+)
+
+// tripJobEndpoint 异步任务产生的模型调用在用量记录中的来源
+const tripJobEndpoint = "trip_job"
+
+// tripJobRecoverInterval 检查遗留任务的间隔
+const tripJobRecoverInterval = time.Minute
+
+// tripJobStaleGrace 任务超过执行时限后再等待多久才认为执行它的进程已经退出
+const tripJobStaleGrace = time.Minute
+
+// ErrJobQueueFull 任务队列已满
+var ErrJobQueueFull = errors.New("trip job queue is full")
+
+// TripPlanGenerator 生成旅行计划的接口，由EinoService实现
+type TripPlanGenerator interface {
+	GenerateTripPlan(ctx context.Context, req *models.PlanRequest) (*models.TripPlan, error)
+}
+
+// TripJobRepository 旅行计划生成任务的存储接口
+type TripJobRepository interface {
+	CreateTripJob(ctx context.Context, job *models.TripJob) error
+	GetTripJobByID(ctx context.Context, id primitive.ObjectID) (*models.TripJob, error)
+	GetTripJobsByStatus(ctx context.Context, statuses ...models.TripJobStatus) ([]*models.TripJob, error)
+	UpdateTripJob(ctx context.Context, job *models.TripJob) error
+	ClaimTripJob(ctx context.Context, id primitive.ObjectID) (*models.TripJob, error)
+	RequeueStaleTripJob(ctx context.Context, id primitive.ObjectID, updatedBefore time.Time) (bool, error)
+	CreateTripPlan(ctx context.Context, plan *models.TripPlan) (*models.TripPlan, error)
+}
+
+// TripJobService 使用有界的worker池异步执行旅行计划生成任务
+type TripJobService struct {
+	generator TripPlanGenerator
+	repo      TripJobRepository
+	cfg       *config.JobConfig
+	queue     chan primitive.ObjectID
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewTripJobService 创建新的任务服务，需要调用Start启动worker
+func NewTripJobService(generator TripPlanGenerator, repo TripJobRepository, cfg *config.JobConfig) *TripJobService {
+	if cfg == nil {
+		cfg = &config.JobConfig{}
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Minute
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+
+	return &TripJobService{
+		generator: generator,
+		repo:      repo,
+		cfg:       cfg,
+		queue:     make(chan primitive.ObjectID, cfg.QueueSize),
+	}
+}
+
+// Start 启动worker池，重新入队上次进程退出时未完成的任务，并定期接管已退出的实例遗留的任务
+func (s *TripJobService) Start(ctx context.Context) error {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	for i := 0; i < s.cfg.Workers; i++ {
+		s.wg.Add(1)
+		go s.worker(ctx)
+	}
+	logger.Infof("旅行计划任务服务已启动, worker数量: %d", s.cfg.Workers)
+
+	if err := s.recover(ctx, true); err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(tripJobRecoverInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.recover(ctx, false); err != nil {
+					logger.Errorf("检查遗留的旅行计划任务失败: %v", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop 停止接收新任务并等待正在执行的任务退出
+// 被中断的任务重置为queued状态，下次启动时或由其他实例重新执行
+func (s *TripJobService) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// Submit 提交一个新的旅行计划生成任务
+func (s *TripJobService) Submit(ctx context.Context, userID primitive.ObjectID, req *models.PlanRequest) (*models.TripJob, error) {
+	job := &models.TripJob{
+		UserID:  userID,
+		Status:  models.TripJobQueued,
+		Request: *req,
+	}
+	if err := s.repo.CreateTripJob(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to create trip job: %w", err)
+	}
+
+	select {
+	case s.queue <- job.ID:
+		return job, nil
+	default:
+		// 队列已满，直接将任务标记为失败，避免客户端无限等待
+		s.finish(ctx, job, nil, ErrJobQueueFull)
+		return nil, ErrJobQueueFull
+	}
+}
+
+// GetJob 获取任务状态
+func (s *TripJobService) GetJob(ctx context.Context, id primitive.ObjectID) (*models.TripJob, error) {
+	return s.repo.GetTripJobByID(ctx, id)
+}
+
+// recover 重新入队未完成的任务。超过执行时限仍未更新的queued和running任务说明执行或排队它的进程已经退出，
+// 原子地重置为queued后入队，多个实例同时检查时只有一个接管；startup为true时还会入队所有queued任务，
+// 包括上次关闭时被中断和尚未执行的任务。同一个任务被多次入队时只有一个worker能领取
+func (s *TripJobService) recover(ctx context.Context, startup bool) error {
+	jobs, err := s.repo.GetTripJobsByStatus(ctx, models.TripJobQueued, models.TripJobRunning)
+	if err != nil {
+		return fmt.Errorf("failed to load unfinished trip jobs: %w", err)
+	}
+
+	staleBefore := time.Now().Add(-(s.cfg.Timeout + tripJobStaleGrace))
+	ids := make([]primitive.ObjectID, 0, len(jobs))
+	for _, job := range jobs {
+		if startup && job.Status == models.TripJobQueued {
+			ids = append(ids, job.ID)
+			continue
+		}
+		if !job.UpdatedAt.Before(staleBefore) {
+			continue
+		}
+		requeued, err := s.repo.RequeueStaleTripJob(ctx, job.ID, staleBefore)
+		if err != nil {
+			logger.Errorf("重置任务 %s 状态失败: %v", job.ID.Hex(), err)
+			continue
+		}
+		if requeued {
+			ids = append(ids, job.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	logger.Infof("恢复 %d 个未完成的旅行计划任务", len(ids))
+
+	// 恢复的任务可能多于队列长度，在后台逐个入队
+	go func() {
+		for _, id := range ids {
+			select {
+			case s.queue <- id:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// worker 从队列中取出任务并执行
+func (s *TripJobService) worker(ctx context.Context) {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-s.queue:
+			s.run(ctx, id)
+		}
+	}
+}
+
+// run 领取并执行单个任务
+func (s *TripJobService) run(ctx context.Context, id primitive.ObjectID) {
+	job, err := s.repo.ClaimTripJob(ctx, id)
+	if err != nil {
+		// 已被其他worker或实例领取，或者已经结束
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			logger.Errorf("领取任务 %s 失败: %v", id.Hex(), err)
+		}
+		return
+	}
+
+	if job.Attempts > s.cfg.MaxAttempts {
+		s.finish(ctx, job, nil, fmt.Errorf("exceeded max attempts (%d)", s.cfg.MaxAttempts))
+		return
+	}
+
+	logger.Infof("开始执行旅行计划任务 %s (第%d次)", job.ID.Hex(), job.Attempts)
+	jobCtx, cancel := context.WithTimeout(WithCallInfo(ctx, job.UserID.Hex(), tripJobEndpoint), s.cfg.Timeout)
+	defer cancel()
+
+	plan, err := s.generator.GenerateTripPlan(jobCtx, &job.Request)
+	if err != nil && ctx.Err() != nil {
+		// 服务关闭导致的中断不记为失败，重置为queued以便重启后或由其他实例继续执行
+		logger.Warnf("服务关闭，任务 %s 将在重启后继续执行", job.ID.Hex())
+		job.Status = models.TripJobQueued
+		if err := s.repo.UpdateTripJob(context.WithoutCancel(ctx), job); err != nil {
+			logger.Errorf("重置任务 %s 状态失败: %v", job.ID.Hex(), err)
+		}
+		return
+	}
+
+	// 计划已经生成，服务正在关闭时也要保存计划并记录结果，否则重启后会再生成一份
+	saveCtx := context.WithoutCancel(jobCtx)
+	if err == nil {
+		plan.UserID = job.UserID
+		if plan.Title == "" {
+			plan.Title = job.Request.Destination + " Trip " + time.Now().Format("2006-01-02")
+		}
+		plan, err = s.repo.CreateTripPlan(saveCtx, plan)
+	}
+
+	s.finish(saveCtx, job, plan, err)
+}
+
+// finish 记录任务的最终结果
+func (s *TripJobService) finish(ctx context.Context, job *models.TripJob, plan *models.TripPlan, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+	if err != nil {
+		logger.Errorf("旅行计划任务 %s 失败: %v", job.ID.Hex(), err)
+		job.Status = models.TripJobFailed
+		job.Error = err.Error()
+	} else {
+		logger.Infof("旅行计划任务 %s 完成, 计划ID: %s", job.ID.Hex(), plan.ID.Hex())
+		job.Status = models.TripJobSucceeded
+		job.Error = ""
+		job.PlanID = &plan.ID
+	}
+
+	if err := s.repo.UpdateTripJob(ctx, job); err != nil {
+		logger.Errorf("保存任务 %s 结果失败: %v", job.ID.Hex(), err)
+	}
+}
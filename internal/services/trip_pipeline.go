@@ -0,0 +1,527 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"personatrip/internal/models"
+	"personatrip/internal/toolpolicy"
+	"personatrip/pkg/einosdk"
+)
+
+// stageMaxTokens 分阶段生成时每个阶段的最大输出token数
+const stageMaxTokens = 4000
+
+// pipelineOutlineKey 并行阶段中原样传递行程框架的输出键
+const pipelineOutlineKey = "outline"
+
+// ErrInvalidTripDay 要重新生成的天数不在行程范围内
+var ErrInvalidTripDay = errors.New("天数不在行程范围内")
+
+// 分阶段生成各阶段输出的Schema，名称与对应的提示词模板相同
+var (
+	skeletonOutput   = newOutputSchema(models.PromptTripSkeleton, &tripSkeleton{})
+	dayOutput        = newOutputSchema(models.PromptTripDay, &models.TripDay{})
+	essentialsOutput = newOutputSchema(models.PromptTripEssentials, &tripEssentials{})
+	localGuideOutput = newOutputSchema(models.PromptTripLocalGuide, &tripLocalGuide{})
+)
+
+// tripSkeleton 行程框架阶段的输出
+type tripSkeleton struct {
+	Title           string                 `json:"title" jsonschema:"required"`
+	DestinationInfo models.DestinationInfo `json:"destination_info" jsonschema:"required"`
+	Currency        string                 `json:"currency" jsonschema:"required"` // 计划中所有费用使用的货币
+	Days            []skeletonDay          `json:"days" jsonschema:"required"`
+	Notes           string                 `json:"notes"`
+}
+
+// skeletonDay 行程框架中的一天
+type skeletonDay struct {
+	Day        int      `json:"day" jsonschema:"required"`
+	Theme      string   `json:"theme" jsonschema:"required"`
+	Area       string   `json:"area"`
+	Highlights []string `json:"highlights"`
+}
+
+// tripEssentials 出行信息阶段的输出
+type tripEssentials struct {
+	TravelInfo        models.TravelInfo        `json:"travel_info" jsonschema:"required"`
+	WeatherForecast   models.WeatherForecast   `json:"weather_forecast" jsonschema:"required"`
+	PackingList       models.PackingList       `json:"packing_list" jsonschema:"required"`
+	EmergencyContacts models.EmergencyContacts `json:"emergency_contacts" jsonschema:"required"`
+	PracticalInfo     models.PracticalInfo     `json:"practical_information"`
+	ExchangeRate      string                   `json:"exchange_rate"`
+	PaymentTips       models.PaymentTips       `json:"payment_tips"`
+}
+
+// tripLocalGuide 当地指南阶段的输出
+type tripLocalGuide struct {
+	LocalAttractions       []models.LocalAttraction `json:"local_attractions" jsonschema:"required"`
+	LocalCuisine           []models.LocalCuisine    `json:"local_cuisine" jsonschema:"required"`
+	Shopping               models.Shopping          `json:"shopping"`
+	ShoppingBudget         float64                  `json:"shopping_budget"`
+	CulturalEvents         []models.CulturalEvent   `json:"cultural_events"`
+	SuggestedModifications string                   `json:"suggested_modifications"`
+}
+
+// tripOutline 行程框架阶段完成后传给各并行阶段的输入
+type tripOutline struct {
+	skeleton *tripSkeleton
+	data     *TripSectionPromptData
+}
+
+// day 获取行程框架中第day天的安排，框架缺少这一天时返回只有天数的空安排
+func (o *tripOutline) day(day int) skeletonDay {
+	for _, d := range o.skeleton.Days {
+		if d.Day == day {
+			return d
+		}
+	}
+	return skeletonDay{Day: day}
+}
+
+// tripPipeline 一次分阶段生成旅行计划的过程：先生成行程框架，再并行生成每天的行程、
+// 出行信息和当地指南，最后合并、计算预算并按完整计划的Schema校验
+type tripPipeline struct {
+	service      *EinoService
+	req          *models.PlanRequest
+	chain        []models.ModelConfig
+	systemPrompt string
+	tools        []tool.BaseTool
+	emit         einosdk.StreamHandler // 为空时不推送事件
+	cancel       context.CancelFunc    // 一个阶段失败后取消其他阶段
+
+	mu         sync.Mutex
+	generation *models.GenerationInfo
+	err        error // 第一个失败阶段的错误
+}
+
+// generateStaged 分阶段并行生成旅行计划，每个阶段独立按故障切换链调用模型。
+// 流式生成时只推送工具调用、故障切换、修正和阶段完成事件，不推送各阶段交错的增量输出
+func (s *EinoService) generateStaged(ctx context.Context, req *models.PlanRequest, chain []models.ModelConfig, handler einosdk.StreamHandler) (*models.TripPlan, *models.GenerationInfo, error) {
+	systemPrompt, _, err := s.renderPrompt(ctx, models.PromptAgentSystem, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p := &tripPipeline{
+		service:      s,
+		req:          req,
+		chain:        chain,
+		systemPrompt: systemPrompt,
+		tools:        s.agentTools(ctx, toolpolicy.UseCasePlan),
+		emit:         stageEmitter(handler),
+		cancel:       cancel,
+	}
+
+	runnable, err := p.compile(ctx, TripDays(req.StartDate, req.EndDate))
+	if err != nil {
+		return nil, nil, fmt.Errorf("构建生成流程失败: %w", err)
+	}
+	plan, err := runnable.Invoke(ctx, NewTripPlanPromptData(req))
+	if err != nil {
+		// 编排框架的错误不支持errors.As，返回阶段的原始错误，以便调用方识别模型繁忙等错误
+		if stageErr := p.firstError(); stageErr != nil {
+			return nil, nil, stageErr
+		}
+		return nil, nil, err
+	}
+	return plan, p.generation, nil
+}
+
+// compile 构建生成流程：行程框架 -> 并行的每天行程、出行信息和当地指南 -> 合并
+func (p *tripPipeline) compile(ctx context.Context, days int) (compose.Runnable[*TripPlanPromptData, *models.TripPlan], error) {
+	parallel := compose.NewParallel().AddPassthrough(pipelineOutlineKey)
+	for day := 1; day <= days; day++ {
+		parallel.AddLambda(dayStageKey(day), compose.InvokableLambda(p.dayStage(day)))
+	}
+	parallel.AddLambda(models.PromptTripEssentials, compose.InvokableLambda(p.essentialsStage))
+	parallel.AddLambda(models.PromptTripLocalGuide, compose.InvokableLambda(p.localGuideStage))
+
+	return compose.NewChain[*TripPlanPromptData, *models.TripPlan]().
+		AppendLambda(compose.InvokableLambda(p.skeletonStage)).
+		AppendParallel(parallel).
+		AppendLambda(compose.InvokableLambda(p.merge)).
+		Compile(ctx)
+}
+
+// skeletonStage 生成行程框架
+func (p *tripPipeline) skeletonStage(ctx context.Context, data *TripPlanPromptData) (*tripOutline, error) {
+	var skeleton tripSkeleton
+	if err := p.runStage(ctx, "行程框架", skeletonOutput, data, &skeleton); err != nil {
+		return nil, err
+	}
+
+	outline := &tripOutline{skeleton: &skeleton}
+	lines := make([]string, 0, data.Days)
+	for day := 1; day <= data.Days; day++ {
+		d := outline.day(day)
+		line := fmt.Sprintf("第%d天(%s): %s", day, p.date(day), d.Theme)
+		if d.Area != "" {
+			line += "，" + d.Area
+		}
+		if len(d.Highlights) > 0 {
+			line += "，" + strings.Join(d.Highlights, "、")
+		}
+		lines = append(lines, line)
+	}
+
+	outline.data = &TripSectionPromptData{
+		TripPlanPromptData: *data,
+		Title:              skeleton.Title,
+		Currency:           skeleton.Currency,
+		Outline:            strings.Join(lines, "\n"),
+	}
+	return outline, nil
+}
+
+// dayStage 返回生成第day天行程的阶段
+func (p *tripPipeline) dayStage(day int) func(ctx context.Context, outline *tripOutline) (*models.TripDay, error) {
+	return func(ctx context.Context, outline *tripOutline) (*models.TripDay, error) {
+		d := outline.day(day)
+		data := &TripDayPromptData{
+			TripPlanPromptData: outline.data.TripPlanPromptData,
+			Title:              outline.data.Title,
+			Currency:           outline.data.Currency,
+			Outline:            outline.data.Outline,
+			Day:                day,
+			Date:               p.date(day),
+			Theme:              d.Theme,
+			Area:               d.Area,
+			Highlights:         d.Highlights,
+		}
+
+		var tripDay models.TripDay
+		if err := p.runStage(ctx, fmt.Sprintf("第%d天行程", day), dayOutput, data, &tripDay); err != nil {
+			return nil, err
+		}
+		// 天数和日期以请求为准
+		tripDay.Day = day
+		tripDay.Date = data.Date
+		return &tripDay, nil
+	}
+}
+
+// essentialsStage 生成出行信息
+func (p *tripPipeline) essentialsStage(ctx context.Context, outline *tripOutline) (*tripEssentials, error) {
+	var essentials tripEssentials
+	if err := p.runStage(ctx, "出行信息", essentialsOutput, outline.data, &essentials); err != nil {
+		return nil, err
+	}
+	return &essentials, nil
+}
+
+// localGuideStage 生成当地指南
+func (p *tripPipeline) localGuideStage(ctx context.Context, outline *tripOutline) (*tripLocalGuide, error) {
+	var guide tripLocalGuide
+	if err := p.runStage(ctx, "当地指南", localGuideOutput, outline.data, &guide); err != nil {
+		return nil, err
+	}
+	return &guide, nil
+}
+
+// merge 合并各阶段的输出，由每天的费用计算预算，并按完整计划的Schema校验
+func (p *tripPipeline) merge(ctx context.Context, outputs map[string]any) (*models.TripPlan, error) {
+	outline := outputs[pipelineOutlineKey].(*tripOutline)
+	essentials := outputs[models.PromptTripEssentials].(*tripEssentials)
+	guide := outputs[models.PromptTripLocalGuide].(*tripLocalGuide)
+
+	plan := &models.TripPlan{
+		Title:                  outline.skeleton.Title,
+		DestinationInfo:        outline.skeleton.DestinationInfo,
+		TravelInfo:             essentials.TravelInfo,
+		WeatherForecast:        essentials.WeatherForecast,
+		PackingList:            essentials.PackingList,
+		EmergencyContacts:      essentials.EmergencyContacts,
+		LocalAttractions:       guide.LocalAttractions,
+		LocalCuisine:           guide.LocalCuisine,
+		Shopping:               guide.Shopping,
+		CulturalEvents:         guide.CulturalEvents,
+		PracticalInfo:          essentials.PracticalInfo,
+		Notes:                  outline.skeleton.Notes,
+		SuggestedModifications: guide.SuggestedModifications,
+	}
+	for day := 1; day <= outline.data.Days; day++ {
+		plan.Days = append(plan.Days, *outputs[dayStageKey(day)].(*models.TripDay))
+	}
+	plan.Budget = buildBudget(plan.Days, outline.skeleton.Currency, guide.ShoppingBudget)
+	plan.Budget.ExchangeRate = essentials.ExchangeRate
+	plan.Budget.PaymentTips = essentials.PaymentTips
+
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return nil, p.fail(err)
+	}
+	if err := validateTripPlanJSON(data); err != nil {
+		return nil, p.fail(fmt.Errorf("合并后的%w", err))
+	}
+
+	p.emitStage("旅行计划已合并")
+	return plan, nil
+}
+
+// runStage 渲染阶段的提示词，按故障切换链生成并解析到out，失败时取消其他阶段
+func (p *tripPipeline) runStage(ctx context.Context, label string, output *outputSchema, data interface{}, out interface{}) error {
+	prompt, version, err := p.service.renderPrompt(ctx, output.name, data)
+	if err != nil {
+		return p.fail(err)
+	}
+	responseSchema, err := output.responseSchema()
+	if err != nil {
+		return p.fail(err)
+	}
+
+	generation, err := p.service.generateWithFailover(withTraceStage(ctx, label), p.chain, &einosdk.GenerateTextRequest{
+		Prompt:         prompt,
+		SystemPrompt:   p.systemPrompt,
+		MaxTokens:      stageMaxTokens,
+		Tools:          p.tools,
+		ResponseSchema: responseSchema,
+	}, p.emit, func(text string) error {
+		return output.parse(text, out)
+	})
+	if err != nil {
+		return p.fail(fmt.Errorf("生成%s失败: %w", label, err))
+	}
+
+	p.addGeneration(generation, output.name, version)
+	p.emitStage(label + "已生成")
+	return nil
+}
+
+// addGeneration 累加各阶段的用量，模型配置信息和提示词版本以行程框架阶段为准
+func (p *tripPipeline) addGeneration(generation *models.GenerationInfo, stage string, version int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if stage == models.PromptTripSkeleton {
+		generation.PromptVersion = version
+		generation.Stages = 1
+		p.generation = generation
+		return
+	}
+	p.generation.Stages++
+	if generation.Attempts > p.generation.Attempts {
+		p.generation.Attempts = generation.Attempts
+	}
+	p.generation.PromptTokens += generation.PromptTokens
+	p.generation.CompletionTokens += generation.CompletionTokens
+	p.generation.TotalTokens += generation.TotalTokens
+	p.generation.Cost += generation.Cost
+}
+
+// fail 记录第一个失败阶段的错误并取消其他阶段，返回err
+func (p *tripPipeline) fail(err error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+		p.cancel()
+	}
+	return err
+}
+
+// firstError 返回第一个失败阶段的错误
+func (p *tripPipeline) firstError() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// emitStage 推送阶段完成事件
+func (p *tripPipeline) emitStage(content string) {
+	if p.emit != nil {
+		p.emit(&einosdk.StreamEvent{Type: einosdk.StreamEventStage, Content: content})
+	}
+}
+
+// date 返回第day天的日期
+func (p *tripPipeline) date(day int) string {
+	return dateOnly(p.req.StartDate).AddDate(0, 0, day-1).Format("2006-01-02")
+}
+
+// dayStageKey 并行阶段中第day天行程的输出键
+func dayStageKey(day int) string {
+	return fmt.Sprintf("day_%d", day)
+}
+
+// stageEmitter 包装handler，使并行的阶段可以同时推送事件，并丢弃各阶段交错的增量输出和推理内容；handler为空时返回nil
+func stageEmitter(handler einosdk.StreamHandler) einosdk.StreamHandler {
+	if handler == nil {
+		return nil
+	}
+	var mu sync.Mutex
+	return func(event *einosdk.StreamEvent) {
+		if event.Type == einosdk.StreamEventDelta || event.Type == einosdk.StreamEventReasoning {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		handler(event)
+	}
+}
+
+// buildBudget 按每天的住宿、餐饮、活动和交通费用计算预算和每日明细
+func buildBudget(days []models.TripDay, currency string, shopping float64) models.Budget {
+	budget := models.Budget{
+		Currency: currency,
+		Shopping: shopping,
+	}
+	for _, day := range days {
+		details := models.DailyExpenseDetails{
+			Accommodation: day.Accommodation.Cost,
+		}
+		for _, activity := range day.Activities {
+			details.Activities += activity.Cost
+		}
+		for _, meal := range day.Meals {
+			details.Food += meal.Cost
+		}
+		for _, transport := range day.Transportation {
+			details.Transportation += transport.Cost
+		}
+		total := details.Accommodation + details.Activities + details.Food + details.Transportation + details.Other
+
+		budget.Accommodation += details.Accommodation
+		budget.Activities += details.Activities
+		budget.Food += details.Food
+		budget.Transportation += details.Transportation
+		budget.Other += details.Other
+		budget.TotalEstimate += total
+		budget.DailyBreakdown = append(budget.DailyBreakdown, models.DailyBudget{
+			Day:     day.Day,
+			Date:    day.Date,
+			Total:   total,
+			Details: details,
+		})
+	}
+	budget.TotalEstimate += shopping
+	return budget
+}
+
+// RegenerateTripDay 按计划中其他天的安排重新生成第day天的行程，并由每天的费用重新计算预算，返回修改后的计划副本，不保存。
+// instructions是对这一天的修改要求，为空时只要求与原安排不同
+func (s *EinoService) RegenerateTripDay(ctx context.Context, plan *models.TripPlan, day int, instructions string) (*models.TripPlan, error) {
+	if day < 1 || day > len(plan.Days) {
+		return nil, fmt.Errorf("%w: 第%d天，行程共%d天", ErrInvalidTripDay, day, len(plan.Days))
+	}
+	start, err := planStartDate(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := s.modelChain(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	systemPrompt, _, err := s.renderPrompt(ctx, models.PromptAgentSystem, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 沿用生成时的预算、风格和饮食等偏好，较早的计划没有保存请求时只使用目的地
+	req := &models.PlanRequest{Destination: plan.Destination}
+	if plan.Request != nil {
+		req = copyPlanRequest(plan.Request)
+	}
+	req.StartDate = start
+	req.EndDate = start.AddDate(0, 0, len(plan.Days)-1)
+	if instructions != "" {
+		if req.SpecialRequests != "" {
+			req.SpecialRequests += "；"
+		}
+		req.SpecialRequests += "本次调整: " + instructions
+	}
+	p := &tripPipeline{
+		service:      s,
+		req:          req,
+		chain:        chain,
+		systemPrompt: systemPrompt,
+		tools:        s.agentTools(ctx, toolpolicy.UseCasePlan),
+		cancel:       cancel,
+		generation:   &models.GenerationInfo{},
+	}
+
+	theme := instructions
+	if theme == "" {
+		theme = "重新安排这一天，活动与原安排不同"
+	}
+	data := NewTripPlanPromptData(req)
+	outline := &tripOutline{
+		skeleton: &tripSkeleton{Days: []skeletonDay{{Day: day, Theme: theme}}},
+		data: &TripSectionPromptData{
+			TripPlanPromptData: *data,
+			Title:              plan.Title,
+			Currency:           plan.Budget.Currency,
+			Outline:            existingOutline(plan.Days, day),
+		},
+	}
+	tripDay, err := p.dayStage(day)(ctx, outline)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *plan
+	updated.Days = append([]models.TripDay(nil), plan.Days...)
+	updated.Days[day-1] = *tripDay
+	updated.Budget = buildBudget(updated.Days, plan.Budget.Currency, plan.Budget.Shopping)
+	updated.Budget.ExchangeRate = plan.Budget.ExchangeRate
+	updated.Budget.PaymentTips = plan.Budget.PaymentTips
+	return &updated, nil
+}
+
+// planStartDate 计划的开始日期，保存的开始日期无法解析时由第一天的日期推算
+func planStartDate(plan *models.TripPlan) (time.Time, error) {
+	if len(plan.StartDate) >= len("2006-01-02") {
+		if start, err := time.Parse("2006-01-02", plan.StartDate[:len("2006-01-02")]); err == nil {
+			return start, nil
+		}
+	}
+	if len(plan.Days) > 0 {
+		if date, err := time.Parse("2006-01-02", plan.Days[0].Date); err == nil {
+			return date.AddDate(0, 0, 1-max(plan.Days[0].Day, 1)), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法确定旅行计划的开始日期: %q", plan.StartDate)
+}
+
+// existingOutline 列出已有行程每天的活动，作为重新生成某一天时的行程框架
+func existingOutline(days []models.TripDay, regenerate int) string {
+	lines := make([]string, 0, len(days))
+	for i, d := range days {
+		names := make([]string, 0, len(d.Activities))
+		for _, activity := range d.Activities {
+			names = append(names, activity.Name)
+		}
+		line := fmt.Sprintf("第%d天(%s): %s", i+1, d.Date, strings.Join(names, "、"))
+		if i+1 == regenerate {
+			line = fmt.Sprintf("第%d天(%s): 待重新安排，不要重复原安排的%s", i+1, d.Date, strings.Join(names, "、"))
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// copyPlanRequest 复制旅行计划请求，保存到计划中的请求不与调用方共用切片
+func copyPlanRequest(req *models.PlanRequest) *models.PlanRequest {
+	c := *req
+	c.TravelStyle = append([]string(nil), req.TravelStyle...)
+	c.Accommodation = append([]string(nil), req.Accommodation...)
+	c.Transportation = append([]string(nil), req.Transportation...)
+	c.Activities = append([]string(nil), req.Activities...)
+	c.FoodPreferences = append([]string(nil), req.FoodPreferences...)
+	return &c
+}
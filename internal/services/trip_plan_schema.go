@@ -0,0 +1,182 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+	"personatrip/internal/models"
+	"personatrip/pkg/einosdk"
+)
+
+// maxReportedProblems 反馈给模型的校验错误条数上限，避免修正提示词过长
+const maxReportedProblems = 20
+
+// tripPlanOutput 完整旅行计划的Schema
+var tripPlanOutput = newOutputSchema("trip_plan", &models.TripPlan{})
+
+// outputSchema 由Go结构体生成的模型输出Schema，只在第一次使用时生成
+type outputSchema struct {
+	name  string
+	value interface{}
+
+	once   sync.Once
+	schema *openapi3.Schema
+	json   json.RawMessage
+	err    error
+}
+
+// newOutputSchema 创建由value的类型生成的输出Schema，name为发送给模型提供者的Schema名称
+func newOutputSchema(name string, value interface{}) *outputSchema {
+	return &outputSchema{name: name, value: value}
+}
+
+// load 生成Schema
+func (o *outputSchema) load() (*openapi3.Schema, error) {
+	o.once.Do(func() {
+		ref, err := openapi3gen.NewSchemaRefForValue(o.value, nil, openapi3gen.SchemaCustomizer(customizeTripPlanSchema))
+		if err != nil {
+			o.err = fmt.Errorf("生成%s的Schema失败: %w", o.name, err)
+			return
+		}
+		o.schema = ref.Value
+		o.json, o.err = json.Marshal(o.schema)
+	})
+	return o.schema, o.err
+}
+
+// responseSchema 返回发送给模型提供者的结构化输出约束
+func (o *outputSchema) responseSchema() (*einosdk.ResponseSchema, error) {
+	if _, err := o.load(); err != nil {
+		return nil, err
+	}
+	return &einosdk.ResponseSchema{Name: o.name, Schema: o.json}, nil
+}
+
+// validate 校验JSON是否符合Schema，值为null的字段视为未提供
+func (o *outputSchema) validate(data []byte) error {
+	schema, err := o.load()
+	if err != nil {
+		return err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	err = schema.VisitJSON(dropNulls(value), openapi3.MultiErrors())
+	if err == nil {
+		return nil
+	}
+
+	var problems []string
+	collectSchemaProblems(err, &problems)
+	if len(problems) > maxReportedProblems {
+		problems = append(problems[:maxReportedProblems], fmt.Sprintf("另有%d处错误", len(problems)-maxReportedProblems))
+	}
+	return &PlanValidationError{Problems: problems}
+}
+
+// parse 从模型输出中提取JSON对象，通过Schema校验后解析到out
+func (o *outputSchema) parse(response string, out interface{}) error {
+	jsonStr, err := extractJSONObject(response)
+	if err != nil {
+		return err
+	}
+	if err := o.validate([]byte(jsonStr)); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(jsonStr), out); err != nil {
+		return fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	return nil
+}
+
+// PlanValidationError 模型输出的旅行计划不符合Schema
+type PlanValidationError struct {
+	Problems []string // 逐条的校验错误，格式为"字段路径: 原因"
+}
+
+// Error 实现error接口
+func (e *PlanValidationError) Error() string {
+	return "旅行计划不符合Schema: " + strings.Join(e.Problems, "; ")
+}
+
+// TripPlanSchema 返回由models.TripPlan生成的JSON Schema，只在第一次调用时生成
+func TripPlanSchema() (*openapi3.Schema, error) {
+	return tripPlanOutput.load()
+}
+
+// tripPlanResponseSchema 返回发送给模型提供者的结构化输出约束
+func tripPlanResponseSchema() (*einosdk.ResponseSchema, error) {
+	return tripPlanOutput.responseSchema()
+}
+
+// customizeTripPlanSchema 根据jsonschema标签排除服务端填充的字段，并设置对象的必填字段
+func customizeTripPlanSchema(name string, t reflect.Type, tag reflect.StructTag, schema *openapi3.Schema) error {
+	if tag.Get("jsonschema") == "-" {
+		return &openapi3gen.ExcludeSchemaSentinel{}
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("jsonschema") != "required" {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		schema.Required = append(schema.Required, jsonName)
+	}
+	return nil
+}
+
+// validateTripPlanJSON 校验旅行计划JSON是否符合Schema，值为null的字段视为未提供
+func validateTripPlanJSON(data []byte) error {
+	return tripPlanOutput.validate(data)
+}
+
+// collectSchemaProblems 将校验错误展开为"字段路径: 原因"的列表
+func collectSchemaProblems(err error, problems *[]string) {
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		for _, e := range multi {
+			collectSchemaProblems(e, problems)
+		}
+		return
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		path := "/" + strings.Join(schemaErr.JSONPointer(), "/")
+		*problems = append(*problems, fmt.Sprintf("%s: %s", path, schemaErr.Reason))
+		return
+	}
+	*problems = append(*problems, err.Error())
+}
+
+// dropNulls 递归删除对象中值为null的字段
+func dropNulls(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if item == nil {
+				delete(v, key)
+				continue
+			}
+			v[key] = dropNulls(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = dropNulls(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"personatrip/internal/models"
+	"personatrip/internal/repository"
+	"personatrip/pkg/einosdk"
+)
+
+// defaultUsageRange 未指定时间范围时统计最近的天数
+const defaultUsageRange = 30 * 24 * time.Hour
+
+// UsageService 定义大模型用量统计服务接口
+type UsageService interface {
+	RecordUsage(ctx context.Context, record *models.UsageRecord) error
+	GetUsageByUser(ctx context.Context, query *models.UsageQuery) ([]models.UsageSummary, error)
+	GetUsageByModel(ctx context.Context, query *models.UsageQuery) ([]models.UsageSummary, error)
+	GetUsageByDay(ctx context.Context, query *models.UsageQuery) ([]models.UsageSummary, error)
+}
+
+// UsageServiceImpl 是用量统计服务的实现
+type UsageServiceImpl struct {
+	db repository.Database
+}
+
+// NewUsageService 创建新的用量统计服务
+func NewUsageService(db repository.Database) UsageService {
+	return &UsageServiceImpl{db: db}
+}
+
+// RecordUsage 保存一次模型调用的用量
+func (s *UsageServiceImpl) RecordUsage(ctx context.Context, record *models.UsageRecord) error {
+	return s.db.UsageRepo().Create(ctx, record)
+}
+
+// newUsageRecord 根据context中的调用来源构建一次模型调用的用量记录
+func newUsageRecord(ctx context.Context, config *models.ModelConfig, usage einosdk.TokenUsage, latency time.Duration, success bool) *models.UsageRecord {
+	info := CallInfoFromContext(ctx)
+	return &models.UsageRecord{
+		UserID:           info.UserID,
+		ModelConfigID:    config.ID,
+		ModelType:        config.ModelType,
+		ModelName:        config.ModelName,
+		Endpoint:         info.Endpoint,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		Cost:             config.CalculateCost(usage.PromptTokens, usage.CompletionTokens),
+		LatencyMs:        latency.Milliseconds(),
+		Success:          success,
+	}
+}
+
+// GetUsageByUser 按用户汇总用量
+func (s *UsageServiceImpl) GetUsageByUser(ctx context.Context, query *models.UsageQuery) ([]models.UsageSummary, error) {
+	from, to := usageRange(query)
+	return s.db.UsageRepo().SummarizeByUser(ctx, from, to)
+}
+
+// GetUsageByModel 按模型配置汇总用量
+func (s *UsageServiceImpl) GetUsageByModel(ctx context.Context, query *models.UsageQuery) ([]models.UsageSummary, error) {
+	from, to := usageRange(query)
+	return s.db.UsageRepo().SummarizeByModel(ctx, from, to)
+}
+
+// GetUsageByDay 按天汇总用量
+func (s *UsageServiceImpl) GetUsageByDay(ctx context.Context, query *models.UsageQuery) ([]models.UsageSummary, error) {
+	from, to := usageRange(query)
+	return s.db.UsageRepo().SummarizeByDay(ctx, from, to)
+}
+
+// usageRange 将查询条件转换为[from, to)区间，结束日期包含当天，默认统计最近30天
+func usageRange(query *models.UsageQuery) (time.Time, time.Time) {
+	to := query.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, to.Location()).AddDate(0, 0, 1)
+
+	from := query.From
+	if from.IsZero() {
+		from = to.Add(-defaultUsageRange)
+	}
+	return from, to
+}
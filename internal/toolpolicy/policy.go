@@ -0,0 +1,290 @@
+package toolpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+	"personatrip/internal/utils/logger"
+)
+
+// 使用工具的场景
+const (
+	UseCasePlan            = "plan"            // 生成旅行计划，包括分阶段生成的每个阶段
+	UseCaseRecommendations = "recommendations" // 推荐目的地
+	UseCaseChat            = "chat"            // 对话
+)
+
+// UseCases 所有场景
+var UseCases = []string{UseCasePlan, UseCaseRecommendations, UseCaseChat}
+
+// rejectedPrefix 被策略拦截的工具调用返回给模型的结果前缀，模型据此知道调用没有执行
+const rejectedPrefix = "工具调用被策略拒绝: "
+
+// Policy 一个场景中智能体使用工具的限制
+type Policy struct {
+	Allow            []string      `yaml:"allow"`              // 允许的工具名称，支持*和?通配符，为空时允许所有工具
+	Deny             []string      `yaml:"deny"`               // 禁止的工具名称，优先于allow
+	MaxCallsPerTool  int           `yaml:"max_calls_per_tool"` // 一次运行中每个工具最多调用的次数，为0时不限制
+	MaxArgumentBytes int           `yaml:"max_argument_bytes"` // 参数JSON的最大字节数，为0时不限制
+	RunTimeout       time.Duration `yaml:"run_timeout"`        // 一次运行中调用工具的截止时间，从运行开始计算，为0时不限制
+}
+
+// Policies 各个场景的工具策略
+type Policies map[string]Policy
+
+// DefaultPolicies 未配置策略文件时使用的策略：生成旅行计划可以使用所有工具，其他场景不使用工具
+func DefaultPolicies() Policies {
+	return Policies{
+		UseCasePlan: {
+			MaxCallsPerTool:  10,
+			MaxArgumentBytes: 4096,
+			RunTimeout:       5 * time.Minute,
+		},
+		UseCaseRecommendations: {Deny: []string{"*"}},
+		UseCaseChat:            {Deny: []string{"*"}},
+	}
+}
+
+// Load 读取YAML格式的策略文件，文件中未出现的场景使用默认策略，path为空时全部使用默认策略
+func Load(path string) (Policies, error) {
+	policies := DefaultPolicies()
+	if path == "" {
+		return policies, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取工具策略失败: %w", err)
+	}
+	var file struct {
+		Policies map[string]Policy `yaml:"policies"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析工具策略%s失败: %w", path, err)
+	}
+	for useCase, policy := range file.Policies {
+		if _, ok := policies[useCase]; !ok {
+			return nil, fmt.Errorf("未知的工具策略场景: %s", useCase)
+		}
+		if err := policy.validate(); err != nil {
+			return nil, fmt.Errorf("场景%s的工具策略无效: %w", useCase, err)
+		}
+		policies[useCase] = policy
+	}
+	return policies, nil
+}
+
+// Get 返回场景的策略，未知场景不允许使用任何工具
+func (p Policies) Get(useCase string) Policy {
+	if policy, ok := p[useCase]; ok {
+		return policy
+	}
+	return Policy{Deny: []string{"*"}}
+}
+
+// validate 校验通配符和限制
+func (p *Policy) validate() error {
+	for _, pattern := range append(append([]string{}, p.Allow...), p.Deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("无效的工具名称模式 %q", pattern)
+		}
+	}
+	if p.MaxCallsPerTool < 0 || p.MaxArgumentBytes < 0 || p.RunTimeout < 0 {
+		return errors.New("限制不能为负数")
+	}
+	return nil
+}
+
+// Allows 是否允许使用该工具
+func (p *Policy) Allows(name string) bool {
+	if matchAny(p.Deny, name) {
+		return false
+	}
+	return len(p.Allow) == 0 || matchAny(p.Allow, name)
+}
+
+// DeniesAll 是否禁止所有工具，此时不需要获取工具
+func (p *Policy) DeniesAll() bool {
+	for _, pattern := range p.Deny {
+		if pattern == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny 名称是否匹配任一模式
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter 去掉策略不允许的工具，其余工具包装为受策略限制的工具。只能限制InvokableTool，
+// 其他工具（如只支持流式调用的StreamableTool）无法执行限制，记录日志后去掉。包装后的工具还没有调用计数和截止时间，每次运行智能体前需要用StartRun开始一次运行
+func (p Policy) Filter(ctx context.Context, tools []tool.BaseTool) ([]tool.BaseTool, error) {
+	policy := &p
+	filtered := make([]tool.BaseTool, 0, len(tools))
+	for _, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取工具信息失败: %w", err)
+		}
+		if !p.Allows(info.Name) {
+			continue
+		}
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			logger.Warnf("工具%s不支持InvokableRun，无法执行工具策略，不使用该工具", info.Name)
+			continue
+		}
+		wrapped := &policyTool{inner: invokable, name: info.Name, policy: policy}
+		if info.ParamsOneOf != nil {
+			if wrapped.params, err = info.ParamsOneOf.ToOpenAPIV3(); err != nil {
+				return nil, fmt.Errorf("解析工具%s的参数定义失败: %w", info.Name, err)
+			}
+		}
+		filtered = append(filtered, wrapped)
+	}
+	return filtered, nil
+}
+
+// StartRun 为Filter返回的工具开始一次运行，返回的工具共享新的调用计数和截止时间，其他工具原样返回。
+// 每次运行智能体都需要单独开始，故障切换的每次尝试和分阶段生成的每个阶段互不影响
+func StartRun(tools []tool.BaseTool) []tool.BaseTool {
+	runs := make(map[*Policy]*run)
+	started := make([]tool.BaseTool, len(tools))
+	for i, t := range tools {
+		wrapped, ok := t.(*policyTool)
+		if !ok {
+			started[i] = t
+			continue
+		}
+		r, ok := runs[wrapped.policy]
+		if !ok {
+			r = newRun(wrapped.policy)
+			runs[wrapped.policy] = r
+		}
+		withRun := *wrapped
+		withRun.run = r
+		started[i] = &withRun
+	}
+	return started
+}
+
+// run 一次运行中工具调用的计数和截止时间
+type run struct {
+	policy   *Policy
+	deadline time.Time // 为零时不限制
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// newRun 从现在开始一次运行
+func newRun(policy *Policy) *run {
+	r := &run{
+		policy: policy,
+		calls:  make(map[string]int),
+	}
+	if policy.RunTimeout > 0 {
+		r.deadline = time.Now().Add(policy.RunTimeout)
+	}
+	return r
+}
+
+// acquire 检查截止时间和调用次数，允许调用时计入一次
+func (r *run) acquire(name string) error {
+	if !r.deadline.IsZero() && !time.Now().Before(r.deadline) {
+		return fmt.Errorf("本次生成调用工具的时间已超过%s，请不要再调用工具，直接根据已有信息完成回答", r.policy.RunTimeout)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if max := r.policy.MaxCallsPerTool; max > 0 && r.calls[name] >= max {
+		return fmt.Errorf("工具%s在本次生成中已调用%d次，达到上限，请不要再调用该工具", name, max)
+	}
+	r.calls[name]++
+	return nil
+}
+
+// policyTool 受策略限制的工具，被拦截的调用把原因作为结果返回给模型，不中断智能体
+type policyTool struct {
+	inner  tool.InvokableTool
+	name   string
+	params *openapi3.Schema // 为空时不校验参数
+	policy *Policy
+	run    *run // 为空时还没有开始运行
+}
+
+// Info 返回被包装工具的信息
+func (t *policyTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return t.inner.Info(ctx)
+}
+
+// InvokableRun 校验参数和限制后调用被包装的工具
+func (t *policyTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	if t.run == nil {
+		return "", fmt.Errorf("工具%s还没有开始运行，不能调用", t.name)
+	}
+	if err := t.validate(argumentsInJSON); err != nil {
+		return rejectedPrefix + err.Error(), nil
+	}
+	if err := t.run.acquire(t.name); err != nil {
+		return rejectedPrefix + err.Error(), nil
+	}
+
+	if !t.run.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, t.run.deadline)
+		defer cancel()
+	}
+	result, err := t.inner.InvokableRun(ctx, argumentsInJSON, opts...)
+	if err != nil && !t.run.deadline.IsZero() && !time.Now().Before(t.run.deadline) {
+		return rejectedPrefix + fmt.Sprintf("工具%s超过本次生成的截止时间仍未返回，请不要再调用工具，直接根据已有信息完成回答", t.name), nil
+	}
+	return result, err
+}
+
+// validate 检查参数大小，并按工具的参数定义校验参数
+func (t *policyTool) validate(argumentsInJSON string) error {
+	if max := t.policy.MaxArgumentBytes; max > 0 && len(argumentsInJSON) > max {
+		return fmt.Errorf("工具%s的参数有%d字节，超过上限%d字节", t.name, len(argumentsInJSON), max)
+	}
+	if t.params == nil {
+		return nil
+	}
+
+	var arguments interface{}
+	if argumentsInJSON == "" {
+		arguments = map[string]interface{}{}
+	} else if err := json.Unmarshal([]byte(argumentsInJSON), &arguments); err != nil {
+		return fmt.Errorf("工具%s的参数不是有效的JSON: %v", t.name, err)
+	}
+	if err := t.params.VisitJSON(arguments, openapi3.MultiErrors(), openapi3.SetSchemaErrorMessageCustomizer(schemaErrorMessage)); err != nil {
+		return fmt.Errorf("工具%s的参数不符合定义: %v", t.name, err)
+	}
+	return nil
+}
+
+// schemaErrorMessage 只保留出错的参数路径和原因，不把整个参数定义返回给模型
+func schemaErrorMessage(err *openapi3.SchemaError) string {
+	if pointer := err.JSONPointer(); len(pointer) > 0 {
+		return fmt.Sprintf("%s: %s", strings.Join(pointer, "."), err.Reason)
+	}
+	return err.Reason
+}
@@ -0,0 +1,256 @@
+package toolpolicy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// fakeTool 记录调用次数的工具
+type fakeTool struct {
+	name  string
+	calls int
+	block bool // 为true时一直等到ctx结束
+}
+
+func (t *fakeTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: t.name,
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"city": {Type: schema.String, Required: true},
+			"days": {Type: schema.Integer},
+		}),
+	}, nil
+}
+
+func (t *fakeTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	t.calls++
+	if t.block {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	return "ok", nil
+}
+
+// streamOnlyTool 只支持流式调用的工具
+type streamOnlyTool struct{}
+
+func (streamOnlyTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: "stream_only"}, nil
+}
+
+func (streamOnlyTool) StreamableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (*schema.StreamReader[string], error) {
+	return schema.StreamReaderFromArray([]string{"ok"}), nil
+}
+
+// toolNames 返回工具名称
+func toolNames(t *testing.T, tools []tool.BaseTool) []string {
+	t.Helper()
+	names := make([]string, 0, len(tools))
+	for _, tl := range tools {
+		info, err := tl.Info(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, info.Name)
+	}
+	return names
+}
+
+// invoke 调用工具
+func invoke(t *testing.T, tl tool.BaseTool, arguments string) string {
+	t.Helper()
+	result, err := tl.(tool.InvokableTool).InvokableRun(context.Background(), arguments)
+	if err != nil {
+		t.Fatalf("InvokableRun: %v", err)
+	}
+	return result
+}
+
+func TestPolicyAllows(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		tool   string
+		want   bool
+	}{
+		{name: "empty policy", policy: Policy{}, tool: "maps_weather", want: true},
+		{name: "allowed by pattern", policy: Policy{Allow: []string{"maps_*"}}, tool: "maps_weather", want: true},
+		{name: "not in allow list", policy: Policy{Allow: []string{"maps_*"}}, tool: "distance", want: false},
+		{name: "deny wins over allow", policy: Policy{Allow: []string{"maps_*"}, Deny: []string{"maps_weather"}}, tool: "maps_weather", want: false},
+		{name: "single character wildcard", policy: Policy{Deny: []string{"date_inf?"}}, tool: "date_info", want: false},
+		{name: "deny all", policy: Policy{Deny: []string{"*"}}, tool: "distance", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Allows(tt.tool); got != tt.want {
+				t.Fatalf("Allows(%q) = %v, want %v", tt.tool, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	policy := Policy{Deny: []string{"blocked"}}
+	tools := []tool.BaseTool{&fakeTool{name: "allowed"}, &fakeTool{name: "blocked"}, streamOnlyTool{}}
+
+	filtered, err := policy.Filter(context.Background(), tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := toolNames(t, filtered); len(names) != 1 || names[0] != "allowed" {
+		t.Fatalf("Filter kept %v, want [allowed]", names)
+	}
+}
+
+func TestStartRunLimitsCalls(t *testing.T) {
+	inner := &fakeTool{name: "weather"}
+	filtered, err := Policy{MaxCallsPerTool: 2}.Filter(context.Background(), []tool.BaseTool{inner})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := StartRun(filtered)[0]
+	for i := 0; i < 2; i++ {
+		if result := invoke(t, first, `{"city":"杭州"}`); result != "ok" {
+			t.Fatalf("call %d = %q, want ok", i+1, result)
+		}
+	}
+	if result := invoke(t, first, `{"city":"杭州"}`); !strings.HasPrefix(result, rejectedPrefix) {
+		t.Fatalf("third call = %q, want rejection", result)
+	}
+
+	// 每次运行重新计数
+	second := StartRun(filtered)[0]
+	if result := invoke(t, second, `{"city":"杭州"}`); result != "ok" {
+		t.Fatalf("call in a new run = %q, want ok", result)
+	}
+	if inner.calls != 3 {
+		t.Fatalf("inner tool called %d times, want 3", inner.calls)
+	}
+}
+
+func TestValidateArguments(t *testing.T) {
+	inner := &fakeTool{name: "weather"}
+	filtered, err := Policy{MaxArgumentBytes: 40}.Filter(context.Background(), []tool.BaseTool{inner})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped := StartRun(filtered)[0]
+
+	tests := []struct {
+		name      string
+		arguments string
+		rejected  bool
+	}{
+		{name: "valid", arguments: `{"city":"杭州","days":3}`, rejected: false},
+		{name: "missing required", arguments: `{"days":3}`, rejected: true},
+		{name: "wrong type", arguments: `{"city":"杭州","days":"three"}`, rejected: true},
+		{name: "invalid json", arguments: `{"city":`, rejected: true},
+		{name: "too large", arguments: `{"city":"` + strings.Repeat("a", 40) + `"}`, rejected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := invoke(t, wrapped, tt.arguments)
+			if rejected := strings.HasPrefix(result, rejectedPrefix); rejected != tt.rejected {
+				t.Fatalf("result = %q, rejected = %v, want %v", result, rejected, tt.rejected)
+			}
+		})
+	}
+	if inner.calls != 1 {
+		t.Fatalf("inner tool called %d times, want 1", inner.calls)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	inner := &fakeTool{name: "slow", block: true}
+	filtered, err := Policy{RunTimeout: 20 * time.Millisecond}.Filter(context.Background(), []tool.BaseTool{inner})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped := StartRun(filtered)[0]
+
+	if result := invoke(t, wrapped, `{"city":"杭州"}`); !strings.HasPrefix(result, rejectedPrefix) {
+		t.Fatalf("call past the deadline = %q, want rejection", result)
+	}
+	if result := invoke(t, wrapped, `{"city":"杭州"}`); !strings.HasPrefix(result, rejectedPrefix) {
+		t.Fatalf("call after the deadline = %q, want rejection", result)
+	}
+	if inner.calls != 1 {
+		t.Fatalf("inner tool called %d times, want 1", inner.calls)
+	}
+}
+
+func TestInvokeWithoutRun(t *testing.T) {
+	filtered, err := Policy{}.Filter(context.Background(), []tool.BaseTool{&fakeTool{name: "weather"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := filtered[0].(tool.InvokableTool).InvokableRun(context.Background(), `{"city":"杭州"}`); err == nil {
+		t.Fatal("calling a tool without StartRun succeeded, want error")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "policies.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		policies, err := Load("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plan := policies.Get(UseCasePlan); plan.DeniesAll() || plan.MaxCallsPerTool != 10 {
+			t.Fatalf("default plan policy = %+v", plan)
+		}
+		if chat := policies.Get(UseCaseChat); !chat.DeniesAll() {
+			t.Fatalf("default chat policy = %+v, want deny all", chat)
+		}
+		if unknown := policies.Get("unknown"); !unknown.DeniesAll() {
+			t.Fatalf("unknown use case policy = %+v, want deny all", unknown)
+		}
+	})
+
+	t.Run("override one use case", func(t *testing.T) {
+		policies, err := Load(write(t, "policies:\n  recommendations:\n    allow: [\"maps_*\"]\n    run_timeout: 30s\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		recommendations := policies.Get(UseCaseRecommendations)
+		if recommendations.DeniesAll() || recommendations.RunTimeout != 30*time.Second {
+			t.Fatalf("recommendations policy = %+v", recommendations)
+		}
+		if plan := policies.Get(UseCasePlan); plan.MaxCallsPerTool != 10 {
+			t.Fatalf("plan policy = %+v, want default", plan)
+		}
+	})
+
+	errorCases := []struct {
+		name    string
+		content string
+	}{
+		{name: "unknown use case", content: "policies:\n  booking:\n    deny: [\"*\"]\n"},
+		{name: "invalid pattern", content: "policies:\n  plan:\n    allow: [\"[\"]\n"},
+		{name: "negative limit", content: "policies:\n  plan:\n    max_calls_per_tool: -1\n"},
+		{name: "invalid yaml", content: "policies: [\n"},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(write(t, tt.content)); err == nil {
+				t.Fatal("Load succeeded, want error")
+			}
+		})
+	}
+}
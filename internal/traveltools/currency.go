@@ -0,0 +1,116 @@
+package traveltools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"personatrip/internal/models"
+)
+
+// convertCurrencyArgs convert_currency工具的参数
+type convertCurrencyArgs struct {
+	Amount float64 `json:"amount"`
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+}
+
+// convertCurrencyResult convert_currency工具的结果
+type convertCurrencyResult struct {
+	Amount float64 `json:"amount"`
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Result float64 `json:"result"`
+	Rate   float64 `json:"rate"`             // 1单位from兑换的to
+	AsOf   string  `json:"as_of,omitempty"`  // 用到的汇率中最早的更新日期
+	Source string  `json:"source,omitempty"` // 汇率来源
+}
+
+// convertCurrencyTool 按管理员维护的汇率表换算货币，汇率以人民币为基准
+func convertCurrencyTool(rates RateSource) tool.InvokableTool {
+	return newTool("convert_currency",
+		"按系统维护的汇率换算货币金额，用于估算预算和当地消费。货币使用ISO 4217三位代码，如CNY、JPY、USD、EUR",
+		map[string]*schema.ParameterInfo{
+			"amount": {Type: schema.Number, Desc: "金额", Required: true},
+			"from":   {Type: schema.String, Desc: "原货币代码", Required: true},
+			"to":     {Type: schema.String, Desc: "目标货币代码", Required: true},
+		},
+		func(ctx context.Context, args *convertCurrencyArgs) (interface{}, error) {
+			return convertCurrency(ctx, rates, args)
+		})
+}
+
+// convertCurrency 先把金额换算为人民币再换算为目标货币
+func convertCurrency(ctx context.Context, rates RateSource, args *convertCurrencyArgs) (*convertCurrencyResult, error) {
+	from := strings.ToUpper(strings.TrimSpace(args.From))
+	to := strings.ToUpper(strings.TrimSpace(args.To))
+	if from == "" || to == "" {
+		return nil, fmt.Errorf("需要指定from和to货币代码")
+	}
+
+	list, err := rates.ListExchangeRates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("查询汇率失败: %v", err)
+	}
+	table := make(map[string]models.ExchangeRate, len(list)+1)
+	for _, rate := range list {
+		table[rate.Currency] = rate
+	}
+	table[models.ExchangeRateBaseCurrency] = models.ExchangeRate{Currency: models.ExchangeRateBaseCurrency, Rate: 1}
+
+	fromRate, ok := table[from]
+	if !ok {
+		return nil, fmt.Errorf("没有%s的汇率，可用的货币: %s", from, currencyCodes(table))
+	}
+	toRate, ok := table[to]
+	if !ok {
+		return nil, fmt.Errorf("没有%s的汇率，可用的货币: %s", to, currencyCodes(table))
+	}
+
+	result := &convertCurrencyResult{
+		Amount: args.Amount,
+		From:   from,
+		To:     to,
+		Rate:   round(fromRate.Rate/toRate.Rate, 6),
+		Result: round(args.Amount*fromRate.Rate/toRate.Rate, 2),
+	}
+	var asOf time.Time
+	var sources []string
+	for _, rate := range []models.ExchangeRate{fromRate, toRate} {
+		if rate.Currency == models.ExchangeRateBaseCurrency {
+			continue
+		}
+		if asOf.IsZero() || rate.UpdatedAt.Before(asOf) {
+			asOf = rate.UpdatedAt
+		}
+		if rate.Source != "" && (len(sources) == 0 || sources[0] != rate.Source) {
+			sources = append(sources, rate.Source)
+		}
+	}
+	if !asOf.IsZero() {
+		result.AsOf = asOf.Format(dateLayout)
+	}
+	result.Source = strings.Join(sources, ", ")
+	return result, nil
+}
+
+// currencyCodes 汇率表中的货币代码，按字母排序
+func currencyCodes(table map[string]models.ExchangeRate) string {
+	codes := make([]string, 0, len(table))
+	for code := range table {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return strings.Join(codes, ", ")
+}
+
+// round 四舍五入到指定的小数位数
+func round(value float64, digits int) float64 {
+	scale := math.Pow(10, float64(digits))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,88 @@
+package traveltools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// maxDateOffset add_days的上限，旅行规划用不到更远的日期
+const maxDateOffset = 3660
+
+// weekdaysZH 星期的中文名称
+var weekdaysZH = [...]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"}
+
+// dateInfoArgs date_info工具的参数
+type dateInfoArgs struct {
+	Date    string `json:"date"`
+	AddDays int    `json:"add_days"`
+	EndDate string `json:"end_date"`
+}
+
+// dateInfoResult date_info工具的结果
+type dateInfoResult struct {
+	Date          string `json:"date"`
+	Weekday       string `json:"weekday"`
+	WeekdayZH     string `json:"weekday_zh"`
+	ISOWeek       int    `json:"iso_week"`
+	IsWeekend     bool   `json:"is_weekend"`
+	ResultDate    string `json:"result_date,omitempty"` // date加上add_days之后的日期
+	ResultWeekday string `json:"result_weekday,omitempty"`
+	EndDate       string `json:"end_date,omitempty"`
+	EndWeekday    string `json:"end_weekday,omitempty"`
+	DaysBetween   *int   `json:"days_between,omitempty"` // end_date减去date的天数
+	Days          *int   `json:"days,omitempty"`         // 包含首尾两天的行程天数
+	Nights        *int   `json:"nights,omitempty"`       // 住宿晚数
+}
+
+// dateInfoTool 查询日期是星期几，并做日期加减和间隔计算
+func dateInfoTool() tool.InvokableTool {
+	return newTool("date_info",
+		"查询日期是星期几、第几周、是否周末，计算若干天之后的日期，以及两个日期之间的天数和住宿晚数",
+		map[string]*schema.ParameterInfo{
+			"date":     {Type: schema.String, Desc: "日期，格式YYYY-MM-DD，默认今天"},
+			"add_days": {Type: schema.Integer, Desc: "在date上加的天数，可以为负数"},
+			"end_date": {Type: schema.String, Desc: "结束日期，格式YYYY-MM-DD，指定后计算与date之间的天数"},
+		},
+		func(ctx context.Context, args *dateInfoArgs) (interface{}, error) {
+			date, err := parseDate("date", args.Date)
+			if err != nil {
+				return nil, err
+			}
+			_, week := date.ISOWeek()
+			result := &dateInfoResult{
+				Date:      date.Format(dateLayout),
+				Weekday:   date.Weekday().String(),
+				WeekdayZH: weekdaysZH[date.Weekday()],
+				ISOWeek:   week,
+				IsWeekend: date.Weekday() == time.Saturday || date.Weekday() == time.Sunday,
+			}
+			if args.AddDays != 0 {
+				if args.AddDays > maxDateOffset || args.AddDays < -maxDateOffset {
+					return nil, fmt.Errorf("add_days不能超过%d天", maxDateOffset)
+				}
+				resultDate := date.AddDate(0, 0, args.AddDays)
+				result.ResultDate = resultDate.Format(dateLayout)
+				result.ResultWeekday = weekdaysZH[resultDate.Weekday()]
+			}
+			if args.EndDate != "" {
+				end, err := parseDate("end_date", args.EndDate)
+				if err != nil {
+					return nil, err
+				}
+				between := int(end.Sub(date).Hours() / 24)
+				result.EndDate = end.Format(dateLayout)
+				result.EndWeekday = weekdaysZH[end.Weekday()]
+				result.DaysBetween = &between
+				if between >= 0 {
+					days := between + 1
+					result.Days = &days
+					result.Nights = &between
+				}
+			}
+			return result, nil
+		})
+}
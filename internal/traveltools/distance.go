@@ -0,0 +1,68 @@
+package traveltools
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// earthRadiusKm 地球平均半径
+const earthRadiusKm = 6371.0
+
+// distanceArgs distance工具的参数
+type distanceArgs struct {
+	FromLat *float64 `json:"from_lat"`
+	FromLng *float64 `json:"from_lng"`
+	ToLat   *float64 `json:"to_lat"`
+	ToLng   *float64 `json:"to_lng"`
+}
+
+// distanceResult distance工具的结果
+type distanceResult struct {
+	DistanceKm float64 `json:"distance_km"`
+	Note       string  `json:"note"`
+}
+
+// distanceTool 计算两个坐标之间的直线距离
+func distanceTool() tool.InvokableTool {
+	return newTool("distance",
+		"计算两个经纬度坐标之间的直线距离（公里），用于判断景点是否适合安排在同一天、步行是否可达。实际路程通常更长，需要路线时请使用地图工具",
+		map[string]*schema.ParameterInfo{
+			"from_lat": {Type: schema.Number, Desc: "起点纬度", Required: true},
+			"from_lng": {Type: schema.Number, Desc: "起点经度", Required: true},
+			"to_lat":   {Type: schema.Number, Desc: "终点纬度", Required: true},
+			"to_lng":   {Type: schema.Number, Desc: "终点经度", Required: true},
+		},
+		func(ctx context.Context, args *distanceArgs) (interface{}, error) {
+			if args.FromLat == nil || args.FromLng == nil || args.ToLat == nil || args.ToLng == nil {
+				return nil, fmt.Errorf("需要指定from_lat、from_lng、to_lat和to_lng")
+			}
+			for _, lat := range []float64{*args.FromLat, *args.ToLat} {
+				if lat < -90 || lat > 90 {
+					return nil, fmt.Errorf("纬度应在-90到90之间: %v", lat)
+				}
+			}
+			for _, lng := range []float64{*args.FromLng, *args.ToLng} {
+				if lng < -180 || lng > 180 {
+					return nil, fmt.Errorf("经度应在-180到180之间: %v", lng)
+				}
+			}
+			return &distanceResult{
+				DistanceKm: round(Haversine(*args.FromLat, *args.FromLng, *args.ToLat, *args.ToLng), 2),
+				Note:       "直线距离",
+			}, nil
+		})
+}
+
+// Haversine 按球面计算两点之间的大圆距离，单位公里
+func Haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
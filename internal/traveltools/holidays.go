@@ -0,0 +1,154 @@
+package traveltools
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// maxHolidayRange 一次查询节假日的最长天数
+const maxHolidayRange = 400
+
+// holidaysJSON 内置的法定节假日数据，每年更新一次，覆盖的年份之外返回covered=false
+//
+//go:embed holidays.json
+var holidaysJSON []byte
+
+// holiday 一个节假日，单日节假日的起止日期相同
+type holiday struct {
+	Name  string `json:"name"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// holidayCountry 一个国家的节假日数据
+type holidayCountry struct {
+	Name     string    `json:"name"`
+	Years    []int     `json:"years"`
+	Holidays []holiday `json:"holidays"`
+	Workdays []string  `json:"workdays,omitempty"` // 因调休需要上班的周末
+}
+
+// holidayCountries 按国家代码索引的节假日数据
+var holidayCountries = mustLoadHolidays()
+
+// mustLoadHolidays 解析内置的节假日数据，数据有误时启动即失败
+func mustLoadHolidays() map[string]*holidayCountry {
+	var countries map[string]*holidayCountry
+	if err := json.Unmarshal(holidaysJSON, &countries); err != nil {
+		panic(fmt.Sprintf("解析内置节假日数据失败: %v", err))
+	}
+	return countries
+}
+
+// holidayCountryCodes 有节假日数据的国家代码，按字母排序
+func holidayCountryCodes() []string {
+	codes := make([]string, 0, len(holidayCountries))
+	for code := range holidayCountries {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// publicHolidaysArgs public_holidays工具的参数
+type publicHolidaysArgs struct {
+	Country   string `json:"country"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+// publicHolidaysResult public_holidays工具的结果
+type publicHolidaysResult struct {
+	Country  string    `json:"country"`
+	Name     string    `json:"name"`
+	Covered  bool      `json:"covered"` // 查询范围是否都在数据覆盖的年份内，为false时结果可能不完整
+	Years    []int     `json:"years"`
+	Holidays []holiday `json:"holidays"`
+	Workdays []string  `json:"workdays,omitempty"`
+}
+
+// publicHolidaysTool 查询日期范围内的法定节假日，节假日期间景点和交通通常更拥挤、价格更高
+func publicHolidaysTool() tool.InvokableTool {
+	codes := holidayCountryCodes()
+	return newTool("public_holidays",
+		"查询日期范围内目的地国家的法定节假日和调休上班日，用于避开人流高峰或提醒用户提前预订。covered为false表示超出了内置数据覆盖的年份",
+		map[string]*schema.ParameterInfo{
+			"country":    {Type: schema.String, Desc: "国家代码：" + strings.Join(codes, "、"), Enum: codes, Required: true},
+			"start_date": {Type: schema.String, Desc: "开始日期，格式YYYY-MM-DD", Required: true},
+			"end_date":   {Type: schema.String, Desc: "结束日期，格式YYYY-MM-DD，默认与开始日期相同"},
+		},
+		func(ctx context.Context, args *publicHolidaysArgs) (interface{}, error) {
+			return publicHolidays(args)
+		})
+}
+
+// publicHolidays 返回与日期范围有重叠的节假日
+func publicHolidays(args *publicHolidaysArgs) (*publicHolidaysResult, error) {
+	code := strings.ToUpper(strings.TrimSpace(args.Country))
+	country, ok := holidayCountries[code]
+	if !ok {
+		return nil, fmt.Errorf("没有%s的节假日数据，支持的国家: %s", args.Country, strings.Join(holidayCountryCodes(), ", "))
+	}
+	if args.StartDate == "" {
+		return nil, fmt.Errorf("需要指定start_date")
+	}
+	start, err := parseDate("start_date", args.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	end := start
+	if args.EndDate != "" {
+		if end, err = parseDate("end_date", args.EndDate); err != nil {
+			return nil, err
+		}
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end_date不能早于start_date")
+	}
+	if end.Sub(start).Hours()/24 > maxHolidayRange {
+		return nil, fmt.Errorf("一次最多查询%d天", maxHolidayRange)
+	}
+
+	startDate, endDate := start.Format(dateLayout), end.Format(dateLayout)
+	result := &publicHolidaysResult{
+		Country:  code,
+		Name:     country.Name,
+		Covered:  true,
+		Years:    country.Years,
+		Holidays: []holiday{},
+	}
+	for year := start.Year(); year <= end.Year(); year++ {
+		if !containsYear(country.Years, year) {
+			result.Covered = false
+		}
+	}
+	// 日期都是YYYY-MM-DD格式，可以直接按字符串比较
+	for _, h := range country.Holidays {
+		if h.End >= startDate && h.Start <= endDate {
+			result.Holidays = append(result.Holidays, h)
+		}
+	}
+	for _, day := range country.Workdays {
+		if day >= startDate && day <= endDate {
+			result.Workdays = append(result.Workdays, day)
+		}
+	}
+	return result, nil
+}
+
+// containsYear 年份是否在列表中
+func containsYear(years []int, year int) bool {
+	for _, y := range years {
+		if y == year {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,261 @@
+package traveltools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	// 内置时区数据，不依赖系统的zoneinfo
+	_ "time/tzdata"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// timeLayout convert_time工具中时间的格式
+const timeLayout = "2006-01-02 15:04"
+
+// cityZones 常见旅行目的地对应的IANA时区，键为小写的中文或英文名称。不在表中的地点需要直接使用IANA时区名称
+var cityZones = map[string]string{
+	// 中国
+	"中国": "Asia/Shanghai", "china": "Asia/Shanghai",
+	"北京": "Asia/Shanghai", "beijing": "Asia/Shanghai",
+	"上海": "Asia/Shanghai", "shanghai": "Asia/Shanghai",
+	"广州": "Asia/Shanghai", "guangzhou": "Asia/Shanghai",
+	"深圳": "Asia/Shanghai", "shenzhen": "Asia/Shanghai",
+	"成都": "Asia/Shanghai", "chengdu": "Asia/Shanghai",
+	"杭州": "Asia/Shanghai", "hangzhou": "Asia/Shanghai",
+	"西安": "Asia/Shanghai", "xi'an": "Asia/Shanghai", "xian": "Asia/Shanghai",
+	"三亚": "Asia/Shanghai", "sanya": "Asia/Shanghai",
+	"丽江": "Asia/Shanghai", "lijiang": "Asia/Shanghai",
+	"拉萨": "Asia/Shanghai", "lhasa": "Asia/Shanghai",
+	"乌鲁木齐": "Asia/Shanghai", "urumqi": "Asia/Shanghai",
+	"香港": "Asia/Hong_Kong", "hong kong": "Asia/Hong_Kong",
+	"澳门": "Asia/Macau", "macau": "Asia/Macau", "macao": "Asia/Macau",
+	"台北": "Asia/Taipei", "taipei": "Asia/Taipei",
+	// 亚洲
+	"日本": "Asia/Tokyo", "japan": "Asia/Tokyo",
+	"东京": "Asia/Tokyo", "tokyo": "Asia/Tokyo",
+	"大阪": "Asia/Tokyo", "osaka": "Asia/Tokyo",
+	"京都": "Asia/Tokyo", "kyoto": "Asia/Tokyo",
+	"札幌": "Asia/Tokyo", "sapporo": "Asia/Tokyo",
+	"冲绳": "Asia/Tokyo", "okinawa": "Asia/Tokyo",
+	"韩国": "Asia/Seoul", "south korea": "Asia/Seoul",
+	"首尔": "Asia/Seoul", "seoul": "Asia/Seoul",
+	"济州": "Asia/Seoul", "济州岛": "Asia/Seoul", "jeju": "Asia/Seoul",
+	"釜山": "Asia/Seoul", "busan": "Asia/Seoul",
+	"泰国": "Asia/Bangkok", "thailand": "Asia/Bangkok",
+	"曼谷": "Asia/Bangkok", "bangkok": "Asia/Bangkok",
+	"清迈": "Asia/Bangkok", "chiang mai": "Asia/Bangkok",
+	"普吉": "Asia/Bangkok", "普吉岛": "Asia/Bangkok", "phuket": "Asia/Bangkok",
+	"新加坡": "Asia/Singapore", "singapore": "Asia/Singapore",
+	"吉隆坡": "Asia/Kuala_Lumpur", "kuala lumpur": "Asia/Kuala_Lumpur",
+	"越南": "Asia/Ho_Chi_Minh", "vietnam": "Asia/Ho_Chi_Minh",
+	"河内": "Asia/Ho_Chi_Minh", "hanoi": "Asia/Ho_Chi_Minh",
+	"胡志明市": "Asia/Ho_Chi_Minh", "ho chi minh city": "Asia/Ho_Chi_Minh",
+	"岘港": "Asia/Ho_Chi_Minh", "da nang": "Asia/Ho_Chi_Minh",
+	"巴厘岛": "Asia/Makassar", "bali": "Asia/Makassar",
+	"雅加达": "Asia/Jakarta", "jakarta": "Asia/Jakarta",
+	"马尼拉": "Asia/Manila", "manila": "Asia/Manila",
+	"马尔代夫": "Indian/Maldives", "maldives": "Indian/Maldives",
+	"印度": "Asia/Kolkata", "india": "Asia/Kolkata",
+	"新德里": "Asia/Kolkata", "new delhi": "Asia/Kolkata",
+	"加德满都": "Asia/Kathmandu", "kathmandu": "Asia/Kathmandu",
+	"迪拜": "Asia/Dubai", "dubai": "Asia/Dubai",
+	"伊斯坦布尔": "Europe/Istanbul", "istanbul": "Europe/Istanbul",
+	// 欧洲
+	"英国": "Europe/London", "united kingdom": "Europe/London", "uk": "Europe/London",
+	"伦敦": "Europe/London", "london": "Europe/London",
+	"法国": "Europe/Paris", "france": "Europe/Paris",
+	"巴黎": "Europe/Paris", "paris": "Europe/Paris",
+	"德国": "Europe/Berlin", "germany": "Europe/Berlin",
+	"柏林": "Europe/Berlin", "berlin": "Europe/Berlin",
+	"慕尼黑": "Europe/Berlin", "munich": "Europe/Berlin",
+	"意大利": "Europe/Rome", "italy": "Europe/Rome",
+	"罗马": "Europe/Rome", "rome": "Europe/Rome",
+	"米兰": "Europe/Rome", "milan": "Europe/Rome",
+	"威尼斯": "Europe/Rome", "venice": "Europe/Rome",
+	"佛罗伦萨": "Europe/Rome", "florence": "Europe/Rome",
+	"西班牙": "Europe/Madrid", "spain": "Europe/Madrid",
+	"马德里": "Europe/Madrid", "madrid": "Europe/Madrid",
+	"巴塞罗那": "Europe/Madrid", "barcelona": "Europe/Madrid",
+	"荷兰": "Europe/Amsterdam", "netherlands": "Europe/Amsterdam",
+	"阿姆斯特丹": "Europe/Amsterdam", "amsterdam": "Europe/Amsterdam",
+	"瑞士": "Europe/Zurich", "switzerland": "Europe/Zurich",
+	"苏黎世": "Europe/Zurich", "zurich": "Europe/Zurich",
+	"奥地利": "Europe/Vienna", "austria": "Europe/Vienna",
+	"维也纳": "Europe/Vienna", "vienna": "Europe/Vienna",
+	"布拉格": "Europe/Prague", "prague": "Europe/Prague",
+	"希腊": "Europe/Athens", "greece": "Europe/Athens",
+	"雅典": "Europe/Athens", "athens": "Europe/Athens",
+	"葡萄牙": "Europe/Lisbon", "portugal": "Europe/Lisbon",
+	"里斯本": "Europe/Lisbon", "lisbon": "Europe/Lisbon",
+	"冰岛": "Atlantic/Reykjavik", "iceland": "Atlantic/Reykjavik",
+	"雷克雅未克": "Atlantic/Reykjavik", "reykjavik": "Atlantic/Reykjavik",
+	"莫斯科": "Europe/Moscow", "moscow": "Europe/Moscow",
+	// 美洲
+	"纽约": "America/New_York", "new york": "America/New_York",
+	"华盛顿": "America/New_York", "washington": "America/New_York",
+	"波士顿": "America/New_York", "boston": "America/New_York",
+	"迈阿密": "America/New_York", "miami": "America/New_York",
+	"芝加哥": "America/Chicago", "chicago": "America/Chicago",
+	"丹佛": "America/Denver", "denver": "America/Denver",
+	"洛杉矶": "America/Los_Angeles", "los angeles": "America/Los_Angeles",
+	"旧金山": "America/Los_Angeles", "san francisco": "America/Los_Angeles",
+	"西雅图": "America/Los_Angeles", "seattle": "America/Los_Angeles",
+	"拉斯维加斯": "America/Los_Angeles", "las vegas": "America/Los_Angeles",
+	"夏威夷": "Pacific/Honolulu", "hawaii": "Pacific/Honolulu",
+	"檀香山": "Pacific/Honolulu", "honolulu": "Pacific/Honolulu",
+	"温哥华": "America/Vancouver", "vancouver": "America/Vancouver",
+	"多伦多": "America/Toronto", "toronto": "America/Toronto",
+	"墨西哥城": "America/Mexico_City", "mexico city": "America/Mexico_City",
+	"坎昆": "America/Cancun", "cancun": "America/Cancun",
+	// 大洋洲和非洲
+	"悉尼": "Australia/Sydney", "sydney": "Australia/Sydney",
+	"墨尔本": "Australia/Melbourne", "melbourne": "Australia/Melbourne",
+	"布里斯班": "Australia/Brisbane", "brisbane": "Australia/Brisbane",
+	"珀斯": "Australia/Perth", "perth": "Australia/Perth",
+	"奥克兰": "Pacific/Auckland", "auckland": "Pacific/Auckland",
+	"皇后镇": "Pacific/Auckland", "queenstown": "Pacific/Auckland",
+	"开罗": "Africa/Cairo", "cairo": "Africa/Cairo",
+	"开普敦": "Africa/Johannesburg", "cape town": "Africa/Johannesburg",
+	"内罗毕": "Africa/Nairobi", "nairobi": "Africa/Nairobi",
+}
+
+// loadLocation 按IANA时区名称或常见城市、国家名称查找时区
+func loadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("需要指定地点或时区")
+	}
+	if zone, ok := cityZones[strings.ToLower(name)]; ok {
+		name = zone
+	}
+	// 不接受Local，它取决于服务器的时区
+	if strings.EqualFold(name, "Local") {
+		return nil, fmt.Errorf("未知的地点或时区: %s", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("未知的地点或时区: %s，请使用IANA时区名称，如Asia/Tokyo、Europe/Paris", name)
+	}
+	return loc, nil
+}
+
+// formatOffset 把相对UTC的秒数格式化为UTC+08:00的形式
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("UTC%s%02d:%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// getTimezoneArgs get_timezone工具的参数
+type getTimezoneArgs struct {
+	Location string `json:"location"`
+	Date     string `json:"date"`
+}
+
+// getTimezoneResult get_timezone工具的结果
+type getTimezoneResult struct {
+	Location     string `json:"location"`
+	TimeZone     string `json:"time_zone"`
+	Date         string `json:"date"`
+	UTCOffset    string `json:"utc_offset"`
+	Abbreviation string `json:"abbreviation"`
+	IsDST        bool   `json:"is_dst"`
+	LocalTime    string `json:"local_time,omitempty"` // 未指定日期时为当前的当地时间
+}
+
+// timezoneTool 查询地点的时区和某天的UTC偏移
+func timezoneTool() tool.InvokableTool {
+	return newTool("get_timezone",
+		"查询城市或国家的时区、UTC偏移以及指定日期是否处于夏令时",
+		map[string]*schema.ParameterInfo{
+			"location": {Type: schema.String, Desc: "城市或国家的中文或英文名称，或IANA时区名称，如Asia/Tokyo", Required: true},
+			"date":     {Type: schema.String, Desc: "日期，格式YYYY-MM-DD，默认今天"},
+		},
+		func(ctx context.Context, args *getTimezoneArgs) (interface{}, error) {
+			loc, err := loadLocation(args.Location)
+			if err != nil {
+				return nil, err
+			}
+			result := &getTimezoneResult{Location: args.Location, TimeZone: loc.String()}
+			var at time.Time
+			if args.Date == "" {
+				at = time.Now().In(loc)
+				result.LocalTime = at.Format(timeLayout)
+			} else {
+				date, err := parseDate("date", args.Date)
+				if err != nil {
+					return nil, err
+				}
+				// 取当地正午，避开夏令时切换的时刻
+				at = time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, loc)
+			}
+			abbreviation, offset := at.Zone()
+			result.Date = at.Format(dateLayout)
+			result.UTCOffset = formatOffset(offset)
+			result.Abbreviation = abbreviation
+			result.IsDST = at.IsDST()
+			return result, nil
+		})
+}
+
+// convertTimeArgs convert_time工具的参数
+type convertTimeArgs struct {
+	Time string `json:"time"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// convertTimeResult convert_time工具的结果
+type convertTimeResult struct {
+	FromTime       string  `json:"from_time"`
+	FromTimeZone   string  `json:"from_time_zone"`
+	ToTime         string  `json:"to_time"`
+	ToTimeZone     string  `json:"to_time_zone"`
+	DayOffset      int     `json:"day_offset"`      // 目标时间的日期比原时间的日期晚几天，可以为负数
+	HourDifference float64 `json:"hour_difference"` // 目标时区比原时区快几小时
+}
+
+// convertTimeTool 把一个时区的当地时间换算为另一个时区的当地时间
+func convertTimeTool() tool.InvokableTool {
+	return newTool("convert_time",
+		"把一个地点的当地时间换算为另一个地点的当地时间，考虑夏令时，用于安排航班落地、跨时区行程和时差调整",
+		map[string]*schema.ParameterInfo{
+			"time": {Type: schema.String, Desc: "原地点的当地时间，格式YYYY-MM-DD HH:MM", Required: true},
+			"from": {Type: schema.String, Desc: "原地点，城市或国家名称或IANA时区名称", Required: true},
+			"to":   {Type: schema.String, Desc: "目标地点，城市或国家名称或IANA时区名称", Required: true},
+		},
+		func(ctx context.Context, args *convertTimeArgs) (interface{}, error) {
+			from, err := loadLocation(args.From)
+			if err != nil {
+				return nil, err
+			}
+			to, err := loadLocation(args.To)
+			if err != nil {
+				return nil, err
+			}
+			at, err := time.ParseInLocation(timeLayout, strings.TrimSpace(args.Time), from)
+			if err != nil {
+				return nil, fmt.Errorf("time的格式应为YYYY-MM-DD HH:MM: %q", args.Time)
+			}
+			converted := at.In(to)
+			_, fromOffset := at.Zone()
+			_, toOffset := converted.Zone()
+			fromDate := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+			toDate := time.Date(converted.Year(), converted.Month(), converted.Day(), 0, 0, 0, 0, time.UTC)
+			return &convertTimeResult{
+				FromTime:       at.Format(timeLayout),
+				FromTimeZone:   from.String(),
+				ToTime:         converted.Format(timeLayout),
+				ToTimeZone:     to.String(),
+				DayOffset:      int(toDate.Sub(fromDate).Hours() / 24),
+				HourDifference: float64(toOffset-fromOffset) / 3600,
+			}, nil
+		})
+}
@@ -0,0 +1,97 @@
+package traveltools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"personatrip/internal/models"
+)
+
+// dateLayout 工具参数和结果中日期的格式
+const dateLayout = "2006-01-02"
+
+// RateSource 管理员维护的汇率表
+type RateSource interface {
+	ListExchangeRates(ctx context.Context) ([]models.ExchangeRate, error)
+}
+
+// New 返回在进程内运行、不需要访问网络的旅行工具：时区、日期、节假日、距离，
+// 以及rates不为空时的货币换算。工具与MCP工具一起交给智能体，同样受工具策略限制
+func New(rates RateSource) []tool.BaseTool {
+	tools := []tool.BaseTool{
+		timezoneTool(),
+		convertTimeTool(),
+		dateInfoTool(),
+		publicHolidaysTool(),
+		distanceTool(),
+	}
+	if rates != nil {
+		tools = append([]tool.BaseTool{convertCurrencyTool(rates)}, tools...)
+	}
+	return tools
+}
+
+// localTool 参数为T的进程内工具，run返回的错误作为结果返回给模型，模型可以修正参数后重试
+type localTool[T any] struct {
+	info *schema.ToolInfo
+	run  func(ctx context.Context, args *T) (interface{}, error)
+}
+
+// newTool 创建进程内工具
+func newTool[T any](name, desc string, params map[string]*schema.ParameterInfo, run func(ctx context.Context, args *T) (interface{}, error)) tool.InvokableTool {
+	return &localTool[T]{
+		info: &schema.ToolInfo{
+			Name:        name,
+			Desc:        desc,
+			ParamsOneOf: schema.NewParamsOneOfByParams(params),
+		},
+		run: run,
+	}
+}
+
+// Info 返回工具信息
+func (t *localTool[T]) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return t.info, nil
+}
+
+// InvokableRun 解析参数并运行工具，结果编码为JSON
+func (t *localTool[T]) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	args := new(T)
+	if argumentsInJSON != "" {
+		if err := json.Unmarshal([]byte(argumentsInJSON), args); err != nil {
+			return errorResult(fmt.Errorf("参数不是有效的JSON: %v", err)), nil
+		}
+	}
+	result, err := t.run(ctx, args)
+	if err != nil {
+		return errorResult(err), nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// errorResult 工具失败时返回给模型的结果
+func errorResult(err error) string {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return string(data)
+}
+
+// parseDate 解析YYYY-MM-DD格式的日期，为空时返回今天
+func parseDate(name, value string) (time.Time, error) {
+	if value == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s的格式应为YYYY-MM-DD: %q", name, value)
+	}
+	return date, nil
+}
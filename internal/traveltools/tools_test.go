@@ -0,0 +1,284 @@
+package traveltools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"personatrip/internal/models"
+)
+
+// fakeRates 固定的汇率表
+type fakeRates struct {
+	rates []models.ExchangeRate
+	err   error
+}
+
+func (f *fakeRates) ListExchangeRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	return f.rates, f.err
+}
+
+// testRates 测试使用的汇率，以人民币为基准
+var testRates = &fakeRates{rates: []models.ExchangeRate{
+	{Currency: "USD", Rate: 7.2, Source: "test", UpdatedAt: time.Date(2025, 4, 20, 0, 0, 0, 0, time.UTC)},
+	{Currency: "JPY", Rate: 0.048, Source: "test", UpdatedAt: time.Date(2025, 4, 21, 0, 0, 0, 0, time.UTC)},
+}}
+
+// call 按名称调用工具，返回解码后的JSON结果
+func call(t *testing.T, tools []tool.BaseTool, name, arguments string) map[string]interface{} {
+	t.Helper()
+	for _, tl := range tools {
+		info, err := tl.Info(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Name != name {
+			continue
+		}
+		text, err := tl.(tool.InvokableTool).InvokableRun(context.Background(), arguments)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var result map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &result); err != nil {
+			t.Fatalf("%s returned invalid JSON %q: %v", name, text, err)
+		}
+		return result
+	}
+	t.Fatalf("tool %s not found", name)
+	return nil
+}
+
+// checkFields 检查结果中的字段，want为nil时要求工具返回错误
+func checkFields(t *testing.T, result map[string]interface{}, want map[string]interface{}) {
+	t.Helper()
+	if want == nil {
+		if _, ok := result["error"]; !ok {
+			t.Fatalf("result = %v, want error", result)
+		}
+		return
+	}
+	if errMsg, ok := result["error"]; ok {
+		t.Fatalf("unexpected error: %v", errMsg)
+	}
+	for key, value := range want {
+		if got := result[key]; !reflect.DeepEqual(got, value) {
+			t.Fatalf("%s = %#v, want %#v (result %v)", key, got, value, result)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if got := len(New(nil)); got != 5 {
+		t.Fatalf("New(nil) returned %d tools, want 5 without convert_currency", got)
+	}
+	if got := len(New(testRates)); got != 6 {
+		t.Fatalf("New(rates) returned %d tools, want 6", got)
+	}
+}
+
+func TestConvertCurrency(t *testing.T) {
+	tools := New(testRates)
+	tests := []struct {
+		name      string
+		arguments string
+		want      map[string]interface{}
+	}{
+		{name: "through base currency", arguments: `{"amount":100,"from":"USD","to":"JPY"}`,
+			want: map[string]interface{}{"result": 15000.0, "rate": 150.0, "as_of": "2025-04-20", "source": "test"}},
+		{name: "to base currency", arguments: `{"amount":10,"from":"usd","to":"cny"}`,
+			want: map[string]interface{}{"from": "USD", "to": "CNY", "result": 72.0}},
+		{name: "from base currency", arguments: `{"amount":72,"from":"CNY","to":"USD"}`,
+			want: map[string]interface{}{"result": 10.0, "as_of": "2025-04-20"}},
+		{name: "unknown currency", arguments: `{"amount":1,"from":"EUR","to":"CNY"}`},
+		{name: "missing currency", arguments: `{"amount":1,"from":"USD"}`},
+		{name: "invalid json", arguments: `{"amount":`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkFields(t, call(t, tools, "convert_currency", tt.arguments), tt.want)
+		})
+	}
+
+	failing := New(&fakeRates{err: errors.New("db down")})
+	checkFields(t, call(t, failing, "convert_currency", `{"amount":1,"from":"USD","to":"CNY"}`), nil)
+}
+
+func TestGetTimezone(t *testing.T) {
+	tools := New(nil)
+	tests := []struct {
+		name      string
+		arguments string
+		want      map[string]interface{}
+	}{
+		{name: "chinese city name", arguments: `{"location":"东京","date":"2025-07-01"}`,
+			want: map[string]interface{}{"time_zone": "Asia/Tokyo", "utc_offset": "UTC+09:00", "is_dst": false}},
+		{name: "daylight saving time", arguments: `{"location":"纽约","date":"2025-07-01"}`,
+			want: map[string]interface{}{"time_zone": "America/New_York", "utc_offset": "UTC-04:00", "is_dst": true}},
+		{name: "standard time", arguments: `{"location":"New York","date":"2025-01-15"}`,
+			want: map[string]interface{}{"utc_offset": "UTC-05:00", "is_dst": false}},
+		{name: "iana name", arguments: `{"location":"Asia/Kolkata","date":"2025-01-15"}`,
+			want: map[string]interface{}{"utc_offset": "UTC+05:30"}},
+		{name: "server local zone", arguments: `{"location":"Local"}`},
+		{name: "unknown location", arguments: `{"location":"Atlantis"}`},
+		{name: "invalid date", arguments: `{"location":"东京","date":"2025/07/01"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkFields(t, call(t, tools, "get_timezone", tt.arguments), tt.want)
+		})
+	}
+}
+
+func TestConvertTime(t *testing.T) {
+	tools := New(nil)
+	tests := []struct {
+		name      string
+		arguments string
+		want      map[string]interface{}
+	}{
+		{name: "same day", arguments: `{"time":"2025-03-01 23:00","from":"北京","to":"伦敦"}`,
+			want: map[string]interface{}{"to_time": "2025-03-01 15:00", "day_offset": 0.0, "hour_difference": -8.0}},
+		{name: "previous day", arguments: `{"time":"2025-03-01 08:00","from":"上海","to":"洛杉矶"}`,
+			want: map[string]interface{}{"to_time": "2025-02-28 16:00", "day_offset": -1.0, "hour_difference": -16.0}},
+		{name: "next day with daylight saving time", arguments: `{"time":"2025-07-01 20:00","from":"纽约","to":"东京"}`,
+			want: map[string]interface{}{"to_time": "2025-07-02 09:00", "day_offset": 1.0, "hour_difference": 13.0}},
+		{name: "invalid time", arguments: `{"time":"8am","from":"北京","to":"伦敦"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkFields(t, call(t, tools, "convert_time", tt.arguments), tt.want)
+		})
+	}
+}
+
+func TestDateInfo(t *testing.T) {
+	tools := New(nil)
+	tests := []struct {
+		name      string
+		arguments string
+		want      map[string]interface{}
+	}{
+		{name: "weekday", arguments: `{"date":"2025-10-01"}`,
+			want: map[string]interface{}{"weekday": "Wednesday", "weekday_zh": "星期三", "is_weekend": false, "iso_week": 40.0}},
+		{name: "add days across month", arguments: `{"date":"2025-10-01","add_days":31}`,
+			want: map[string]interface{}{"result_date": "2025-11-01", "result_weekday": "星期六"}},
+		{name: "days and nights", arguments: `{"date":"2025-10-01","end_date":"2025-10-07"}`,
+			want: map[string]interface{}{"days_between": 6.0, "days": 7.0, "nights": 6.0}},
+		{name: "end before start", arguments: `{"date":"2025-10-07","end_date":"2025-10-01"}`,
+			want: map[string]interface{}{"days_between": -6.0, "days": nil}},
+		{name: "offset too large", arguments: `{"date":"2025-10-01","add_days":100000}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkFields(t, call(t, tools, "date_info", tt.arguments), tt.want)
+		})
+	}
+}
+
+func TestPublicHolidays(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     publicHolidaysArgs
+		holidays []string
+		workdays []string
+		covered  bool
+		wantErr  bool
+	}{
+		{name: "overlapping holiday and make-up workday", args: publicHolidaysArgs{Country: "cn", StartDate: "2025-10-05", EndDate: "2025-10-12"},
+			holidays: []string{"国庆节、中秋节"}, workdays: []string{"2025-10-11"}, covered: true},
+		{name: "single day", args: publicHolidaysArgs{Country: "JP", StartDate: "2025-05-05"},
+			holidays: []string{"こどもの日"}, covered: true},
+		{name: "no holidays", args: publicHolidaysArgs{Country: "GB", StartDate: "2025-06-01", EndDate: "2025-06-30"},
+			holidays: []string{}, covered: true},
+		{name: "outside covered years", args: publicHolidaysArgs{Country: "US", StartDate: "2026-12-20", EndDate: "2027-01-05"},
+			holidays: []string{"Christmas Day"}, covered: false},
+		{name: "unknown country", args: publicHolidaysArgs{Country: "XX", StartDate: "2025-01-01"}, wantErr: true},
+		{name: "end before start", args: publicHolidaysArgs{Country: "CN", StartDate: "2025-10-05", EndDate: "2025-10-01"}, wantErr: true},
+		{name: "range too long", args: publicHolidaysArgs{Country: "CN", StartDate: "2025-01-01", EndDate: "2026-12-31"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := publicHolidays(&tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("publicHolidays succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := make([]string, 0, len(result.Holidays))
+			for _, h := range result.Holidays {
+				names = append(names, h.Name)
+			}
+			if !reflect.DeepEqual(names, tt.holidays) {
+				t.Fatalf("holidays = %v, want %v", names, tt.holidays)
+			}
+			if len(result.Workdays) != 0 || len(tt.workdays) != 0 {
+				if !reflect.DeepEqual(result.Workdays, tt.workdays) {
+					t.Fatalf("workdays = %v, want %v", result.Workdays, tt.workdays)
+				}
+			}
+			if result.Covered != tt.covered {
+				t.Fatalf("covered = %v, want %v", result.Covered, tt.covered)
+			}
+		})
+	}
+}
+
+func TestHolidayData(t *testing.T) {
+	for code, country := range holidayCountries {
+		for _, h := range country.Holidays {
+			start, err := time.Parse(dateLayout, h.Start)
+			if err != nil {
+				t.Fatalf("%s %s: invalid start %q", code, h.Name, h.Start)
+			}
+			end, err := time.Parse(dateLayout, h.End)
+			if err != nil {
+				t.Fatalf("%s %s: invalid end %q", code, h.Name, h.End)
+			}
+			if end.Before(start) {
+				t.Fatalf("%s %s: ends before it starts", code, h.Name)
+			}
+			if !containsYear(country.Years, start.Year()) {
+				t.Fatalf("%s %s: year %d not listed in years", code, h.Name, start.Year())
+			}
+		}
+		for _, day := range country.Workdays {
+			date, err := time.Parse(dateLayout, day)
+			if err != nil {
+				t.Fatalf("%s: invalid workday %q", code, day)
+			}
+			if date.Weekday() != time.Saturday && date.Weekday() != time.Sunday {
+				t.Fatalf("%s: make-up workday %s is not a weekend", code, day)
+			}
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tools := New(nil)
+	result := call(t, tools, "distance", `{"from_lat":39.9042,"from_lng":116.4074,"to_lat":31.2304,"to_lng":121.4737}`)
+	if got, _ := result["distance_km"].(float64); math.Abs(got-1067) > 5 {
+		t.Fatalf("Beijing to Shanghai = %v km, want about 1067", result["distance_km"])
+	}
+
+	for _, arguments := range []string{
+		`{"from_lat":91,"from_lng":0,"to_lat":0,"to_lng":0}`,
+		`{"from_lat":0,"from_lng":0,"to_lat":0,"to_lng":-181}`,
+		`{"from_lat":0,"from_lng":0,"to_lat":0}`,
+	} {
+		checkFields(t, call(t, tools, "distance", arguments), nil)
+	}
+
+	// 坐标为0时不能当作未填写
+	checkFields(t, call(t, tools, "distance", `{"from_lat":0,"from_lng":0,"to_lat":0,"to_lng":0}`),
+		map[string]interface{}{"distance_km": 0.0})
+}
@@ -2,6 +2,7 @@ package httputil
 
 import (
 	"net/http"
+	"strconv"
 
 	"personatrip/internal/models"
 
@@ -99,3 +100,9 @@ func ReturnNotFound(c *gin.Context, message string) {
 func ReturnInternalError(c *gin.Context, message string) {
 	c.JSON(http.StatusInternalServerError, models.NewErrorResponse(http.StatusInternalServerError, message))
 }
+
+// ReturnTooManyRequests 返回429错误响应，并通过Retry-After头告知客户端多少秒后重试
+func ReturnTooManyRequests(c *gin.Context, message string, retryAfter int) {
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, models.NewErrorResponse(http.StatusTooManyRequests, message))
+}
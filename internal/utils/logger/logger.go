@@ -13,6 +13,9 @@ import (
 var (
 	// Log 是全局日志实例
 	Log *logrus.Logger
+
+	// console 控制台输出，默认为标准输出
+	console io.Writer = os.Stdout
 )
 
 // 初始化日志
@@ -23,8 +26,9 @@ func init() {
 		FullTimestamp:   true,
 		TimestampFormat: "2006-01-02 15:04:05",
 	})
-	Log.SetOutput(os.Stdout)
+	Log.SetOutput(console)
 	Log.SetLevel(logrus.InfoLevel)
+	Log.AddHook(redactor)
 }
 
 // SetLogLevel 设置日志级别
@@ -43,6 +47,12 @@ func SetLogLevel(level string) {
 	}
 }
 
+// SetConsole 设置控制台输出，如标准输出需要留给其他用途时改为标准错误，需要在SetLogOutput之前调用
+func SetConsole(w io.Writer) {
+	console = w
+	Log.SetOutput(w)
+}
+
 // SetLogOutput 设置日志输出到文件和控制台
 func SetLogOutput(logPath string) {
 	if logPath == "" {
@@ -64,7 +74,7 @@ func SetLogOutput(logPath string) {
 	}
 
 	// 同时输出到文件和控制台
-	mw := io.MultiWriter(os.Stdout, file)
+	mw := io.MultiWriter(console, file)
 	Log.SetOutput(mw)
 }
 
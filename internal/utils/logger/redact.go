@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// redactedText 替换密钥的文本
+const redactedText = "[REDACTED]"
+
+// minSecretLength 登记的密钥的最短长度，过短的值容易误伤正常日志
+const minSecretLength = 8
+
+// redactHook 在日志输出前把消息和字段中已登记的密钥替换为redactedText
+type redactHook struct {
+	mu       sync.RWMutex
+	secrets  map[string]struct{}
+	replacer *strings.Replacer
+}
+
+var redactor = &redactHook{secrets: make(map[string]struct{})}
+
+// RegisterSecret 登记需要在日志中隐藏的密钥，之后输出的日志中出现该值时会被替换
+func RegisterSecret(secret string) {
+	if len(secret) < minSecretLength {
+		return
+	}
+	redactor.mu.Lock()
+	defer redactor.mu.Unlock()
+	if _, ok := redactor.secrets[secret]; ok {
+		return
+	}
+	redactor.secrets[secret] = struct{}{}
+
+	pairs := make([]string, 0, len(redactor.secrets)*2)
+	for s := range redactor.secrets {
+		pairs = append(pairs, s, redactedText)
+	}
+	redactor.replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact 隐藏文本中已登记的密钥
+func Redact(text string) string {
+	redactor.mu.RLock()
+	replacer := redactor.replacer
+	redactor.mu.RUnlock()
+	if replacer == nil {
+		return text
+	}
+	return replacer.Replace(text)
+}
+
+// Levels 对所有级别的日志生效
+func (h *redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire 隐藏消息和字段中的密钥，非字符串字段按格式化后的文本检查
+func (h *redactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = Redact(entry.Message)
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = Redact(v)
+		case error, fmt.Stringer:
+			text := fmt.Sprint(v)
+			if redacted := Redact(text); redacted != text {
+				entry.Data[key] = redacted
+			}
+		}
+	}
+	return nil
+}
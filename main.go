@@ -1,11 +1,27 @@
 package main
 
 import (
+	"os"
+
 	"personatrip/cmd"
 	"personatrip/internal/utils/logger"
 )
 
 func main() {
+	// personatrip eval 运行离线评测，personatrip mcp-serve 作为MCP服务器运行，其他情况启动服务器
+	if len(os.Args) > 1 && os.Args[1] == "eval" {
+		if err := cmd.Eval(os.Args[2:]); err != nil {
+			logger.Fatalf("Evaluation failed: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "mcp-serve" {
+		if err := cmd.MCPServe(os.Args[2:]); err != nil {
+			logger.Fatalf("MCP server failed: %v", err)
+		}
+		return
+	}
+
 	if err := cmd.Execute(); err != nil {
 		logger.Fatalf("Failed to start server: %v", err)
 	}
@@ -0,0 +1,120 @@
+package einosdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/flow/agent/react"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	// agentMaxStep react智能体的最大步数
+	agentMaxStep = 80
+	// DefaultSystemPrompt 请求未指定系统提示词时智能体使用的系统提示词
+	DefaultSystemPrompt = "你是一个旅行规划助手，帮助用户规划旅行计划，请返回有效的JSON格式数据，不要添加任何代码块反引号(```)或其他标记。"
+
+	// arkReasoningKey Ark模型在Extra中存放推理内容的键
+	arkReasoningKey = "ark-reasoning-content"
+	// reasoningKey 本包实现的对话模型在Extra中存放推理内容的键
+	reasoningKey = "reasoning-content"
+)
+
+// ToolCallChecker 判断模型的流式输出是否包含工具调用
+type ToolCallChecker func(ctx context.Context, sr *schema.StreamReader[*schema.Message]) (bool, error)
+
+// runAgent 使用react智能体驱动对话模型，模型可以多轮调用req.Tools中的工具，
+// 推理内容、增量文本和工具调用通过emit回调，ctx中有生成过程记录时同时记录每一步模型调用和工具调用，
+// 返回最终的完整文本和各步token用量之和
+func runAgent(ctx context.Context, chatModel model.ToolCallingChatModel, req *GenerateTextRequest, emit StreamHandler, checker ToolCallChecker) (*GenerateTextResponse, error) {
+	counter := &usageCounter{}
+	trace := traceFromContext(ctx)
+	systemPrompt := req.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = DefaultSystemPrompt
+	}
+	messages := []*schema.Message{
+		schema.SystemMessage(systemPrompt),
+		schema.UserMessage(req.Prompt),
+	}
+
+	ragent, err := react.NewAgent(ctx, &react.AgentConfig{
+		ToolCallingModel: withTracing(withUsageTracking(chatModel, counter), trace),
+		ToolsConfig: compose.ToolsNodeConfig{
+			Tools: req.Tools,
+		},
+		MaxStep:               agentMaxStep,
+		StreamToolCallChecker: checker,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建智能体失败: %w", err)
+	}
+
+	reader, err := ragent.Stream(ctx, messages, toolEventOption(emit, trace))
+	if err != nil {
+		return nil, fmt.Errorf("智能体生成失败: %w", err)
+	}
+	defer reader.Close()
+
+	var fullResponse strings.Builder
+	for {
+		chunk, err := reader.Recv()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("接收数据时出错: %w", err)
+			}
+			break
+		}
+		// 同一分片可能同时带有推理内容和正文，分别处理
+		if reasoning := reasoningContent(chunk); reasoning != "" {
+			emit(&StreamEvent{Type: StreamEventReasoning, Content: reasoning})
+		}
+		if chunk.Content != "" {
+			fullResponse.WriteString(chunk.Content)
+			emit(&StreamEvent{Type: StreamEventDelta, Content: chunk.Content})
+		}
+	}
+
+	return &GenerateTextResponse{
+		Text:  fullResponse.String(),
+		Usage: counter.total(),
+	}, nil
+}
+
+// reasoningContent 从消息的Extra中取出推理内容
+func reasoningContent(msg *schema.Message) string {
+	if msg == nil || msg.Extra == nil {
+		return ""
+	}
+	for _, key := range []string{arkReasoningKey, reasoningKey} {
+		if v, ok := msg.Extra[key]; ok && v != nil {
+			if s := fmt.Sprint(v); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// StreamToolCallChecker 读取完整的流式输出，只要任一分片包含工具调用即返回true，
+// 适用于先输出文本再输出工具调用的模型
+func StreamToolCallChecker(ctx context.Context, sr *schema.StreamReader[*schema.Message]) (bool, error) {
+	defer sr.Close()
+	for {
+		msg, err := sr.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return false, nil
+			}
+			return false, err
+		}
+		if len(msg.ToolCalls) > 0 {
+			return true, nil
+		}
+	}
+}
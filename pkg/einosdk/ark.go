@@ -5,92 +5,106 @@ import (
 	"errors"
 	"fmt"
 	"github.com/cloudwego/eino-ext/components/model/ark"
-	"github.com/cloudwego/eino/compose"
-	"github.com/cloudwego/eino/flow/agent/react"
 	"github.com/cloudwego/eino/schema"
+	arkmodel "github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
 	"io"
 	"personatrip/internal/utils/logger"
-	"strings"
 )
 
-// generateTextWithArk 使用Ark生成文本
-func (c *Client) generateTextWithArk(ctx context.Context, req *GenerateTextRequest) (*GenerateTextResponse, error) {
+func init() {
+	Register(arkProvider{})
+}
+
+// arkProvider 火山引擎Ark模型提供者，使用react智能体支持工具调用
+type arkProvider struct{}
+
+// Type 返回模型类型
+func (arkProvider) Type() ModelType {
+	return ModelTypeArk
+}
+
+// Defaults 返回默认配置
+func (arkProvider) Defaults() ProviderDefaults {
+	return ProviderDefaults{
+		Model:     "ark-large",
+		BaseURL:   "https://api.ark.com/v1",
+		APIKeyEnv: "ARK_API_KEY",
+	}
+}
+
+// Capabilities 返回支持的能力
+func (arkProvider) Capabilities() Capabilities {
+	return Capabilities{
+		ToolCalling: true,
+		Streaming:   true,
+	}
+}
+
+// Validate 校验配置
+func (arkProvider) Validate(cfg *ProviderConfig) error {
+	if cfg.APIKey == "" {
+		return fmt.Errorf("ARK API密钥是必需的")
+	}
+	if cfg.Model == "" {
+		return fmt.Errorf("ARK模型名称是必需的")
+	}
+	return nil
+}
+
+// GenerateText 使用Ark生成文本
+func (p arkProvider) GenerateText(ctx context.Context, cfg *ProviderConfig, req *GenerateTextRequest) (*GenerateTextResponse, error) {
+	return p.StreamText(ctx, cfg, req, newEmitter(nil))
+}
+
+// StreamText 使用Ark以流式方式生成文本，增量内容和工具调用通过emit回调
+func (p arkProvider) StreamText(ctx context.Context, cfg *ProviderConfig, req *GenerateTextRequest, emit StreamHandler) (*GenerateTextResponse, error) {
 	// 检查API密钥
-	apiKey := c.apiKey
+	apiKey := cfg.APIKey
 	if apiKey == "" {
 		return nil, fmt.Errorf("ARK API密钥是必需的")
 	}
-	logger.Info("正在准备调用ARK模型:", c.model)
+	logger.Info("正在准备调用ARK模型:", cfg.Model)
 	// 初始化模型
-	logger.Debugf("ARK配置: APIKey=%s, Model=%s, BaseURL=%s", apiKey, c.model, c.baseURL)
+	logger.Debugf("ARK配置: Model=%s, BaseURL=%s", cfg.Model, cfg.BaseURL)
 	model, err := ark.NewChatModel(ctx, &ark.ChatModelConfig{
 		APIKey:    apiKey,
-		Model:     c.model,
-		BaseURL:   c.baseURL,
+		Model:     cfg.Model,
+		BaseURL:   cfg.BaseURL,
 		MaxTokens: &req.MaxTokens,
 	})
 	if err != nil {
 		logger.Errorf("初始化ARK模型失败: %v", err)
-		logger.Info("切换到模拟模式...")
-		return c.generateTextMock(ctx, req)
+		return nil, fmt.Errorf("初始化ARK模型失败: %w", err)
 	}
 
-	// 准备消息
-	messages := []*schema.Message{
-		schema.SystemMessage("你是一个旅行规划助手，帮助用户规划旅行计划，请返回有效的JSON格式数据，不要添加任何代码块反引号(```)或其他标记。"),
-		schema.UserMessage(req.Prompt),
-	}
-
-	ragent, err := react.NewAgent(ctx, &react.AgentConfig{
-		Model:            model,
-		ToolCallingModel: model,
-		ToolsConfig: compose.ToolsNodeConfig{
-			Tools: req.Tools,
-		},
-		MaxStep:               80,
-		StreamToolCallChecker: ARKToolCallChecker,
-	})
+	logger.Info("--- ARK模型开始生成回复 ---")
+	resp, err := runAgent(ctx, model, req, emit, ARKToolCallChecker)
 	if err != nil {
-		logger.Errorf("react new agent failure: %v", err)
-		return nil, err
+		logger.Errorf("ARK智能体执行失败: %v", err)
+		return nil, wrapArkError(err)
 	}
+	logger.Info("--- ARK模型回复结束 ---")
 
-	reader, err := ragent.Stream(ctx, messages)
-	if err != nil {
-		logger.Errorf("ragent generate failure: %v", err)
-		return nil, err
+	if resp.Text == "" {
+		return nil, ErrNoTextGenerated
 	}
+	return resp, nil
+}
 
-	// 处理流式内容
-	logger.Info("--- ARK模型开始生成回复 ---")
-	var fullResponse strings.Builder
-	for {
-		chunk, err := reader.Recv()
-		if err != nil {
-			if err != io.EOF {
-				logger.Errorf("接收数据时出错: %v", err)
-			}
-			break
-		}
-		if chunk.Extra["ark-reasoning-content"] != nil {
-			fmt.Printf("%v", chunk.Extra["ark-reasoning-content"])
-		} else if chunk.Content != "" {
-			fullResponse.WriteString(chunk.Content)
-		}
+// wrapArkError 将Ark SDK的HTTP错误转换为APIError，便于上层判断是否可以切换模型重试
+func wrapArkError(err error) error {
+	var apiErr *arkmodel.APIError
+	if errors.As(err, &apiErr) && apiErr.HTTPStatusCode != 0 {
+		return fmt.Errorf("%w: %v", &APIError{StatusCode: apiErr.HTTPStatusCode, Body: apiErr.Message}, err)
 	}
-
-	logger.Info("--- ARK模型回复结束 ---")
-
-	// 如果没有收到任何内容，返回错误
-	if fullResponse.Len() == 0 {
-		logger.Info("未收到任何内容，切换到模拟模式...")
-		return c.generateTextMock(ctx, req)
+	var reqErr *arkmodel.RequestError
+	if errors.As(err, &reqErr) && reqErr.HTTPStatusCode != 0 {
+		return fmt.Errorf("%w: %v", &APIError{StatusCode: reqErr.HTTPStatusCode, Body: reqErr.Error()}, err)
 	}
-	return &GenerateTextResponse{
-		Text: fullResponse.String(),
-	}, nil
+	return err
 }
 
+// ARKToolCallChecker 检查Ark模型的流式输出是否包含工具调用，输出内容和推理内容由生成过程记录保存
 func ARKToolCallChecker(ctx context.Context, sr *schema.StreamReader[*schema.Message]) (bool, error) {
 	defer sr.Close()
 	for {
@@ -102,11 +116,6 @@ func ARKToolCallChecker(ctx context.Context, sr *schema.StreamReader[*schema.Mes
 			}
 			return false, err
 		}
-		if msg.Content != "" {
-			fmt.Printf(msg.Content)
-		} else if msg.Extra["ark-reasoning-content"] != "" {
-			fmt.Printf("%v", msg.Extra["ark-reasoning-content"])
-		}
 		if len(msg.ToolCalls) > 0 {
 			return true, nil
 		}
@@ -2,24 +2,28 @@ package einosdk
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/cloudwego/eino/components/tool"
 	"os"
+	"personatrip/internal/utils/logger"
 )
 
-// ModelType 表示支持的大模型类型
+// ModelType 表示支持的大模型类型，每种类型对应一个已注册的Provider
 type ModelType string
 
 const (
 	ModelTypeOpenAI ModelType = "openai"
 	ModelTypeOllama ModelType = "ollama"
 	ModelTypeArk    ModelType = "ark"
-	ModelTypeMock   ModelType = "mock" // 用于测试
+	ModelTypeMock   ModelType = "mock"   // 用于测试
+	ModelTypeReplay ModelType = "replay" // 录制上游模型的调用，之后离线回放
 )
 
 // Client 是Eino API的客户端
 type Client struct {
 	modelType ModelType
+	provider  Provider
 	apiKey    string
 	baseURL   string
 	model     string
@@ -49,68 +53,71 @@ func WithModel(model string) ClientOption {
 	}
 }
 
-// NewClient 创建一个新的Eino客户端
+// NewClient 创建一个新的Eino客户端，未设置的选项使用提供者的默认值
 func NewClient(modelType ModelType, opts ...ClientOption) *Client {
 	c := &Client{
 		modelType: modelType,
-		model:     getDefaultModel(modelType),
 	}
+	c.provider, _ = GetProvider(modelType)
 
 	// 应用选项
 	for _, opt := range opts {
 		opt(c)
 	}
 
+	if c.provider == nil {
+		return c
+	}
+	defaults := c.provider.Defaults()
+
+	// 如果没有设置模型名称，使用默认值
+	if c.model == "" {
+		c.model = defaults.Model
+	}
+
 	// 如果没有设置API密钥，尝试从环境变量获取
-	if c.apiKey == "" {
-		c.apiKey = getAPIKeyFromEnv(modelType)
+	if c.apiKey == "" && defaults.APIKeyEnv != "" {
+		c.apiKey = os.Getenv(defaults.APIKeyEnv)
 	}
 
 	// 如果没有设置基础URL，使用默认值
 	if c.baseURL == "" {
-		c.baseURL = getDefaultBaseURL(modelType)
+		c.baseURL = defaults.BaseURL
 	}
 
+	// 在日志中隐藏API密钥
+	logger.RegisterSecret(c.apiKey)
+
 	return c
 }
 
-// getDefaultModel 根据模型类型返回默认模型名称
-func getDefaultModel(modelType ModelType) string {
-	switch modelType {
-	case ModelTypeOpenAI:
-		return "gpt-3.5-turbo"
-	case ModelTypeOllama:
-		return "llama2"
-	case ModelTypeArk:
-		return "ark-large"
-	case ModelTypeMock:
-		return "mock-model"
-	default:
-		return "gpt-3.5-turbo"
+// ModelType 返回客户端使用的模型类型
+func (c *Client) ModelType() ModelType {
+	return c.modelType
+}
+
+// Capabilities 返回客户端所用提供者支持的能力
+func (c *Client) Capabilities() Capabilities {
+	if c.provider == nil {
+		return Capabilities{}
 	}
+	return c.provider.Capabilities()
 }
 
-// getAPIKeyFromEnv 从环境变量获取API密钥
-func getAPIKeyFromEnv(modelType ModelType) string {
-	switch modelType {
-	case ModelTypeOpenAI:
-		return os.Getenv("OPENAI_API_KEY")
-	case ModelTypeArk:
-		return os.Getenv("ARK_API_KEY")
-	default:
-		return ""
+// Validate 校验客户端配置是否可用
+func (c *Client) Validate() error {
+	if c.provider == nil {
+		return fmt.Errorf("unsupported model type: %s", c.modelType)
 	}
+	return c.provider.Validate(c.config())
 }
 
-// getDefaultBaseURL 获取默认的基础URL
-func getDefaultBaseURL(modelType ModelType) string {
-	switch modelType {
-	case ModelTypeOllama:
-		return "http://localhost:11434"
-	case ModelTypeArk:
-		return "https://api.ark.com/v1"
-	default:
-		return ""
+// config 返回传递给提供者的连接配置
+func (c *Client) config() *ProviderConfig {
+	return &ProviderConfig{
+		Model:   c.model,
+		APIKey:  c.apiKey,
+		BaseURL: c.baseURL,
 	}
 }
 
@@ -121,31 +128,33 @@ func getDefaultMaxToken() int {
 
 // GenerateTextRequest 是生成文本的请求参数
 type GenerateTextRequest struct {
-	Model       string          `json:"model"`
-	Prompt      string          `json:"prompt"`
-	MaxTokens   int             `json:"max_tokens"`
-	Temperature float32         `json:"temperature"`
-	Tools       []tool.BaseTool `json:"tools"`
+	Model        string          `json:"model"`
+	Prompt       string          `json:"prompt"`
+	SystemPrompt string          `json:"system_prompt,omitempty"` // 系统提示词，为空时使用DefaultSystemPrompt
+	MaxTokens    int             `json:"max_tokens"`
+	Temperature  float32         `json:"temperature"`
+	Tools        []tool.BaseTool `json:"tools"`
+	JSONMode     bool            `json:"json_mode"` // 要求模型只输出JSON，仅对支持JSONMode的提供者生效
+	// ResponseSchema 输出需要满足的JSON Schema，仅对支持StructuredOutput的提供者生效
+	ResponseSchema *ResponseSchema `json:"response_schema,omitempty"`
+}
+
+// ResponseSchema 约束模型输出结构的JSON Schema
+type ResponseSchema struct {
+	Name   string          `json:"name"` // 结构名称，OpenAI要求提供
+	Schema json.RawMessage `json:"schema"`
 }
 
 // GenerateTextResponse 是生成文本的响应
 type GenerateTextResponse struct {
-	Text string `json:"text"`
+	Text  string     `json:"text"`
+	Usage TokenUsage `json:"usage"` // token用量，智能体多步调用时为各步之和
 }
 
 // GenerateText 调用Eino API生成文本
 func (c *Client) GenerateText(ctx context.Context, req *GenerateTextRequest) (*GenerateTextResponse, error) {
-	// 根据不同的模型类型调用不同的API
-	switch c.modelType {
-	case ModelTypeOpenAI:
-		return c.generateTextWithOpenAI(ctx, req)
-	case ModelTypeOllama:
-		return c.generateTextWithOllama(ctx, req)
-	case ModelTypeArk:
-		return c.generateTextWithArk(ctx, req)
-	case ModelTypeMock:
-		return c.generateTextMock(ctx, req)
-	default:
+	if c.provider == nil {
 		return nil, fmt.Errorf("unsupported model type: %s", c.modelType)
 	}
+	return c.provider.GenerateText(ctx, c.config(), req)
 }
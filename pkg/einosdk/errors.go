@@ -0,0 +1,47 @@
+package einosdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// ErrNoTextGenerated 模型没有返回任何内容
+var ErrNoTextGenerated = errors.New("no text generated")
+
+// APIError 模型接口返回的非200响应
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error 实现error接口
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// newAPIError 根据HTTP状态码和响应体创建APIError
+func newAPIError(statusCode int, body []byte) error {
+	return &APIError{StatusCode: statusCode, Body: string(body)}
+}
+
+// IsTransient 判断错误是否为暂时性的模型故障，换一个模型重试可能成功：
+// 超时、网络连接失败、限流(429)、服务端错误(5xx)以及模型没有返回内容
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrNoTextGenerated) {
+		return true
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
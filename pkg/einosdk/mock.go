@@ -4,77 +4,441 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 )
 
-// generateTextMock 生成模拟文本响应
-func (c *Client) generateTextMock(ctx context.Context, req *GenerateTextRequest) (*GenerateTextResponse, error) {
-	// 这是一个模拟实现，用于测试
-	// 根据提示词中的目的地生成不同的响应
-	var destination string
-	if req.Prompt != "" {
-		// 尝试从提示词中提取目的地信息
-		var destMap map[string]string
-		if err := json.Unmarshal([]byte(`{"destination":"东京"}`), &destMap); err == nil {
-			destination = destMap["destination"]
+// mockStreamChunkSize 模拟流式输出时每个增量块包含的字符数
+const mockStreamChunkSize = 64
+
+// 内置的模拟脚本，通过模型配置中的模型名称选择
+const (
+	MockModelDefault     = "mock-model"       // 按提示词中的目的地和日期生成旅行计划，或返回目的地推荐
+	MockModelInvalid     = "mock-invalid"     // 总是返回无法解析的文本，用于测试修正和故障切换
+	MockModelUnavailable = "mock-unavailable" // 总是返回503，用于测试故障切换
+)
+
+// mockMaxDays 模拟旅行计划最多生成的天数
+const mockMaxDays = 14
+
+// MockResponder 模拟脚本，根据请求返回模型输出
+type MockResponder func(ctx context.Context, req *GenerateTextRequest) (string, error)
+
+// mockResponders 已注册的模拟脚本，键为模型名称
+var mockResponders = struct {
+	sync.RWMutex
+	scripts map[string]MockResponder
+}{
+	scripts: map[string]MockResponder{
+		MockModelDefault: planMockResponder,
+		MockModelInvalid: func(ctx context.Context, req *GenerateTextRequest) (string, error) {
+			return "抱歉，我暂时无法生成旅行计划。", nil
+		},
+		MockModelUnavailable: func(ctx context.Context, req *GenerateTextRequest) (string, error) {
+			return "", newAPIError(http.StatusServiceUnavailable, []byte("mock model unavailable"))
+		},
+	},
+}
+
+// RegisterMockResponder 注册模拟脚本，模型名称为name的模拟模型配置使用该脚本生成输出，重复注册会覆盖
+func RegisterMockResponder(name string, responder MockResponder) {
+	mockResponders.Lock()
+	defer mockResponders.Unlock()
+	mockResponders.scripts[name] = responder
+}
+
+// getMockResponder 获取模型名称对应的模拟脚本，未注册的名称使用默认脚本
+func getMockResponder(name string) MockResponder {
+	mockResponders.RLock()
+	defer mockResponders.RUnlock()
+	if responder, ok := mockResponders.scripts[name]; ok {
+		return responder
+	}
+	return planMockResponder
+}
+
+func init() {
+	Register(mockProvider{})
+}
+
+// mockProvider 模拟模型提供者，不访问网络，用于测试
+type mockProvider struct{}
+
+// Type 返回模型类型
+func (mockProvider) Type() ModelType {
+	return ModelTypeMock
+}
+
+// Defaults 返回默认配置
+func (mockProvider) Defaults() ProviderDefaults {
+	return ProviderDefaults{
+		Model: MockModelDefault,
+	}
+}
+
+// Capabilities 返回支持的能力，模拟输出总是JSON
+func (mockProvider) Capabilities() Capabilities {
+	return Capabilities{
+		Streaming: true,
+		JSONMode:  true,
+	}
+}
+
+// Validate 模拟模型不需要任何配置
+func (mockProvider) Validate(cfg *ProviderConfig) error {
+	return nil
+}
+
+// GenerateText 生成模拟文本响应
+func (p mockProvider) GenerateText(ctx context.Context, cfg *ProviderConfig, req *GenerateTextRequest) (*GenerateTextResponse, error) {
+	return p.StreamText(ctx, cfg, req, newEmitter(nil))
+}
+
+// StreamText 执行模型名称对应的模拟脚本，将输出按固定大小分块，以流式事件的形式输出
+func (mockProvider) StreamText(ctx context.Context, cfg *ProviderConfig, req *GenerateTextRequest, emit StreamHandler) (*GenerateTextResponse, error) {
+	started := time.Now()
+	text, err := getMockResponder(cfg.Model)(ctx, req)
+	if err != nil {
+		traceFromContext(ctx).add(TraceStep{Type: TraceStepModel, Error: err.Error(), StartedAt: started, LatencyMs: time.Since(started).Milliseconds()})
+		return nil, err
+	}
+
+	runes := []rune(text)
+	for start := 0; start < len(runes); start += mockStreamChunkSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := start + mockStreamChunkSize
+		if end > len(runes) {
+			end = len(runes)
 		}
+		emit(&StreamEvent{Type: StreamEventDelta, Content: string(runes[start:end])})
+	}
+
+	// 按字符数粗略估算token用量
+	promptTokens := len([]rune(req.SystemPrompt+req.Prompt)) / 2
+	completionTokens := len(runes) / 2
+	usage := TokenUsage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+	traceFromContext(ctx).add(TraceStep{
+		Type:      TraceStepModel,
+		Content:   text,
+		Usage:     &usage,
+		StartedAt: started,
+		LatencyMs: time.Since(started).Milliseconds(),
+	})
+	return &GenerateTextResponse{Text: text, Usage: usage}, nil
+}
+
+// 从提示词中提取旅行需求的规则，对应内置trip_plan和分阶段生成模板中的字段
+var (
+	mockDestinationPattern = regexp.MustCompile(`目的地[:：]\s*([^\n]+)`)
+	mockStartDatePattern   = regexp.MustCompile(`开始日期[:：]\s*(\d{4}-\d{2}-\d{2})`)
+	mockEndDatePattern     = regexp.MustCompile(`结束日期[:：]\s*(\d{4}-\d{2}-\d{2})`)
+	mockBudgetPattern      = regexp.MustCompile(`预算[:：]\s*([^\n]+)`)
+	mockDayPattern         = regexp.MustCompile(`第(\d+)天`)
+	mockDayDatePattern     = regexp.MustCompile(`当天日期[:：]\s*(\d{4}-\d{2}-\d{2})`)
+)
+
+// mockCostTier 预算等级对应的每日住宿、餐饮和活动费用
+type mockCostTier struct {
+	keywords              []string
+	hotel, meal, activity float64
+	hotelType, hotelName  string
+}
+
+// mockDailyCosts 按关键字匹配的预算等级，最后一项为默认等级
+var mockDailyCosts = []mockCostTier{
+	{[]string{"经济", "economy", "budget", "low"}, 200, 60, 50, "青年旅舍", "%s青年旅舍"},
+	{[]string{"豪华", "luxury", "high", "premium"}, 2000, 400, 500, "五星级酒店", "%s君悦酒店"},
+	{nil, 600, 150, 150, "酒店", "%s中心酒店"},
+}
 
-		if destination == "" {
-			destination = "东京" // 默认目的地
+// mockTrip 从提示词中提取的旅行需求
+type mockTrip struct {
+	destination string
+	start       time.Time
+	days        int
+	costs       mockCostTier
+}
+
+// planMockResponder 默认模拟脚本：要求结构化输出时按提示词中的目的地、日期和预算生成旅行计划，
+// 分阶段生成时按Schema名称返回对应阶段的输出，提示词要求推荐目的地时返回推荐列表
+func planMockResponder(ctx context.Context, req *GenerateTextRequest) (string, error) {
+	if req.ResponseSchema == nil && strings.Contains(req.Prompt, "推荐") {
+		return mockRecommendations(), nil
+	}
+
+	trip := parseMockTrip(req.Prompt)
+	var output interface{}
+	schemaName := ""
+	if req.ResponseSchema != nil {
+		schemaName = req.ResponseSchema.Name
+	}
+	// 分阶段生成的Schema名称与服务层的提示词模板名称相同
+	switch schemaName {
+	case "trip_skeleton":
+		output = trip.skeleton()
+	case "trip_day":
+		day := 1
+		if m := mockDayPattern.FindStringSubmatch(req.Prompt); m != nil {
+			fmt.Sscan(m[1], &day)
+		}
+		date := trip.start.AddDate(0, 0, day-1)
+		if m := mockDayDatePattern.FindStringSubmatch(req.Prompt); m != nil {
+			if t, err := time.Parse("2006-01-02", m[1]); err == nil {
+				date = t
+			}
 		}
+		output = trip.day(day, date)
+	case "trip_essentials":
+		output = trip.essentials()
+	case "trip_local_guide":
+		output = trip.localGuide()
+	default:
+		output = trip.plan()
 	}
 
-	// 生成示例旅行计划
-	planJSON := fmt.Sprintf(`{
-		"title": "%s旅行计划",
-		"days": [
-			{
-				"day": 1,
-				"date": "2025-05-01",
-				"activities": [
-					{
-						"name": "参观%s塔",
-						"type": "景点",
-						"location": {
-							"name": "%s塔",
-							"address": "%s中心区",
-							"city": "%s",
-							"country": "日本"
-						},
-						"start_time": "10:00",
-						"end_time": "12:00",
-						"description": "欣赏%s全景",
-						"cost": 2000
-					}
-				],
-				"meals": [
-					{
-						"type": "午餐",
-						"venue": "%s特色餐厅",
-						"description": "品尝当地美食",
-						"cost": 1500
-					}
-				],
-				"accommodation": {
-					"name": "%s中心酒店",
-					"type": "酒店",
-					"description": "位于市中心的舒适酒店",
-					"cost": 8000
+	data, err := json.Marshal(output)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// parseMockTrip 提取提示词中的目的地、日期和预算等级，缺少的字段使用默认值
+func parseMockTrip(prompt string) *mockTrip {
+	trip := &mockTrip{destination: "东京", start: time.Now()}
+	if m := mockDestinationPattern.FindStringSubmatch(prompt); m != nil && strings.TrimSpace(m[1]) != "" {
+		trip.destination = strings.TrimSpace(m[1])
+	}
+
+	if m := mockStartDatePattern.FindStringSubmatch(prompt); m != nil {
+		if t, err := time.Parse("2006-01-02", m[1]); err == nil {
+			trip.start = t
+		}
+	}
+	end := trip.start.AddDate(0, 0, 2)
+	if m := mockEndDatePattern.FindStringSubmatch(prompt); m != nil {
+		if t, err := time.Parse("2006-01-02", m[1]); err == nil && !t.Before(trip.start) {
+			end = t
+		}
+	}
+	trip.days = int(end.Sub(trip.start).Hours()/24) + 1
+	if trip.days > mockMaxDays {
+		trip.days = mockMaxDays
+	}
+
+	trip.costs = mockDailyCosts[len(mockDailyCosts)-1]
+	if m := mockBudgetPattern.FindStringSubmatch(prompt); m != nil {
+		budget := strings.ToLower(m[1])
+	tiers:
+		for _, tier := range mockDailyCosts {
+			for _, keyword := range tier.keywords {
+				if strings.Contains(budget, keyword) {
+					trip.costs = tier
+					break tiers
 				}
 			}
-		],
-		"budget": {
-			"currency": "JPY",
-			"total_estimate": 50000,
-			"accommodation": 24000,
-			"transportation": 10000,
-			"food": 10000,
-			"activities": 5000,
-			"other": 1000
+		}
+	}
+	return trip
+}
+
+// plan 生成完整的旅行计划
+func (t *mockTrip) plan() *mockPlan {
+	plan := &mockPlan{
+		Title:           t.title(),
+		DestinationInfo: t.destinationInfo(),
+		Notes:           t.notes(),
+	}
+	for i := 0; i < t.days; i++ {
+		plan.Days = append(plan.Days, t.day(i+1, t.start.AddDate(0, 0, i)))
+	}
+
+	n := float64(t.days)
+	plan.Budget = map[string]interface{}{
+		"currency":       "CNY",
+		"accommodation":  t.costs.hotel * n,
+		"food":           t.costs.meal * n,
+		"activities":     t.costs.activity * n,
+		"transportation": 100 * n,
+		"other":          50 * n,
+		"total_estimate": (t.costs.hotel + t.costs.meal + t.costs.activity + 150) * n,
+	}
+	return plan
+}
+
+// skeleton 生成分阶段生成的行程框架
+func (t *mockTrip) skeleton() map[string]interface{} {
+	days := make([]map[string]interface{}, 0, t.days)
+	for i := 1; i <= t.days; i++ {
+		days = append(days, map[string]interface{}{
+			"day":        i,
+			"theme":      fmt.Sprintf("%s城市漫步第%d站", t.destination, i),
+			"area":       t.destination + "老城区",
+			"highlights": []string{fmt.Sprintf("%s城市漫步第%d站", t.destination, i)},
+		})
+	}
+	return map[string]interface{}{
+		"title":            t.title(),
+		"destination_info": t.destinationInfo(),
+		"currency":         "CNY",
+		"days":             days,
+		"notes":            t.notes(),
+	}
+}
+
+// day 生成第day天的行程
+func (t *mockTrip) day(day int, date time.Time) mockDay {
+	return mockDay{
+		Day:  day,
+		Date: date.Format("2006-01-02"),
+		Activities: []mockActivity{
+			{
+				Name:        fmt.Sprintf("%s城市漫步第%d站", t.destination, day),
+				Type:        "景点",
+				Location:    map[string]string{"name": t.destination + "老城区", "city": t.destination},
+				StartTime:   "09:30",
+				EndTime:     "12:00",
+				Description: fmt.Sprintf("游览%s的代表性街区", t.destination),
+				Cost:        t.costs.activity / 2,
+			},
+			{
+				Name:        fmt.Sprintf("%s博物馆", t.destination),
+				Type:        "文化",
+				Location:    map[string]string{"name": t.destination + "博物馆", "city": t.destination},
+				StartTime:   "14:00",
+				EndTime:     "17:00",
+				Description: fmt.Sprintf("了解%s的历史和文化", t.destination),
+				Cost:        t.costs.activity / 2,
+			},
+		},
+		Meals: []mockMeal{
+			{Type: "早餐", Venue: "酒店餐厅", Cost: t.costs.meal * 0.2},
+			{Type: "午餐", Venue: t.destination + "特色餐馆", Description: "品尝当地美食", Cost: t.costs.meal * 0.3},
+			{Type: "晚餐", Venue: t.destination + "夜市", Description: "体验当地夜生活", Cost: t.costs.meal * 0.5},
+		},
+		Accommodation: map[string]interface{}{
+			"name": fmt.Sprintf(t.costs.hotelName, t.destination),
+			"type": t.costs.hotelType,
+			"cost": t.costs.hotel,
+		},
+	}
+}
+
+// essentials 生成分阶段生成的出行信息
+func (t *mockTrip) essentials() map[string]interface{} {
+	return map[string]interface{}{
+		"travel_info": map[string]interface{}{
+			"visa_required": false,
+			"safety_tips":   fmt.Sprintf("%s治安良好，注意保管随身物品", t.destination),
 		},
-		"notes": "这是一个AI生成的%s旅行计划示例"
-	}`, destination, destination, destination, destination, destination, destination, destination, destination, destination)
+		"weather_forecast": map[string]interface{}{
+			"climate_overview": "气候温和，早晚温差较大",
+		},
+		"packing_list": map[string]interface{}{
+			"essentials": []string{"身份证件", "充电宝"},
+			"clothing":   []string{"薄外套", "舒适的步行鞋"},
+		},
+		"emergency_contacts": map[string]interface{}{
+			"police":    "110",
+			"ambulance": "120",
+			"fire":      "119",
+		},
+		"payment_tips": map[string]interface{}{
+			"credit_cards_accepted":       true,
+			"recommended_payment_methods": []string{"移动支付", "银行卡"},
+		},
+	}
+}
+
+// localGuide 生成分阶段生成的当地指南
+func (t *mockTrip) localGuide() map[string]interface{} {
+	return map[string]interface{}{
+		"local_attractions": []map[string]interface{}{
+			{"name": t.destination + "博物馆", "category": "文化", "must_see": true, "cost": t.costs.activity / 2},
+		},
+		"local_cuisine": []map[string]interface{}{
+			{"name": t.destination + "特色小吃", "must_try": true, "where_to_find": []string{t.destination + "夜市"}},
+		},
+		"shopping": map[string]interface{}{
+			"souvenirs": []string{t.destination + "明信片"},
+		},
+		"shopping_budget":         t.costs.meal,
+		"suggested_modifications": "遇到雨天时可以把户外活动换成博物馆参观",
+	}
+}
+
+// title 返回计划标题
+func (t *mockTrip) title() string {
+	return fmt.Sprintf("%s%d日游", t.destination, t.days)
+}
+
+// destinationInfo 返回目的地信息
+func (t *mockTrip) destinationInfo() map[string]string {
+	return map[string]string{
+		"name":               t.destination,
+		"best_time_to_visit": "春秋两季",
+	}
+}
+
+// notes 返回计划备注
+func (t *mockTrip) notes() string {
+	return fmt.Sprintf("这是模拟模型根据请求生成的%s旅行计划，仅用于测试", t.destination)
+}
+
+// mockRecommendations 返回固定的目的地推荐
+func mockRecommendations() string {
+	data, _ := json.Marshal([]string{
+		"杭州：西湖山水与龙井茶文化",
+		"成都：美食之都，节奏悠闲",
+		"西安：十三朝古都，历史遗迹丰富",
+		"厦门：海滨城市，鼓浪屿文艺气息浓厚",
+		"大理：苍山洱海，适合慢旅行",
+	})
+	return string(data)
+}
+
+// mockPlan 模拟旅行计划的输出结构，字段与旅行计划的JSON格式一致
+type mockPlan struct {
+	Title           string                 `json:"title"`
+	DestinationInfo map[string]string      `json:"destination_info"`
+	Days            []mockDay              `json:"days"`
+	Budget          map[string]interface{} `json:"budget"`
+	Notes           string                 `json:"notes"`
+}
+
+// mockDay 模拟旅行计划的一天
+type mockDay struct {
+	Day           int                    `json:"day"`
+	Date          string                 `json:"date"`
+	Activities    []mockActivity         `json:"activities"`
+	Meals         []mockMeal             `json:"meals"`
+	Accommodation map[string]interface{} `json:"accommodation"`
+}
+
+// mockActivity 模拟旅行计划中的活动
+type mockActivity struct {
+	Name        string            `json:"name"`
+	Type        string            `json:"type"`
+	Location    map[string]string `json:"location"`
+	StartTime   string            `json:"start_time"`
+	EndTime     string            `json:"end_time"`
+	Description string            `json:"description"`
+	Cost        float64           `json:"cost"`
+}
 
-	return &GenerateTextResponse{
-		Text: planJSON,
-	}, nil
+// mockMeal 模拟旅行计划中的餐饮
+type mockMeal struct {
+	Type        string  `json:"type"`
+	Venue       string  `json:"venue"`
+	Description string  `json:"description,omitempty"`
+	Cost        float64 `json:"cost"`
 }
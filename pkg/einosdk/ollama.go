@@ -1,47 +1,137 @@
 package einosdk
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
 )
 
-// generateTextWithOllama 使用Ollama生成文本
-func (c *Client) generateTextWithOllama(ctx context.Context, req *GenerateTextRequest) (*GenerateTextResponse, error) {
-	// 构建请求URL
-	baseURL := c.baseURL
-	if baseURL == "" {
-		baseURL = "http://localhost:11434"
+func init() {
+	Register(ollamaProvider{})
+}
+
+// ollamaProvider 本地部署的Ollama模型提供者
+type ollamaProvider struct{}
+
+// Type 返回模型类型
+func (ollamaProvider) Type() ModelType {
+	return ModelTypeOllama
+}
+
+// Defaults 返回默认配置
+func (ollamaProvider) Defaults() ProviderDefaults {
+	return ProviderDefaults{
+		Model:   "llama2",
+		BaseURL: "http://localhost:11434",
 	}
-	url := fmt.Sprintf("%s/api/generate", baseURL)
+}
 
-	// 构建请求体
-	ollamaReq := map[string]interface{}{
-		"model":       c.model,
-		"prompt":      req.Prompt,
-		"temperature": req.Temperature,
-		"num_predict": req.MaxTokens,
-		"stream":      false,
+// Capabilities 返回支持的能力
+func (ollamaProvider) Capabilities() Capabilities {
+	return Capabilities{
+		ToolCalling:      true,
+		Streaming:        true,
+		JSONMode:         true,
+		StructuredOutput: true,
 	}
+}
 
-	// 将请求体转换为JSON
-	reqBody, err := json.Marshal(ollamaReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+// Validate 校验配置
+func (ollamaProvider) Validate(cfg *ProviderConfig) error {
+	if cfg.Model == "" {
+		return fmt.Errorf("Ollama model name is required")
 	}
+	return nil
+}
 
-	// 创建HTTP请求
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+// GenerateText 使用Ollama生成文本
+func (p ollamaProvider) GenerateText(ctx context.Context, cfg *ProviderConfig, req *GenerateTextRequest) (*GenerateTextResponse, error) {
+	return p.StreamText(ctx, cfg, req, newEmitter(nil))
+}
+
+// StreamText 使用Ollama对话接口驱动react智能体，增量内容和工具调用通过emit回调
+func (ollamaProvider) StreamText(ctx context.Context, cfg *ProviderConfig, req *GenerateTextRequest, emit StreamHandler) (*GenerateTextResponse, error) {
+	resp, err := runAgent(ctx, newOllamaChatModel(cfg, req), req, emit, StreamToolCallChecker)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
+	}
+	if resp.Text == "" {
+		return nil, ErrNoTextGenerated
 	}
 
-	// 设置请求头
-	httpReq.Header.Set("Content-Type", "application/json")
+	return resp, nil
+}
+
+// ollamaChatModel 基于Ollama /api/chat接口的ToolCallingChatModel实现
+type ollamaChatModel struct {
+	cfg         *ProviderConfig
+	temperature float32
+	maxTokens   int
+	jsonMode    bool
+	schema      *ResponseSchema
+	tools       []*schema.ToolInfo
+}
+
+// newOllamaChatModel 创建Ollama对话模型
+func newOllamaChatModel(cfg *ProviderConfig, req *GenerateTextRequest) *ollamaChatModel {
+	return &ollamaChatModel{
+		cfg:         cfg,
+		temperature: req.Temperature,
+		maxTokens:   req.MaxTokens,
+		jsonMode:    req.JSONMode,
+		schema:      req.ResponseSchema,
+	}
+}
+
+// WithTools 返回绑定了工具的新模型实例
+func (m *ollamaChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	nm := *m
+	nm.tools = tools
+	return &nm, nil
+}
+
+// ollamaMessage Ollama对话接口的消息格式
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+// ollamaToolCall Ollama的工具调用格式，参数为JSON对象而不是字符串，且没有调用ID
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// ollamaChatResponse Ollama对话接口的响应，流式模式下每行一个
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// Generate 以非流式方式调用对话接口
+func (m *ollamaChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	httpReq, err := m.newRequest(ctx, input, false, opts...)
+	if err != nil {
+		return nil, err
+	}
 
 	// 发送请求
 	client := &http.Client{Timeout: 120 * time.Second} // Ollama可能需要更长的超时时间
@@ -59,19 +149,189 @@ func (c *Client) generateTextWithOllama(ctx context.Context, req *GenerateTextRe
 
 	// 检查响应状态码
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(respBody))
+		return nil, newAPIError(resp.StatusCode, respBody)
 	}
 
 	// 解析响应
-	var ollamaResp struct {
-		Response string `json:"response"`
-	}
-
+	var ollamaResp ollamaChatResponse
 	if err := json.Unmarshal(respBody, &ollamaResp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
+	if ollamaResp.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", ollamaResp.Error)
+	}
+
+	var callIndex int
+	return fromOllamaResponse(&ollamaResp, &callIndex), nil
+}
+
+// Stream 以流式方式调用对话接口，响应为逐行的JSON对象
+func (m *ollamaChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	httpReq, err := m.newRequest(ctx, input, true, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// 流式请求的总时长由ctx控制，不设置整体超时
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(resp.StatusCode, respBody)
+	}
+
+	reader, writer := schema.Pipe[*schema.Message](16)
+	go func() {
+		defer resp.Body.Close()
+		defer writer.Close()
+
+		// 同一次响应中的工具调用按出现顺序编号
+		var callIndex int
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+
+			var chunk ollamaChatResponse
+			if err := json.Unmarshal(line, &chunk); err != nil {
+				writer.Send(nil, fmt.Errorf("failed to unmarshal stream chunk: %w", err))
+				return
+			}
+			if chunk.Error != "" {
+				writer.Send(nil, fmt.Errorf("ollama stream error: %s", chunk.Error))
+				return
+			}
+			if writer.Send(fromOllamaResponse(&chunk, &callIndex), nil) {
+				return
+			}
+			if chunk.Done {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			writer.Send(nil, fmt.Errorf("failed to read stream: %w", err))
+		}
+	}()
+
+	return reader, nil
+}
+
+// newRequest 构建Ollama对话接口的HTTP请求，请求地址为BaseURL下的/api/chat
+func (m *ollamaChatModel) newRequest(ctx context.Context, input []*schema.Message, stream bool, opts ...model.Option) (*http.Request, error) {
+	options := model.GetCommonOptions(&model.Options{
+		Temperature: &m.temperature,
+		MaxTokens:   &m.maxTokens,
+		Model:       &m.cfg.Model,
+		Tools:       m.tools,
+	}, opts...)
+
+	// 工具调用的结果消息需要带上工具名称
+	toolNames := make(map[string]string)
+	messages := make([]ollamaMessage, 0, len(input))
+	for _, msg := range input {
+		om := ollamaMessage{
+			Role:    string(msg.Role),
+			Content: msg.Content,
+		}
+		for _, tc := range msg.ToolCalls {
+			toolNames[tc.ID] = tc.Function.Name
+			call := ollamaToolCall{}
+			call.Function.Name = tc.Function.Name
+			call.Function.Arguments = json.RawMessage("{}")
+			if json.Valid([]byte(tc.Function.Arguments)) {
+				call.Function.Arguments = json.RawMessage(tc.Function.Arguments)
+			}
+			om.ToolCalls = append(om.ToolCalls, call)
+		}
+		if msg.Role == schema.Tool {
+			om.ToolName = toolNames[msg.ToolCallID]
+		}
+		messages = append(messages, om)
+	}
+
+	// 构建请求体
+	ollamaReq := map[string]interface{}{
+		"model":    *options.Model,
+		"messages": messages,
+		"stream":   stream,
+		"options": map[string]interface{}{
+			"temperature": *options.Temperature,
+			"num_predict": *options.MaxTokens,
+		},
+	}
+	if len(options.Tools) > 0 {
+		tools, err := toFunctionTools(options.Tools)
+		if err != nil {
+			return nil, err
+		}
+		ollamaReq["tools"] = tools
+	} else if m.schema != nil {
+		// 约束输出格式会抑制工具调用，只在没有绑定工具时启用
+		ollamaReq["format"] = m.schema.Schema
+	} else if m.jsonMode {
+		ollamaReq["format"] = "json"
+	}
+
+	// 将请求体转换为JSON
+	reqBody, err := json.Marshal(ollamaReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// 创建HTTP请求
+	url := strings.TrimRight(m.cfg.BaseURL, "/") + "/api/chat"
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// 设置请求头
+	httpReq.Header.Set("Content-Type", "application/json")
 
-	return &GenerateTextResponse{
-		Text: ollamaResp.Response,
-	}, nil
+	return httpReq, nil
+}
+
+// fromOllamaResponse 将Ollama的响应转换为eino消息，Ollama不返回调用ID，按callIndex生成
+func fromOllamaResponse(resp *ollamaChatResponse, callIndex *int) *schema.Message {
+	msg := &schema.Message{
+		Role:    schema.Assistant,
+		Content: resp.Message.Content,
+	}
+	if resp.Message.Thinking != "" {
+		msg.Extra = map[string]any{reasoningKey: resp.Message.Thinking}
+	}
+	for _, tc := range resp.Message.ToolCalls {
+		index := *callIndex
+		*callIndex++
+		arguments := string(tc.Function.Arguments)
+		if arguments == "" || arguments == "null" {
+			arguments = "{}"
+		}
+		msg.ToolCalls = append(msg.ToolCalls, schema.ToolCall{
+			Index: &index,
+			ID:    fmt.Sprintf("call_%d", index),
+			Type:  "function",
+			Function: schema.FunctionCall{
+				Name:      tc.Function.Name,
+				Arguments: arguments,
+			},
+		})
+	}
+	if resp.Done {
+		msg.ResponseMeta = &schema.ResponseMeta{
+			FinishReason: resp.DoneReason,
+			Usage: &schema.TokenUsage{
+				PromptTokens:     resp.PromptEvalCount,
+				CompletionTokens: resp.EvalCount,
+				TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+			},
+		}
+	}
+	return msg
 }
@@ -1,52 +1,146 @@
 package einosdk
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
 )
 
-// generateTextWithOpenAI 使用OpenAI API生成文本
-func (c *Client) generateTextWithOpenAI(ctx context.Context, req *GenerateTextRequest) (*GenerateTextResponse, error) {
-	if c.apiKey == "" {
-		return nil, fmt.Errorf("OpenAI API key is required")
+func init() {
+	Register(openAIProvider{})
+}
+
+// openAIProvider OpenAI模型提供者，兼容所有实现了对话补全接口的网关
+type openAIProvider struct{}
+
+// Type 返回模型类型
+func (openAIProvider) Type() ModelType {
+	return ModelTypeOpenAI
+}
+
+// Defaults 返回默认配置
+func (openAIProvider) Defaults() ProviderDefaults {
+	return ProviderDefaults{
+		Model:     "gpt-3.5-turbo",
+		BaseURL:   "https://api.openai.com/v1",
+		APIKeyEnv: "OPENAI_API_KEY",
 	}
+}
 
-	// 构建请求URL
-	url := "https://api.openai.com/v1/chat/completions"
+// Capabilities 返回支持的能力
+func (openAIProvider) Capabilities() Capabilities {
+	return Capabilities{
+		ToolCalling:      true,
+		Streaming:        true,
+		JSONMode:         true,
+		StructuredOutput: true,
+	}
+}
 
-	// 构建请求体
-	openaiReq := map[string]interface{}{
-		"model": c.model,
-		"messages": []map[string]string{
-			{"role": "user", "content": req.Prompt},
-		},
-		"temperature": req.Temperature,
-		"max_tokens":  req.MaxTokens,
+// Validate 校验配置
+func (openAIProvider) Validate(cfg *ProviderConfig) error {
+	if cfg.APIKey == "" {
+		return fmt.Errorf("OpenAI API key is required")
 	}
+	return nil
+}
 
-	// 将请求体转换为JSON
-	reqBody, err := json.Marshal(openaiReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+// GenerateText 使用OpenAI API生成文本
+func (p openAIProvider) GenerateText(ctx context.Context, cfg *ProviderConfig, req *GenerateTextRequest) (*GenerateTextResponse, error) {
+	return p.StreamText(ctx, cfg, req, newEmitter(nil))
+}
+
+// StreamText 使用OpenAI对话补全接口驱动react智能体，增量内容和工具调用通过emit回调
+func (openAIProvider) StreamText(ctx context.Context, cfg *ProviderConfig, req *GenerateTextRequest, emit StreamHandler) (*GenerateTextResponse, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("OpenAI API key is required")
 	}
 
-	// 创建HTTP请求
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	resp, err := runAgent(ctx, newOpenAIChatModel(cfg, req), req, emit, StreamToolCallChecker)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
+	}
+	if resp.Text == "" {
+		return nil, ErrNoTextGenerated
 	}
 
-	// 设置请求头
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	return resp, nil
+}
+
+// openAIChatModel 基于OpenAI对话补全接口的ToolCallingChatModel实现
+type openAIChatModel struct {
+	cfg         *ProviderConfig
+	temperature float32
+	maxTokens   int
+	jsonMode    bool
+	schema      *ResponseSchema
+	tools       []*schema.ToolInfo
+}
+
+// newOpenAIChatModel 创建OpenAI对话模型
+func newOpenAIChatModel(cfg *ProviderConfig, req *GenerateTextRequest) *openAIChatModel {
+	return &openAIChatModel{
+		cfg:         cfg,
+		temperature: req.Temperature,
+		maxTokens:   req.MaxTokens,
+		jsonMode:    req.JSONMode,
+		schema:      req.ResponseSchema,
+	}
+}
+
+// WithTools 返回绑定了工具的新模型实例
+func (m *openAIChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	nm := *m
+	nm.tools = tools
+	return &nm, nil
+}
+
+// openAIMessage 对话补全接口的消息格式
+type openAIMessage struct {
+	Role             string           `json:"role"`
+	Content          string           `json:"content"`
+	ReasoningContent string           `json:"reasoning_content,omitempty"`
+	Name             string           `json:"name,omitempty"`
+	ToolCalls        []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID       string           `json:"tool_call_id,omitempty"`
+}
+
+// openAIToolCall 对话补全接口的工具调用格式，流式响应中通过Index合并分片
+type openAIToolCall struct {
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
+// openAIUsage 对话补全接口的token用量
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Generate 以非流式方式调用对话补全接口
+func (m *openAIChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	httpReq, err := m.newRequest(ctx, input, false, opts...)
+	if err != nil {
+		return nil, err
+	}
 
 	// 发送请求
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := &http.Client{Timeout: 120 * time.Second}
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
@@ -61,28 +155,249 @@ func (c *Client) generateTextWithOpenAI(ctx context.Context, req *GenerateTextRe
 
 	// 检查响应状态码
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(respBody))
+		return nil, newAPIError(resp.StatusCode, respBody)
 	}
 
 	// 解析响应
 	var openaiResp struct {
 		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
+			Message      openAIMessage `json:"message"`
+			FinishReason string        `json:"finish_reason"`
 		} `json:"choices"`
+		Usage *openAIUsage `json:"usage"`
 	}
-
 	if err := json.Unmarshal(respBody, &openaiResp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
-
-	// 提取生成的文本
 	if len(openaiResp.Choices) == 0 {
-		return nil, fmt.Errorf("no text generated")
+		return nil, ErrNoTextGenerated
 	}
 
-	return &GenerateTextResponse{
-		Text: openaiResp.Choices[0].Message.Content,
-	}, nil
+	choice := openaiResp.Choices[0]
+	msg := fromOpenAIMessage(&choice.Message)
+	msg.ResponseMeta = &schema.ResponseMeta{
+		FinishReason: choice.FinishReason,
+		Usage:        toTokenUsage(openaiResp.Usage),
+	}
+	return msg, nil
+}
+
+// Stream 以SSE流式方式调用对话补全接口，数据格式为 "data: {...}"，以 "data: [DONE]" 结束
+func (m *openAIChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	httpReq, err := m.newRequest(ctx, input, true, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// 流式请求的总时长由ctx控制，不设置整体超时
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(resp.StatusCode, respBody)
+	}
+
+	reader, writer := schema.Pipe[*schema.Message](16)
+	go func() {
+		defer resp.Body.Close()
+		defer writer.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "[DONE]" {
+				return
+			}
+
+			var chunk struct {
+				Choices []struct {
+					Delta        openAIMessage `json:"delta"`
+					FinishReason string        `json:"finish_reason"`
+				} `json:"choices"`
+				Usage *openAIUsage `json:"usage"`
+			}
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				writer.Send(nil, fmt.Errorf("failed to unmarshal stream chunk: %w", err))
+				return
+			}
+
+			msg := &schema.Message{Role: schema.Assistant}
+			if len(chunk.Choices) > 0 {
+				msg = fromOpenAIMessage(&chunk.Choices[0].Delta)
+				if chunk.Choices[0].FinishReason != "" {
+					msg.ResponseMeta = &schema.ResponseMeta{FinishReason: chunk.Choices[0].FinishReason}
+				}
+			}
+			if chunk.Usage != nil {
+				if msg.ResponseMeta == nil {
+					msg.ResponseMeta = &schema.ResponseMeta{}
+				}
+				msg.ResponseMeta.Usage = toTokenUsage(chunk.Usage)
+			}
+			if writer.Send(msg, nil) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			writer.Send(nil, fmt.Errorf("failed to read stream: %w", err))
+		}
+	}()
+
+	return reader, nil
+}
+
+// newRequest 构建对话补全接口的HTTP请求，请求地址为BaseURL下的/chat/completions
+func (m *openAIChatModel) newRequest(ctx context.Context, input []*schema.Message, stream bool, opts ...model.Option) (*http.Request, error) {
+	options := model.GetCommonOptions(&model.Options{
+		Temperature: &m.temperature,
+		MaxTokens:   &m.maxTokens,
+		Model:       &m.cfg.Model,
+		Tools:       m.tools,
+	}, opts...)
+
+	messages := make([]openAIMessage, 0, len(input))
+	for _, msg := range input {
+		messages = append(messages, toOpenAIMessage(msg))
+	}
+
+	// 构建请求体
+	openaiReq := map[string]interface{}{
+		"model":       *options.Model,
+		"messages":    messages,
+		"temperature": *options.Temperature,
+		"max_tokens":  *options.MaxTokens,
+		"stream":      stream,
+	}
+	if len(options.Tools) > 0 {
+		tools, err := toFunctionTools(options.Tools)
+		if err != nil {
+			return nil, err
+		}
+		openaiReq["tools"] = tools
+	}
+	if stream {
+		// 要求在最后一个分片中返回token用量
+		openaiReq["stream_options"] = map[string]bool{"include_usage": true}
+	}
+	if m.schema != nil {
+		// 非严格模式，Schema中的可选字段和扩展关键字不会导致请求被拒绝
+		openaiReq["response_format"] = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   m.schema.Name,
+				"schema": m.schema.Schema,
+				"strict": false,
+			},
+		}
+	} else if m.jsonMode {
+		openaiReq["response_format"] = map[string]string{"type": "json_object"}
+	}
+
+	// 将请求体转换为JSON
+	reqBody, err := json.Marshal(openaiReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// 创建HTTP请求
+	url := strings.TrimRight(m.cfg.BaseURL, "/") + "/chat/completions"
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// 设置请求头
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+m.cfg.APIKey)
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	return httpReq, nil
+}
+
+// toOpenAIMessage 将eino消息转换为对话补全接口的消息
+func toOpenAIMessage(msg *schema.Message) openAIMessage {
+	m := openAIMessage{
+		Role:       string(msg.Role),
+		Content:    msg.Content,
+		Name:       msg.Name,
+		ToolCallID: msg.ToolCallID,
+	}
+	for _, tc := range msg.ToolCalls {
+		call := openAIToolCall{ID: tc.ID, Type: "function"}
+		call.Function.Name = tc.Function.Name
+		call.Function.Arguments = tc.Function.Arguments
+		m.ToolCalls = append(m.ToolCalls, call)
+	}
+	return m
+}
+
+// fromOpenAIMessage 将对话补全接口的消息或增量转换为eino消息
+func fromOpenAIMessage(m *openAIMessage) *schema.Message {
+	msg := &schema.Message{
+		Role:    schema.Assistant,
+		Content: m.Content,
+	}
+	if m.ReasoningContent != "" {
+		msg.Extra = map[string]any{reasoningKey: m.ReasoningContent}
+	}
+	for _, tc := range m.ToolCalls {
+		msg.ToolCalls = append(msg.ToolCalls, schema.ToolCall{
+			Index: tc.Index,
+			ID:    tc.ID,
+			Type:  tc.Type,
+			Function: schema.FunctionCall{
+				Name:      tc.Function.Name,
+				Arguments: tc.Function.Arguments,
+			},
+		})
+	}
+	return msg
+}
+
+// toFunctionTools 将工具描述转换为function calling格式
+func toFunctionTools(tools []*schema.ToolInfo) ([]map[string]interface{}, error) {
+	result := make([]map[string]interface{}, 0, len(tools))
+	for _, t := range tools {
+		var params interface{} = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		if t.ParamsOneOf != nil {
+			s, err := t.ToOpenAPIV3()
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert parameters of tool %s: %w", t.Name, err)
+			}
+			if s != nil {
+				params = s
+			}
+		}
+		result = append(result, map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        t.Name,
+				"description": t.Desc,
+				"parameters":  params,
+			},
+		})
+	}
+	return result, nil
+}
+
+// toTokenUsage 转换token用量
+func toTokenUsage(u *openAIUsage) *schema.TokenUsage {
+	if u == nil {
+		return nil
+	}
+	return &schema.TokenUsage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
 }
@@ -0,0 +1,111 @@
+package einosdk
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Capabilities 描述模型提供者支持的能力
+type Capabilities struct {
+	ToolCalling bool `json:"tool_calling"` // 是否支持智能体工具调用
+	Streaming   bool `json:"streaming"`    // 是否支持流式输出
+	JSONMode    bool `json:"json_mode"`    // 是否支持强制输出JSON
+	// StructuredOutput 是否支持按JSON Schema约束输出
+	StructuredOutput bool `json:"structured_output"`
+}
+
+// ProviderDefaults 模型提供者的默认配置
+type ProviderDefaults struct {
+	Model     string `json:"model"`
+	BaseURL   string `json:"base_url,omitempty"`
+	APIKeyEnv string `json:"api_key_env,omitempty"` // 未配置API密钥时读取的环境变量
+}
+
+// ProviderConfig 是调用提供者时使用的连接配置，已经合并了默认值
+type ProviderConfig struct {
+	Model   string
+	APIKey  string
+	BaseURL string
+}
+
+// Provider 是大模型提供者接口，新的后端实现该接口并通过Register注册
+type Provider interface {
+	// Type 返回提供者对应的模型类型
+	Type() ModelType
+	// Defaults 返回默认的模型名称、基础URL和API密钥环境变量
+	Defaults() ProviderDefaults
+	// Capabilities 返回提供者支持的能力
+	Capabilities() Capabilities
+	// Validate 校验连接配置是否可用
+	Validate(cfg *ProviderConfig) error
+	// GenerateText 生成文本
+	GenerateText(ctx context.Context, cfg *ProviderConfig, req *GenerateTextRequest) (*GenerateTextResponse, error)
+	// StreamText 以流式方式生成文本，Capabilities().Streaming为false时不会被调用
+	StreamText(ctx context.Context, cfg *ProviderConfig, req *GenerateTextRequest, emit StreamHandler) (*GenerateTextResponse, error)
+}
+
+// registry 保存所有已注册的提供者
+var registry = struct {
+	sync.RWMutex
+	providers map[ModelType]Provider
+}{
+	providers: make(map[ModelType]Provider),
+}
+
+// Register 注册模型提供者，重复注册同一类型会panic
+func Register(p Provider) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, exists := registry.providers[p.Type()]; exists {
+		panic(fmt.Sprintf("einosdk: provider %s already registered", p.Type()))
+	}
+	registry.providers[p.Type()] = p
+}
+
+// GetProvider 根据模型类型获取提供者
+func GetProvider(modelType ModelType) (Provider, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	p, ok := registry.providers[modelType]
+	return p, ok
+}
+
+// Providers 返回所有已注册的提供者，按类型名排序
+func Providers() []Provider {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	providers := make([]Provider, 0, len(registry.providers))
+	for _, p := range registry.providers {
+		providers = append(providers, p)
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Type() < providers[j].Type()
+	})
+	return providers
+}
+
+// ProviderTypes 返回所有已注册的模型类型名称，按名称排序
+func ProviderTypes() []string {
+	providers := Providers()
+	types := make([]string, 0, len(providers))
+	for _, p := range providers {
+		types = append(types, string(p.Type()))
+	}
+	return types
+}
+
+// ParseModelType 将字符串解析为已注册的模型类型
+func ParseModelType(s string) (ModelType, bool) {
+	_, ok := GetProvider(ModelType(s))
+	return ModelType(s), ok
+}
+
+// ValidateConfig 合并默认值后校验指定类型的连接配置
+func ValidateConfig(modelType ModelType, opts ...ClientOption) error {
+	return NewClient(modelType, opts...).Validate()
+}
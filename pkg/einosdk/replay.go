@@ -0,0 +1,314 @@
+package einosdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReplayMode 录制回放提供者的工作模式
+type ReplayMode string
+
+const (
+	ReplayModeReplay ReplayMode = "replay" // 只读取录制文件，缺失时返回ErrFixtureNotFound，不访问网络
+	ReplayModeRecord ReplayMode = "record" // 总是调用上游提供者并覆盖录制文件
+	ReplayModeAuto   ReplayMode = "auto"   // 有录制文件时回放，否则调用上游提供者并录制
+)
+
+// DefaultReplayDir 默认的录制文件目录
+const DefaultReplayDir = "testdata/llm_fixtures"
+
+// ErrFixtureNotFound 回放模式下没有与请求匹配的录制文件
+var ErrFixtureNotFound = errors.New("replay fixture not found")
+
+// replaySettings 录制回放提供者的全局设置，由ConfigureReplay修改
+var replaySettings = struct {
+	sync.RWMutex
+	dir  string
+	mode ReplayMode
+}{
+	dir:  DefaultReplayDir,
+	mode: ReplayModeReplay,
+}
+
+// ConfigureReplay 设置录制文件目录和工作模式，dir为空时使用DefaultReplayDir
+func ConfigureReplay(dir string, mode ReplayMode) error {
+	switch mode {
+	case ReplayModeReplay, ReplayModeRecord, ReplayModeAuto:
+	default:
+		return fmt.Errorf("unsupported replay mode: %s", mode)
+	}
+	if dir == "" {
+		dir = DefaultReplayDir
+	}
+
+	replaySettings.Lock()
+	defer replaySettings.Unlock()
+	replaySettings.dir = dir
+	replaySettings.mode = mode
+	return nil
+}
+
+// replayConfig 返回当前的录制文件目录和工作模式
+func replayConfig() (string, ReplayMode) {
+	replaySettings.RLock()
+	defer replaySettings.RUnlock()
+	return replaySettings.dir, replaySettings.mode
+}
+
+// ReplayFixture 一次录制的模型调用，包含智能体的工具调用过程
+type ReplayFixture struct {
+	Key        string         `json:"key"`      // 请求摘要，同时是文件名
+	Upstream   string         `json:"upstream"` // 录制时使用的上游提供者，格式为 类型:模型
+	Request    ReplayRequest  `json:"request"`
+	Events     []*StreamEvent `json:"events"` // 增量输出、推理和工具调用事件，回放时按顺序推送
+	Text       string         `json:"text"`
+	Usage      TokenUsage     `json:"usage"`
+	RecordedAt time.Time      `json:"recorded_at"`
+}
+
+// ReplayRequest 参与计算请求摘要的字段，温度和最大token数不影响匹配
+type ReplayRequest struct {
+	SystemPrompt   string          `json:"system_prompt,omitempty"`
+	Prompt         string          `json:"prompt"`
+	Tools          []string        `json:"tools,omitempty"` // 绑定的工具名称，已排序
+	JSONMode       bool            `json:"json_mode,omitempty"`
+	ResponseSchema json.RawMessage `json:"response_schema,omitempty"`
+}
+
+func init() {
+	Register(replayProvider{})
+}
+
+// replayProvider 录制回放提供者，模型名称为上游提供者，格式为 类型:模型，如 openai:gpt-4o-mini，
+// API密钥和基础URL原样传给上游。录制文件按请求摘要保存，回放时不访问网络
+type replayProvider struct{}
+
+// Type 返回模型类型
+func (replayProvider) Type() ModelType {
+	return ModelTypeReplay
+}
+
+// Defaults 返回默认配置，默认录制模拟模型的输出
+func (replayProvider) Defaults() ProviderDefaults {
+	return ProviderDefaults{
+		Model: string(ModelTypeMock),
+	}
+}
+
+// Capabilities 请求原样交给上游提供者处理，回放时总能按流式推送录制的事件
+func (replayProvider) Capabilities() Capabilities {
+	return Capabilities{
+		ToolCalling:      true,
+		Streaming:        true,
+		JSONMode:         true,
+		StructuredOutput: true,
+	}
+}
+
+// Validate 校验上游提供者，只回放时不需要上游的API密钥
+func (replayProvider) Validate(cfg *ProviderConfig) error {
+	upstream, err := replayUpstream(cfg)
+	if err != nil {
+		return err
+	}
+	if _, mode := replayConfig(); mode == ReplayModeReplay {
+		return nil
+	}
+	return upstream.Validate()
+}
+
+// GenerateText 回放或录制一次调用
+func (p replayProvider) GenerateText(ctx context.Context, cfg *ProviderConfig, req *GenerateTextRequest) (*GenerateTextResponse, error) {
+	return p.StreamText(ctx, cfg, req, newEmitter(nil))
+}
+
+// StreamText 有录制文件时按顺序推送录制的事件，否则按工作模式调用上游提供者并录制
+func (replayProvider) StreamText(ctx context.Context, cfg *ProviderConfig, req *GenerateTextRequest, emit StreamHandler) (*GenerateTextResponse, error) {
+	upstream, err := replayUpstream(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, mode := replayConfig()
+	fixture := &ReplayFixture{
+		Upstream: string(upstream.modelType) + ":" + upstream.model,
+		Request:  newReplayRequest(ctx, req),
+	}
+	fixture.Key = fixture.Request.key(fixture.Upstream)
+	path := filepath.Join(dir, fixture.Key+".json")
+
+	if mode != ReplayModeRecord {
+		recorded, err := loadFixture(path)
+		if err == nil {
+			return recorded.replay(ctx, emit)
+		}
+		if mode == ReplayModeReplay || !errors.Is(err, ErrFixtureNotFound) {
+			return nil, err
+		}
+	}
+
+	// 调用上游提供者，转发事件的同时记录下来
+	var mu sync.Mutex
+	resp, err := upstream.StreamText(ctx, req, func(event *StreamEvent) {
+		mu.Lock()
+		copied := *event
+		fixture.Events = append(fixture.Events, &copied)
+		mu.Unlock()
+		emit(event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fixture.Text = resp.Text
+	fixture.Usage = resp.Usage
+	fixture.RecordedAt = time.Now()
+	if err := saveFixture(path, fixture); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// replayUpstream 根据模型名称创建上游提供者的客户端
+func replayUpstream(cfg *ProviderConfig) (*Client, error) {
+	upstreamType, model, _ := strings.Cut(cfg.Model, ":")
+	if ModelType(upstreamType) == ModelTypeReplay {
+		return nil, fmt.Errorf("replay upstream cannot be replay")
+	}
+	if _, ok := GetProvider(ModelType(upstreamType)); !ok {
+		return nil, fmt.Errorf("unsupported replay upstream: %q, model name must be <type>:<model>", cfg.Model)
+	}
+
+	opts := []ClientOption{WithModel(model)}
+	if cfg.APIKey != "" {
+		opts = append(opts, WithAPIKey(cfg.APIKey))
+	}
+	if cfg.BaseURL != "" {
+		opts = append(opts, WithBaseURL(cfg.BaseURL))
+	}
+	return NewClient(ModelType(upstreamType), opts...), nil
+}
+
+// newReplayRequest 提取请求中参与匹配的字段
+func newReplayRequest(ctx context.Context, req *GenerateTextRequest) ReplayRequest {
+	r := ReplayRequest{
+		SystemPrompt: req.SystemPrompt,
+		Prompt:       req.Prompt,
+		JSONMode:     req.JSONMode,
+	}
+	if req.ResponseSchema != nil {
+		r.ResponseSchema = req.ResponseSchema.Schema
+	}
+	for _, t := range req.Tools {
+		info, err := t.Info(ctx)
+		if err != nil || info == nil {
+			continue
+		}
+		r.Tools = append(r.Tools, info.Name)
+	}
+	sort.Strings(r.Tools)
+	return r
+}
+
+// key 计算请求摘要，上游提供者不同的录制互不影响
+func (r ReplayRequest) key(upstream string) string {
+	data, _ := json.Marshal(struct {
+		Upstream string        `json:"upstream"`
+		Request  ReplayRequest `json:"request"`
+	}{upstream, r})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// replay 按顺序推送录制的事件，返回录制的完整输出。录制文件不区分智能体的各步，
+// ctx中有生成过程记录时把录制的工具调用和完整输出按顺序记录为步骤
+func (f *ReplayFixture) replay(ctx context.Context, emit StreamHandler) (*GenerateTextResponse, error) {
+	started := time.Now()
+	trace := traceFromContext(ctx)
+	var reasoning strings.Builder
+	var toolCall *StreamEvent
+	for _, event := range f.Events {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		emit(event)
+
+		switch event.Type {
+		case StreamEventReasoning:
+			reasoning.WriteString(event.Content)
+		case StreamEventToolCall:
+			toolCall = event
+		case StreamEventToolResult:
+			step := TraceStep{Type: TraceStepTool, ToolName: event.ToolName, Result: event.Result, Error: event.Error, StartedAt: started}
+			if toolCall != nil && toolCall.ToolName == event.ToolName {
+				step.Arguments = toolCall.Arguments
+			}
+			trace.add(step)
+			toolCall = nil
+		}
+	}
+
+	usage := f.Usage
+	trace.add(TraceStep{
+		Type:      TraceStepModel,
+		Content:   f.Text,
+		Reasoning: reasoning.String(),
+		Usage:     &usage,
+		StartedAt: started,
+		LatencyMs: time.Since(started).Milliseconds(),
+	})
+	return &GenerateTextResponse{Text: f.Text, Usage: f.Usage}, nil
+}
+
+// loadFixture 读取录制文件，文件不存在时返回ErrFixtureNotFound
+func loadFixture(path string) (*ReplayFixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrFixtureNotFound, path)
+		}
+		return nil, err
+	}
+
+	var fixture ReplayFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid replay fixture %s: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+// saveFixture 先写入临时文件再重命名，避免并发读取到不完整的录制文件
+func saveFixture(path string, fixture *ReplayFixture) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
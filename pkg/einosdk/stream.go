@@ -0,0 +1,136 @@
+package einosdk
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/flow/agent"
+	ub "github.com/cloudwego/eino/utils/callbacks"
+)
+
+// StreamEventType 表示流式生成过程中的事件类型
+type StreamEventType string
+
+const (
+	StreamEventDelta      StreamEventType = "delta"       // 模型输出的增量文本
+	StreamEventReasoning  StreamEventType = "reasoning"   // 模型的推理过程
+	StreamEventToolCall   StreamEventType = "tool_call"   // 智能体开始调用工具
+	StreamEventToolResult StreamEventType = "tool_result" // 工具调用返回结果
+	StreamEventFailover   StreamEventType = "failover"    // 切换到备用模型重新生成，此前的增量输出作废
+	StreamEventRepair     StreamEventType = "repair"      // 输出未通过校验，要求模型按校验错误修正
+	StreamEventStage      StreamEventType = "stage"       // 分阶段生成时一个阶段已完成
+)
+
+// StreamEvent 是流式生成过程中产生的事件
+type StreamEvent struct {
+	Type      StreamEventType `json:"type"`
+	Content   string          `json:"content,omitempty"`
+	ToolName  string          `json:"tool_name,omitempty"`
+	Arguments string          `json:"arguments,omitempty"`
+	Result    string          `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// StreamHandler 处理流式事件的回调函数
+type StreamHandler func(event *StreamEvent)
+
+// StreamText 以流式方式调用模型，生成过程中的事件通过handler回调，结束后返回完整文本
+// 提供者不支持流式输出时，生成完成后一次性推送完整文本
+func (c *Client) StreamText(ctx context.Context, req *GenerateTextRequest, handler StreamHandler) (*GenerateTextResponse, error) {
+	if c.provider == nil {
+		return nil, fmt.Errorf("unsupported model type: %s", c.modelType)
+	}
+	emit := newEmitter(handler)
+
+	if !c.provider.Capabilities().Streaming {
+		resp, err := c.provider.GenerateText(ctx, c.config(), req)
+		if err != nil {
+			return nil, err
+		}
+		emit(&StreamEvent{Type: StreamEventDelta, Content: resp.Text})
+		return resp, nil
+	}
+
+	return c.provider.StreamText(ctx, c.config(), req, emit)
+}
+
+// newEmitter 包装handler，保证并发调用时事件按顺序串行投递；handler为空时返回空操作
+func newEmitter(handler StreamHandler) StreamHandler {
+	if handler == nil {
+		return func(*StreamEvent) {}
+	}
+	var mu sync.Mutex
+	return func(event *StreamEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		handler(event)
+	}
+}
+
+// toolStart 工具调用开始时保存在context中的信息，结束时用于记录耗时和参数
+type toolStart struct {
+	at        time.Time
+	arguments string
+}
+
+// toolStartKey 是工具调用开始信息在context中的键
+type toolStartKey struct{}
+
+// toolEventOption 创建将工具调用转换为流式事件的智能体选项，trace不为空时同时记录每次工具调用的参数、结果和耗时
+func toolEventOption(emit StreamHandler, trace *Trace) agent.AgentOption {
+	// addToolStep 记录一次工具调用
+	addToolStep := func(ctx context.Context, name, result string, err error) {
+		start, _ := ctx.Value(toolStartKey{}).(*toolStart)
+		if trace == nil || start == nil {
+			return
+		}
+		step := TraceStep{
+			Type:      TraceStepTool,
+			ToolName:  name,
+			Arguments: start.arguments,
+			Result:    result,
+			StartedAt: start.at,
+			LatencyMs: time.Since(start.at).Milliseconds(),
+		}
+		if err != nil {
+			step.Error = err.Error()
+		}
+		trace.add(step)
+	}
+
+	toolHandler := &ub.ToolCallbackHandler{
+		OnStart: func(ctx context.Context, info *callbacks.RunInfo, input *tool.CallbackInput) context.Context {
+			emit(&StreamEvent{
+				Type:      StreamEventToolCall,
+				ToolName:  info.Name,
+				Arguments: input.ArgumentsInJSON,
+			})
+			return context.WithValue(ctx, toolStartKey{}, &toolStart{at: time.Now(), arguments: input.ArgumentsInJSON})
+		},
+		OnEnd: func(ctx context.Context, info *callbacks.RunInfo, output *tool.CallbackOutput) context.Context {
+			emit(&StreamEvent{
+				Type:     StreamEventToolResult,
+				ToolName: info.Name,
+				Result:   output.Response,
+			})
+			addToolStep(ctx, info.Name, output.Response, nil)
+			return ctx
+		},
+		OnError: func(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
+			emit(&StreamEvent{
+				Type:     StreamEventToolResult,
+				ToolName: info.Name,
+				Error:    err.Error(),
+			})
+			addToolStep(ctx, info.Name, "", err)
+			return ctx
+		},
+	}
+	handler := ub.NewHandlerHelper().Tool(toolHandler).Handler()
+	return agent.WithComposeOptions(compose.WithCallbacks(handler))
+}
@@ -0,0 +1,197 @@
+package einosdk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// TraceStepType 生成过程中步骤的类型
+type TraceStepType string
+
+const (
+	TraceStepModel TraceStepType = "model" // 一次模型调用
+	TraceStepTool  TraceStepType = "tool"  // 一次工具调用
+)
+
+// TraceStep 生成过程中的一个步骤，模型调用记录输出和推理内容，工具调用记录参数和结果
+type TraceStep struct {
+	Type      TraceStepType `json:"type" bson:"type"`
+	Content   string        `json:"content,omitempty" bson:"content,omitempty"`       // 模型输出的文本
+	Reasoning string        `json:"reasoning,omitempty" bson:"reasoning,omitempty"`   // 模型的推理内容
+	ToolCalls []string      `json:"tool_calls,omitempty" bson:"tool_calls,omitempty"` // 模型要求调用的工具名称
+	ToolName  string        `json:"tool_name,omitempty" bson:"tool_name,omitempty"`
+	Arguments string        `json:"arguments,omitempty" bson:"arguments,omitempty"`
+	Result    string        `json:"result,omitempty" bson:"result,omitempty"`
+	Error     string        `json:"error,omitempty" bson:"error,omitempty"`
+	Usage     *TokenUsage   `json:"usage,omitempty" bson:"usage,omitempty"`
+	StartedAt time.Time     `json:"started_at" bson:"started_at"`
+	LatencyMs int64         `json:"latency_ms" bson:"latency_ms"`
+}
+
+// Trace 记录一次生成中的每一步模型调用和工具调用，可以被并行的步骤同时写入
+type Trace struct {
+	mu      sync.Mutex
+	pending sync.WaitGroup // 还在读取中的流式输出
+	steps   []TraceStep
+}
+
+// traceKey 是生成过程记录在context中的键
+type traceKey struct{}
+
+// NewTrace 创建空的生成过程记录
+func NewTrace() *Trace {
+	return &Trace{}
+}
+
+// WithTrace 在context中附加生成过程记录，使用该context的调用会把步骤写入trace
+func WithTrace(ctx context.Context, trace *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// traceFromContext 获取context中的生成过程记录，不存在时返回nil
+func traceFromContext(ctx context.Context) *Trace {
+	trace, _ := ctx.Value(traceKey{}).(*Trace)
+	return trace
+}
+
+// Steps 等待流式输出读取完成后，返回按开始时间排序的步骤
+func (t *Trace) Steps() []TraceStep {
+	if t == nil {
+		return nil
+	}
+	t.pending.Wait()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	steps := make([]TraceStep, len(t.steps))
+	copy(steps, t.steps)
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].StartedAt.Before(steps[j].StartedAt)
+	})
+	return steps
+}
+
+// add 记录一个步骤，trace为空时不记录
+func (t *Trace) add(step TraceStep) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.steps = append(t.steps, step)
+}
+
+// addModelStep 根据模型输出的消息分片记录一次模型调用
+func (t *Trace) addModelStep(started time.Time, chunks []*schema.Message, err error) {
+	step := TraceStep{
+		Type:      TraceStepModel,
+		StartedAt: started,
+		LatencyMs: time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		step.Error = err.Error()
+	}
+
+	var content, reasoning strings.Builder
+	for _, chunk := range chunks {
+		if chunk == nil {
+			continue
+		}
+		content.WriteString(chunk.Content)
+		reasoning.WriteString(reasoningContent(chunk))
+		if chunk.ResponseMeta != nil && chunk.ResponseMeta.Usage != nil {
+			// 流式输出时以最后一次上报的用量为准
+			usage := TokenUsage{}
+			usage.Add(chunk.ResponseMeta.Usage)
+			step.Usage = &usage
+		}
+	}
+	step.Content = content.String()
+	step.Reasoning = reasoning.String()
+	if len(chunks) > 0 {
+		// 工具调用的参数分散在多个分片中，合并后再取工具名称
+		if msg, err := schema.ConcatMessages(chunks); err == nil {
+			for _, call := range msg.ToolCalls {
+				step.ToolCalls = append(step.ToolCalls, call.Function.Name)
+			}
+		}
+	}
+	t.add(step)
+}
+
+// tracingModel 包装对话模型，把每次调用的输出、推理内容和要求调用的工具写入trace
+type tracingModel struct {
+	inner model.ToolCallingChatModel
+	trace *Trace
+}
+
+// withTracing 为对话模型加上生成过程记录，trace为空时返回原模型
+func withTracing(inner model.ToolCallingChatModel, trace *Trace) model.ToolCallingChatModel {
+	if trace == nil {
+		return inner
+	}
+	return &tracingModel{inner: inner, trace: trace}
+}
+
+// Generate 调用被包装的模型并记录本次调用
+func (m *tracingModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	started := time.Now()
+	msg, err := m.inner.Generate(ctx, input, opts...)
+	m.trace.addModelStep(started, []*schema.Message{msg}, err)
+	return msg, err
+}
+
+// Stream 调用被包装的模型，复制一份流式输出在后台读取完整后记录本次调用，
+// 智能体检测到工具调用后提前关闭流时仍然能记录完整的输出
+func (m *tracingModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	started := time.Now()
+	sr, err := m.inner.Stream(ctx, input, opts...)
+	if err != nil {
+		m.trace.addModelStep(started, nil, err)
+		return nil, err
+	}
+
+	copies := sr.Copy(2)
+	m.trace.pending.Add(1)
+	go func() {
+		defer m.trace.pending.Done()
+		defer copies[1].Close()
+
+		var chunks []*schema.Message
+		for {
+			msg, err := copies[1].Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				m.trace.addModelStep(started, chunks, err)
+				return
+			}
+			chunks = append(chunks, msg)
+		}
+	}()
+	return copies[0], nil
+}
+
+// WithTools 绑定工具后继续记录
+func (m *tracingModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	inner, err := m.inner.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return withTracing(inner, m.trace), nil
+}
+
+// IsCallbacksEnabled 保持被包装模型自身的回调行为，避免回调被重复触发
+func (m *tracingModel) IsCallbacksEnabled() bool {
+	return components.IsCallbacksEnabled(m.inner)
+}
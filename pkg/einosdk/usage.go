@@ -0,0 +1,124 @@
+package einosdk
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// TokenUsage 一次生成消耗的token数量，智能体多步调用时为各步之和
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens" bson:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens" bson:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens" bson:"total_tokens"`
+}
+
+// Add 累加另一份用量
+func (u *TokenUsage) Add(other *schema.TokenUsage) {
+	if other == nil {
+		return
+	}
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	total := other.TotalTokens
+	if total == 0 {
+		total = other.PromptTokens + other.CompletionTokens
+	}
+	u.TotalTokens += total
+}
+
+// Merge 累加另一次生成的用量
+func (u *TokenUsage) Merge(other TokenUsage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// usageCounter 统计智能体每一步模型调用的token用量
+type usageCounter struct {
+	mu    sync.Mutex
+	calls []*schema.TokenUsage // 每次模型调用最后上报的用量
+}
+
+// track 登记一次模型调用，返回用于记录该次调用用量的槽位
+func (c *usageCounter) track() *schema.TokenUsage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	slot := &schema.TokenUsage{}
+	c.calls = append(c.calls, slot)
+	return slot
+}
+
+// record 将消息中的用量写入槽位，流式输出时以最后一次上报为准
+func (c *usageCounter) record(slot *schema.TokenUsage, msg *schema.Message) {
+	if msg == nil || msg.ResponseMeta == nil || msg.ResponseMeta.Usage == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*slot = *msg.ResponseMeta.Usage
+}
+
+// total 返回所有调用的用量之和
+func (c *usageCounter) total() TokenUsage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var usage TokenUsage
+	for _, call := range c.calls {
+		usage.Add(call)
+	}
+	return usage
+}
+
+// usageTrackingModel 包装对话模型，记录每次调用返回的token用量
+type usageTrackingModel struct {
+	inner   model.ToolCallingChatModel
+	counter *usageCounter
+}
+
+// withUsageTracking 为对话模型加上用量统计
+func withUsageTracking(inner model.ToolCallingChatModel, counter *usageCounter) model.ToolCallingChatModel {
+	return &usageTrackingModel{inner: inner, counter: counter}
+}
+
+// Generate 调用被包装的模型并记录用量
+func (m *usageTrackingModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	msg, err := m.inner.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m.counter.record(m.counter.track(), msg)
+	return msg, nil
+}
+
+// Stream 调用被包装的模型，在读取流的过程中记录用量
+func (m *usageTrackingModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	sr, err := m.inner.Stream(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	slot := m.counter.track()
+	return schema.StreamReaderWithConvert(sr, func(msg *schema.Message) (*schema.Message, error) {
+		m.counter.record(slot, msg)
+		return msg, nil
+	}), nil
+}
+
+// WithTools 绑定工具后继续统计用量
+func (m *usageTrackingModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	inner, err := m.inner.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return withUsageTracking(inner, m.counter), nil
+}
+
+// IsCallbacksEnabled 保持被包装模型自身的回调行为，避免回调被重复触发
+func (m *usageTrackingModel) IsCallbacksEnabled() bool {
+	return components.IsCallbacksEnabled(m.inner)
+}
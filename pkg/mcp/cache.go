@@ -0,0 +1,105 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ToolResultCache 工具调用结果的缓存，结果是调用结果(CallToolResult)的JSON，与eino工具返回给模型的内容相同
+type ToolResultCache interface {
+	// Get 返回未过期的结果，不缓存该工具时总是未命中
+	Get(ctx context.Context, provider, toolName string, arguments map[string]interface{}) (string, bool)
+	// Set 保存调用成功的结果，不缓存该工具时忽略
+	Set(ctx context.Context, provider, toolName string, arguments map[string]interface{}, result string)
+}
+
+// cachedProvider 先查缓存再调用工具的提供者，CallTool和GetTools返回的工具共用同一份缓存
+type cachedProvider struct {
+	MCPProvider
+	name  string
+	cache ToolResultCache
+}
+
+// NewCachedProvider 用缓存包装提供者，name是缓存中区分提供者的名称
+func NewCachedProvider(name string, provider MCPProvider, cache ToolResultCache) MCPProvider {
+	return &cachedProvider{MCPProvider: provider, name: name, cache: cache}
+}
+
+// GetTools 返回先查缓存再调用的工具
+func (p *cachedProvider) GetTools(ctx context.Context) ([]tool.BaseTool, error) {
+	tools, err := p.MCPProvider.GetTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := make([]tool.BaseTool, 0, len(tools))
+	for _, t := range tools {
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			cached = append(cached, t)
+			continue
+		}
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		cached = append(cached, &cachedTool{inner: invokable, name: info.Name, provider: p})
+	}
+	return cached, nil
+}
+
+// CallTool 命中缓存时直接返回缓存的结果，未命中时调用工具并缓存成功的结果
+func (p *cachedProvider) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	if cached, ok := p.cache.Get(ctx, p.name, toolName, arguments); ok {
+		raw := json.RawMessage(cached)
+		if result, err := mcp.ParseCallToolResult(&raw); err == nil {
+			return result, nil
+		}
+	}
+
+	result, err := p.MCPProvider.CallTool(ctx, toolName, arguments)
+	if err != nil || result.IsError {
+		return result, err
+	}
+	if data, err := json.Marshal(result); err == nil {
+		p.cache.Set(ctx, p.name, toolName, arguments, string(data))
+	}
+	return result, nil
+}
+
+// cachedTool 先查缓存再调用的eino工具，调用失败（包括工具返回isError）时不缓存
+type cachedTool struct {
+	inner    tool.InvokableTool
+	name     string
+	provider *cachedProvider
+}
+
+// Info 返回被包装工具的信息
+func (t *cachedTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return t.inner.Info(ctx)
+}
+
+// InvokableRun 命中缓存时直接返回缓存的结果，参数不是JSON对象时不使用缓存
+func (t *cachedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	arguments := map[string]interface{}{}
+	if argumentsInJSON != "" {
+		if err := json.Unmarshal([]byte(argumentsInJSON), &arguments); err != nil {
+			return t.inner.InvokableRun(ctx, argumentsInJSON, opts...)
+		}
+	}
+
+	cache := t.provider.cache
+	if cached, ok := cache.Get(ctx, t.provider.name, t.name, arguments); ok {
+		return cached, nil
+	}
+	result, err := t.inner.InvokableRun(ctx, argumentsInJSON, opts...)
+	if err != nil {
+		return result, err
+	}
+	cache.Set(ctx, t.provider.name, t.name, arguments, result)
+	return result, nil
+}
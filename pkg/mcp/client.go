@@ -2,23 +2,39 @@ package mcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/cloudwego/eino/components/tool"
+	"sort"
 	"sync"
 
+	"personatrip/internal/utils/logger"
+	"personatrip/pkg/mcp/providers/generic"
+
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
 // Client 是MCPClient接口的实现
 type Client struct {
 	providers map[string]MCPProvider
+	failures  map[string]providerFailure // 初始化失败的提供者，保留下来用于查看错误和重启
+	degraded  map[string]error           // 已连接但探测失败的提供者，恢复前不把它们的工具交给智能体
+	cache     ToolResultCache            // 为空时不缓存工具调用结果
 	mu        sync.RWMutex
 }
 
+// providerFailure 初始化失败的提供者及其错误
+type providerFailure struct {
+	provider MCPProvider
+	err      error
+}
+
 // NewClient 创建一个新的MCP客户端
 func NewClient() *Client {
 	return &Client{
 		providers: make(map[string]MCPProvider),
+		failures:  make(map[string]providerFailure),
+		degraded:  make(map[string]error),
 	}
 }
 
@@ -29,28 +45,272 @@ func (c *Client) AddProvider(name string, provider MCPProvider) {
 	c.providers[name] = provider
 }
 
-// Initialize 初始化所有提供者
-func (c *Client) Initialize(ctx context.Context) error {
+// SetToolCache 设置工具调用结果的缓存，之后GetTools返回的工具和CallTool先查缓存
+func (c *Client) SetToolCache(cache ToolResultCache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache = cache
+}
+
+// connected 返回已连接的提供者，设置了缓存时用缓存包装
+func (c *Client) connected(name string) (MCPProvider, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	return c.connectedLocked(name)
+}
 
+// connectedLocked 与connected相同，调用方需要持有锁
+func (c *Client) connectedLocked(name string) (MCPProvider, bool) {
+	provider, ok := c.providers[name]
+	if !ok {
+		return nil, false
+	}
+	if c.cache != nil {
+		provider = NewCachedProvider(name, provider, c.cache)
+	}
+	return provider, true
+}
+
+// Initialize 并行初始化所有提供者。初始化失败的提供者会被关闭并移到失败列表，不影响其他提供者，
+// 返回所有失败提供者的错误
+func (c *Client) Initialize(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	errs := make(map[string]error)
 	for name, provider := range c.providers {
-		if err := provider.Initialize(ctx); err != nil {
-			return fmt.Errorf("初始化提供者 %s 失败: %w", name, err)
+		wg.Add(1)
+		go func(name string, provider MCPProvider) {
+			defer wg.Done()
+			if err := provider.Initialize(ctx); err != nil {
+				errMu.Lock()
+				errs[name] = err
+				errMu.Unlock()
+			}
+		}(name, provider)
+	}
+	wg.Wait()
+
+	failed := make([]error, 0, len(errs))
+	for name, err := range errs {
+		provider := c.providers[name]
+		_ = provider.Close()
+		delete(c.providers, name)
+		c.failures[name] = providerFailure{provider: provider, err: err}
+		failed = append(failed, fmt.Errorf("初始化提供者 %s 失败: %w", name, err))
+	}
+	return errors.Join(failed...)
+}
+
+// ConnectProvider 初始化提供者并以name注册，替换同名的提供者，原来的提供者在新的初始化成功后才关闭。
+// 初始化失败时原来已连接的同名提供者保持不变，没有时记录到失败列表
+func (c *Client) ConnectProvider(ctx context.Context, name string, provider MCPProvider) error {
+	return c.connect(ctx, name, provider, nil)
+}
+
+// RestartProvider 按原来的配置重新连接提供者，初始化失败的提供者也可以重启。
+// 只支持按配置连接的通用提供者。重新连接期间提供者被移除或替换时放弃本次连接
+func (c *Client) RestartProvider(ctx context.Context, name string) error {
+	c.mu.RLock()
+	provider := c.currentLocked(name)
+	c.mu.RUnlock()
+
+	if provider == nil {
+		return fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+	}
+	configured, ok := provider.(*generic.Provider)
+	if !ok {
+		return fmt.Errorf("提供者 %s 不支持重启", name)
+	}
+	return c.connect(ctx, name, generic.NewProvider(configured.Config()), provider)
+}
+
+// connect 初始化提供者并以name注册。expected不为空时，只有name对应的提供者（已连接或初始化失败的）
+// 在初始化完成后仍然是expected才注册，否则关闭新的提供者并返回ErrProviderNotFound，
+// 避免初始化期间被移除的提供者又被加回来
+func (c *Client) connect(ctx context.Context, name string, provider MCPProvider, expected MCPProvider) error {
+	if err := provider.Initialize(ctx); err != nil {
+		_ = provider.Close()
+		c.mu.Lock()
+		_, connected := c.providers[name]
+		if !connected && (expected == nil || c.currentLocked(name) == expected) {
+			c.failures[name] = providerFailure{provider: provider, err: err}
 		}
+		c.mu.Unlock()
+		return fmt.Errorf("初始化提供者 %s 失败: %w", name, err)
+	}
+
+	c.mu.Lock()
+	if expected != nil && c.currentLocked(name) != expected {
+		c.mu.Unlock()
+		_ = provider.Close()
+		return fmt.Errorf("%w: %s 在重新连接期间被移除或替换", ErrProviderNotFound, name)
 	}
+	old := c.providers[name]
+	c.providers[name] = provider
+	delete(c.failures, name)
+	delete(c.degraded, name)
+	c.mu.Unlock()
 
+	if old != nil {
+		if err := old.Close(); err != nil {
+			logger.Warnf("关闭被替换的提供者 %s 失败: %v", name, err)
+		}
+	}
 	return nil
 }
 
-// GetTools 获取指定提供者的所有工具
-func (c *Client) GetTools(ctx context.Context, providerName string) ([]tool.BaseTool, error) {
+// currentLocked 返回name对应的已连接或初始化失败的提供者，不存在时返回nil，调用方需要持有锁
+func (c *Client) currentLocked(name string) MCPProvider {
+	if provider, ok := c.providers[name]; ok {
+		return provider
+	}
+	return c.failures[name].provider
+}
+
+// RemoveProvider 关闭并移除提供者，包括初始化失败的提供者
+func (c *Client) RemoveProvider(name string) error {
+	c.mu.Lock()
+	provider, connected := c.providers[name]
+	_, failed := c.failures[name]
+	delete(c.providers, name)
+	delete(c.failures, name)
+	delete(c.degraded, name)
+	c.mu.Unlock()
+
+	if !connected && !failed {
+		return fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+	}
+	if connected {
+		return provider.Close()
+	}
+	return nil
+}
+
+// ProviderConfig 返回按配置连接的提供者的配置，其他类型的提供者返回false
+func (c *Client) ProviderConfig(name string) (generic.ServerConfig, bool) {
+	c.mu.RLock()
+	provider, ok := c.providers[name]
+	if !ok {
+		provider = c.failures[name].provider
+	}
+	c.mu.RUnlock()
+
+	configured, ok := provider.(*generic.Provider)
+	if !ok {
+		return generic.ServerConfig{}, false
+	}
+	return configured.Config(), true
+}
+
+// PingProvider 检查已连接的提供者是否存活，不支持Ping的提供者总是返回nil
+func (c *Client) PingProvider(ctx context.Context, name string) error {
 	c.mu.RLock()
-	provider, ok := c.providers[providerName]
+	provider, ok := c.providers[name]
 	c.mu.RUnlock()
 
 	if !ok {
-		return nil, fmt.Errorf("提供者 %s 不存在", providerName)
+		return fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+	}
+	pinger, ok := provider.(Pinger)
+	if !ok {
+		return nil
+	}
+	return pinger.Ping(ctx)
+}
+
+// MarkDegraded 标记已连接的提供者探测失败，重新连接成功或MarkHealthy后恢复
+func (c *Client) MarkDegraded(name string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.providers[name]; ok {
+		c.degraded[name] = err
+	}
+}
+
+// MarkHealthy 清除提供者的探测失败标记
+func (c *Client) MarkHealthy(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.degraded, name)
+}
+
+// DegradedProviders 返回已连接但探测失败的提供者及其错误
+func (c *Client) DegradedProviders() map[string]error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	degraded := make(map[string]error, len(c.degraded))
+	for name, err := range c.degraded {
+		degraded[name] = err
+	}
+	return degraded
+}
+
+// HealthyProviderNames 返回已连接且没有被标记为探测失败的提供者名称，按名称排序
+func (c *Client) HealthyProviderNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.providers))
+	for name := range c.providers {
+		if _, ok := c.degraded[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsConnected 提供者是否已连接
+func (c *Client) IsConnected(name string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.providers[name]
+	return ok
+}
+
+// HasProvider 是否有该名称的提供者，包括初始化失败的提供者
+func (c *Client) HasProvider(name string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, connected := c.providers[name]
+	_, failed := c.failures[name]
+	return connected || failed
+}
+
+// FailedProviders 返回初始化失败的提供者及其错误
+func (c *Client) FailedProviders() map[string]error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	failures := make(map[string]error, len(c.failures))
+	for name, failure := range c.failures {
+		failures[name] = failure.err
+	}
+	return failures
+}
+
+// ProviderNames 返回所有已注册的提供者名称，按名称排序
+func (c *Client) ProviderNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.providers))
+	for name := range c.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetTools 获取指定提供者的所有工具
+func (c *Client) GetTools(ctx context.Context, providerName string) ([]tool.BaseTool, error) {
+	provider, ok := c.connected(providerName)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, providerName)
 	}
 
 	return provider.GetTools(ctx)
@@ -102,7 +362,8 @@ func (c *Client) GetAllTools(ctx context.Context) (map[string][]tool.BaseTool, e
 	result := make(map[string][]tool.BaseTool)
 	var errs []error
 
-	for name, provider := range c.providers {
+	for name := range c.providers {
+		provider, _ := c.connectedLocked(name)
 		tools, err := provider.GetTools(ctx)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("获取提供者 %s 的工具失败: %w", name, err))
@@ -120,12 +381,9 @@ func (c *Client) GetAllTools(ctx context.Context) (map[string][]tool.BaseTool, e
 
 // CallTool 调用指定提供者的指定工具
 func (c *Client) CallTool(ctx context.Context, providerName, toolName string, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
-	c.mu.RLock()
-	provider, ok := c.providers[providerName]
-	c.mu.RUnlock()
-
+	provider, ok := c.connected(providerName)
 	if !ok {
-		return nil, fmt.Errorf("提供者 %s 不存在", providerName)
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, providerName)
 	}
 
 	return provider.CallTool(ctx, toolName, arguments)
@@ -1,5 +1,10 @@
 package mcp
 
+import "errors"
+
+// ErrProviderNotFound 提供者不存在
+var ErrProviderNotFound = errors.New("MCP提供者不存在")
+
 // 提供者名称常量
 const (
 	// ProviderAMap 高德地图提供者名称
@@ -3,28 +3,39 @@ package mcp
 import (
 	"context"
 	"fmt"
+	"os"
 	"personatrip/internal/config"
 	"personatrip/internal/utils/logger"
 
 	"personatrip/pkg/mcp/providers/amap"
+	"personatrip/pkg/mcp/providers/generic"
 )
 
 // InitOptions 初始化选项
 type InitOptions struct {
 	// AMapAPIKey 高德地图API密钥，如果为空则不初始化高德地图提供者
 	AMapAPIKey string
-	// 其他提供者的配置可以在这里添加
+	// Servers 配置文件中声明的MCP服务器，与高德地图同名时替换内置的高德地图提供者
+	Servers []generic.ServerConfig
 }
 
-// NewInitOptionsFromConfig 从应用配置创建初始化选项
-func NewInitOptionsFromConfig(cfg *config.Config) *InitOptions {
+// NewInitOptionsFromConfig 从应用配置创建初始化选项，配置了MCP服务器文件时读取其中的服务器
+func NewInitOptionsFromConfig(cfg *config.Config) (*InitOptions, error) {
 	if cfg == nil || cfg.MCPConfig == nil {
-		return &InitOptions{}
+		return &InitOptions{}, nil
 	}
 
-	return &InitOptions{
+	opts := &InitOptions{
 		AMapAPIKey: cfg.MCPConfig.AMapAPIKey,
 	}
+	if cfg.MCPConfig.ServersFile != "" {
+		servers, err := generic.LoadServerConfigs(cfg.MCPConfig.ServersFile)
+		if err != nil {
+			return opts, err
+		}
+		opts.Servers = servers
+	}
+	return opts, nil
 }
 
 // InitMCPClient 初始化MCP客户端及所有配置的提供者
@@ -35,8 +46,8 @@ func InitMCPClient(ctx context.Context, opts *InitOptions) (*Client, error) {
 		if err != nil {
 			logger.Warnf("加载配置文件失败，使用默认选项: %v", err)
 			opts = &InitOptions{}
-		} else {
-			opts = NewInitOptionsFromConfig(cfg)
+		} else if opts, err = NewInitOptionsFromConfig(cfg); err != nil {
+			logger.Warnf("加载MCP服务器配置失败，只使用内置提供者: %v", err)
 		}
 	}
 
@@ -48,14 +59,17 @@ func InitMCPClient(ctx context.Context, opts *InitOptions) (*Client, error) {
 		client.AddProvider(ProviderAMap, amapProvider)
 	}
 
-	// 这里可以添加其他提供者的初始化
+	// 初始化配置文件中声明的MCP服务器
+	for _, server := range opts.Servers {
+		client.AddProvider(server.Name, generic.NewProvider(server))
+	}
 
-	// 初始化所有提供者
+	// 初始化所有提供者，失败的提供者可以之后通过管理接口重启
 	if err := client.Initialize(ctx); err != nil {
-		return client, fmt.Errorf("初始化MCP客户端失败: %w", err)
+		logger.Warnf("部分MCP提供者初始化失败: %v", err)
 	}
 
-	logger.Infof("MCP客户端初始化成功")
+	logger.Infof("MCP客户端初始化完成，已连接的提供者: %v", client.ProviderNames())
 
 	// 打印所有已加载的工具
 	PrintLoadedTools(ctx, client)
@@ -63,7 +77,7 @@ func InitMCPClient(ctx context.Context, opts *InitOptions) (*Client, error) {
 	return client, nil
 }
 
-// PrintLoadedTools 打印所有已加载的工具
+// PrintLoadedTools 把所有已加载的工具打印到标准错误，标准输出可能被stdio模式的MCP服务占用
 func PrintLoadedTools(ctx context.Context, client *Client) {
 	tools, err := client.GetAllTools(ctx)
 	if err != nil {
@@ -71,15 +85,16 @@ func PrintLoadedTools(ctx context.Context, client *Client) {
 		return
 	}
 
-	fmt.Println("已加载的MCP工具:")
+	fmt.Fprintln(os.Stderr, "已加载的MCP工具:")
 	for provider, providerTools := range tools {
-		fmt.Println("提供者: %s\n", provider)
+		fmt.Fprintf(os.Stderr, "提供者: %s\n", provider)
 		for _, tool := range providerTools {
 			toolInfo, err := tool.Info(ctx)
 			if err != nil {
 				logger.Errorf("tool info failure: %v", err)
+				continue
 			}
-			fmt.Printf("  - %s: %s\n", toolInfo.Name, toolInfo.Desc)
+			fmt.Fprintf(os.Stderr, "  - %s: %s\n", toolInfo.Name, toolInfo.Desc)
 		}
 	}
 }
@@ -18,3 +18,9 @@ type MCPProvider interface {
 	// CallTool 调用指定的工具
 	CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (*mcp.CallToolResult, error)
 }
+
+// Pinger 是可以检查连接是否存活的提供者，不支持的提供者总是视为存活
+type Pinger interface {
+	// Ping 检查服务器是否仍然可以响应请求
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,128 @@
+package generic
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MCP服务器的传输方式
+const (
+	TransportStdio = "stdio" // 启动子进程，通过标准输入输出通信
+	TransportSSE   = "sse"   // 通过SSE连接远程服务器
+	TransportHTTP  = "http"  // 通过Streamable HTTP连接远程服务器
+)
+
+// defaultInitTimeout 未配置时连接和初始化MCP服务器的最长时间
+const defaultInitTimeout = 30 * time.Second
+
+// namePattern 服务器名称只能包含字母、数字、下划线和连字符
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ServerConfig 一个MCP服务器的配置
+type ServerConfig struct {
+	Name      string            `json:"name" yaml:"name"`                           // 提供者名称，在所有MCP服务器中唯一
+	Transport string            `json:"transport" yaml:"transport"`                 // stdio、sse或http
+	Command   string            `json:"command,omitempty" yaml:"command,omitempty"` // stdio: 启动的命令
+	Args      []string          `json:"args,omitempty" yaml:"args,omitempty"`       // stdio: 命令参数
+	Env       map[string]string `json:"env,omitempty" yaml:"env,omitempty"`         // stdio: 附加的环境变量
+	URL       string            `json:"url,omitempty" yaml:"url,omitempty"`         // sse/http: 服务器地址
+	Headers   map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"` // sse/http: 附加的请求头
+	Timeout   string            `json:"timeout,omitempty" yaml:"timeout,omitempty"` // 连接和初始化的最长时间，如"30s"
+	Disabled  bool              `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+}
+
+// Validate 校验配置是否完整
+func (c *ServerConfig) Validate() error {
+	if !namePattern.MatchString(c.Name) {
+		return fmt.Errorf("MCP服务器名称只能包含字母、数字、下划线和连字符: %q", c.Name)
+	}
+	switch c.Transport {
+	case TransportStdio:
+		if c.Command == "" {
+			return fmt.Errorf("MCP服务器%s使用stdio时必须配置command", c.Name)
+		}
+	case TransportSSE, TransportHTTP:
+		if c.URL == "" {
+			return fmt.Errorf("MCP服务器%s使用%s时必须配置url", c.Name, c.Transport)
+		}
+	default:
+		return fmt.Errorf("MCP服务器%s的transport必须是stdio、sse或http: %q", c.Name, c.Transport)
+	}
+	if c.Timeout != "" {
+		if d, err := time.ParseDuration(c.Timeout); err != nil || d <= 0 {
+			return fmt.Errorf("MCP服务器%s的timeout无效: %q", c.Name, c.Timeout)
+		}
+	}
+	return nil
+}
+
+// InitTimeout 连接和初始化的最长时间
+func (c *ServerConfig) InitTimeout() time.Duration {
+	if d, err := time.ParseDuration(c.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return defaultInitTimeout
+}
+
+// expandEnv 展开配置中的${VAR}环境变量引用，密钥可以只保存在环境变量中
+func (c ServerConfig) expandEnv() ServerConfig {
+	c.Command = os.ExpandEnv(c.Command)
+	c.URL = os.ExpandEnv(c.URL)
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = os.ExpandEnv(arg)
+	}
+	c.Args = args
+	c.Env = expandMap(c.Env)
+	c.Headers = expandMap(c.Headers)
+	return c
+}
+
+// expandMap 展开map中各个值的环境变量引用
+func expandMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	expanded := make(map[string]string, len(m))
+	for k, v := range m {
+		expanded[k] = os.ExpandEnv(v)
+	}
+	return expanded
+}
+
+// LoadServerConfigs 读取MCP服务器配置文件。文件为YAML或JSON格式，顶层是servers数组，
+// 配置中的${VAR}在连接时替换为环境变量的值。返回的配置已校验并跳过了disabled的服务器
+func LoadServerConfigs(path string) ([]ServerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取MCP服务器配置失败: %w", err)
+	}
+
+	// YAML兼容JSON，两种格式都用YAML解析
+	var file struct {
+		Servers []ServerConfig `yaml:"servers"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析MCP服务器配置%s失败: %w", path, err)
+	}
+
+	names := make(map[string]bool, len(file.Servers))
+	servers := make([]ServerConfig, 0, len(file.Servers))
+	for _, server := range file.Servers {
+		if err := server.Validate(); err != nil {
+			return nil, err
+		}
+		if names[server.Name] {
+			return nil, fmt.Errorf("MCP服务器名称重复: %s", server.Name)
+		}
+		names[server.Name] = true
+		if !server.Disabled {
+			servers = append(servers, server)
+		}
+	}
+	return servers, nil
+}
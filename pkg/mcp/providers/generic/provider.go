@@ -0,0 +1,189 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	tmcp "github.com/cloudwego/eino-ext/components/tool/mcp"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Provider 按配置连接任意MCP服务器的工具提供者
+type Provider struct {
+	cfg ServerConfig
+
+	mu      sync.RWMutex
+	client  *client.Client
+	tools   []tool.BaseTool
+	noTools bool               // 服务器没有声明tools能力，不请求工具列表
+	cancel  context.CancelFunc // 结束stdio子进程或SSE连接
+}
+
+// NewProvider 创建通用MCP提供者，调用Initialize后才连接服务器
+func NewProvider(cfg ServerConfig) *Provider {
+	return &Provider{cfg: cfg}
+}
+
+// Config 返回提供者的配置
+func (p *Provider) Config() ServerConfig {
+	return p.cfg
+}
+
+// Initialize 连接MCP服务器并完成初始化握手，然后缓存工具列表。
+// ctx只用于初始化，stdio子进程和SSE连接一直保持到Close
+func (p *Provider) Initialize(ctx context.Context) error {
+	cfg := p.cfg.expandEnv()
+	cli, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	connCtx, cancel := context.WithCancel(context.Background())
+	if err := cli.Start(connCtx); err != nil {
+		cancel()
+		return fmt.Errorf("连接MCP服务器%s失败: %w", cfg.Name, err)
+	}
+
+	initCtx, cancelInit := context.WithTimeout(ctx, cfg.InitTimeout())
+	defer cancelInit()
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    "personatrip-client",
+		Version: "1.0.0",
+	}
+	result, err := cli.Initialize(initCtx, initRequest)
+	if err != nil {
+		cli.Close()
+		cancel()
+		return fmt.Errorf("初始化MCP服务器%s失败: %w", cfg.Name, err)
+	}
+
+	p.mu.Lock()
+	p.client = cli
+	p.cancel = cancel
+	p.noTools = result.Capabilities.Tools == nil
+	p.mu.Unlock()
+
+	return p.refreshTools(initCtx)
+}
+
+// newClient 根据传输方式创建尚未连接的MCP客户端
+func newClient(cfg ServerConfig) (*client.Client, error) {
+	switch cfg.Transport {
+	case TransportStdio:
+		return client.NewClient(transport.NewStdio(cfg.Command, envList(cfg.Env), cfg.Args...)), nil
+	case TransportSSE:
+		return client.NewSSEMCPClient(cfg.URL, transport.WithHeaders(cfg.Headers))
+	case TransportHTTP:
+		return client.NewStreamableHttpClient(cfg.URL, transport.WithHTTPHeaders(cfg.Headers))
+	default:
+		return nil, fmt.Errorf("不支持的MCP传输方式: %s", cfg.Transport)
+	}
+}
+
+// envList 把环境变量转换为KEY=VALUE列表，按名称排序保证子进程环境稳定
+func envList(env map[string]string) []string {
+	list := make([]string, 0, len(env))
+	for k, v := range env {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// refreshTools 刷新工具列表
+func (p *Provider) refreshTools(ctx context.Context) error {
+	cli, err := p.currentClient()
+	if err != nil {
+		return err
+	}
+	p.mu.RLock()
+	noTools := p.noTools
+	p.mu.RUnlock()
+	if noTools {
+		return nil
+	}
+	tools, err := tmcp.GetTools(ctx, &tmcp.Config{Cli: cli})
+	if err != nil {
+		return fmt.Errorf("获取MCP服务器%s的工具失败: %w", p.cfg.Name, err)
+	}
+
+	p.mu.Lock()
+	p.tools = tools
+	p.mu.Unlock()
+	return nil
+}
+
+// GetTools 获取服务器提供的所有工具
+func (p *Provider) GetTools(ctx context.Context) ([]tool.BaseTool, error) {
+	p.mu.RLock()
+	cached := len(p.tools) > 0
+	p.mu.RUnlock()
+
+	// 如果工具列表为空，则刷新
+	if !cached {
+		if err := p.refreshTools(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	tools := make([]tool.BaseTool, len(p.tools))
+	copy(tools, p.tools)
+	return tools, nil
+}
+
+// CallTool 调用指定的工具
+func (p *Provider) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	cli, err := p.currentClient()
+	if err != nil {
+		return nil, err
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Name = toolName
+	request.Params.Arguments = arguments
+	return cli.CallTool(ctx, request)
+}
+
+// Ping 检查服务器是否仍然可以响应请求，stdio服务器的子进程退出后返回错误
+func (p *Provider) Ping(ctx context.Context) error {
+	cli, err := p.currentClient()
+	if err != nil {
+		return err
+	}
+	return cli.Ping(ctx)
+}
+
+// Close 关闭连接，stdio服务器的子进程随之退出
+func (p *Provider) Close() error {
+	p.mu.Lock()
+	cli, cancel := p.client, p.cancel
+	p.client, p.cancel, p.tools, p.noTools = nil, nil, nil, false
+	p.mu.Unlock()
+
+	var err error
+	if cli != nil {
+		err = cli.Close()
+	}
+	if cancel != nil {
+		cancel()
+	}
+	return err
+}
+
+// currentClient 返回已连接的MCP客户端
+func (p *Provider) currentClient() (*client.Client, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.client == nil {
+		return nil, fmt.Errorf("MCP服务器%s未连接", p.cfg.Name)
+	}
+	return p.client, nil
+}